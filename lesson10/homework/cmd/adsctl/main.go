@@ -0,0 +1,20 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"homework10/internal/cli/adsctl"
+	"log"
+	"os"
+)
+
+func main() {
+	err := adsctl.Run(context.Background(), os.Args[1:], os.Stdout)
+	if errors.Is(err, adsctl.ErrUsage) {
+		log.Println(err)
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"homework10/internal/cli/adsd"
+	"log"
+	"os"
+)
+
+func main() {
+	err := adsd.Run(context.Background(), os.Args[1:], os.Stdout)
+	if errors.Is(err, adsd.ErrUsage) {
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
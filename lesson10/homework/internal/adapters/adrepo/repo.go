@@ -1,51 +1,99 @@
 package adrepo
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"homework10/internal/ads"
 	"homework10/internal/app"
-	"sync"
+	"homework10/internal/geo"
+	"maps"
+	"sort"
+	"time"
+
+	"golang.org/x/sync/semaphore"
 )
 
 type repo struct {
-	mtx       sync.RWMutex
+	// sem is the storage lock: a reader holds one unit, a writer all of
+	// them. Unlike sync.RWMutex it can be given up while waiting.
+	sem       *semaphore.Weighted
 	index     int64
 	adStorage map[int64]ads.Ad
+	// grid indexes the ads with a location
+	grid     *geo.Grid
+	cellSize float64
+	lockWait func(time.Duration)
 }
 
-func (r *repo) AppendAd(Title string, Text string, AuthorID int64) *ads.Ad {
-	r.mtx.Lock()
+func (r *repo) AppendAd(ctx context.Context, Title string, Text string, AuthorID int64, Location *ads.Location, Offer *ads.Offer) (*ads.Ad, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
 	ad := ads.CreateAd(r.index, Title, Text, AuthorID)
 	r.index++
+	if Location != nil {
+		loc := *Location
+		ad.Location = &loc
+		r.grid.Insert(ad.ID, loc.Point())
+	}
+	if Offer != nil {
+		o := *Offer
+		ad.Offer = &o
+	}
 	r.adStorage[ad.ID] = ad
-	r.mtx.Unlock()
-	return &ad
+	r.unlock()
+	return &ad, nil
 }
 
-func (r *repo) ChangeAdStatus(ID int64, status bool) {
-	r.mtx.Lock()
-	ad := r.adStorage[ID]
+func (r *repo) ChangeAdStatus(ctx context.Context, ID int64, status bool) error {
+	if err := r.lock(ctx); err != nil {
+		return err
+	}
+	defer r.unlock()
+	ad, ok := r.adStorage[ID]
+	if !ok {
+		return errors.New("not found")
+	}
 	ad.ChangeAdStatus(status)
 	r.adStorage[ad.ID] = ad
-	r.mtx.Unlock()
+	return nil
 }
 
-func (r *repo) UpdateAd(ID int64, Text string, Title string) {
-	r.mtx.Lock()
-	ad := r.adStorage[ID]
+func (r *repo) UpdateAd(ctx context.Context, ID int64, Text string, Title string, Location *ads.Location, Offer *ads.Offer) error {
+	if err := r.lock(ctx); err != nil {
+		return err
+	}
+	defer r.unlock()
+	ad, ok := r.adStorage[ID]
+	if !ok {
+		return errors.New("not found")
+	}
 	if len(Text) > 0 {
 		ad.UpdateText(Text)
 	}
 	if len(Title) > 0 {
 		ad.UpdateTitle(Title)
 	}
+	if Location != nil {
+		if ad.Location != nil {
+			r.grid.Remove(ad.ID, ad.Location.Point())
+		}
+		ad.UpdateLocation(*Location)
+		r.grid.Insert(ad.ID, Location.Point())
+	}
+	if Offer != nil {
+		ad.UpdateOffer(*Offer)
+	}
 	r.adStorage[ad.ID] = ad
-	r.mtx.Unlock()
+	return nil
 }
 
-func (r *repo) GetAdByID(ID int64) (*ads.Ad, error) {
-	r.mtx.RLock()
-	defer r.mtx.RUnlock()
+func (r *repo) GetAdByID(ctx context.Context, ID int64) (*ads.Ad, error) {
+	if err := r.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.runlock()
 	a, ok := r.adStorage[ID]
 	if !ok {
 		return nil, errors.New("not found")
@@ -53,29 +101,138 @@ func (r *repo) GetAdByID(ID int64) (*ads.Ad, error) {
 	return &a, nil
 }
 
-func (r *repo) Select(f func(ads.Ad) bool) []ads.Ad {
-	r.mtx.RLock()
+func (r *repo) Select(ctx context.Context, f func(ads.Ad) bool) ([]ads.Ad, error) {
+	if err := r.rlock(ctx); err != nil {
+		return nil, err
+	}
 	resultArray := make([]ads.Ad, 0)
 	for _, v := range r.adStorage {
 		if f(v) {
 			resultArray = append(resultArray, v)
 		}
 	}
-	r.mtx.RUnlock()
-	return resultArray
+	r.runlock()
+	sort.Slice(resultArray, func(i, j int) bool { return resultArray[i].ID < resultArray[j].ID })
+	return resultArray, nil
 }
 
-func (r *repo) DeleteAd(ID int64) (*ads.Ad, error) {
-	r.mtx.Lock()
-	defer r.mtx.Unlock()
+// SelectArea finds the ads in the cells of the grid covering box, so it
+// does not scan the storage.
+func (r *repo) SelectArea(ctx context.Context, box geo.Box, f func(ads.Ad) bool) ([]ads.Ad, error) {
+	if err := r.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.runlock()
+	resultArray := make([]ads.Ad, 0)
+	r.grid.Search(box, func(id int64, _ geo.Point) {
+		if v := r.adStorage[id]; f(v) {
+			resultArray = append(resultArray, v)
+		}
+	})
+	return resultArray, nil
+}
+
+func (r *repo) DeleteAd(ctx context.Context, ID int64) (*ads.Ad, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.unlock()
 	a, ok := r.adStorage[ID]
 	if !ok {
 		return nil, errors.New("not found")
 	}
 	delete(r.adStorage, a.ID)
+	if a.Location != nil {
+		r.grid.Remove(a.ID, a.Location.Point())
+	}
 	return &a, nil
 }
 
-func New() app.AdRepository {
-	return &repo{index: 0, adStorage: map[int64]ads.Ad{}}
+// Transaction runs fn on a copy of the storage under the write lock and
+// swaps the copy in when fn succeeds. The copies of the storage and the grid
+// make a transaction O(n) in the number of ads.
+func (r *repo) Transaction(ctx context.Context, fn func(tx app.AdRepository) error) error {
+	if err := r.lock(ctx); err != nil {
+		return err
+	}
+	defer r.unlock()
+	tx := &repo{index: r.index, adStorage: maps.Clone(r.adStorage), grid: r.grid.Clone(), lockWait: func(time.Duration) {}, sem: semaphore.NewWeighted(maxReaders)}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.index, r.adStorage, r.grid = tx.index, tx.adStorage, tx.grid
+	return nil
+}
+
+// Check reports whether the storage lock can be taken in time, so a stuck
+// writer makes the repository unhealthy.
+func (r *repo) Check(ctx context.Context) error {
+	if err := r.sem.Acquire(ctx, 1); err != nil {
+		return fmt.Errorf("storage lock: %w", err)
+	}
+	r.sem.Release(1)
+	return nil
+}
+
+type Option func(*repo)
+
+// WithLockWait makes the repository report how long every call waited for
+// the storage lock.
+func WithLockWait(observe func(time.Duration)) Option {
+	return func(r *repo) {
+		r.lockWait = observe
+	}
+}
+
+// WithCellSize sets the side of the cells of the location grid in degrees,
+// see geo.NewGrid.
+func WithCellSize(deg float64) Option {
+	return func(r *repo) {
+		r.cellSize = deg
+	}
+}
+
+// maxReaders is the weight of the storage lock, so a writer excludes every
+// reader.
+const maxReaders = 1 << 30
+
+// lock takes the storage lock unless ctx is done before or while waiting
+// for it, in which case it returns ctx.Err() without holding the lock.
+func (r *repo) lock(ctx context.Context) error {
+	return r.acquire(ctx, maxReaders)
+}
+
+func (r *repo) unlock() {
+	r.sem.Release(maxReaders)
+}
+
+func (r *repo) rlock(ctx context.Context) error {
+	return r.acquire(ctx, 1)
+}
+
+func (r *repo) runlock() {
+	r.sem.Release(1)
+}
+
+func (r *repo) acquire(ctx context.Context, n int64) error {
+	// Acquire succeeds without looking at ctx when the lock is free.
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	t := time.Now()
+	err := r.sem.Acquire(ctx, n)
+	r.lockWait(time.Since(t))
+	return err
+}
+
+func New(opts ...Option) app.AdRepository {
+	r := &repo{index: 0, adStorage: map[int64]ads.Ad{}, lockWait: func(time.Duration) {}, sem: semaphore.NewWeighted(maxReaders)}
+	for _, opt := range opts {
+		opt(r)
+	}
+	r.grid = geo.NewGrid(r.cellSize)
+	return r
 }
@@ -0,0 +1,423 @@
+package repotest
+
+import (
+	"context"
+	"fmt"
+	"homework10/internal/ads"
+	"homework10/internal/app"
+	"homework10/internal/geo"
+	"strconv"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// AdRepositorySuite checks an app.AdRepository. New returns an empty
+// repository for every test.
+type AdRepositorySuite struct {
+	suite.Suite
+	New  func() app.AdRepository
+	repo app.AdRepository
+	ctx  context.Context
+}
+
+func (s *AdRepositorySuite) SetupTest() {
+	s.Require().NotNil(s.New, "AdRepositorySuite.New is not set")
+	s.repo = s.New()
+	s.ctx = context.Background()
+}
+
+func (s *AdRepositorySuite) append(title string, authorID int64, loc *ads.Location) *ads.Ad {
+	ad, err := s.repo.AppendAd(s.ctx, title, "text of "+title, authorID, loc, nil)
+	s.Require().NoError(err)
+	return ad
+}
+
+func (s *AdRepositorySuite) get(ID int64) *ads.Ad {
+	ad, err := s.repo.GetAdByID(s.ctx, ID)
+	s.Require().NoError(err)
+	return ad
+}
+
+func (s *AdRepositorySuite) selectAll() []ads.Ad {
+	arr, err := s.repo.Select(s.ctx, all[ads.Ad])
+	s.Require().NoError(err)
+	return arr
+}
+
+func (s *AdRepositorySuite) TestAppendAd() {
+	before := time.Now().UTC()
+	loc := &ads.Location{Lat: 55.75, Lon: 37.62, City: "Moscow"}
+	ad, err := s.repo.AppendAd(s.ctx, "bike", "red", 7, loc, nil)
+	s.Require().NoError(err)
+	s.Equal("bike", ad.Title)
+	s.Equal("red", ad.Text)
+	s.EqualValues(7, ad.AuthorID)
+	s.False(ad.Published)
+	s.False(ad.CreationDate.Before(before.Add(-time.Second)))
+	s.Equal(ad.CreationDate, ad.UpdateTime)
+	s.Equal(loc, ad.Location)
+
+	// the repository keeps its own copy of the location
+	loc.City = "Tver"
+	stored := s.get(ad.ID)
+	s.Equal("Moscow", stored.Location.City)
+	s.Equal(*ad, *stored)
+
+	noLoc := s.append("sofa", 7, nil)
+	s.Nil(s.get(noLoc.ID).Location)
+}
+
+func (s *AdRepositorySuite) TestIDsAreMonotonic() {
+	var last int64 = -1
+	for i := 0; i < 5; i++ {
+		ad := s.append(fmt.Sprint("ad ", i), 0, nil)
+		s.Greater(ad.ID, last)
+		last = ad.ID
+	}
+	// deleted IDs are not reused
+	_, err := s.repo.DeleteAd(s.ctx, last)
+	s.Require().NoError(err)
+	s.Greater(s.append("next", 0, nil).ID, last)
+}
+
+func (s *AdRepositorySuite) TestMissingID() {
+	ad := s.append("bike", 0, nil)
+	missing := ad.ID + 100
+
+	_, err := s.repo.GetAdByID(s.ctx, missing)
+	s.Error(err)
+	_, err = s.repo.DeleteAd(s.ctx, missing)
+	s.Error(err)
+	s.Error(s.repo.ChangeAdStatus(s.ctx, missing, true))
+	s.Error(s.repo.UpdateAd(s.ctx, missing, "text", "title", &ads.Location{Lat: 1, Lon: 1}, nil))
+
+	// the failed calls change nothing
+	s.Equal([]ads.Ad{*ad}, s.selectAll())
+	arr, err := s.repo.SelectArea(s.ctx, world, all[ads.Ad])
+	s.Require().NoError(err)
+	s.Empty(arr)
+}
+
+func (s *AdRepositorySuite) TestGetAdByIDReturnsCopy() {
+	ad := s.append("bike", 0, nil)
+	got := s.get(ad.ID)
+	got.Title = "changed"
+	got.Published = true
+	s.Equal(*ad, *s.get(ad.ID))
+}
+
+func (s *AdRepositorySuite) TestChangeAdStatus() {
+	ad := s.append("bike", 0, nil)
+	s.Require().NoError(s.repo.ChangeAdStatus(s.ctx, ad.ID, true))
+	got := s.get(ad.ID)
+	s.True(got.Published)
+	s.Equal(ad.Title, got.Title)
+	// the status is a part of the ad, its change moves UpdateTime
+	s.True(got.UpdateTime.After(ad.UpdateTime))
+	s.Require().NoError(s.repo.ChangeAdStatus(s.ctx, ad.ID, true))
+	s.True(s.get(ad.ID).Published)
+	s.Require().NoError(s.repo.ChangeAdStatus(s.ctx, ad.ID, false))
+	s.False(s.get(ad.ID).Published)
+}
+
+func (s *AdRepositorySuite) TestUpdateAdIgnoresEmptyValues() {
+	loc := &ads.Location{Lat: 55.75, Lon: 37.62, City: "Moscow"}
+	ad := s.append("bike", 0, loc)
+
+	s.Require().NoError(s.repo.UpdateAd(s.ctx, ad.ID, "", "", nil, nil))
+	got := s.get(ad.ID)
+	s.Equal("bike", got.Title)
+	s.Equal("text of bike", got.Text)
+	s.Equal(loc, got.Location)
+
+	s.Require().NoError(s.repo.UpdateAd(s.ctx, ad.ID, "", "blue bike", nil, nil))
+	got = s.get(ad.ID)
+	s.Equal("blue bike", got.Title)
+	s.Equal("text of bike", got.Text)
+	s.False(got.UpdateTime.Before(ad.UpdateTime))
+
+	s.Require().NoError(s.repo.UpdateAd(s.ctx, ad.ID, "new text", "", nil, nil))
+	got = s.get(ad.ID)
+	s.Equal("blue bike", got.Title)
+	s.Equal("new text", got.Text)
+	s.Equal(loc, got.Location)
+	s.Equal(ad.CreationDate, got.CreationDate)
+	s.Equal(ad.AuthorID, got.AuthorID)
+}
+
+func (s *AdRepositorySuite) TestUpdateAdMovesLocation() {
+	moscow := geo.Box{MinLat: 55, MinLon: 37, MaxLat: 56, MaxLon: 38}
+	tver := geo.Box{MinLat: 56, MinLon: 35, MaxLat: 57.5, MaxLon: 36.5}
+	ad := s.append("bike", 0, &ads.Location{Lat: 55.75, Lon: 37.62})
+	noLoc := s.append("sofa", 0, nil)
+
+	area := func(box geo.Box) []int64 {
+		arr, err := s.repo.SelectArea(s.ctx, box, all[ads.Ad])
+		s.Require().NoError(err)
+		return adIDs(arr)
+	}
+	s.Equal([]int64{ad.ID}, area(moscow))
+	s.Empty(area(tver))
+
+	s.Require().NoError(s.repo.UpdateAd(s.ctx, ad.ID, "", "", &ads.Location{Lat: 56.86, Lon: 35.9, City: "Tver"}, nil))
+	s.Empty(area(moscow))
+	s.Equal([]int64{ad.ID}, area(tver))
+	s.Equal("Tver", s.get(ad.ID).Location.City)
+
+	s.Require().NoError(s.repo.UpdateAd(s.ctx, noLoc.ID, "", "", &ads.Location{Lat: 55.7, Lon: 37.5}, nil))
+	s.Equal([]int64{noLoc.ID}, area(moscow))
+	s.ElementsMatch([]int64{ad.ID, noLoc.ID}, area(world))
+}
+
+func (s *AdRepositorySuite) TestDeleteAd() {
+	ad := s.append("bike", 0, &ads.Location{Lat: 55.75, Lon: 37.62})
+	other := s.append("sofa", 0, nil)
+
+	deleted, err := s.repo.DeleteAd(s.ctx, ad.ID)
+	s.Require().NoError(err)
+	s.Equal(*ad, *deleted)
+
+	_, err = s.repo.GetAdByID(s.ctx, ad.ID)
+	s.Error(err)
+	_, err = s.repo.DeleteAd(s.ctx, ad.ID)
+	s.Error(err, "an ad is deleted once")
+	s.Equal([]ads.Ad{*other}, s.selectAll())
+	arr, err := s.repo.SelectArea(s.ctx, world, all[ads.Ad])
+	s.Require().NoError(err)
+	s.Empty(arr)
+}
+
+func (s *AdRepositorySuite) TestSelect() {
+	var published []int64
+	for i := 0; i < 6; i++ {
+		ad := s.append(fmt.Sprint("ad ", i), int64(i%2), nil)
+		if i%3 == 0 {
+			s.Require().NoError(s.repo.ChangeAdStatus(s.ctx, ad.ID, true))
+			published = append(published, ad.ID)
+		}
+	}
+	arr, err := s.repo.Select(s.ctx, func(ad ads.Ad) bool { return ad.Published })
+	s.Require().NoError(err)
+	s.Equal(published, adIDs(arr))
+
+	arr, err = s.repo.Select(s.ctx, func(ad ads.Ad) bool { return false })
+	s.Require().NoError(err)
+	s.NotNil(arr)
+	s.Empty(arr)
+
+	ids := adIDs(s.selectAll())
+	s.Len(ids, 6)
+	s.IsIncreasing(ids)
+}
+
+func (s *AdRepositorySuite) TestSelectArea() {
+	in := s.append("in", 0, &ads.Location{Lat: 10, Lon: 10})
+	s.append("other author", 1, &ads.Location{Lat: 10.5, Lon: 10.5})
+	s.append("out", 0, &ads.Location{Lat: -10, Lon: -10})
+	s.append("nowhere", 0, nil)
+
+	box := geo.Box{MinLat: 9, MinLon: 9, MaxLat: 11, MaxLon: 11}
+	arr, err := s.repo.SelectArea(s.ctx, box, func(ad ads.Ad) bool { return ad.AuthorID == 0 })
+	s.Require().NoError(err)
+	s.Equal([]int64{in.ID}, adIDs(arr))
+
+	arr, err = s.repo.SelectArea(s.ctx, world, all[ads.Ad])
+	s.Require().NoError(err)
+	s.Len(arr, 3)
+}
+
+func (s *AdRepositorySuite) TestTransaction() {
+	ad := s.append("bike", 0, nil)
+
+	err := s.repo.Transaction(s.ctx, func(tx app.AdRepository) error {
+		if err := tx.ChangeAdStatus(s.ctx, ad.ID, true); err != nil {
+			return err
+		}
+		if _, err := tx.AppendAd(s.ctx, "sofa", "soft", 0, &ads.Location{Lat: 1, Lon: 1}, nil); err != nil {
+			return err
+		}
+		// the transaction reads its own writes
+		got, err := tx.GetAdByID(s.ctx, ad.ID)
+		if err != nil {
+			return err
+		}
+		s.True(got.Published)
+		return nil
+	})
+	s.Require().NoError(err)
+	s.True(s.get(ad.ID).Published)
+	s.Len(s.selectAll(), 2)
+
+	err = s.repo.Transaction(s.ctx, func(tx app.AdRepository) error {
+		s.Require().NoError(tx.UpdateAd(s.ctx, ad.ID, "changed", "changed", nil, nil))
+		_, err := tx.DeleteAd(s.ctx, ad.ID)
+		s.Require().NoError(err)
+		_, err = tx.AppendAd(s.ctx, "car", "fast", 0, &ads.Location{Lat: 2, Lon: 2}, nil)
+		s.Require().NoError(err)
+		return fmt.Errorf("stop: %w", errRollback)
+	})
+	s.ErrorIs(err, errRollback)
+	got := s.get(ad.ID)
+	s.Equal("bike", got.Title)
+	s.Len(s.selectAll(), 2)
+	arr, err := s.repo.SelectArea(s.ctx, world, all[ads.Ad])
+	s.Require().NoError(err)
+	s.Len(arr, 1)
+}
+
+func (s *AdRepositorySuite) TestCanceledContext() {
+	ad := s.append("bike", 0, nil)
+	ctx := canceled()
+
+	_, err := s.repo.AppendAd(ctx, "sofa", "soft", 0, nil, nil)
+	s.ErrorIs(err, context.Canceled)
+	s.ErrorIs(s.repo.ChangeAdStatus(ctx, ad.ID, true), context.Canceled)
+	s.ErrorIs(s.repo.UpdateAd(ctx, ad.ID, "changed", "changed", nil, nil), context.Canceled)
+	_, err = s.repo.GetAdByID(ctx, ad.ID)
+	s.ErrorIs(err, context.Canceled)
+	_, err = s.repo.Select(ctx, all[ads.Ad])
+	s.ErrorIs(err, context.Canceled)
+	_, err = s.repo.SelectArea(ctx, world, all[ads.Ad])
+	s.ErrorIs(err, context.Canceled)
+	_, err = s.repo.DeleteAd(ctx, ad.ID)
+	s.ErrorIs(err, context.Canceled)
+	called := false
+	err = s.repo.Transaction(ctx, func(app.AdRepository) error {
+		called = true
+		return nil
+	})
+	s.ErrorIs(err, context.Canceled)
+	s.False(called)
+
+	s.Equal([]ads.Ad{*ad}, s.selectAll())
+}
+
+func (s *AdRepositorySuite) TestContextDoneWhileLocked() {
+	ad := s.append("bike", 0, nil)
+	locked := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- s.repo.Transaction(s.ctx, func(app.AdRepository) error {
+			close(locked)
+			<-release
+			return nil
+		})
+	}()
+	<-locked
+
+	// the callers give up while the transaction still holds the lock
+	ctx, cancel := context.WithTimeout(s.ctx, 50*time.Millisecond)
+	defer cancel()
+	_, err := s.repo.AppendAd(ctx, "sofa", "soft", 0, nil, nil)
+	s.ErrorIs(err, context.DeadlineExceeded)
+	s.ErrorIs(s.repo.ChangeAdStatus(ctx, ad.ID, true), context.DeadlineExceeded)
+
+	close(release)
+	s.Require().NoError(<-done)
+	s.Equal([]ads.Ad{*ad}, s.selectAll())
+}
+
+func (s *AdRepositorySuite) TestConcurrentAppend() {
+	ids := make([][]int64, Workers)
+	parallel(func(w int) {
+		for i := 0; i < OpsPerWorker; i++ {
+			ad, err := s.repo.AppendAd(s.ctx, "ad", "text", int64(w), &ads.Location{Lat: float64(w), Lon: float64(i)}, nil)
+			if s.NoError(err) {
+				ids[w] = append(ids[w], ad.ID)
+			}
+		}
+	})
+
+	seen := map[int64]bool{}
+	for _, arr := range ids {
+		// the IDs of a worker grow
+		s.IsIncreasing(arr)
+		for _, id := range arr {
+			s.False(seen[id], "duplicate ID %d", id)
+			seen[id] = true
+		}
+	}
+	arr := s.selectAll()
+	s.Len(arr, Workers*OpsPerWorker)
+	s.IsIncreasing(adIDs(arr))
+	for _, ad := range arr {
+		s.True(seen[ad.ID])
+	}
+}
+
+// TestConcurrentChanges changes, reads and deletes the ads of every worker
+// at the same time and checks the final state.
+func (s *AdRepositorySuite) TestConcurrentChanges() {
+	owned := make([][]int64, Workers)
+	for w := range owned {
+		for i := 0; i < OpsPerWorker/5; i++ {
+			owned[w] = append(owned[w], s.append(fmt.Sprint("ad ", i), int64(w), nil).ID)
+		}
+	}
+
+	parallel(func(w int) {
+		for i, id := range owned[w] {
+			title := fmt.Sprintf("worker %d", w)
+			s.NoError(s.repo.UpdateAd(s.ctx, id, "", title, &ads.Location{Lat: float64(w), Lon: float64(i)}, nil))
+			s.NoError(s.repo.ChangeAdStatus(s.ctx, id, i%2 == 0))
+			if ad, err := s.repo.GetAdByID(s.ctx, id); s.NoError(err) {
+				s.Equal(title, ad.Title)
+			}
+			_, err := s.repo.Select(s.ctx, func(ad ads.Ad) bool { return ad.Published })
+			s.NoError(err)
+			_, err = s.repo.SelectArea(s.ctx, world, all[ads.Ad])
+			s.NoError(err)
+			if i%3 == 0 {
+				_, err := s.repo.DeleteAd(s.ctx, id)
+				s.NoError(err)
+			}
+		}
+	})
+
+	for w, ids := range owned {
+		for i, id := range ids {
+			ad, err := s.repo.GetAdByID(s.ctx, id)
+			if i%3 == 0 {
+				s.Error(err, "ad %d was deleted", id)
+				continue
+			}
+			s.Require().NoError(err)
+			s.Equal(fmt.Sprintf("worker %d", w), ad.Title)
+			s.Equal(i%2 == 0, ad.Published)
+			s.Equal(&ads.Location{Lat: float64(w), Lon: float64(i)}, ad.Location)
+		}
+	}
+	arr, err := s.repo.SelectArea(s.ctx, world, all[ads.Ad])
+	s.Require().NoError(err)
+	s.Len(arr, len(s.selectAll()))
+}
+
+// TestConcurrentTransactions increments a counter kept in the text of an
+// ad from many transactions, none of the increments may be lost.
+func (s *AdRepositorySuite) TestConcurrentTransactions() {
+	counter, err := s.repo.AppendAd(s.ctx, "counter", "0", 0, nil, nil)
+	s.Require().NoError(err)
+
+	parallel(func(w int) {
+		for i := 0; i < OpsPerWorker/5; i++ {
+			err := s.repo.Transaction(s.ctx, func(tx app.AdRepository) error {
+				ad, err := tx.GetAdByID(s.ctx, counter.ID)
+				if err != nil {
+					return err
+				}
+				n, err := strconv.Atoi(ad.Text)
+				if err != nil {
+					return err
+				}
+				return tx.UpdateAd(s.ctx, counter.ID, strconv.Itoa(n+1), "", nil, nil)
+			})
+			s.NoError(err)
+			_, err = s.repo.GetAdByID(s.ctx, counter.ID)
+			s.NoError(err)
+		}
+	})
+
+	s.Equal(strconv.Itoa(Workers*OpsPerWorker/5), s.get(counter.ID).Text)
+}
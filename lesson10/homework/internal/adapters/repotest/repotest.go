@@ -0,0 +1,72 @@
+// Package repotest is the conformance suite of the repositories of the
+// app. Every storage backend runs it to behave like the in-memory adrepo
+// and userrepo:
+//
+//	func TestAdRepository(t *testing.T) {
+//		suite.Run(t, &repotest.AdRepositorySuite{New: func() app.AdRepository {
+//			return adrepo.New()
+//		}})
+//	}
+//
+// The stress tests run operations from many goroutines and are meant to be
+// run with -race.
+package repotest
+
+import (
+	"context"
+	"errors"
+	"homework10/internal/ads"
+	"homework10/internal/geo"
+	"homework10/internal/users"
+	"sync"
+)
+
+// Workers and OpsPerWorker size the stress tests.
+const (
+	Workers      = 8
+	OpsPerWorker = 50
+)
+
+var errRollback = errors.New("rollback")
+
+// parallel runs fn for every worker and waits for them.
+func parallel(fn func(worker int)) {
+	var wg sync.WaitGroup
+	for w := 0; w < Workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			fn(w)
+		}(w)
+	}
+	wg.Wait()
+}
+
+func canceled() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return ctx
+}
+
+func all[T any](T) bool {
+	return true
+}
+
+func adIDs(arr []ads.Ad) []int64 {
+	res := make([]int64, 0, len(arr))
+	for _, ad := range arr {
+		res = append(res, ad.ID)
+	}
+	return res
+}
+
+func userIDs(arr []users.User) []int64 {
+	res := make([]int64, 0, len(arr))
+	for _, usr := range arr {
+		res = append(res, usr.ID)
+	}
+	return res
+}
+
+// world is a box covering every location.
+var world = geo.Box{MinLat: -90, MinLon: -180, MaxLat: 90, MaxLon: 180}
@@ -0,0 +1,226 @@
+package repotest
+
+import (
+	"context"
+	"fmt"
+	"homework10/internal/app"
+	"homework10/internal/users"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// UserRepositorySuite checks an app.UserRepository. New returns an empty
+// repository for every test.
+type UserRepositorySuite struct {
+	suite.Suite
+	New  func() app.UserRepository
+	repo app.UserRepository
+	ctx  context.Context
+}
+
+func (s *UserRepositorySuite) SetupTest() {
+	s.Require().NotNil(s.New, "UserRepositorySuite.New is not set")
+	s.repo = s.New()
+	s.ctx = context.Background()
+}
+
+func (s *UserRepositorySuite) append(nickname string) *users.User {
+	usr, err := s.repo.AppendUser(s.ctx, nickname, nickname+"@mail.org")
+	s.Require().NoError(err)
+	return usr
+}
+
+func (s *UserRepositorySuite) get(ID int64) *users.User {
+	usr, err := s.repo.GetUserByID(s.ctx, ID)
+	s.Require().NoError(err)
+	return usr
+}
+
+func (s *UserRepositorySuite) selectAll() []users.User {
+	arr, err := s.repo.Select(s.ctx, all[users.User])
+	s.Require().NoError(err)
+	return arr
+}
+
+func (s *UserRepositorySuite) TestAppendUser() {
+	usr, err := s.repo.AppendUser(s.ctx, "Oleg", "oleg@mail.org")
+	s.Require().NoError(err)
+	s.Equal("Oleg", usr.Nickname)
+	s.Equal("oleg@mail.org", usr.Email)
+	s.Equal(*usr, *s.get(usr.ID))
+
+	noEmail, err := s.repo.AppendUser(s.ctx, "Anna", "")
+	s.Require().NoError(err)
+	s.Empty(s.get(noEmail.ID).Email)
+}
+
+func (s *UserRepositorySuite) TestIDsAreMonotonic() {
+	var last int64 = -1
+	for i := 0; i < 5; i++ {
+		usr := s.append(fmt.Sprint("user", i))
+		s.Greater(usr.ID, last)
+		last = usr.ID
+	}
+	// deleted IDs are not reused
+	_, err := s.repo.DeleteUser(s.ctx, last)
+	s.Require().NoError(err)
+	s.Greater(s.append("next").ID, last)
+}
+
+func (s *UserRepositorySuite) TestMissingID() {
+	usr := s.append("oleg")
+	missing := usr.ID + 100
+
+	_, err := s.repo.GetUserByID(s.ctx, missing)
+	s.Error(err)
+	_, err = s.repo.DeleteUser(s.ctx, missing)
+	s.Error(err)
+	s.Error(s.repo.UpdateUser(s.ctx, missing, "anna", "anna@mail.org"))
+
+	// the failed calls change nothing
+	s.Equal([]users.User{*usr}, s.selectAll())
+}
+
+func (s *UserRepositorySuite) TestGetUserByIDReturnsCopy() {
+	usr := s.append("oleg")
+	got := s.get(usr.ID)
+	got.Nickname = "changed"
+	s.Equal(*usr, *s.get(usr.ID))
+}
+
+func (s *UserRepositorySuite) TestUpdateUserIgnoresEmptyValues() {
+	usr := s.append("oleg")
+
+	s.Require().NoError(s.repo.UpdateUser(s.ctx, usr.ID, "", ""))
+	s.Equal(*usr, *s.get(usr.ID))
+
+	s.Require().NoError(s.repo.UpdateUser(s.ctx, usr.ID, "Oleg", ""))
+	s.Equal(users.User{ID: usr.ID, Nickname: "Oleg", Email: "oleg@mail.org"}, *s.get(usr.ID))
+
+	s.Require().NoError(s.repo.UpdateUser(s.ctx, usr.ID, "", "o@mail.org"))
+	s.Equal(users.User{ID: usr.ID, Nickname: "Oleg", Email: "o@mail.org"}, *s.get(usr.ID))
+}
+
+func (s *UserRepositorySuite) TestDeleteUser() {
+	usr := s.append("oleg")
+	other := s.append("anna")
+
+	deleted, err := s.repo.DeleteUser(s.ctx, usr.ID)
+	s.Require().NoError(err)
+	s.Equal(*usr, *deleted)
+
+	_, err = s.repo.GetUserByID(s.ctx, usr.ID)
+	s.Error(err)
+	_, err = s.repo.DeleteUser(s.ctx, usr.ID)
+	s.Error(err, "a user is deleted once")
+	s.Error(s.repo.UpdateUser(s.ctx, usr.ID, "Oleg", ""))
+	s.Equal([]users.User{*other}, s.selectAll())
+}
+
+func (s *UserRepositorySuite) TestSelect() {
+	var even []int64
+	for i := 0; i < 6; i++ {
+		usr := s.append(fmt.Sprint("user", i))
+		if i%2 == 0 {
+			even = append(even, usr.ID)
+		}
+	}
+	arr, err := s.repo.Select(s.ctx, func(usr users.User) bool {
+		var i int
+		_, err := fmt.Sscanf(usr.Nickname, "user%d", &i)
+		return err == nil && i%2 == 0
+	})
+	s.Require().NoError(err)
+	s.Equal(even, userIDs(arr))
+
+	arr, err = s.repo.Select(s.ctx, func(users.User) bool { return false })
+	s.Require().NoError(err)
+	s.NotNil(arr)
+	s.Empty(arr)
+
+	ids := userIDs(s.selectAll())
+	s.Len(ids, 6)
+	s.IsIncreasing(ids)
+}
+
+func (s *UserRepositorySuite) TestCanceledContext() {
+	usr := s.append("oleg")
+	ctx := canceled()
+
+	_, err := s.repo.AppendUser(ctx, "anna", "")
+	s.ErrorIs(err, context.Canceled)
+	s.ErrorIs(s.repo.UpdateUser(ctx, usr.ID, "changed", ""), context.Canceled)
+	_, err = s.repo.GetUserByID(ctx, usr.ID)
+	s.ErrorIs(err, context.Canceled)
+	_, err = s.repo.Select(ctx, all[users.User])
+	s.ErrorIs(err, context.Canceled)
+	_, err = s.repo.DeleteUser(ctx, usr.ID)
+	s.ErrorIs(err, context.Canceled)
+
+	s.Equal([]users.User{*usr}, s.selectAll())
+}
+
+func (s *UserRepositorySuite) TestConcurrentAppend() {
+	ids := make([][]int64, Workers)
+	parallel(func(w int) {
+		for i := 0; i < OpsPerWorker; i++ {
+			usr, err := s.repo.AppendUser(s.ctx, fmt.Sprintf("user%d-%d", w, i), "")
+			if s.NoError(err) {
+				ids[w] = append(ids[w], usr.ID)
+			}
+		}
+	})
+
+	seen := map[int64]bool{}
+	for _, arr := range ids {
+		// the IDs of a worker grow
+		s.IsIncreasing(arr)
+		for _, id := range arr {
+			s.False(seen[id], "duplicate ID %d", id)
+			seen[id] = true
+		}
+	}
+	arr := s.selectAll()
+	s.Len(arr, Workers*OpsPerWorker)
+	s.IsIncreasing(userIDs(arr))
+}
+
+// TestConcurrentChanges updates, reads and deletes the users of every
+// worker at the same time and checks the final state.
+func (s *UserRepositorySuite) TestConcurrentChanges() {
+	owned := make([][]int64, Workers)
+	for w := range owned {
+		for i := 0; i < OpsPerWorker/5; i++ {
+			owned[w] = append(owned[w], s.append(fmt.Sprintf("user%d-%d", w, i)).ID)
+		}
+	}
+
+	parallel(func(w int) {
+		for i, id := range owned[w] {
+			nickname := fmt.Sprintf("worker%d", w)
+			s.NoError(s.repo.UpdateUser(s.ctx, id, nickname, ""))
+			if usr, err := s.repo.GetUserByID(s.ctx, id); s.NoError(err) {
+				s.Equal(nickname, usr.Nickname)
+			}
+			_, err := s.repo.Select(s.ctx, all[users.User])
+			s.NoError(err)
+			if i%3 == 0 {
+				_, err := s.repo.DeleteUser(s.ctx, id)
+				s.NoError(err)
+			}
+		}
+	})
+
+	for w, ids := range owned {
+		for i, id := range ids {
+			usr, err := s.repo.GetUserByID(s.ctx, id)
+			if i%3 == 0 {
+				s.Error(err, "user %d was deleted", id)
+				continue
+			}
+			s.Require().NoError(err)
+			s.Equal(fmt.Sprintf("worker%d", w), usr.Nickname)
+			s.Equal(fmt.Sprintf("user%d-%d@mail.org", w, i), usr.Email)
+		}
+	}
+}
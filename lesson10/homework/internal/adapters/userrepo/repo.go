@@ -1,30 +1,46 @@
 package userrepo
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"homework10/internal/app"
 	"homework10/internal/users"
-	"sync"
+	"sort"
+	"time"
+
+	"golang.org/x/sync/semaphore"
 )
 
 type repo struct {
-	mtx sync.RWMutex
+	// sem is the storage lock: a reader holds one unit, a writer all of
+	// them. Unlike sync.RWMutex it can be given up while waiting.
+	sem *semaphore.Weighted
 	index int64
 	usrStorage map[int64]users.User
+	lockWait func(time.Duration)
 }
 
-func (r * repo) AppendUser(nickname string, email string) *users.User {
-	r.mtx.Lock()
+func (r * repo) AppendUser(ctx context.Context, nickname string, email string) (*users.User, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
 	usr := users.CreateUser(r.index, nickname, email)
 	r.index++
 	r.usrStorage[usr.ID] = usr
-	r.mtx.Unlock()
-	return &usr
+	r.unlock()
+	return &usr, nil
 }
 
-func (r * repo) UpdateUser(ID int64, nickname string, email string) {
-	r.mtx.Lock()
-	usr := r.usrStorage[ID]
+func (r * repo) UpdateUser(ctx context.Context, ID int64, nickname string, email string) error {
+	if err := r.lock(ctx); err != nil {
+		return err
+	}
+	defer r.unlock()
+	usr, ok := r.usrStorage[ID]
+	if !ok {
+		return errors.New("not found")
+	}
 	if len(nickname) > 0 {
 		usr.UpdateNickname(nickname)
 	}
@@ -32,12 +48,14 @@ func (r * repo) UpdateUser(ID int64, nickname string, email string) {
 		usr.UpdateEmail(email)
 	}
 	r.usrStorage[usr.ID] = usr
-	r.mtx.Unlock()
+	return nil
 }
 
-func (r * repo) GetUserByID(ID int64) (*users.User, error) {
-	r.mtx.RLock()
-	defer r.mtx.RUnlock()
+func (r * repo) GetUserByID(ctx context.Context, ID int64) (*users.User, error) {
+	if err := r.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.runlock()
 	a, ok := r.usrStorage[ID]
 	if !ok {
 		return nil, errors.New("not found")
@@ -45,9 +63,26 @@ func (r * repo) GetUserByID(ID int64) (*users.User, error) {
 	return &a, nil
 }
 
-func (r * repo) DeleteUser(ID int64) (*users.User, error) {
-	r.mtx.Lock()
-	defer r.mtx.Unlock()
+func (r *repo) Select(ctx context.Context, f func(users.User) bool) ([]users.User, error) {
+	if err := r.rlock(ctx); err != nil {
+		return nil, err
+	}
+	res := make([]users.User, 0)
+	for _, usr := range r.usrStorage {
+		if f(usr) {
+			res = append(res, usr)
+		}
+	}
+	r.runlock()
+	sort.Slice(res, func(i, j int) bool { return res[i].ID < res[j].ID })
+	return res, nil
+}
+
+func (r * repo) DeleteUser(ctx context.Context, ID int64) (*users.User, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.unlock()
 	usr, ok := r.usrStorage[ID]
 	if !ok {
 		return nil, errors.New("not found")
@@ -56,6 +91,63 @@ func (r * repo) DeleteUser(ID int64) (*users.User, error) {
 	return &usr, nil;
 }
 
-func New() app.UserRepository {
-	return &repo{index: 0, usrStorage: map[int64]users.User{}}
+// Check reports whether the storage lock can be taken in time, so a stuck
+// writer makes the repository unhealthy.
+func (r *repo) Check(ctx context.Context) error {
+	if err := r.sem.Acquire(ctx, 1); err != nil {
+		return fmt.Errorf("storage lock: %w", err)
+	}
+	r.sem.Release(1)
+	return nil
+}
+
+type Option func(*repo)
+
+// WithLockWait makes the repository report how long every call waited for
+// the storage lock.
+func WithLockWait(observe func(time.Duration)) Option {
+	return func(r *repo) {
+		r.lockWait = observe
+	}
+}
+
+// maxReaders is the weight of the storage lock, so a writer excludes every
+// reader.
+const maxReaders = 1 << 30
+
+// lock takes the storage lock unless ctx is done before or while waiting
+// for it, in which case it returns ctx.Err() without holding the lock.
+func (r *repo) lock(ctx context.Context) error {
+	return r.acquire(ctx, maxReaders)
+}
+
+func (r *repo) unlock() {
+	r.sem.Release(maxReaders)
+}
+
+func (r *repo) rlock(ctx context.Context) error {
+	return r.acquire(ctx, 1)
+}
+
+func (r *repo) runlock() {
+	r.sem.Release(1)
+}
+
+func (r *repo) acquire(ctx context.Context, n int64) error {
+	// Acquire succeeds without looking at ctx when the lock is free.
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	t := time.Now()
+	err := r.sem.Acquire(ctx, n)
+	r.lockWait(time.Since(t))
+	return err
+}
+
+func New(opts ...Option) app.UserRepository {
+	r := &repo{index: 0, usrStorage: map[int64]users.User{}, lockWait: func(time.Duration) {}, sem: semaphore.NewWeighted(maxReaders)}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
 }
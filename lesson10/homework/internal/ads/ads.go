@@ -1,6 +1,9 @@
 package ads
 
-import "time"
+import (
+	"homework10/internal/geo"
+	"time"
+)
 
 type Ad struct {
 	ID           int64     `json:"id"`
@@ -10,15 +13,40 @@ type Ad struct {
 	Published    bool      `json:"published"`
 	CreationDate time.Time `json:"creation_time"`
 	UpdateTime   time.Time `json:"update_time"`
+	// Location is nil when unknown. It is replaced on update and never
+	// changed in place, so copies of an ad may share it.
+	Location *Location `json:"location,omitempty"`
+	// Offer is nil when unknown. Like Location it is replaced on update.
+	Offer *Offer `json:"offer,omitempty"`
+}
+
+// Offer is the category of the item of an ad and its price in the minor
+// units of the currency, e.g. kopecks.
+type Offer struct {
+	Category string `json:"category"`
+	Price    int64  `json:"price"`
+}
+
+// Location is where the item of an ad is. City is shown to people, the
+// search uses only the coordinates.
+type Location struct {
+	Lat  float64 `json:"lat"`
+	Lon  float64 `json:"lon"`
+	City string  `json:"city,omitempty"`
+}
+
+func (l Location) Point() geo.Point {
+	return geo.Point{Lat: l.Lat, Lon: l.Lon}
 }
 
 func CreateAd(ID int64, Title string, Text string, AuthorID int64) Ad {
 	current_time := time.Now().UTC()
-	return Ad{ID, Title, Text, AuthorID, false, current_time, current_time}
+	return Ad{ID, Title, Text, AuthorID, false, current_time, current_time, nil, nil}
 }
 
 func (a *Ad) ChangeAdStatus(status bool) {
 	a.Published = status
+	a.UpdateTime = time.Now().UTC()
 }
 
 func (a *Ad) UpdateTitle(title string) {
@@ -30,3 +58,13 @@ func (a *Ad) UpdateText(text string) {
 	a.Text = text
 	a.UpdateTime = time.Now().UTC()
 }
+
+func (a *Ad) UpdateLocation(l Location) {
+	a.Location = &l
+	a.UpdateTime = time.Now().UTC()
+}
+
+func (a *Ad) UpdateOffer(o Offer) {
+	a.Offer = &o
+	a.UpdateTime = time.Now().UTC()
+}
@@ -0,0 +1,262 @@
+// Package analytics counts the views of ads and aggregates them into hourly
+// and daily buckets for the statistics of sellers. The counters live apart
+// from the ads repository and are sharded by ad, so counting a view never
+// waits for the repository or for views of other ads.
+package analytics
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	shards = 64
+	// HourlyBuckets and DailyBuckets are the lengths of the series kept per
+	// ad, older views are only counted in the totals.
+	HourlyBuckets = 24
+	DailyBuckets  = 30
+
+	defaultWindow = time.Hour
+)
+
+type ctxKey struct{}
+
+// WithViewer returns a copy of ctx that carries the identity of the viewer,
+// see AddrViewer. Views are counted only with a viewer.
+func WithViewer(ctx context.Context, viewer string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, viewer)
+}
+
+// Viewer returns the viewer stored in ctx or an empty string.
+func Viewer(ctx context.Context) string {
+	v, _ := ctx.Value(ctxKey{}).(string)
+	return v
+}
+
+// AddrViewer identifies an anonymous viewer by the IP address.
+func AddrViewer(ip string) string {
+	return "ip:" + ip
+}
+
+// Bucket is the number of views in the hour or day starting at Start.
+type Bucket struct {
+	Start time.Time `json:"start"`
+	Views int64     `json:"views"`
+}
+
+// AdViews is the number of views of an ad.
+type AdViews struct {
+	AdID  int64 `json:"ad_id"`
+	Views int64 `json:"views"`
+}
+
+// SellerViews are the views of all ads of a seller. Hourly ends with the
+// current hour, Daily with the current day, both in UTC. PerAd holds the
+// total of every viewed ad, the most viewed first.
+type SellerViews struct {
+	Total  int64     `json:"total"`
+	Today  int64     `json:"today"`
+	Hourly []Bucket  `json:"hourly"`
+	Daily  []Bucket  `json:"daily"`
+	PerAd  []AdViews `json:"per_ad"`
+}
+
+type counter struct {
+	authorID int64
+	total    int64
+	// hourly and daily are indexed by the hour and day since the epoch
+	// modulo their length, the slot keeps its index to detect stale counts
+	hourly [HourlyBuckets]slot
+	daily  [DailyBuckets]slot
+}
+
+type slot struct {
+	index int64
+	views int64
+}
+
+func (s *slot) add(index int64) {
+	if s.index != index {
+		*s = slot{index: index}
+	}
+	s.views++
+}
+
+func (s slot) get(index int64) int64 {
+	if s.index != index {
+		return 0
+	}
+	return s.views
+}
+
+type seenKey struct {
+	adID   int64
+	viewer string
+}
+
+type shard struct {
+	mtx       sync.Mutex
+	ads       map[int64]*counter
+	seen      map[seenKey]time.Time
+	nextSweep time.Time
+}
+
+// Tracker counts a viewer once per ad within a window.
+type Tracker struct {
+	window time.Duration
+	now    func() time.Time
+	shards [shards]shard
+}
+
+type Option func(*Tracker)
+
+// WithWindow sets the time in which repeated views of an ad by the same
+// viewer are counted once.
+func WithWindow(d time.Duration) Option {
+	return func(t *Tracker) {
+		t.window = d
+	}
+}
+
+// WithClock replaces time.Now, so tests can move between buckets.
+func WithClock(now func() time.Time) Option {
+	return func(t *Tracker) {
+		t.now = now
+	}
+}
+
+func New(opts ...Option) *Tracker {
+	t := &Tracker{window: defaultWindow, now: time.Now}
+	for _, opt := range opts {
+		opt(t)
+	}
+	for i := range t.shards {
+		t.shards[i].ads = map[int64]*counter{}
+		t.shards[i].seen = map[seenKey]time.Time{}
+	}
+	return t
+}
+
+func (t *Tracker) shard(adID int64) *shard {
+	return &t.shards[uint64(adID)%shards]
+}
+
+func hourIndex(at time.Time) int64 { return at.Unix() / 3600 }
+func dayIndex(at time.Time) int64  { return at.Unix() / 86400 }
+
+// View counts a view of the ad of authorID by the viewer in ctx. Views
+// without a viewer and repeated views within the window are not counted.
+// Viewers are anonymous, so the views of the author are counted too. It
+// reports whether the view was counted.
+func (t *Tracker) View(ctx context.Context, adID int64, authorID int64) bool {
+	viewer := Viewer(ctx)
+	if viewer == "" {
+		return false
+	}
+	now := t.now()
+	s := t.shard(adID)
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if now.After(s.nextSweep) {
+		for k, at := range s.seen {
+			if now.Sub(at) >= t.window {
+				delete(s.seen, k)
+			}
+		}
+		s.nextSweep = now.Add(t.window)
+	}
+	key := seenKey{adID: adID, viewer: viewer}
+	if at, ok := s.seen[key]; ok && now.Sub(at) < t.window {
+		return false
+	}
+	s.seen[key] = now
+
+	c, ok := s.ads[adID]
+	if !ok {
+		c = &counter{authorID: authorID}
+		s.ads[adID] = c
+	}
+	c.total++
+	hour, day := hourIndex(now), dayIndex(now)
+	c.hourly[hour%HourlyBuckets].add(hour)
+	c.daily[day%DailyBuckets].add(day)
+	return true
+}
+
+// Forget drops the counters of a deleted ad.
+func (t *Tracker) Forget(adID int64) {
+	s := t.shard(adID)
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	delete(s.ads, adID)
+}
+
+// Seller sums the views of the ads of authorID.
+func (t *Tracker) Seller(authorID int64) SellerViews {
+	now := t.now().UTC()
+	hour, day := hourIndex(now), dayIndex(now)
+	res := SellerViews{Hourly: make([]Bucket, HourlyBuckets), Daily: make([]Bucket, DailyBuckets), PerAd: []AdViews{}}
+	for i := range res.Hourly {
+		idx := hour - int64(HourlyBuckets-1-i)
+		res.Hourly[i].Start = time.Unix(idx*3600, 0).UTC()
+	}
+	for i := range res.Daily {
+		idx := day - int64(DailyBuckets-1-i)
+		res.Daily[i].Start = time.Unix(idx*86400, 0).UTC()
+	}
+
+	for i := range t.shards {
+		s := &t.shards[i]
+		s.mtx.Lock()
+		for id, c := range s.ads {
+			if c.authorID != authorID {
+				continue
+			}
+			res.Total += c.total
+			res.PerAd = append(res.PerAd, AdViews{AdID: id, Views: c.total})
+			for j := range res.Hourly {
+				idx := hour - int64(HourlyBuckets-1-j)
+				res.Hourly[j].Views += c.hourly[idx%HourlyBuckets].get(idx)
+			}
+			for j := range res.Daily {
+				idx := day - int64(DailyBuckets-1-j)
+				res.Daily[j].Views += c.daily[idx%DailyBuckets].get(idx)
+			}
+		}
+		s.mtx.Unlock()
+	}
+	res.Today = res.Daily[DailyBuckets-1].Views
+	sortViews(res.PerAd)
+	return res
+}
+
+// Today returns the ads viewed today (UTC), the most viewed first.
+func (t *Tracker) Today() []AdViews {
+	day := dayIndex(t.now())
+	res := []AdViews{}
+	for i := range t.shards {
+		s := &t.shards[i]
+		s.mtx.Lock()
+		for id, c := range s.ads {
+			if v := c.daily[day%DailyBuckets].get(day); v > 0 {
+				res = append(res, AdViews{AdID: id, Views: v})
+			}
+		}
+		s.mtx.Unlock()
+	}
+	sortViews(res)
+	return res
+}
+
+// sortViews orders by views descending, ties by ID.
+func sortViews(v []AdViews) {
+	sort.Slice(v, func(i, j int) bool {
+		if v[i].Views != v[j].Views {
+			return v[i].Views > v[j].Views
+		}
+		return v[i].AdID < v[j].AdID
+	})
+}
@@ -0,0 +1,123 @@
+package app
+
+import (
+	"context"
+	"homework10/internal/ads"
+	"homework10/internal/analytics"
+)
+
+// MaxMostViewed limits the length of the most viewed listing.
+const MaxMostViewed = 100
+
+// Views counts the views of ads. View is called when GetAdByID returns a
+// published ad and reads the viewer from ctx, see analytics.WithViewer.
+type Views interface {
+	View(ctx context.Context, adID int64, authorID int64) bool
+	Forget(adID int64)
+	Seller(authorID int64) analytics.SellerViews
+	Today() []analytics.AdViews
+}
+
+// WithViews sets the counter of ad views. Without it views are not counted.
+func WithViews(v Views) Option {
+	return func(a *app) {
+		a.views = v
+	}
+}
+
+// SellerStats are the statistics of the ads of a seller. ViewedAds is the
+// number of existing ads with views, Conversion the share of them that was
+// unpublished since, e.g. because the item was sold. Favorites counts the
+// users keeping the ads in their favorites, Messages the messages sent to
+// the seller about them.
+type SellerStats struct {
+	UserID      int64
+	Ads         int
+	Published   int
+	Unpublished int
+	ViewedAds   int
+	Conversion  float64
+	Favorites   int64
+	Messages    int64
+	Views       analytics.SellerViews
+}
+
+// ViewedAd is a published ad with its views today.
+type ViewedAd struct {
+	Ad    ads.Ad
+	Views int64
+}
+
+func (a *app) SellerStats(ctx context.Context, UserID int64) (*SellerStats, error) {
+	ctx, cancel := a.withTimeout(ctx, "SellerStats")
+	defer cancel()
+	if _, err := a.usrrepo.GetUserByID(ctx, UserID); err != nil {
+		return nil, repoError(err, "user", UserID)
+	}
+	own, err := a.adrepo.Select(ctx, func(ad ads.Ad) bool { return ad.AuthorID == UserID })
+	if err != nil {
+		return nil, FromError(err)
+	}
+	e := a.engagement.Seller(UserID)
+	res := &SellerStats{UserID: UserID, Ads: len(own), Favorites: e.Favorites, Messages: e.Messages, Views: a.views.Seller(UserID)}
+	published := make(map[int64]bool, len(own))
+	for _, ad := range own {
+		published[ad.ID] = ad.Published
+		if ad.Published {
+			res.Published++
+		}
+	}
+	res.Unpublished = res.Ads - res.Published
+	converted := 0
+	for _, v := range res.Views.PerAd {
+		p, ok := published[v.AdID]
+		if !ok {
+			continue
+		}
+		res.ViewedAds++
+		if !p {
+			converted++
+		}
+	}
+	if res.ViewedAds > 0 {
+		res.Conversion = float64(converted) / float64(res.ViewedAds)
+	}
+	return res, nil
+}
+
+// MostViewed returns up to Limit published ads viewed today, the most
+// viewed first.
+func (a *app) MostViewed(ctx context.Context, Limit int) ([]ViewedAd, error) {
+	ctx, cancel := a.withTimeout(ctx, "MostViewed")
+	defer cancel()
+	if Limit < 1 || Limit > MaxMostViewed {
+		return nil, &Error{Code: CodeInvalidArgument, Message: "validation of the limit failed",
+			Fields: []FieldViolation{{Field: "limit", Description: "must be from 1 to 100"}}}
+	}
+	res := []ViewedAd{}
+	for _, v := range a.views.Today() {
+		if len(res) == Limit {
+			break
+		}
+		ad, err := a.adrepo.GetAdByID(ctx, v.AdID)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, FromError(ctx.Err())
+			}
+			continue
+		}
+		if ad.Published {
+			res = append(res, ViewedAd{Ad: *ad, Views: v.Views})
+		}
+	}
+	return res, nil
+}
+
+type nopViews struct{}
+
+func (nopViews) View(context.Context, int64, int64) bool { return false }
+func (nopViews) Forget(int64)                            {}
+func (nopViews) Seller(int64) analytics.SellerViews {
+	return analytics.SellerViews{Hourly: []analytics.Bucket{}, Daily: []analytics.Bucket{}, PerAd: []analytics.AdViews{}}
+}
+func (nopViews) Today() []analytics.AdViews { return nil }
@@ -1,185 +1,503 @@
 package app
 
 import (
-	"errors"
+	"context"
 	"homework10/internal/ads"
+	"homework10/internal/engagement"
+	"homework10/internal/geo"
+	"homework10/internal/revisions"
 	"homework10/internal/users"
+	"homework10/internal/webhooks"
+	"log/slog"
+	"reflect"
 	"strings"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	"github.com/KatherinaLiponina/validation"
 )
 
-var ErrNotFound = errors.New("repository does not contain ad with given ID")
-var ErrForbidden = errors.New("authorID does not match given ID")
-var ErrBadRequest = errors.New("validation for title or text was failed")
-
 type App interface {
-	CreateAd(Title string, Text string, AuthorID int64) (*ads.Ad, error)
-	ChangeAdStatus(ID int64, AuthorID int64, status bool) (*ads.Ad, error)
-	UpdateAd(ID int64, AuthorID int64, Title string, Text string) (*ads.Ad, error)
-	GetAdByID(ID int64) (*ads.Ad, error)
-	DeleteAd(ID int64, AuthorID int64) (*ads.Ad, error)
+	// CreateAd and UpdateAd take an optional Location and Offer, UpdateAd
+	// keeps the old ones when they are nil.
+	CreateAd(ctx context.Context, Title string, Text string, AuthorID int64, Location *ads.Location, Offer *ads.Offer) (*ads.Ad, error)
+	// ValidateAd runs the checks of CreateAd without creating the ad.
+	ValidateAd(ctx context.Context, Title string, Text string, AuthorID int64, Location *ads.Location, Offer *ads.Offer) error
+	ChangeAdStatus(ctx context.Context, ID int64, AuthorID int64, status bool) (*ads.Ad, error)
+	UpdateAd(ctx context.Context, ID int64, AuthorID int64, Title string, Text string, Location *ads.Location, Offer *ads.Offer) (*ads.Ad, error)
+	// GetAdByID counts a view of a published ad when ctx carries a viewer,
+	// see analytics.WithViewer.
+	GetAdByID(ctx context.Context, ID int64) (*ads.Ad, error)
+	DeleteAd(ctx context.Context, ID int64, AuthorID int64) (*ads.Ad, error)
+	BulkAds(ctx context.Context, AuthorID int64, ops []BulkOp, atomic bool) ([]BulkResult, error)
+	// FavoriteAd, UnfavoriteAd and SendMessage are the operations of a
+	// buyer, who can't be the author. ListMessages is for the author.
+	FavoriteAd(ctx context.Context, ID int64, UserID int64) (*ads.Ad, error)
+	UnfavoriteAd(ctx context.Context, ID int64, UserID int64) (*ads.Ad, error)
+	SendMessage(ctx context.Context, ID int64, SenderID int64, Text string) (*engagement.Message, error)
+	ListMessages(ctx context.Context, ID int64, AuthorID int64) ([]engagement.Message, error)
+
+	Select(ctx context.Context) ([]ads.Ad, error)
+	SelectByAuthor(ctx context.Context, authorID int64) ([]ads.Ad, error)
+	SelectByCreation(ctx context.Context, time time.Time) ([]ads.Ad, error)
+	SelectAll(ctx context.Context) ([]ads.Ad, error)
+	FindByTitle(ctx context.Context, Title string) ([]ads.Ad, error)
+	MostViewed(ctx context.Context, Limit int) ([]ViewedAd, error)
+	SearchNearby(ctx context.Context, q NearbyQuery) ([]NearbyAd, error)
+	SimilarAds(ctx context.Context, ID int64, ViewerID int64, Limit int) ([]SimilarAd, error)
+	// AdsModified returns the time of the last change of any ad, or of the
+	// creation of App. A listing read after calling it is not older.
+	AdsModified(ctx context.Context) time.Time
+
+	CreateUser(ctx context.Context, nickname string, email string) (*users.User, error)
+	UpdateUser(ctx context.Context, ID int64, nickname string, email string) (*users.User, error)
+	GetUserByID(ctx context.Context, ID int64) (*users.User, error)
+	// ListUsers returns all users ordered by ID.
+	ListUsers(ctx context.Context) ([]users.User, error)
+	DeleteUser(ctx context.Context, ID int64) (*users.User, error)
+	SellerStats(ctx context.Context, UserID int64) (*SellerStats, error)
 
-	Select() []ads.Ad
-	SelectByAuthor(authorID int64) ([]ads.Ad, error)
-	SelectByCreation(time time.Time) []ads.Ad
-	SelectAll() []ads.Ad
-	FindByTitle(Title string) []ads.Ad
+	// The staff operations skip the author checks, the caller must have
+	// authenticated Staff.
+	FindUsers(ctx context.Context, Query string) ([]users.User, error)
+	AdRevisions(ctx context.Context, ID int64) ([]revisions.Revision, error)
+	UnpublishAdAsStaff(ctx context.Context, ID int64, Staff string) (*ads.Ad, error)
+	DeleteAdAsStaff(ctx context.Context, ID int64, Staff string) (*ads.Ad, error)
 
-	CreateUser(nickname string, email string) *users.User
-	UpdateUser(ID int64, nickname string, email string) (*users.User, error)
-	GetUserByID(ID int64) (*users.User, error)
-	DeleteUser(ID int64) (*users.User, error)
+	CreateWebhook(ctx context.Context, UserID int64, URL string, Events []webhooks.EventType, Secret string) (*webhooks.Subscription, error)
+	ListWebhooks(ctx context.Context, UserID int64) ([]webhooks.Subscription, error)
+	DeleteWebhook(ctx context.Context, ID int64, UserID int64) (*webhooks.Subscription, error)
+	ListDeliveries(ctx context.Context, WebhookID int64, UserID int64) ([]webhooks.Delivery, error)
+	ReplayDelivery(ctx context.Context, ID int64, WebhookID int64, UserID int64) (*webhooks.Delivery, error)
 }
 
+// AdRepository and UserRepository return ctx.Err() when ctx is done before
+// the operation completes.
 type AdRepository interface {
-	AppendAd(Title string, Text string, AuthorID int64) *ads.Ad
-	ChangeAdStatus(ID int64, status bool)
-	UpdateAd(ID int64, Text string, Title string)
-	GetAdByID(ID int64) (*ads.Ad, error)
-	Select(f func(ads.Ad) bool) []ads.Ad
-	DeleteAd(ID int64) (*ads.Ad, error)
+	AppendAd(ctx context.Context, Title string, Text string, AuthorID int64, Location *ads.Location, Offer *ads.Offer) (*ads.Ad, error)
+	ChangeAdStatus(ctx context.Context, ID int64, status bool) error
+	// UpdateAd keeps the old value of an empty Text or Title or a nil
+	// Location or Offer.
+	UpdateAd(ctx context.Context, ID int64, Text string, Title string, Location *ads.Location, Offer *ads.Offer) error
+	GetAdByID(ctx context.Context, ID int64) (*ads.Ad, error)
+	// Select returns the ads that satisfy f ordered by ID.
+	Select(ctx context.Context, f func(ads.Ad) bool) ([]ads.Ad, error)
+	// SelectArea returns the ads located in box that satisfy f.
+	SelectArea(ctx context.Context, box geo.Box, f func(ads.Ad) bool) ([]ads.Ad, error)
+	DeleteAd(ctx context.Context, ID int64) (*ads.Ad, error)
+	// Transaction runs fn with a repository whose changes are stored only
+	// when fn returns nil. Other calls wait until the transaction ends.
+	Transaction(ctx context.Context, fn func(tx AdRepository) error) error
 }
 
 type UserRepository interface {
-	AppendUser(nickname string, email string) *users.User
-	UpdateUser(ID int64, nickname string, email string)
-	GetUserByID(ID int64) (*users.User, error)
-	DeleteUser(ID int64) (*users.User, error)
+	AppendUser(ctx context.Context, nickname string, email string) (*users.User, error)
+	UpdateUser(ctx context.Context, ID int64, nickname string, email string) error
+	GetUserByID(ctx context.Context, ID int64) (*users.User, error)
+	// Select returns the users that satisfy f ordered by ID.
+	Select(ctx context.Context, f func(users.User) bool) ([]users.User, error)
+	DeleteUser(ctx context.Context, ID int64) (*users.User, error)
 }
 
 type app struct {
-	adrepo  AdRepository
-	usrrepo UserRepository
+	adrepo     AdRepository
+	usrrepo    UserRepository
+	logger     *slog.Logger
+	metrics    Metrics
+	hooks      Webhooks
+	views      Views
+	engagement Engagement
+	similar    Similarities
+	revisions  Revisions
+	// modified is the time of the last change of an ad in Unix nanoseconds
+	modified atomic.Int64
+
+	defaultTimeout time.Duration
+	timeouts       map[string]time.Duration
+}
+
+// Metrics receives the domain events needed to keep counters and gauges of
+// ads and users.
+type Metrics interface {
+	AdCreated()
+	AdDeleted(published bool)
+	AdStatusChanged(published bool)
+	UserCreated()
+	UserDeleted()
+}
+
+// Webhooks keeps the webhook subscriptions and delivers the events of ads
+// to them. Publish is called after a change is stored and must not wait for
+// the deliveries. Lookups return an error when the entity is missing, like
+// the repositories.
+type Webhooks interface {
+	Publish(ctx context.Context, e webhooks.Event)
+	Subscribe(ctx context.Context, s webhooks.Subscription) (*webhooks.Subscription, error)
+	Subscriptions(ctx context.Context, userID int64) ([]webhooks.Subscription, error)
+	Subscription(ctx context.Context, ID int64) (*webhooks.Subscription, error)
+	Unsubscribe(ctx context.Context, ID int64) (*webhooks.Subscription, error)
+	Deliveries(ctx context.Context, subscriptionID int64) ([]webhooks.Delivery, error)
+	Delivery(ctx context.Context, ID int64) (*webhooks.Delivery, error)
+	Replay(ctx context.Context, ID int64) (*webhooks.Delivery, error)
+}
+
+type Option func(*app)
+
+// WithLogger sets the logger used for domain events. Records are written
+// with the request context, so request scoped attributes are kept.
+func WithLogger(logger *slog.Logger) Option {
+	return func(a *app) {
+		a.logger = logger
+	}
+}
+
+// WithMetrics sets the receiver of domain events.
+func WithMetrics(m Metrics) Option {
+	return func(a *app) {
+		a.metrics = m
+	}
 }
 
-type validationStruct struct {
+// WithWebhooks sets the receiver of the ad events and the store of the
+// webhook subscriptions. Without it the webhook operations are unavailable.
+func WithWebhooks(w Webhooks) Option {
+	return func(a *app) {
+		a.hooks = w
+	}
+}
+
+// WithTimeouts bounds every operation by def, or by the timeout given for its
+// name in ops, e.g. "CreateAd". Zero means no bound. Use IsOperation to check
+// the names.
+func WithTimeouts(def time.Duration, ops map[string]time.Duration) Option {
+	return func(a *app) {
+		a.defaultTimeout = def
+		a.timeouts = ops
+	}
+}
+
+// IsOperation reports whether name is a method of App.
+func IsOperation(name string) bool {
+	_, ok := reflect.TypeOf((*App)(nil)).Elem().MethodByName(name)
+	return ok
+}
+
+func (a *app) withTimeout(ctx context.Context, op string) (context.Context, context.CancelFunc) {
+	d, ok := a.timeouts[op]
+	if !ok {
+		d = a.defaultTimeout
+	}
+	if d <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+type titleValidation struct {
 	Title string `validate:"title"`
-	Text  string `validate:"text"`
 }
 
-func newValidationStruct(title string, text string) validationStruct {
-	return validationStruct{Title: title, Text: text}
+type textValidation struct {
+	Text string `validate:"text"`
 }
 
-func (a *app) CreateAd(Title string, Text string, AuthorID int64) (*ads.Ad, error) {
-	err := validation.Validate(newValidationStruct(Title, Text))
-	if err != nil {
-		return nil, ErrBadRequest
+func validateAd(title string, text string, loc *ads.Location, offer *ads.Offer) error {
+	var fields []FieldViolation
+	if validation.Validate(titleValidation{Title: title}) != nil {
+		fields = append(fields, FieldViolation{Field: "title", Description: "must be from 1 to 99 characters long"})
 	}
-	_, err = a.usrrepo.GetUserByID(AuthorID)
+	if validation.Validate(textValidation{Text: text}) != nil {
+		fields = append(fields, FieldViolation{Field: "text", Description: "must be from 1 to 499 characters long"})
+	}
+	fields = append(fields, validateLocation(loc)...)
+	fields = append(fields, validateOffer(offer)...)
+	if len(fields) > 0 {
+		return invalid(fields...)
+	}
+	return nil
+}
+
+const maxCategoryLength = 50
+
+func validateOffer(offer *ads.Offer) []FieldViolation {
+	if offer == nil {
+		return nil
+	}
+	var fields []FieldViolation
+	if n := utf8.RuneCountInString(offer.Category); n < 1 || n > maxCategoryLength {
+		fields = append(fields, FieldViolation{Field: "offer.category", Description: "must be from 1 to 50 characters long"})
+	}
+	if offer.Price < 0 {
+		fields = append(fields, FieldViolation{Field: "offer.price", Description: "must not be negative"})
+	}
+	return fields
+}
+
+func (a *app) CreateAd(ctx context.Context, Title string, Text string, AuthorID int64, Location *ads.Location, Offer *ads.Offer) (*ads.Ad, error) {
+	ctx, cancel := a.withTimeout(ctx, "CreateAd")
+	defer cancel()
+	if err := a.checkNewAd(ctx, Title, Text, AuthorID, Location, Offer); err != nil {
+		return nil, err
+	}
+	ad, err := a.adrepo.AppendAd(ctx, Title, Text, AuthorID, Location, Offer)
 	if err != nil {
-		return nil, ErrNotFound
+		return nil, FromError(err)
 	}
-	return a.adrepo.AppendAd(Title, Text, AuthorID), nil
+	a.logger.InfoContext(ctx, "ad created", "ad_id", ad.ID, "author_id", AuthorID)
+	a.metrics.AdCreated()
+	a.publish(ctx, webhooks.AdCreated, ad)
+	return ad, nil
+}
+
+func (a *app) ValidateAd(ctx context.Context, Title string, Text string, AuthorID int64, Location *ads.Location, Offer *ads.Offer) error {
+	ctx, cancel := a.withTimeout(ctx, "ValidateAd")
+	defer cancel()
+	return a.checkNewAd(ctx, Title, Text, AuthorID, Location, Offer)
 }
 
-func (a *app) ChangeAdStatus(ID int64, AuthorID int64, status bool) (*ads.Ad, error) {
-	ad, err := a.adrepo.GetAdByID(ID)
+func (a *app) checkNewAd(ctx context.Context, Title string, Text string, AuthorID int64, Location *ads.Location, Offer *ads.Offer) error {
+	if err := validateAd(Title, Text, Location, Offer); err != nil {
+		return err
+	}
+	if _, err := a.usrrepo.GetUserByID(ctx, AuthorID); err != nil {
+		return repoError(err, "user", AuthorID)
+	}
+	return nil
+}
+
+func (a *app) ChangeAdStatus(ctx context.Context, ID int64, AuthorID int64, status bool) (*ads.Ad, error) {
+	ctx, cancel := a.withTimeout(ctx, "ChangeAdStatus")
+	defer cancel()
+	ad, err := a.adrepo.GetAdByID(ctx, ID)
 	if err != nil {
-		return nil, ErrNotFound
+		return nil, repoError(err, "ad", ID)
 	}
 	if ad.AuthorID != AuthorID {
-		return nil, ErrForbidden
+		return nil, forbidden(ID, AuthorID)
+	}
+	if err := a.adrepo.ChangeAdStatus(ctx, ID, status); err != nil {
+		return nil, FromError(err)
+	}
+	a.logger.InfoContext(ctx, "ad status changed", "ad_id", ID, "published", status)
+	changed := ad.Published != status
+	if changed {
+		a.metrics.AdStatusChanged(status)
+	}
+	ad, err = a.adrepo.GetAdByID(ctx, ID)
+	if err != nil {
+		return nil, repoError(err, "ad", ID)
 	}
-	a.adrepo.ChangeAdStatus(ID, status)
-	return a.adrepo.GetAdByID(ID)
+	if changed {
+		a.publish(ctx, statusEvent(status), ad)
+	}
+	return ad, nil
 }
 
-func (a *app) UpdateAd(ID int64, AuthorID int64, Title string, Text string) (*ads.Ad, error) {
-	err := validation.Validate(newValidationStruct(Title, Text))
+func (a *app) UpdateAd(ctx context.Context, ID int64, AuthorID int64, Title string, Text string, Location *ads.Location, Offer *ads.Offer) (*ads.Ad, error) {
+	ctx, cancel := a.withTimeout(ctx, "UpdateAd")
+	defer cancel()
+	err := validateAd(Title, Text, Location, Offer)
 	if err != nil {
-		return nil, ErrBadRequest
+		return nil, err
 	}
-	_, err = a.usrrepo.GetUserByID(AuthorID)
+	_, err = a.usrrepo.GetUserByID(ctx, AuthorID)
 	if err != nil {
-		return nil, ErrNotFound
+		return nil, repoError(err, "user", AuthorID)
 	}
-	ad, err := a.adrepo.GetAdByID(ID)
+	ad, err := a.adrepo.GetAdByID(ctx, ID)
 	if err != nil {
-		return nil, ErrNotFound
+		return nil, repoError(err, "ad", ID)
 	}
 	if ad.AuthorID != AuthorID {
-		return nil, ErrForbidden
+		return nil, forbidden(ID, AuthorID)
+	}
+	if err := a.adrepo.UpdateAd(ctx, ID, Text, Title, Location, Offer); err != nil {
+		return nil, FromError(err)
 	}
-	a.adrepo.UpdateAd(ID, Text, Title)
-	return a.adrepo.GetAdByID(ID)
+	a.logger.InfoContext(ctx, "ad updated", "ad_id", ID)
+	ad, err = a.adrepo.GetAdByID(ctx, ID)
+	if err != nil {
+		return nil, repoError(err, "ad", ID)
+	}
+	a.publish(ctx, webhooks.AdUpdated, ad)
+	return ad, nil
 }
 
-func (a *app) GetAdByID(ID int64) (*ads.Ad, error) {
-	return a.adrepo.GetAdByID(ID)
+func (a *app) GetAdByID(ctx context.Context, ID int64) (*ads.Ad, error) {
+	ctx, cancel := a.withTimeout(ctx, "GetAdByID")
+	defer cancel()
+	ad, err := a.adrepo.GetAdByID(ctx, ID)
+	if err != nil {
+		return nil, repoError(err, "ad", ID)
+	}
+	if ad.Published {
+		a.views.View(ctx, ad.ID, ad.AuthorID)
+	}
+	return ad, nil
+}
+
+// selectAds runs a query of the ads repository under the timeout of op.
+func (a *app) selectAds(ctx context.Context, op string, f func(ads.Ad) bool) ([]ads.Ad, error) {
+	ctx, cancel := a.withTimeout(ctx, op)
+	defer cancel()
+	arr, err := a.adrepo.Select(ctx, f)
+	if err != nil {
+		return nil, FromError(err)
+	}
+	return arr, nil
 }
 
-func (a *app) Select() []ads.Ad {
-	return a.adrepo.Select(func(a ads.Ad) bool { return a.Published })
+func (a *app) Select(ctx context.Context) ([]ads.Ad, error) {
+	return a.selectAds(ctx, "Select", func(a ads.Ad) bool { return a.Published })
 }
 
-func (a *app) SelectByAuthor(authorID int64) ([]ads.Ad, error) {
-	_, err := a.usrrepo.GetUserByID(authorID)
+func (a *app) SelectByAuthor(ctx context.Context, authorID int64) ([]ads.Ad, error) {
+	ctx, cancel := a.withTimeout(ctx, "SelectByAuthor")
+	defer cancel()
+	_, err := a.usrrepo.GetUserByID(ctx, authorID)
+	if err != nil {
+		return nil, repoError(err, "user", authorID)
+	}
+	arr, err := a.adrepo.Select(ctx, func(a ads.Ad) bool { return a.AuthorID == authorID })
 	if err != nil {
-		return nil, ErrNotFound
+		return nil, FromError(err)
 	}
-	return a.adrepo.Select(func(a ads.Ad) bool { return a.AuthorID == authorID }), nil
+	return arr, nil
 }
-func (a *app) SelectByCreation(time time.Time) []ads.Ad {
-	return a.adrepo.Select(func(a ads.Ad) bool { return a.CreationDate.After(time) })
+func (a *app) SelectByCreation(ctx context.Context, time time.Time) ([]ads.Ad, error) {
+	return a.selectAds(ctx, "SelectByCreation", func(a ads.Ad) bool { return a.CreationDate.After(time) })
 }
 
-func (a *app) SelectAll() []ads.Ad {
-	return a.adrepo.Select(func(a ads.Ad) bool { return true })
+func (a *app) SelectAll(ctx context.Context) ([]ads.Ad, error) {
+	return a.selectAds(ctx, "SelectAll", func(a ads.Ad) bool { return true })
 }
 
-func (a *app) DeleteAd(ID int64, AuthorID int64) (*ads.Ad, error) {
-	_, err := a.usrrepo.GetUserByID(AuthorID)
+func (a *app) DeleteAd(ctx context.Context, ID int64, AuthorID int64) (*ads.Ad, error) {
+	ctx, cancel := a.withTimeout(ctx, "DeleteAd")
+	defer cancel()
+	_, err := a.usrrepo.GetUserByID(ctx, AuthorID)
 	if err != nil {
-		return nil, ErrNotFound
+		return nil, repoError(err, "user", AuthorID)
 	}
-	ad, err := a.adrepo.GetAdByID(ID)
+	ad, err := a.adrepo.GetAdByID(ctx, ID)
 	if err != nil {
-		return nil, ErrNotFound
+		return nil, repoError(err, "ad", ID)
 	}
 	if ad.AuthorID != AuthorID {
-		return nil, ErrForbidden
+		return nil, forbidden(ID, AuthorID)
+	}
+	ad, err = a.adrepo.DeleteAd(ctx, ID)
+	if err != nil {
+		return nil, repoError(err, "ad", ID)
 	}
-	return a.adrepo.DeleteAd(ID)
+	a.logger.InfoContext(ctx, "ad deleted", "ad_id", ID)
+	a.metrics.AdDeleted(ad.Published)
+	a.views.Forget(ID)
+	a.engagement.Forget(ID)
+	a.publish(ctx, webhooks.AdDeleted, ad)
+	return ad, nil
 }
 
-func (a *app) CreateUser(nickname string, email string) *users.User {
-	return a.usrrepo.AppendUser(nickname, email)
+func (a *app) CreateUser(ctx context.Context, nickname string, email string) (*users.User, error) {
+	ctx, cancel := a.withTimeout(ctx, "CreateUser")
+	defer cancel()
+	usr, err := a.usrrepo.AppendUser(ctx, nickname, email)
+	if err != nil {
+		return nil, FromError(err)
+	}
+	a.logger.InfoContext(ctx, "user created", "user_id", usr.ID)
+	a.metrics.UserCreated()
+	return usr, nil
 }
 
-func (a *app) UpdateUser(ID int64, nickname string, email string) (*users.User, error) {
-	_, err := a.usrrepo.GetUserByID(ID)
+func (a *app) UpdateUser(ctx context.Context, ID int64, nickname string, email string) (*users.User, error) {
+	ctx, cancel := a.withTimeout(ctx, "UpdateUser")
+	defer cancel()
+	_, err := a.usrrepo.GetUserByID(ctx, ID)
+	if err != nil {
+		return nil, repoError(err, "user", ID)
+	}
+	if err := a.usrrepo.UpdateUser(ctx, ID, nickname, email); err != nil {
+		return nil, FromError(err)
+	}
+	a.logger.InfoContext(ctx, "user updated", "user_id", ID)
+	usr, err := a.usrrepo.GetUserByID(ctx, ID)
 	if err != nil {
-		return nil, ErrNotFound
+		return nil, repoError(err, "user", ID)
 	}
-	a.usrrepo.UpdateUser(ID, nickname, email)
-	return a.usrrepo.GetUserByID(ID)
+	return usr, nil
 }
 
-func (a *app) FindByTitle(Title string) []ads.Ad {
-	return a.adrepo.Select(func(a ads.Ad) bool {
+func (a *app) FindByTitle(ctx context.Context, Title string) ([]ads.Ad, error) {
+	return a.selectAds(ctx, "FindByTitle", func(a ads.Ad) bool {
 		return strings.Contains(a.Title, Title)
 	})
 }
 
-func (a *app) GetUserByID(ID int64) (*users.User, error) {
-	usr, err := a.usrrepo.GetUserByID(ID)
+func (a *app) GetUserByID(ctx context.Context, ID int64) (*users.User, error) {
+	ctx, cancel := a.withTimeout(ctx, "GetUserByID")
+	defer cancel()
+	usr, err := a.usrrepo.GetUserByID(ctx, ID)
 	if err != nil {
-		return nil, ErrNotFound
+		return nil, repoError(err, "user", ID)
 	}
 	return usr, nil
 }
 
-func (a *app) DeleteUser(ID int64) (*users.User, error) {
-	_, err := a.usrrepo.GetUserByID(ID)
+func (a *app) ListUsers(ctx context.Context) ([]users.User, error) {
+	ctx, cancel := a.withTimeout(ctx, "ListUsers")
+	defer cancel()
+	arr, err := a.usrrepo.Select(ctx, func(users.User) bool { return true })
 	if err != nil {
-		return nil, ErrNotFound
+		return nil, FromError(err)
 	}
-	return a.usrrepo.DeleteUser(ID)
+	return arr, nil
 }
 
-func NewApp(a AdRepository, u UserRepository) App {
-	return &app{adrepo: a, usrrepo: u}
+func (a *app) DeleteUser(ctx context.Context, ID int64) (*users.User, error) {
+	ctx, cancel := a.withTimeout(ctx, "DeleteUser")
+	defer cancel()
+	_, err := a.usrrepo.GetUserByID(ctx, ID)
+	if err != nil {
+		return nil, repoError(err, "user", ID)
+	}
+	usr, err := a.usrrepo.DeleteUser(ctx, ID)
+	if err != nil {
+		return nil, repoError(err, "user", ID)
+	}
+	a.logger.InfoContext(ctx, "user deleted", "user_id", ID)
+	a.metrics.UserDeleted()
+	a.dropWebhooks(ctx, ID)
+	return usr, nil
+}
+
+func NewApp(a AdRepository, u UserRepository, opts ...Option) App {
+	res := &app{adrepo: a, usrrepo: u, logger: slog.New(discardHandler{}), metrics: nopMetrics{}, hooks: nopWebhooks{}, views: nopViews{}, engagement: nopEngagement{}, similar: nopSimilarities{}, revisions: nopRevisions{}}
+	for _, opt := range opts {
+		opt(res)
+	}
+	res.modified.Store(time.Now().UnixNano())
+	return res
+}
+
+func (a *app) AdsModified(context.Context) time.Time {
+	return time.Unix(0, a.modified.Load())
 }
+
+// discardHandler drops all records. It is the default so App can be created
+// without a logger, e.g. in tests.
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+type nopMetrics struct{}
+
+func (nopMetrics) AdCreated()           {}
+func (nopMetrics) AdDeleted(bool)       {}
+func (nopMetrics) AdStatusChanged(bool) {}
+func (nopMetrics) UserCreated()         {}
+func (nopMetrics) UserDeleted()         {}
@@ -0,0 +1,173 @@
+package app
+
+import (
+	"context"
+	"homework10/internal/ads"
+	"homework10/internal/webhooks"
+)
+
+// MaxBulkOperations limits the size of a BulkAds batch.
+const MaxBulkOperations = 1000
+
+type BulkAction string
+
+const (
+	BulkPublish   BulkAction = "publish"
+	BulkUnpublish BulkAction = "unpublish"
+	BulkUpdate    BulkAction = "update"
+	BulkDelete    BulkAction = "delete"
+)
+
+// BulkOp is a single operation of a batch. Title and Text are used by
+// BulkUpdate only.
+type BulkOp struct {
+	Action BulkAction
+	AdID   int64
+	Title  string
+	Text   string
+}
+
+// BulkResult is the outcome of the operation with the same index. Ad is the
+// ad after the operation, for BulkDelete the deleted one. Err is set when the
+// operation was not applied.
+type BulkResult struct {
+	AdID int64
+	Ad   *ads.Ad
+	Err  *Error
+}
+
+// ErrRolledBack is the result of operations that succeeded but were undone,
+// because another operation of an atomic batch failed.
+var ErrRolledBack = &Error{Code: CodeConflict, Message: "rolled back, another operation of the batch failed"}
+
+// bulkEffect is what an applied operation changed, reported to the metrics
+// and the webhooks once the batch is stored.
+type bulkEffect struct {
+	published bool
+	changed   bool
+	updated   bool
+	deleted   bool
+}
+
+// BulkAds applies ops of the author in order. Each operation is checked like
+// its single counterpart and gets its own result. When atomic is set, the
+// batch runs in a repository transaction and is applied only if every
+// operation succeeds.
+func (a *app) BulkAds(ctx context.Context, AuthorID int64, ops []BulkOp, atomic bool) ([]BulkResult, error) {
+	ctx, cancel := a.withTimeout(ctx, "BulkAds")
+	defer cancel()
+	if len(ops) == 0 || len(ops) > MaxBulkOperations {
+		return nil, Errorf(CodeInvalidArgument, "batch must contain from 1 to %d operations", MaxBulkOperations)
+	}
+	_, err := a.usrrepo.GetUserByID(ctx, AuthorID)
+	if err != nil {
+		return nil, repoError(err, "user", AuthorID)
+	}
+
+	results := make([]BulkResult, len(ops))
+	effects := make([]bulkEffect, len(ops))
+	run := func(repo AdRepository) error {
+		failed := false
+		for i, op := range ops {
+			results[i], effects[i] = a.applyBulkOp(ctx, repo, AuthorID, op)
+			failed = failed || results[i].Err != nil
+		}
+		if failed && atomic {
+			return ErrRolledBack
+		}
+		return nil
+	}
+
+	if !atomic {
+		if err := run(a.adrepo); err != nil {
+			return nil, FromError(err)
+		}
+	} else if err := a.adrepo.Transaction(ctx, run); err != nil {
+		if err != ErrRolledBack {
+			return nil, FromError(err)
+		}
+		for i := range results {
+			if results[i].Err == nil {
+				results[i] = BulkResult{AdID: results[i].AdID, Err: ErrRolledBack}
+			}
+		}
+		a.logger.InfoContext(ctx, "ads bulk rolled back", "author_id", AuthorID, "operations", len(ops))
+		return results, nil
+	}
+
+	applied := 0
+	for i, e := range effects {
+		if results[i].Err != nil {
+			continue
+		}
+		applied++
+		switch {
+		case e.deleted:
+			a.metrics.AdDeleted(e.published)
+			a.views.Forget(results[i].AdID)
+			a.engagement.Forget(results[i].AdID)
+			a.publish(ctx, webhooks.AdDeleted, results[i].Ad)
+		case e.changed:
+			a.metrics.AdStatusChanged(e.published)
+			a.publish(ctx, statusEvent(e.published), results[i].Ad)
+		case e.updated:
+			a.publish(ctx, webhooks.AdUpdated, results[i].Ad)
+		}
+	}
+	a.logger.InfoContext(ctx, "ads bulk applied", "author_id", AuthorID, "operations", len(ops), "applied", applied)
+	return results, nil
+}
+
+func (a *app) applyBulkOp(ctx context.Context, repo AdRepository, AuthorID int64, op BulkOp) (BulkResult, bulkEffect) {
+	res := BulkResult{AdID: op.AdID}
+	fail := func(err error) (BulkResult, bulkEffect) {
+		res.Err = FromError(err)
+		return res, bulkEffect{}
+	}
+
+	switch op.Action {
+	case BulkPublish, BulkUnpublish, BulkDelete:
+	case BulkUpdate:
+		if err := validateAd(op.Title, op.Text, nil, nil); err != nil {
+			return fail(err)
+		}
+	default:
+		return fail(&Error{Code: CodeInvalidArgument, Message: ErrBadRequest.Message,
+			Fields: []FieldViolation{{Field: "action", Description: "must be publish, unpublish, update or delete"}}})
+	}
+
+	ad, err := repo.GetAdByID(ctx, op.AdID)
+	if err != nil {
+		return fail(repoError(err, "ad", op.AdID))
+	}
+	if ad.AuthorID != AuthorID {
+		return fail(forbidden(op.AdID, AuthorID))
+	}
+
+	var effect bulkEffect
+	switch op.Action {
+	case BulkPublish, BulkUnpublish:
+		status := op.Action == BulkPublish
+		if err := repo.ChangeAdStatus(ctx, op.AdID, status); err != nil {
+			return fail(err)
+		}
+		effect = bulkEffect{published: status, changed: ad.Published != status}
+	case BulkUpdate:
+		if err := repo.UpdateAd(ctx, op.AdID, op.Text, op.Title, nil, nil); err != nil {
+			return fail(err)
+		}
+		effect = bulkEffect{updated: true}
+	case BulkDelete:
+		if _, err := repo.DeleteAd(ctx, op.AdID); err != nil {
+			return fail(repoError(err, "ad", op.AdID))
+		}
+		res.Ad = ad
+		return res, bulkEffect{published: ad.Published, deleted: true}
+	}
+
+	res.Ad, err = repo.GetAdByID(ctx, op.AdID)
+	if err != nil {
+		return fail(repoError(err, "ad", op.AdID))
+	}
+	return res, effect
+}
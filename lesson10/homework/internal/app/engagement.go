@@ -0,0 +1,116 @@
+package app
+
+import (
+	"context"
+	"homework10/internal/ads"
+	"homework10/internal/engagement"
+	"time"
+
+	"github.com/KatherinaLiponina/validation"
+)
+
+// Engagement keeps the favorites and the messages of ads for the statistics
+// of sellers. Forget is called with every deleted ad.
+type Engagement interface {
+	Favorite(adID int64, authorID int64, userID int64) bool
+	Unfavorite(adID int64, userID int64) bool
+	Send(m engagement.Message, authorID int64) engagement.Message
+	Messages(adID int64) []engagement.Message
+	Seller(authorID int64) engagement.SellerEngagement
+	Forget(adID int64)
+}
+
+// WithEngagement sets the store of favorites and messages. Without it they
+// are accepted and not kept.
+func WithEngagement(e Engagement) Option {
+	return func(a *app) {
+		a.engagement = e
+	}
+}
+
+// buyerAd returns the published ad ID for UserID, who must exist and must
+// not be its author.
+func (a *app) buyerAd(ctx context.Context, ID int64, UserID int64) (*ads.Ad, error) {
+	if _, err := a.usrrepo.GetUserByID(ctx, UserID); err != nil {
+		return nil, repoError(err, "user", UserID)
+	}
+	ad, err := a.adrepo.GetAdByID(ctx, ID)
+	if err != nil {
+		return nil, repoError(err, "ad", ID)
+	}
+	if !ad.Published {
+		return nil, Errorf(CodeConflict, "ad %d is not published", ID)
+	}
+	if ad.AuthorID == UserID {
+		return nil, Errorf(CodePermissionDenied, "user %d is the author of ad %d", UserID, ID)
+	}
+	return ad, nil
+}
+
+func (a *app) FavoriteAd(ctx context.Context, ID int64, UserID int64) (*ads.Ad, error) {
+	ctx, cancel := a.withTimeout(ctx, "FavoriteAd")
+	defer cancel()
+	ad, err := a.buyerAd(ctx, ID, UserID)
+	if err != nil {
+		return nil, err
+	}
+	if a.engagement.Favorite(ID, ad.AuthorID, UserID) {
+		a.logger.InfoContext(ctx, "ad favorited", "ad_id", ID, "user_id", UserID)
+	}
+	return ad, nil
+}
+
+func (a *app) UnfavoriteAd(ctx context.Context, ID int64, UserID int64) (*ads.Ad, error) {
+	ctx, cancel := a.withTimeout(ctx, "UnfavoriteAd")
+	defer cancel()
+	if _, err := a.usrrepo.GetUserByID(ctx, UserID); err != nil {
+		return nil, repoError(err, "user", UserID)
+	}
+	ad, err := a.adrepo.GetAdByID(ctx, ID)
+	if err != nil {
+		return nil, repoError(err, "ad", ID)
+	}
+	if a.engagement.Unfavorite(ID, UserID) {
+		a.logger.InfoContext(ctx, "ad unfavorited", "ad_id", ID, "user_id", UserID)
+	}
+	return ad, nil
+}
+
+func (a *app) SendMessage(ctx context.Context, ID int64, SenderID int64, Text string) (*engagement.Message, error) {
+	ctx, cancel := a.withTimeout(ctx, "SendMessage")
+	defer cancel()
+	if validation.Validate(textValidation{Text: Text}) != nil {
+		return nil, invalid(FieldViolation{Field: "text", Description: "must be from 1 to 499 characters long"})
+	}
+	ad, err := a.buyerAd(ctx, ID, SenderID)
+	if err != nil {
+		return nil, err
+	}
+	m := a.engagement.Send(engagement.Message{AdID: ID, SenderID: SenderID, Text: Text, Time: time.Now().UTC()}, ad.AuthorID)
+	a.logger.InfoContext(ctx, "message sent", "ad_id", ID, "sender_id", SenderID)
+	return &m, nil
+}
+
+func (a *app) ListMessages(ctx context.Context, ID int64, AuthorID int64) ([]engagement.Message, error) {
+	ctx, cancel := a.withTimeout(ctx, "ListMessages")
+	defer cancel()
+	ad, err := a.adrepo.GetAdByID(ctx, ID)
+	if err != nil {
+		return nil, repoError(err, "ad", ID)
+	}
+	if ad.AuthorID != AuthorID {
+		return nil, forbidden(ID, AuthorID)
+	}
+	return a.engagement.Messages(ID), nil
+}
+
+type nopEngagement struct{}
+
+func (nopEngagement) Favorite(int64, int64, int64) bool { return false }
+func (nopEngagement) Unfavorite(int64, int64) bool      { return false }
+func (nopEngagement) Send(m engagement.Message, _ int64) engagement.Message {
+	return m
+}
+func (nopEngagement) Messages(int64) []engagement.Message      { return []engagement.Message{} }
+func (nopEngagement) Seller(int64) engagement.SellerEngagement { return engagement.SellerEngagement{} }
+func (nopEngagement) Forget(int64)                             {}
@@ -0,0 +1,105 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Code classifies an Error independently of the transport it is sent over.
+type Code string
+
+const (
+	CodeInvalidArgument  Code = "invalid_argument"
+	CodeNotFound         Code = "not_found"
+	CodePermissionDenied Code = "permission_denied"
+	CodeUnavailable      Code = "unavailable"
+	CodeDeadlineExceeded Code = "deadline_exceeded"
+	CodeCanceled         Code = "canceled"
+	CodeConflict         Code = "conflict"
+	CodeUnprocessable    Code = "unprocessable"
+	CodeUnauthenticated  Code = "unauthenticated"
+	CodeUnimplemented    Code = "unimplemented"
+	CodeTooLarge         Code = "too_large"
+	CodeInternal         Code = "internal"
+)
+
+// FieldViolation describes a single invalid field of a request.
+type FieldViolation struct {
+	Field       string `json:"field"`
+	Description string `json:"description"`
+}
+
+// Error is the error type returned by App. Transports map Code to their own
+// status codes and pass Fields and Retryable to the client.
+type Error struct {
+	Code      Code
+	Message   string
+	Fields    []FieldViolation
+	Retryable bool
+	Err       error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is an *Error with the same Code, so that
+// errors.Is(err, ErrNotFound) holds for every not found error.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+var ErrNotFound = &Error{Code: CodeNotFound, Message: "repository does not contain entity with given ID"}
+var ErrForbidden = &Error{Code: CodePermissionDenied, Message: "authorID does not match given ID"}
+var ErrBadRequest = &Error{Code: CodeInvalidArgument, Message: "validation for title or text was failed"}
+
+// Errorf creates an Error with the given code and formatted message.
+func Errorf(code Code, format string, args ...any) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// FromError converts any error to *Error. Context errors keep their meaning,
+// other errors that are not produced by App are reported as internal.
+func FromError(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return &Error{Code: CodeDeadlineExceeded, Message: "operation timed out", Retryable: true, Err: err}
+	case errors.Is(err, context.Canceled):
+		return &Error{Code: CodeCanceled, Message: "operation canceled", Err: err}
+	}
+	return &Error{Code: CodeInternal, Message: "internal error", Err: err}
+}
+
+// repoError converts an error of a repository lookup. Context errors are
+// kept, any other error means that the entity is missing.
+func repoError(err error, entity string, id int64) *Error {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return FromError(err)
+	}
+	return notFound(entity, id)
+}
+
+func notFound(entity string, id int64) *Error {
+	return Errorf(CodeNotFound, "%s with id %d not found", entity, id)
+}
+
+func forbidden(adID int64, authorID int64) *Error {
+	return Errorf(CodePermissionDenied, "user %d is not the author of ad %d", authorID, adID)
+}
+
+func invalid(fields ...FieldViolation) *Error {
+	return &Error{Code: CodeInvalidArgument, Message: ErrBadRequest.Message, Fields: fields}
+}
@@ -0,0 +1,139 @@
+package app
+
+import (
+	"context"
+	"homework10/internal/ads"
+	"homework10/internal/geo"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// MaxRadius bounds the radius of a nearby search in kilometers.
+	MaxRadius = 1000
+	// MaxNearby limits the number of ads found by a nearby search.
+	MaxNearby      = 1000
+	maxCityLength  = 99
+	pointViolation = "latitude must be from -90 to 90 and longitude from -180 to 180"
+)
+
+func validateLocation(loc *ads.Location) []FieldViolation {
+	if loc == nil {
+		return nil
+	}
+	var fields []FieldViolation
+	if !(loc.Lat >= -90 && loc.Lat <= 90) {
+		fields = append(fields, FieldViolation{Field: "location.lat", Description: "must be from -90 to 90"})
+	}
+	if !(loc.Lon >= -180 && loc.Lon <= 180) {
+		fields = append(fields, FieldViolation{Field: "location.lon", Description: "must be from -180 to 180"})
+	}
+	if utf8.RuneCountInString(loc.City) > maxCityLength {
+		fields = append(fields, FieldViolation{Field: "location.city", Description: "must be at most 99 characters long"})
+	}
+	return fields
+}
+
+// NearbyQuery selects the located ads in Circle or Box, exactly one of them
+// must be set. The other fields narrow the selection like the listing
+// filters: only published ads are found unless All is set, AuthorID keeps
+// the ads of an author, CreatedAfter the ads created after a time and Title
+// the ads with it in the title.
+type NearbyQuery struct {
+	Circle         *geo.Circle
+	Box            *geo.Box
+	AuthorID       *int64
+	All            bool
+	CreatedAfter   time.Time
+	Title          string
+	SortByDistance bool
+	Limit          int
+}
+
+func (q NearbyQuery) validate() error {
+	var fields []FieldViolation
+	switch {
+	case (q.Circle == nil) == (q.Box == nil):
+		fields = append(fields, FieldViolation{Field: "area", Description: "exactly one of a circle and a box must be set"})
+	case q.Circle != nil:
+		if !q.Circle.Center.Valid() {
+			fields = append(fields, FieldViolation{Field: "center", Description: pointViolation})
+		}
+		if !(q.Circle.Radius > 0 && q.Circle.Radius <= MaxRadius) {
+			fields = append(fields, FieldViolation{Field: "radius_km", Description: "must be greater than 0 and at most 1000"})
+		}
+	default:
+		if !q.Box.Valid() {
+			fields = append(fields, FieldViolation{Field: "box", Description: pointViolation + ", min_lat must not be greater than max_lat"})
+		}
+	}
+	if q.Limit < 1 || q.Limit > MaxNearby {
+		fields = append(fields, FieldViolation{Field: "limit", Description: "must be from 1 to 1000"})
+	}
+	if len(fields) > 0 {
+		return invalid(fields...)
+	}
+	return nil
+}
+
+// NearbyAd is an ad with its distance in kilometers from the center of the
+// searched circle or box.
+type NearbyAd struct {
+	Ad       ads.Ad
+	Distance float64
+}
+
+// SearchNearby returns up to Limit ads matching q, by ID or, with
+// SortByDistance, the nearest first.
+func (a *app) SearchNearby(ctx context.Context, q NearbyQuery) ([]NearbyAd, error) {
+	ctx, cancel := a.withTimeout(ctx, "SearchNearby")
+	defer cancel()
+	if err := q.validate(); err != nil {
+		return nil, err
+	}
+	if q.AuthorID != nil {
+		if _, err := a.usrrepo.GetUserByID(ctx, *q.AuthorID); err != nil {
+			return nil, repoError(err, "user", *q.AuthorID)
+		}
+	}
+	box, center := q.Box, geo.Point{}
+	if q.Circle != nil {
+		bounds := q.Circle.Bounds()
+		box, center = &bounds, q.Circle.Center
+	} else {
+		center = q.Box.Center()
+	}
+	arr, err := a.adrepo.SelectArea(ctx, *box, func(ad ads.Ad) bool {
+		switch {
+		case !q.All && !ad.Published:
+			return false
+		case q.AuthorID != nil && ad.AuthorID != *q.AuthorID:
+			return false
+		case !q.CreatedAfter.IsZero() && !ad.CreationDate.After(q.CreatedAfter):
+			return false
+		case q.Circle != nil && !q.Circle.Contains(ad.Location.Point()):
+			return false
+		}
+		return strings.Contains(ad.Title, q.Title)
+	})
+	if err != nil {
+		return nil, FromError(err)
+	}
+
+	res := make([]NearbyAd, len(arr))
+	for i, ad := range arr {
+		res[i] = NearbyAd{Ad: ad, Distance: geo.Distance(center, ad.Location.Point())}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if q.SortByDistance && res[i].Distance != res[j].Distance {
+			return res[i].Distance < res[j].Distance
+		}
+		return res[i].Ad.ID < res[j].Ad.ID
+	})
+	if len(res) > q.Limit {
+		res = res[:q.Limit]
+	}
+	return res, nil
+}
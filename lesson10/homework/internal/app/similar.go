@@ -0,0 +1,70 @@
+package app
+
+import (
+	"context"
+	"homework10/internal/ads"
+	"homework10/internal/similar"
+)
+
+// MaxSimilar limits the number of similar ads.
+const MaxSimilar = 50
+
+// Similarities keeps the index of similar ads. Put is called with every
+// created or changed ad and Remove with every deleted one, after the change
+// is stored.
+type Similarities interface {
+	Put(ad ads.Ad)
+	Remove(adID int64)
+	Similar(ad ads.Ad, limit int, viewerID int64) []similar.Match
+}
+
+// WithSimilarities sets the index of similar ads. Without it SimilarAds
+// finds nothing.
+func WithSimilarities(s Similarities) Option {
+	return func(a *app) {
+		a.similar = s
+	}
+}
+
+// SimilarAd is a published ad with its similarity to the requested one,
+// from 0 to 1.
+type SimilarAd struct {
+	Ad    ads.Ad
+	Score float64
+}
+
+// SimilarAds returns up to Limit published ads similar to the ad ID, the
+// most similar first. The ads of ViewerID are skipped, a negative ViewerID
+// is an anonymous viewer.
+func (a *app) SimilarAds(ctx context.Context, ID int64, ViewerID int64, Limit int) ([]SimilarAd, error) {
+	ctx, cancel := a.withTimeout(ctx, "SimilarAds")
+	defer cancel()
+	if Limit < 1 || Limit > MaxSimilar {
+		return nil, invalid(FieldViolation{Field: "limit", Description: "must be from 1 to 50"})
+	}
+	ad, err := a.adrepo.GetAdByID(ctx, ID)
+	if err != nil {
+		return nil, repoError(err, "ad", ID)
+	}
+	res := []SimilarAd{}
+	for _, m := range a.similar.Similar(*ad, Limit, ViewerID) {
+		// the index may lag behind a concurrent change
+		other, err := a.adrepo.GetAdByID(ctx, m.AdID)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, FromError(ctx.Err())
+			}
+			continue
+		}
+		if other.Published {
+			res = append(res, SimilarAd{Ad: *other, Score: m.Score})
+		}
+	}
+	return res, nil
+}
+
+type nopSimilarities struct{}
+
+func (nopSimilarities) Put(ads.Ad)                                 {}
+func (nopSimilarities) Remove(int64)                               {}
+func (nopSimilarities) Similar(ads.Ad, int, int64) []similar.Match { return nil }
@@ -0,0 +1,113 @@
+package app
+
+import (
+	"context"
+	"homework10/internal/ads"
+	"homework10/internal/revisions"
+	"homework10/internal/users"
+	"homework10/internal/webhooks"
+	"strings"
+)
+
+// Revisions keeps the past versions of ads. Record is called with every
+// stored change of an ad, History returns the latest revision first.
+type Revisions interface {
+	Record(r revisions.Revision)
+	History(adID int64) []revisions.Revision
+}
+
+// WithRevisions sets the store of ad revisions. Without it AdRevisions
+// returns nothing.
+func WithRevisions(r Revisions) Option {
+	return func(a *app) {
+		a.revisions = r
+	}
+}
+
+// FindUsers returns the users whose nickname or email contains Query,
+// ignoring case. An empty Query returns all users.
+func (a *app) FindUsers(ctx context.Context, Query string) ([]users.User, error) {
+	ctx, cancel := a.withTimeout(ctx, "FindUsers")
+	defer cancel()
+	q := strings.ToLower(Query)
+	arr, err := a.usrrepo.Select(ctx, func(u users.User) bool {
+		return strings.Contains(strings.ToLower(u.Nickname), q) || strings.Contains(strings.ToLower(u.Email), q)
+	})
+	if err != nil {
+		return nil, FromError(err)
+	}
+	return arr, nil
+}
+
+// AdRevisions returns the revisions of the ad ID, the latest first. The
+// revisions of a deleted ad are kept.
+func (a *app) AdRevisions(ctx context.Context, ID int64) ([]revisions.Revision, error) {
+	ctx, cancel := a.withTimeout(ctx, "AdRevisions")
+	defer cancel()
+	if revs := a.revisions.History(ID); len(revs) > 0 {
+		return revs, nil
+	}
+	if _, err := a.adrepo.GetAdByID(ctx, ID); err != nil {
+		return nil, repoError(err, "ad", ID)
+	}
+	return []revisions.Revision{}, nil
+}
+
+// UnpublishAdAsStaff unpublishes the ad ID whoever its author is. An
+// unpublished ad is returned as it is.
+func (a *app) UnpublishAdAsStaff(ctx context.Context, ID int64, Staff string) (*ads.Ad, error) {
+	ctx, cancel := a.withTimeout(ctx, "UnpublishAdAsStaff")
+	defer cancel()
+	if err := validateStaff(Staff); err != nil {
+		return nil, err
+	}
+	ad, err := a.adrepo.GetAdByID(ctx, ID)
+	if err != nil {
+		return nil, repoError(err, "ad", ID)
+	}
+	if !ad.Published {
+		return ad, nil
+	}
+	if err := a.adrepo.ChangeAdStatus(ctx, ID, false); err != nil {
+		return nil, FromError(err)
+	}
+	a.logger.InfoContext(ctx, "ad unpublished by staff", "ad_id", ID, "staff", Staff)
+	a.metrics.AdStatusChanged(false)
+	ad, err = a.adrepo.GetAdByID(ctx, ID)
+	if err != nil {
+		return nil, repoError(err, "ad", ID)
+	}
+	a.publishAs(ctx, webhooks.AdUnpublished, ad, Staff)
+	return ad, nil
+}
+
+// DeleteAdAsStaff deletes the ad ID whoever its author is.
+func (a *app) DeleteAdAsStaff(ctx context.Context, ID int64, Staff string) (*ads.Ad, error) {
+	ctx, cancel := a.withTimeout(ctx, "DeleteAdAsStaff")
+	defer cancel()
+	if err := validateStaff(Staff); err != nil {
+		return nil, err
+	}
+	ad, err := a.adrepo.DeleteAd(ctx, ID)
+	if err != nil {
+		return nil, repoError(err, "ad", ID)
+	}
+	a.logger.InfoContext(ctx, "ad deleted by staff", "ad_id", ID, "staff", Staff)
+	a.metrics.AdDeleted(ad.Published)
+	a.views.Forget(ID)
+	a.engagement.Forget(ID)
+	a.publishAs(ctx, webhooks.AdDeleted, ad, Staff)
+	return ad, nil
+}
+
+func validateStaff(staff string) error {
+	if staff == "" {
+		return invalid(FieldViolation{Field: "staff", Description: "must not be empty"})
+	}
+	return nil
+}
+
+type nopRevisions struct{}
+
+func (nopRevisions) Record(revisions.Revision)          {}
+func (nopRevisions) History(int64) []revisions.Revision { return nil }
@@ -0,0 +1,226 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"homework10/internal/ads"
+	"homework10/internal/revisions"
+	"homework10/internal/webhooks"
+	"net/url"
+	"slices"
+	"time"
+)
+
+const (
+	minSecretLength = 16
+	maxSecretLength = 256
+)
+
+// publish hands an event about ad to the webhooks and updates the index of
+// similar ads, the revisions and the time of the last change. It is called
+// only after the change is stored.
+func (a *app) publish(ctx context.Context, t webhooks.EventType, ad *ads.Ad) {
+	a.publishAs(ctx, t, ad, "")
+}
+
+// publishAs is publish for a change made by a staff member.
+func (a *app) publishAs(ctx context.Context, t webhooks.EventType, ad *ads.Ad, staff string) {
+	now := time.Now()
+	a.modified.Store(now.UnixNano())
+	if t == webhooks.AdDeleted {
+		a.similar.Remove(ad.ID)
+	} else {
+		a.similar.Put(*ad)
+	}
+	a.revisions.Record(revisions.Revision{Time: now.UTC(), Event: t, Staff: staff, Ad: *ad})
+	a.hooks.Publish(ctx, webhooks.Event{Type: t, Time: now.UTC(), Ad: *ad})
+}
+
+func statusEvent(published bool) webhooks.EventType {
+	if published {
+		return webhooks.AdPublished
+	}
+	return webhooks.AdUnpublished
+}
+
+// dropWebhooks deletes the subscriptions of a deleted user. Failures are only
+// logged, the user is gone anyway.
+func (a *app) dropWebhooks(ctx context.Context, userID int64) {
+	subs, err := a.hooks.Subscriptions(ctx, userID)
+	if err != nil {
+		return
+	}
+	for _, s := range subs {
+		if _, err := a.hooks.Unsubscribe(ctx, s.ID); err != nil {
+			a.logger.WarnContext(ctx, "webhook of deleted user not deleted", "webhook_id", s.ID, "user_id", userID, "error", err)
+		}
+	}
+}
+
+func validateWebhook(URL string, events []webhooks.EventType, secret string) error {
+	var fields []FieldViolation
+	u, err := url.Parse(URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		fields = append(fields, FieldViolation{Field: "url", Description: "must be an absolute http or https URL"})
+	}
+	if len(events) == 0 {
+		fields = append(fields, FieldViolation{Field: "events", Description: "must contain at least one event type"})
+	}
+	for _, e := range events {
+		if !slices.Contains(webhooks.EventTypes, e) {
+			fields = append(fields, FieldViolation{Field: "events", Description: "unknown event type " + string(e)})
+		}
+	}
+	if secret != "" && (len(secret) < minSecretLength || len(secret) > maxSecretLength) {
+		fields = append(fields, FieldViolation{Field: "secret", Description: "must be from 16 to 256 characters long"})
+	}
+	if len(fields) > 0 {
+		return &Error{Code: CodeInvalidArgument, Message: "validation of the webhook failed", Fields: fields}
+	}
+	return nil
+}
+
+// CreateWebhook subscribes the user to the events of their ads. An empty
+// secret is generated, the returned subscription is the only place it is
+// shown.
+func (a *app) CreateWebhook(ctx context.Context, UserID int64, URL string, Events []webhooks.EventType, Secret string) (*webhooks.Subscription, error) {
+	ctx, cancel := a.withTimeout(ctx, "CreateWebhook")
+	defer cancel()
+	if err := validateWebhook(URL, Events, Secret); err != nil {
+		return nil, err
+	}
+	if _, err := a.usrrepo.GetUserByID(ctx, UserID); err != nil {
+		return nil, repoError(err, "user", UserID)
+	}
+	if Secret == "" {
+		var b [32]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			return nil, FromError(err)
+		}
+		Secret = hex.EncodeToString(b[:])
+	}
+	events := slices.Clone(Events)
+	slices.Sort(events)
+	s, err := a.hooks.Subscribe(ctx, webhooks.Subscription{UserID: UserID, URL: URL, Events: slices.Compact(events), Secret: Secret})
+	if err != nil {
+		return nil, FromError(err)
+	}
+	a.logger.InfoContext(ctx, "webhook created", "webhook_id", s.ID, "user_id", UserID, "url", URL)
+	return s, nil
+}
+
+func (a *app) ListWebhooks(ctx context.Context, UserID int64) ([]webhooks.Subscription, error) {
+	ctx, cancel := a.withTimeout(ctx, "ListWebhooks")
+	defer cancel()
+	if _, err := a.usrrepo.GetUserByID(ctx, UserID); err != nil {
+		return nil, repoError(err, "user", UserID)
+	}
+	subs, err := a.hooks.Subscriptions(ctx, UserID)
+	if err != nil {
+		return nil, FromError(err)
+	}
+	return subs, nil
+}
+
+// webhookOf returns the subscription if it belongs to the user.
+func (a *app) webhookOf(ctx context.Context, ID int64, UserID int64) (*webhooks.Subscription, error) {
+	s, err := a.hooks.Subscription(ctx, ID)
+	if err != nil {
+		return nil, hookError(err, "webhook", ID)
+	}
+	if s.UserID != UserID {
+		return nil, Errorf(CodePermissionDenied, "user %d does not own webhook %d", UserID, ID)
+	}
+	return s, nil
+}
+
+func (a *app) DeleteWebhook(ctx context.Context, ID int64, UserID int64) (*webhooks.Subscription, error) {
+	ctx, cancel := a.withTimeout(ctx, "DeleteWebhook")
+	defer cancel()
+	if _, err := a.webhookOf(ctx, ID, UserID); err != nil {
+		return nil, err
+	}
+	s, err := a.hooks.Unsubscribe(ctx, ID)
+	if err != nil {
+		return nil, hookError(err, "webhook", ID)
+	}
+	a.logger.InfoContext(ctx, "webhook deleted", "webhook_id", ID, "user_id", UserID)
+	return s, nil
+}
+
+func (a *app) ListDeliveries(ctx context.Context, WebhookID int64, UserID int64) ([]webhooks.Delivery, error) {
+	ctx, cancel := a.withTimeout(ctx, "ListDeliveries")
+	defer cancel()
+	if _, err := a.webhookOf(ctx, WebhookID, UserID); err != nil {
+		return nil, err
+	}
+	res, err := a.hooks.Deliveries(ctx, WebhookID)
+	if err != nil {
+		return nil, FromError(err)
+	}
+	return res, nil
+}
+
+// ReplayDelivery sends the event of a finished delivery of the webhook
+// again. The result is a new delivery.
+func (a *app) ReplayDelivery(ctx context.Context, ID int64, WebhookID int64, UserID int64) (*webhooks.Delivery, error) {
+	ctx, cancel := a.withTimeout(ctx, "ReplayDelivery")
+	defer cancel()
+	if _, err := a.webhookOf(ctx, WebhookID, UserID); err != nil {
+		return nil, err
+	}
+	d, err := a.hooks.Delivery(ctx, ID)
+	if err != nil {
+		return nil, hookError(err, "delivery", ID)
+	}
+	if d.SubscriptionID != WebhookID {
+		return nil, notFound("delivery", ID)
+	}
+	d, err = a.hooks.Replay(ctx, ID)
+	if errors.Is(err, webhooks.ErrPending) {
+		return nil, &Error{Code: CodeConflict, Message: "delivery is still being attempted", Retryable: true, Err: err}
+	}
+	if err != nil {
+		return nil, hookError(err, "delivery", ID)
+	}
+	return d, nil
+}
+
+// hookError converts an error of a webhooks lookup.
+func hookError(err error, entity string, id int64) *Error {
+	if errors.Is(err, webhooks.ErrNotFound) {
+		return notFound(entity, id)
+	}
+	return FromError(err)
+}
+
+// nopWebhooks drops the events and reports the webhook operations as
+// unavailable.
+type nopWebhooks struct{}
+
+var errNoWebhooks = &Error{Code: CodeUnavailable, Message: "webhooks are not configured"}
+
+func (nopWebhooks) Publish(context.Context, webhooks.Event) {}
+func (nopWebhooks) Subscribe(context.Context, webhooks.Subscription) (*webhooks.Subscription, error) {
+	return nil, errNoWebhooks
+}
+func (nopWebhooks) Subscriptions(context.Context, int64) ([]webhooks.Subscription, error) {
+	return nil, errNoWebhooks
+}
+func (nopWebhooks) Subscription(context.Context, int64) (*webhooks.Subscription, error) {
+	return nil, errNoWebhooks
+}
+func (nopWebhooks) Unsubscribe(context.Context, int64) (*webhooks.Subscription, error) {
+	return nil, errNoWebhooks
+}
+func (nopWebhooks) Deliveries(context.Context, int64) ([]webhooks.Delivery, error) {
+	return nil, errNoWebhooks
+}
+func (nopWebhooks) Delivery(context.Context, int64) (*webhooks.Delivery, error) {
+	return nil, errNoWebhooks
+}
+func (nopWebhooks) Replay(context.Context, int64) (*webhooks.Delivery, error) {
+	return nil, errNoWebhooks
+}
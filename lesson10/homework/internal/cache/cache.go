@@ -0,0 +1,200 @@
+// Package cache decorates the repositories with a read-through cache of the
+// lookups by ID. Entries live in a size-bounded LRU list for a TTL and are
+// invalidated on every write through the decorator, so it must be the only
+// writer of the backend.
+package cache
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	defaultSize = 10000
+	defaultTTL  = time.Minute
+)
+
+// Stats are the counters of a cache since it was created.
+type Stats struct {
+	Hits          uint64
+	Misses        uint64
+	Evictions     uint64
+	Invalidations uint64
+	Entries       int
+}
+
+type Option func(*options)
+
+type options struct {
+	size int
+	ttl  time.Duration
+	now  func() time.Time
+}
+
+// WithSize bounds the number of entries, the least recently used entry is
+// evicted first.
+func WithSize(n int) Option {
+	return func(o *options) {
+		o.size = n
+	}
+}
+
+// WithTTL sets how long an entry is served after it was loaded.
+func WithTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.ttl = ttl
+	}
+}
+
+// WithClock replaces time.Now, so tests can expire entries.
+func WithClock(now func() time.Time) Option {
+	return func(o *options) {
+		o.now = now
+	}
+}
+
+type entry[V any] struct {
+	key     int64
+	value   V
+	expires time.Time
+}
+
+// lru caches values by ID. Loads of the same missing ID are collapsed into
+// one call of the backend.
+type lru[V any] struct {
+	options
+	group singleflight.Group
+
+	mtx   sync.Mutex
+	items map[int64]*list.Element
+	order *list.List
+	// gen changes on every invalidation, a load that started before it is
+	// not stored, because it may have read the old value.
+	gen   uint64
+	stats Stats
+}
+
+func newLRU[V any](opts []Option) *lru[V] {
+	c := &lru[V]{
+		options: options{size: defaultSize, ttl: defaultTTL, now: time.Now},
+		items:   map[int64]*list.Element{},
+		order:   list.New(),
+	}
+	for _, opt := range opts {
+		opt(&c.options)
+	}
+	return c
+}
+
+func (c *lru[V]) get(key int64) (V, bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if el, ok := c.items[key]; ok {
+		e := el.Value.(*entry[V])
+		if c.now().Before(e.expires) {
+			c.order.MoveToFront(el)
+			c.stats.Hits++
+			return e.value, true
+		}
+		c.order.Remove(el)
+		delete(c.items, key)
+	}
+	c.stats.Misses++
+	var zero V
+	return zero, false
+}
+
+func (c *lru[V]) generation() uint64 {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.gen
+}
+
+// add stores a value loaded when the generation was gen.
+func (c *lru[V]) add(key int64, value V, gen uint64) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if gen != c.gen {
+		return
+	}
+	e := &entry[V]{key: key, value: value, expires: c.now().Add(c.ttl)}
+	if el, ok := c.items[key]; ok {
+		el.Value = e
+		c.order.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.order.PushFront(e)
+	for c.order.Len() > c.size {
+		last := c.order.Back()
+		c.order.Remove(last)
+		delete(c.items, last.Value.(*entry[V]).key)
+		c.stats.Evictions++
+	}
+}
+
+// invalidate removes key and makes the running loads of any key stale.
+func (c *lru[V]) invalidate(key int64) {
+	c.mtx.Lock()
+	if el, ok := c.items[key]; ok {
+		c.order.Remove(el)
+		delete(c.items, key)
+	}
+	c.gen++
+	c.stats.Invalidations++
+	c.mtx.Unlock()
+	c.group.Forget(strconv.FormatInt(key, 10))
+}
+
+// purge removes all entries.
+func (c *lru[V]) purge() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.items = map[int64]*list.Element{}
+	c.order.Init()
+	c.gen++
+	c.stats.Invalidations++
+}
+
+func (c *lru[V]) snapshot() Stats {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	s := c.stats
+	s.Entries = c.order.Len()
+	return s
+}
+
+// load returns the cached value of key or loads it by fetch. Errors are not
+// cached. A caller waiting for the load of another caller stops waiting when
+// its ctx is done, and loads by itself when the other caller was canceled.
+func (c *lru[V]) load(ctx context.Context, key int64, fetch func(ctx context.Context) (V, error)) (V, error) {
+	if v, ok := c.get(key); ok {
+		return v, nil
+	}
+	gen := c.generation()
+	ch := c.group.DoChan(strconv.FormatInt(key, 10), func() (any, error) {
+		v, err := fetch(ctx)
+		if err == nil {
+			c.add(key, v, gen)
+		}
+		return v, err
+	})
+	var zero V
+	select {
+	case res := <-ch:
+		if res.Err == nil {
+			return res.Val.(V), nil
+		}
+		if res.Shared && ctx.Err() == nil && (errors.Is(res.Err, context.Canceled) || errors.Is(res.Err, context.DeadlineExceeded)) {
+			return fetch(ctx)
+		}
+		return zero, res.Err
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
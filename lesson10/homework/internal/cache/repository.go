@@ -0,0 +1,133 @@
+package cache
+
+import (
+	"context"
+	"homework10/internal/ads"
+	"homework10/internal/app"
+	"homework10/internal/geo"
+	"homework10/internal/users"
+)
+
+// AdRepository caches GetAdByID of the decorated repository. Select and
+// SelectArea are not cached.
+type AdRepository struct {
+	next    app.AdRepository
+	entries *lru[ads.Ad]
+}
+
+// NewAdRepository decorates r with a cache.
+func NewAdRepository(r app.AdRepository, opts ...Option) *AdRepository {
+	return &AdRepository{next: r, entries: newLRU[ads.Ad](opts)}
+}
+
+// Stats returns the counters of the cache.
+func (c *AdRepository) Stats() Stats {
+	return c.entries.snapshot()
+}
+
+func (c *AdRepository) AppendAd(ctx context.Context, Title string, Text string, AuthorID int64, Location *ads.Location, Offer *ads.Offer) (*ads.Ad, error) {
+	ad, err := c.next.AppendAd(ctx, Title, Text, AuthorID, Location, Offer)
+	if err == nil {
+		c.entries.invalidate(ad.ID)
+	}
+	return ad, err
+}
+
+func (c *AdRepository) ChangeAdStatus(ctx context.Context, ID int64, status bool) error {
+	defer c.entries.invalidate(ID)
+	return c.next.ChangeAdStatus(ctx, ID, status)
+}
+
+func (c *AdRepository) UpdateAd(ctx context.Context, ID int64, Text string, Title string, Location *ads.Location, Offer *ads.Offer) error {
+	defer c.entries.invalidate(ID)
+	return c.next.UpdateAd(ctx, ID, Text, Title, Location, Offer)
+}
+
+// GetAdByID returns a copy of the cached ad, so callers can't change the
+// cache.
+func (c *AdRepository) GetAdByID(ctx context.Context, ID int64) (*ads.Ad, error) {
+	ad, err := c.entries.load(ctx, ID, func(ctx context.Context) (ads.Ad, error) {
+		ad, err := c.next.GetAdByID(ctx, ID)
+		if err != nil {
+			return ads.Ad{}, err
+		}
+		return *ad, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ad, nil
+}
+
+func (c *AdRepository) Select(ctx context.Context, f func(ads.Ad) bool) ([]ads.Ad, error) {
+	return c.next.Select(ctx, f)
+}
+
+func (c *AdRepository) SelectArea(ctx context.Context, box geo.Box, f func(ads.Ad) bool) ([]ads.Ad, error) {
+	return c.next.SelectArea(ctx, box, f)
+}
+
+func (c *AdRepository) DeleteAd(ctx context.Context, ID int64) (*ads.Ad, error) {
+	defer c.entries.invalidate(ID)
+	return c.next.DeleteAd(ctx, ID)
+}
+
+// Transaction runs fn on the backend directly, so it reads its own writes.
+// The whole cache is invalidated after it, the written ads are not tracked.
+func (c *AdRepository) Transaction(ctx context.Context, fn func(tx app.AdRepository) error) error {
+	defer c.entries.purge()
+	return c.next.Transaction(ctx, fn)
+}
+
+// UserRepository caches GetUserByID of the decorated repository.
+type UserRepository struct {
+	next    app.UserRepository
+	entries *lru[users.User]
+}
+
+// NewUserRepository decorates r with a cache.
+func NewUserRepository(r app.UserRepository, opts ...Option) *UserRepository {
+	return &UserRepository{next: r, entries: newLRU[users.User](opts)}
+}
+
+// Stats returns the counters of the cache.
+func (c *UserRepository) Stats() Stats {
+	return c.entries.snapshot()
+}
+
+func (c *UserRepository) AppendUser(ctx context.Context, nickname string, email string) (*users.User, error) {
+	usr, err := c.next.AppendUser(ctx, nickname, email)
+	if err == nil {
+		c.entries.invalidate(usr.ID)
+	}
+	return usr, err
+}
+
+func (c *UserRepository) UpdateUser(ctx context.Context, ID int64, nickname string, email string) error {
+	defer c.entries.invalidate(ID)
+	return c.next.UpdateUser(ctx, ID, nickname, email)
+}
+
+// GetUserByID returns a copy of the cached user.
+func (c *UserRepository) GetUserByID(ctx context.Context, ID int64) (*users.User, error) {
+	usr, err := c.entries.load(ctx, ID, func(ctx context.Context) (users.User, error) {
+		usr, err := c.next.GetUserByID(ctx, ID)
+		if err != nil {
+			return users.User{}, err
+		}
+		return *usr, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &usr, nil
+}
+
+func (c *UserRepository) Select(ctx context.Context, f func(users.User) bool) ([]users.User, error) {
+	return c.next.Select(ctx, f)
+}
+
+func (c *UserRepository) DeleteUser(ctx context.Context, ID int64) (*users.User, error) {
+	defer c.entries.invalidate(ID)
+	return c.next.DeleteUser(ctx, ID)
+}
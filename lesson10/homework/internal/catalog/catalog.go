@@ -0,0 +1,233 @@
+// Package catalog reads and writes ads as CSV and JSON Lines, so catalogs
+// can be exported for backups and imported from other platforms.
+package catalog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"homework10/internal/ads"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Format string
+
+const (
+	FormatCSV    Format = "csv"
+	FormatNDJSON Format = "ndjson"
+)
+
+// maxLine bounds a JSON line, longer lines fail the whole import.
+const maxLine = 1 << 20
+
+// ParseFormat accepts the format names and the file extensions of the
+// formats, "jsonl" is a synonym of "ndjson".
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(strings.TrimPrefix(s, ".")) {
+	case "csv":
+		return FormatCSV, nil
+	case "ndjson", "jsonl":
+		return FormatNDJSON, nil
+	default:
+		return "", fmt.Errorf("unknown format %q, use csv or ndjson", s)
+	}
+}
+
+// ContentType is the media type of the format.
+func (f Format) ContentType() string {
+	if f == FormatCSV {
+		return "text/csv; charset=utf-8"
+	}
+	return "application/x-ndjson"
+}
+
+// Writer encodes ads one by one. Flush must be called after the last one.
+type Writer interface {
+	Write(ad ads.Ad) error
+	Flush() error
+}
+
+var csvHeader = []string{"id", "title", "text", "author_id", "published", "creation_time", "update_time"}
+
+// NewWriter returns a writer of format f. The CSV writer writes the header
+// at once, so an empty export is a valid file too.
+func NewWriter(w io.Writer, f Format) (Writer, error) {
+	switch f {
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(csvHeader); err != nil {
+			return nil, err
+		}
+		return csvWriter{cw}, nil
+	case FormatNDJSON:
+		bw := bufio.NewWriter(w)
+		return ndjsonWriter{bw: bw, enc: json.NewEncoder(bw)}, nil
+	default:
+		return nil, fmt.Errorf("unknown format %q", f)
+	}
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func (w csvWriter) Write(ad ads.Ad) error {
+	return w.w.Write([]string{
+		strconv.FormatInt(ad.ID, 10),
+		ad.Title,
+		ad.Text,
+		strconv.FormatInt(ad.AuthorID, 10),
+		strconv.FormatBool(ad.Published),
+		ad.CreationDate.Format(time.RFC3339Nano),
+		ad.UpdateTime.Format(time.RFC3339Nano),
+	})
+}
+
+func (w csvWriter) Flush() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+type ndjsonWriter struct {
+	bw  *bufio.Writer
+	enc *json.Encoder
+}
+
+func (w ndjsonWriter) Write(ad ads.Ad) error {
+	return w.enc.Encode(ad)
+}
+
+func (w ndjsonWriter) Flush() error {
+	return w.bw.Flush()
+}
+
+// Row is an ad read from an imported file. Line is its line in the file.
+type Row struct {
+	Line  int
+	Title string
+	Text  string
+}
+
+// LineError is a row that can't be decoded. Reading can go on after it.
+type LineError struct {
+	Line int
+	Err  error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// Reader decodes rows one by one. Next returns io.EOF after the last row and
+// *LineError for rows that can't be decoded, other errors end the reading.
+type Reader interface {
+	Next() (Row, error)
+}
+
+// NewReader returns a reader of format f. CSV files must start with a header
+// naming the title and text columns, other columns are ignored. JSON lines
+// are objects with title and text, other fields and blank lines are
+// ignored. Exported files can thus be imported again.
+func NewReader(r io.Reader, f Format) (Reader, error) {
+	switch f {
+	case FormatCSV:
+		cr := csv.NewReader(r)
+		cr.FieldsPerRecord = -1
+		return &csvReader{r: cr}, nil
+	case FormatNDJSON:
+		sc := bufio.NewScanner(r)
+		sc.Buffer(make([]byte, 0, 64*1024), maxLine)
+		return &ndjsonReader{sc: sc}, nil
+	default:
+		return nil, fmt.Errorf("unknown format %q", f)
+	}
+}
+
+type csvReader struct {
+	r     *csv.Reader
+	title int
+	text  int
+	ready bool
+}
+
+func (r *csvReader) header() error {
+	rec, err := r.r.Read()
+	if errors.Is(err, io.EOF) {
+		return io.EOF
+	}
+	if err != nil {
+		return fmt.Errorf("csv header: %w", err)
+	}
+	r.title, r.text = -1, -1
+	for i, name := range rec {
+		switch strings.TrimSpace(strings.ToLower(name)) {
+		case "title":
+			r.title = i
+		case "text":
+			r.text = i
+		}
+	}
+	if r.title < 0 || r.text < 0 {
+		return errors.New("csv header: title and text columns are required")
+	}
+	r.ready = true
+	return nil
+}
+
+func (r *csvReader) Next() (Row, error) {
+	if !r.ready {
+		if err := r.header(); err != nil {
+			return Row{}, err
+		}
+	}
+	rec, err := r.r.Read()
+	var perr *csv.ParseError
+	if errors.As(err, &perr) {
+		return Row{Line: perr.StartLine}, &LineError{Line: perr.StartLine, Err: perr.Err}
+	}
+	if err != nil {
+		return Row{}, err
+	}
+	line, _ := r.r.FieldPos(0)
+	if len(rec) <= r.title || len(rec) <= r.text {
+		return Row{Line: line}, &LineError{Line: line, Err: errors.New("missing title or text column")}
+	}
+	return Row{Line: line, Title: rec[r.title], Text: rec[r.text]}, nil
+}
+
+type ndjsonReader struct {
+	sc   *bufio.Scanner
+	line int
+}
+
+func (r *ndjsonReader) Next() (Row, error) {
+	for r.sc.Scan() {
+		r.line++
+		b := r.sc.Bytes()
+		if len(bytes.TrimSpace(b)) == 0 {
+			continue
+		}
+		var v struct {
+			Title string `json:"title"`
+			Text  string `json:"text"`
+		}
+		if err := json.Unmarshal(b, &v); err != nil {
+			return Row{Line: r.line}, &LineError{Line: r.line, Err: err}
+		}
+		return Row{Line: r.line, Title: v.Title, Text: v.Text}, nil
+	}
+	if err := r.sc.Err(); err != nil {
+		return Row{}, err
+	}
+	return Row{}, io.EOF
+}
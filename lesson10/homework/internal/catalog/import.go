@@ -0,0 +1,99 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"homework10/internal/app"
+	"io"
+)
+
+// ImportError is a line that was not imported.
+type ImportError struct {
+	Line int
+	Err  *app.Error
+}
+
+// Report sums up an import. In a dry run Created counts the rows that would
+// have been created.
+type Report struct {
+	Created int
+	Failed  int
+	DryRun  bool
+	Errors  []ImportError
+}
+
+// Importer creates the ads of one author row by row. Every row goes through
+// app.CreateAd, or app.ValidateAd in a dry run, and a failed row doesn't stop
+// the import.
+type Importer struct {
+	app      app.App
+	authorID int64
+	report   Report
+}
+
+// NewImporter checks that the author exists, so an import for a wrong user
+// fails at once instead of on every row.
+func NewImporter(ctx context.Context, a app.App, authorID int64, dryRun bool) (*Importer, error) {
+	if _, err := a.GetUserByID(ctx, authorID); err != nil {
+		return nil, err
+	}
+	return &Importer{app: a, authorID: authorID, report: Report{DryRun: dryRun, Errors: []ImportError{}}}, nil
+}
+
+// Add imports a row. Errors of the context are returned, since the
+// remaining rows would fail the same way.
+func (im *Importer) Add(ctx context.Context, row Row) error {
+	var err error
+	if im.report.DryRun {
+		err = im.app.ValidateAd(ctx, row.Title, row.Text, im.authorID, nil, nil)
+	} else {
+		_, err = im.app.CreateAd(ctx, row.Title, row.Text, im.authorID, nil, nil)
+	}
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		im.Fail(row.Line, err)
+		return nil
+	}
+	im.report.Created++
+	return nil
+}
+
+// Fail records a line that could not be imported.
+func (im *Importer) Fail(line int, err error) {
+	var e *app.Error
+	var lerr *LineError
+	if errors.As(err, &lerr) {
+		e = app.Errorf(app.CodeInvalidArgument, "malformed row: %v", lerr.Err)
+	} else {
+		e = app.FromError(err)
+	}
+	im.report.Failed++
+	im.report.Errors = append(im.report.Errors, ImportError{Line: line, Err: e})
+}
+
+// ReadFrom imports all rows of r.
+func (im *Importer) ReadFrom(ctx context.Context, r Reader) error {
+	for {
+		row, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		var lerr *LineError
+		if errors.As(err, &lerr) {
+			im.Fail(lerr.Line, lerr)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := im.Add(ctx, row); err != nil {
+			return err
+		}
+	}
+}
+
+func (im *Importer) Report() Report {
+	return im.report
+}
@@ -0,0 +1,183 @@
+// Package certs loads TLS certificates from files and reloads them when the
+// files change, so certificates can be rotated without a restart.
+package certs
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Reloader serves a certificate and, for mutual TLS, a pool of client CAs
+// read from files. The files are checked for changes on handshakes, at
+// most every interval. A change that fails to load is logged and the
+// previous files keep being served.
+type Reloader struct {
+	certFile string
+	keyFile  string
+	caFile   string
+	interval time.Duration
+	logger   *slog.Logger
+
+	mu       sync.Mutex
+	checked  time.Time
+	contents [][]byte
+	cert     *tls.Certificate
+	pool     *x509.CertPool
+}
+
+// NewReloader loads the key pair and, unless caFile is empty, the client
+// CAs.
+func NewReloader(certFile, keyFile, caFile string, interval time.Duration, logger *slog.Logger) (*Reloader, error) {
+	r := &Reloader{certFile: certFile, keyFile: keyFile, caFile: caFile, interval: interval, logger: logger}
+	contents, err := r.read()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.load(contents); err != nil {
+		return nil, err
+	}
+	r.checked = time.Now()
+	return r, nil
+}
+
+// read returns the contents of the certificate, key and CA files. They are
+// compared rather than the modification times, which may not change when
+// files are replaced quickly.
+func (r *Reloader) read() ([][]byte, error) {
+	files := []string{r.certFile, r.keyFile}
+	if r.caFile != "" {
+		files = append(files, r.caFile)
+	}
+	var res [][]byte
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, data)
+	}
+	return res, nil
+}
+
+func (r *Reloader) load(contents [][]byte) error {
+	cert, err := tls.X509KeyPair(contents[0], contents[1])
+	if err != nil {
+		return fmt.Errorf("load key pair: %w", err)
+	}
+	var pool *x509.CertPool
+	if r.caFile != "" {
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(contents[2]) {
+			return fmt.Errorf("no certificates in %s", r.caFile)
+		}
+	}
+	r.contents, r.cert, r.pool = contents, &cert, pool
+	return nil
+}
+
+// current returns the certificate and the client CAs, reloading them if
+// the files changed.
+func (r *Reloader) current() (*tls.Certificate, *x509.CertPool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if time.Since(r.checked) < r.interval {
+		return r.cert, r.pool
+	}
+	r.checked = time.Now()
+	contents, err := r.read()
+	if err == nil && slices.EqualFunc(contents, r.contents, bytes.Equal) {
+		return r.cert, r.pool
+	}
+	if err == nil {
+		err = r.load(contents)
+	}
+	if err != nil {
+		r.logger.Warn("can't reload certificates, serving the previous ones", "cert_file", r.certFile, "error", err)
+		return r.cert, r.pool
+	}
+	r.logger.Info("certificates reloaded", "cert_file", r.certFile)
+	return r.cert, r.pool
+}
+
+// GetCertificate is tls.Config.GetCertificate.
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cert, _ := r.current()
+	return cert, nil
+}
+
+// verifyClient checks the client certificate against the current CAs.
+func (r *Reloader) verifyClient(cs tls.ConnectionState) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("client certificate required")
+	}
+	_, pool := r.current()
+	opts := x509.VerifyOptions{
+		Roots:         pool,
+		Intermediates: x509.NewCertPool(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	for _, c := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(c)
+	}
+	_, err := cs.PeerCertificates[0].Verify(opts)
+	return err
+}
+
+// ServerConfig returns the configuration of a server. With client CAs, it
+// requires a client certificate signed by one of them.
+func (r *Reloader) ServerConfig() *tls.Config {
+	cfg := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: r.GetCertificate,
+	}
+	if r.caFile != "" {
+		// the certificate is verified by VerifyConnection, so the CAs
+		// loaded last are used
+		cfg.ClientAuth = tls.RequireAnyClientCert
+		cfg.VerifyConnection = r.verifyClient
+	}
+	return cfg
+}
+
+// LoadPool reads the PEM encoded certificates of file.
+func LoadPool(file string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates in %s", file)
+	}
+	return pool, nil
+}
+
+// ClientConfig returns the configuration of a client trusting the CAs of
+// caFile, the system ones if it is empty, and presenting the key pair of
+// certFile and keyFile, if set, for mutual TLS.
+func ClientConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		pool, err := LoadPool(caFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = pool
+	}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load key pair: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
@@ -0,0 +1,102 @@
+// Package adsctl implements the admin command line client of the ads service.
+// It talks to the gRPC API.
+package adsctl
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"homework10/internal/certs"
+	grpcPort "homework10/internal/ports/grpc"
+	"io"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+const usage = `usage: adsctl [-addr host:port] [-tls-ca file] [-tls-cert file -tls-key file] [-o table|json] [-timeout d] <command> [flags]
+
+commands:
+  users create -name NAME -email EMAIL
+  users list
+  users get -id ID
+  users delete -id ID
+  ads create -user ID -title TITLE -text TEXT [-category CATEGORY -price PRICE]
+  ads list [-all | -author ID | -title TITLE | -since RFC3339]
+  ads publish -id ID -user ID
+  ads unpublish -id ID -user ID
+  ads delete -id ID -author ID
+`
+
+var ErrUsage = errors.New("invalid usage")
+
+type command struct {
+	client grpcPort.AdServiceClient
+	out    printer
+}
+
+// Run executes the command given by args and writes its result to stdout.
+func Run(ctx context.Context, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("adsctl", flag.ContinueOnError)
+	fs.SetOutput(stdout)
+	fs.Usage = func() { fmt.Fprint(stdout, usage) }
+	addr := fs.String("addr", "localhost:50054", "grpc address of the ads service")
+	output := fs.String("o", "table", "output format: table or json")
+	timeout := fs.Duration("timeout", 5*time.Second, "request timeout")
+	caFile := fs.String("tls-ca", "", "CA file of the server certificate, enables TLS")
+	certFile := fs.String("tls-cert", "", "client certificate file for mutual TLS")
+	keyFile := fs.String("tls-key", "", "client private key file for mutual TLS")
+	if err := fs.Parse(args); err != nil {
+		return ErrUsage
+	}
+
+	out, err := newPrinter(*output, stdout)
+	if err != nil {
+		return err
+	}
+
+	rest := fs.Args()
+	if len(rest) < 2 {
+		fs.Usage()
+		return ErrUsage
+	}
+
+	creds, err := transportCredentials(*caFile, *certFile, *keyFile)
+	if err != nil {
+		return err
+	}
+	conn, err := grpc.DialContext(ctx, *addr, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return fmt.Errorf("can't connect to %s: %w", *addr, err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+
+	c := command{client: grpcPort.NewAdServiceClient(conn), out: out}
+	switch rest[0] {
+	case "users":
+		return c.users(ctx, rest[1], rest[2:])
+	case "ads":
+		return c.ads(ctx, rest[1], rest[2:])
+	default:
+		fs.Usage()
+		return ErrUsage
+	}
+}
+
+// transportCredentials uses TLS when a CA or a client certificate is given.
+func transportCredentials(caFile, certFile, keyFile string) (credentials.TransportCredentials, error) {
+	if caFile == "" && certFile == "" {
+		return insecure.NewCredentials(), nil
+	}
+	cfg, err := certs.ClientConfig(caFile, certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	return credentials.NewTLS(cfg), nil
+}
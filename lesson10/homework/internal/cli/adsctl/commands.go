@@ -0,0 +1,123 @@
+package adsctl
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	grpcPort "homework10/internal/ports/grpc"
+	"io"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return fs
+}
+
+func (c command) users(ctx context.Context, action string, args []string) error {
+	fs := newFlagSet("users " + action)
+	id := fs.Int64("id", -1, "user id")
+	name := fs.String("name", "", "nickname")
+	email := fs.String("email", "", "email")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w: %s", ErrUsage, err)
+	}
+
+	if action == "list" {
+		res, err := c.client.ListUsers(ctx, &grpcPort.ListUsersRequest{})
+		if err != nil {
+			return grpcPort.FromStatus(err)
+		}
+		return c.out.users(res.GetList()...)
+	}
+
+	var res *grpcPort.UserResponse
+	var err error
+	switch action {
+	case "create":
+		res, err = c.client.CreateUser(ctx, &grpcPort.CreateUserRequest{Name: *name, Email: *email})
+	case "get":
+		res, err = c.client.GetUser(ctx, &grpcPort.GetUserRequest{Id: *id})
+	case "delete":
+		res, err = c.client.DeleteUser(ctx, &grpcPort.DeleteUserRequest{Id: *id})
+	default:
+		return fmt.Errorf("%w: unknown users command %q", ErrUsage, action)
+	}
+	if err != nil {
+		return grpcPort.FromStatus(err)
+	}
+	return c.out.users(res)
+}
+
+func (c command) ads(ctx context.Context, action string, args []string) error {
+	fs := newFlagSet("ads " + action)
+	id := fs.Int64("id", -1, "ad id")
+	user := fs.Int64("user", -1, "id of the user performing the action")
+	author := fs.Int64("author", -1, "author id")
+	title := fs.String("title", "", "title")
+	text := fs.String("text", "", "text")
+	category := fs.String("category", "", "category of the item, with -price")
+	price := fs.Int64("price", 0, "price in the minor units of the currency")
+	all := fs.Bool("all", false, "list unpublished ads too")
+	since := fs.String("since", "", "list ads created after the given RFC3339 time")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w: %s", ErrUsage, err)
+	}
+
+	switch action {
+	case "list":
+		mode, err := listMode(*all, *author, *title, *since)
+		if err != nil {
+			return err
+		}
+		res, err := c.client.ListAds(ctx, mode)
+		if err != nil {
+			return grpcPort.FromStatus(err)
+		}
+		return c.out.ads(res.GetList()...)
+	case "create", "publish", "unpublish", "delete":
+	default:
+		return fmt.Errorf("%w: unknown ads command %q", ErrUsage, action)
+	}
+
+	var res *grpcPort.AdResponse
+	var err error
+	switch action {
+	case "create":
+		r := &grpcPort.CreateAdRequest{UserId: *user, Title: *title, Text: *text}
+		if *category != "" {
+			r.Offer = &grpcPort.Offer{Category: *category, Price: *price}
+		}
+		res, err = c.client.CreateAd(ctx, r)
+	case "publish", "unpublish":
+		res, err = c.client.ChangeAdStatus(ctx, &grpcPort.ChangeAdStatusRequest{AdId: *id, UserId: *user, Published: action == "publish"})
+	case "delete":
+		res, err = c.client.DeleteAd(ctx, &grpcPort.DeleteAdRequest{AdId: *id, AuthorId: *author})
+	}
+	if err != nil {
+		return grpcPort.FromStatus(err)
+	}
+	return c.out.ads(res)
+}
+
+func listMode(all bool, author int64, title string, since string) (*grpcPort.Mode, error) {
+	switch {
+	case author >= 0:
+		return &grpcPort.Mode{Mode: grpcPort.ModeType_ByAuthor, Data: &grpcPort.Mode_AuthorId{AuthorId: author}}, nil
+	case title != "":
+		return &grpcPort.Mode{Mode: grpcPort.ModeType_ByTitle, Data: &grpcPort.Mode_Title{Title: title}}, nil
+	case since != "":
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			return nil, fmt.Errorf("%w: -since: %s", ErrUsage, err)
+		}
+		return &grpcPort.Mode{Mode: grpcPort.ModeType_ByCreation, Data: &grpcPort.Mode_Time{Time: timestamppb.New(t)}}, nil
+	case all:
+		return &grpcPort.Mode{Mode: grpcPort.ModeType_All}, nil
+	default:
+		return &grpcPort.Mode{Mode: grpcPort.ModeType_Default}, nil
+	}
+}
@@ -0,0 +1,88 @@
+package adsctl
+
+import (
+	"encoding/json"
+	"fmt"
+	grpcPort "homework10/internal/ports/grpc"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+type printer interface {
+	users(u ...*grpcPort.UserResponse) error
+	ads(a ...*grpcPort.AdResponse) error
+}
+
+func newPrinter(format string, w io.Writer) (printer, error) {
+	switch format {
+	case "table":
+		return tablePrinter{w: w}, nil
+	case "json":
+		return jsonPrinter{w: w}, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown output format %q", ErrUsage, format)
+	}
+}
+
+type tablePrinter struct {
+	w io.Writer
+}
+
+func (p tablePrinter) users(u ...*grpcPort.UserResponse) error {
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tEMAIL")
+	for _, usr := range u {
+		fmt.Fprintf(tw, "%d\t%s\t%s\n", usr.GetId(), usr.GetName(), usr.GetEmail())
+	}
+	return tw.Flush()
+}
+
+func (p tablePrinter) ads(a ...*grpcPort.AdResponse) error {
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tAUTHOR\tPUBLISHED\tTITLE\tCREATED")
+	for _, ad := range a {
+		fmt.Fprintf(tw, "%d\t%d\t%s\t%s\t%s\n", ad.GetId(), ad.GetAuthorId(), strconv.FormatBool(ad.GetPublished()),
+			ad.GetTitle(), ad.GetCreationDate().AsTime().Format(time.RFC3339))
+	}
+	return tw.Flush()
+}
+
+type jsonPrinter struct {
+	w io.Writer
+}
+
+var marshaler = protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}
+
+func (p jsonPrinter) users(u ...*grpcPort.UserResponse) error {
+	list := make([]json.RawMessage, 0, len(u))
+	for _, usr := range u {
+		data, err := marshaler.Marshal(usr)
+		if err != nil {
+			return err
+		}
+		list = append(list, data)
+	}
+	return p.print(list)
+}
+
+func (p jsonPrinter) ads(a ...*grpcPort.AdResponse) error {
+	list := make([]json.RawMessage, 0, len(a))
+	for _, ad := range a {
+		data, err := marshaler.Marshal(ad)
+		if err != nil {
+			return err
+		}
+		list = append(list, data)
+	}
+	return p.print(list)
+}
+
+func (p jsonPrinter) print(list []json.RawMessage) error {
+	enc := json.NewEncoder(p.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(list)
+}
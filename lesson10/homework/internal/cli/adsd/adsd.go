@@ -0,0 +1,127 @@
+// Package adsd implements the subcommands of the ads service binary.
+package adsd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"homework10/internal/config"
+	"homework10/internal/ports"
+	"io"
+	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+	"syscall"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Version and Commit are set at build time with
+// -ldflags "-X homework10/internal/cli/adsd.Version=... -X homework10/internal/cli/adsd.Commit=...".
+var (
+	Version = "dev"
+	Commit  = "unknown"
+)
+
+const usage = `usage: adsd <command> [flags]
+
+commands:
+  serve    start the http and grpc servers (see -help for configuration flags)
+  migrate  prepare the storage of the configured repository backend
+  seed     load users and ads from a YAML file through the grpc API
+  hash-password [login]
+           print the bcrypt hash of the password read from stdin, with a
+           login as a line of the admin staff file
+  version  print version information
+`
+
+var ErrUsage = errors.New("invalid usage")
+
+// Run executes the subcommand given by args.
+func Run(ctx context.Context, args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		fmt.Fprint(stdout, usage)
+		return ErrUsage
+	}
+	switch args[0] {
+	case "serve":
+		return serve(ctx, args[1:])
+	case "migrate":
+		return migrate(args[1:], stdout)
+	case "seed":
+		return seed(ctx, args[1:], stdout)
+	case "hash-password":
+		return hashPassword(args[1:], os.Stdin, stdout)
+	case "version":
+		fmt.Fprintf(stdout, "adsd %s (commit %s, %s)\n", Version, Commit, runtime.Version())
+		return nil
+	default:
+		fmt.Fprint(stdout, usage)
+		return ErrUsage
+	}
+}
+
+func serve(ctx context.Context, args []string) error {
+	cfg, err := config.Load(args)
+	if err != nil {
+		return err
+	}
+	tel, err := ports.NewTelemetry(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	hooks := ports.NewWebhooks(cfg, tel.Logger)
+	a, err := ports.NewApp(cfg, tel, hooks)
+	if err != nil {
+		return errors.Join(err, hooks.Close(ctx))
+	}
+	signal.Ignore(syscall.SIGHUP, syscall.SIGPIPE)
+	srv, err := ports.NewServer(cfg, a, tel, ports.WithWebhooks(hooks), ports.WithSignals(syscall.SIGINT, syscall.SIGTERM))
+	if err != nil {
+		return errors.Join(err, hooks.Close(ctx), tel.Shutdown(ctx))
+	}
+	return srv.Run(ctx)
+}
+
+func migrate(args []string, stdout io.Writer) error {
+	cfg, err := config.Load(args)
+	if err != nil {
+		return err
+	}
+	switch cfg.Repository.Backend {
+	case config.BackendMemory:
+		fmt.Fprintf(stdout, "repository backend %q keeps no schema, nothing to migrate\n", cfg.Repository.Backend)
+		return nil
+	default:
+		return fmt.Errorf("unknown repository backend %q", cfg.Repository.Backend)
+	}
+}
+
+// hashPassword reads a password from the first line of in and prints its
+// bcrypt hash, prefixed with the login if given.
+func hashPassword(args []string, in io.Reader, stdout io.Writer) error {
+	if len(args) > 1 || (len(args) == 1 && (args[0] == "" || strings.Contains(args[0], ":"))) {
+		fmt.Fprint(stdout, usage)
+		return ErrUsage
+	}
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("read password: %w", err)
+	}
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return errors.New("empty password")
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if len(args) == 1 {
+		fmt.Fprintf(stdout, "%s:%s\n", args[0], hash)
+		return nil
+	}
+	fmt.Fprintln(stdout, string(hash))
+	return nil
+}
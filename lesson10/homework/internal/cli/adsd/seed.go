@@ -0,0 +1,93 @@
+package adsd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"homework10/internal/certs"
+	grpcPort "homework10/internal/ports/grpc"
+	"io"
+	"os"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"gopkg.in/yaml.v3"
+)
+
+type seedData struct {
+	Users []seedUser `yaml:"users"`
+}
+
+type seedUser struct {
+	Nickname string   `yaml:"nickname"`
+	Email    string   `yaml:"email"`
+	Ads      []seedAd `yaml:"ads"`
+}
+
+type seedAd struct {
+	Title     string `yaml:"title"`
+	Text      string `yaml:"text"`
+	Published bool   `yaml:"published"`
+}
+
+// seed loads fixtures through the grpc API of a running server, so the data
+// passes the same validation as client requests regardless of the backend.
+func seed(ctx context.Context, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("seed", flag.ContinueOnError)
+	addr := fs.String("addr", "localhost:50054", "grpc address of the ads service")
+	file := fs.String("file", "configs/seed.yaml", "seed file")
+	caFile := fs.String("tls-ca", "", "CA file of the server certificate, enables TLS")
+	certFile := fs.String("tls-cert", "", "client certificate file for mutual TLS")
+	keyFile := fs.String("tls-key", "", "client private key file for mutual TLS")
+	if err := fs.Parse(args); err != nil {
+		return ErrUsage
+	}
+
+	bytes, err := os.ReadFile(*file)
+	if err != nil {
+		return fmt.Errorf("read seed file error: %w", err)
+	}
+	var data seedData
+	if err := yaml.Unmarshal(bytes, &data); err != nil {
+		return fmt.Errorf("broken seed file: %w", err)
+	}
+
+	creds := insecure.NewCredentials()
+	if *caFile != "" || *certFile != "" {
+		cfg, err := certs.ClientConfig(*caFile, *certFile, *keyFile)
+		if err != nil {
+			return err
+		}
+		creds = credentials.NewTLS(cfg)
+	}
+	conn, err := grpc.DialContext(ctx, *addr, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return fmt.Errorf("can't connect to %s: %w", *addr, err)
+	}
+	defer conn.Close()
+	client := grpcPort.NewAdServiceClient(conn)
+
+	var adCount int
+	for _, u := range data.Users {
+		usr, err := client.CreateUser(ctx, &grpcPort.CreateUserRequest{Name: u.Nickname, Email: u.Email})
+		if err != nil {
+			return fmt.Errorf("create user %q: %w", u.Nickname, grpcPort.FromStatus(err))
+		}
+		for _, a := range u.Ads {
+			ad, err := client.CreateAd(ctx, &grpcPort.CreateAdRequest{UserId: usr.GetId(), Title: a.Title, Text: a.Text})
+			if err != nil {
+				return fmt.Errorf("create ad %q: %w", a.Title, grpcPort.FromStatus(err))
+			}
+			if a.Published {
+				_, err = client.ChangeAdStatus(ctx, &grpcPort.ChangeAdStatusRequest{AdId: ad.GetId(), UserId: usr.GetId(), Published: true})
+				if err != nil {
+					return fmt.Errorf("publish ad %q: %w", a.Title, grpcPort.FromStatus(err))
+				}
+			}
+			adCount++
+		}
+	}
+	fmt.Fprintf(stdout, "seeded %d users and %d ads\n", len(data.Users), adCount)
+	return nil
+}
@@ -0,0 +1,574 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type LogLevel string
+
+const (
+	LevelDebug LogLevel = "debug"
+	LevelInfo  LogLevel = "info"
+	LevelWarn  LogLevel = "warn"
+	LevelError LogLevel = "error"
+)
+
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+const BackendMemory = "memory"
+
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterFile   = "file"
+	ExporterOTLP   = "otlp"
+)
+
+const (
+	defaultHTTPAddr        = ":18080"
+	defaultGRPCAddr        = ":50054"
+	defaultMetricsAddr     = ":9090"
+	defaultLogLevel        = LevelInfo
+	defaultLogFormat       = FormatJSON
+	defaultGinMode         = "release"
+	defaultShutdownTimeout = 30 * time.Second
+	defaultBackend         = BackendMemory
+	defaultExporter        = ExporterNone
+	defaultOTLPEndpoint    = "localhost:4317"
+	defaultServiceName     = "ads"
+	defaultTimeout         = 10 * time.Second
+	defaultIdempotencyTTL  = 24 * time.Hour
+	defaultWebhookWorkers  = 4
+	defaultWebhookAttempts = 6
+	defaultWebhookBackoff  = time.Second
+	defaultWebhookMaxWait  = 5 * time.Minute
+	defaultWebhookTimeout  = 10 * time.Second
+	defaultCacheSize       = 10000
+	defaultCacheTTL        = time.Minute
+	defaultViewWindow      = time.Hour
+	defaultTLSReload       = 10 * time.Second
+	defaultAdminSession    = 8 * time.Hour
+)
+
+type Config struct {
+	HTTP            ServerConfig      `yaml:"http"`
+	GRPC            ServerConfig      `yaml:"grpc"`
+	Metrics         ServerConfig      `yaml:"metrics"`
+	LogLevel        LogLevel          `yaml:"log_level"`
+	LogFormat       string            `yaml:"log_format"`
+	GinMode         string            `yaml:"gin_mode"`
+	ShutdownTimeout time.Duration     `yaml:"shutdown_timeout"`
+	DrainDelay      time.Duration     `yaml:"drain_delay"`
+	Repository      RepositoryConfig  `yaml:"repository"`
+	Tracing         TracingConfig     `yaml:"tracing"`
+	Timeouts        TimeoutsConfig    `yaml:"timeouts"`
+	Idempotency     IdempotencyConfig `yaml:"idempotency"`
+	Webhooks        WebhooksConfig    `yaml:"webhooks"`
+	Cache           CacheConfig       `yaml:"cache"`
+	Analytics       AnalyticsConfig   `yaml:"analytics"`
+	Admin           AdminConfig       `yaml:"admin"`
+}
+
+// ServerConfig is the address and TLS of a server. TrustedProxies, http
+// only, are the IPs or CIDRs of the proxies whose X-Forwarded-For and
+// X-Real-IP headers give the client address. Without them the client
+// address is the peer address and the headers are ignored.
+type ServerConfig struct {
+	Addr           string    `yaml:"addr"`
+	TLS            TLSConfig `yaml:"tls"`
+	TrustedProxies []string  `yaml:"trusted_proxies"`
+}
+
+// TLSConfig enables TLS when CertFile and KeyFile are set. The files are
+// checked for changes at most every ReloadInterval, zero checks them on
+// every handshake. ClientCAFile, grpc only, requires client certificates
+// signed by one of its CAs. Principals maps the subject of a client
+// certificate, e.g. "CN=billing,O=ads", to the name of the service calling,
+// calls with other subjects are refused. Without Principals the name is the
+// common name of the subject. The /api/v2 gateway is served by the http
+// server, so the client certificates of grpc don't guard it.
+type TLSConfig struct {
+	CertFile       string            `yaml:"cert_file"`
+	KeyFile        string            `yaml:"key_file"`
+	ClientCAFile   string            `yaml:"client_ca_file"`
+	Principals     map[string]string `yaml:"principals"`
+	ReloadInterval time.Duration     `yaml:"reload_interval"`
+}
+
+// Enabled reports whether the server uses TLS.
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != ""
+}
+
+func (c TLSConfig) validate(server string, mutual bool) []error {
+	var errs []error
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		errs = append(errs, fmt.Errorf("%s.tls: cert_file and key_file must be set together", server))
+	}
+	switch {
+	case c.ClientCAFile != "" && !mutual:
+		errs = append(errs, fmt.Errorf("%s.tls.client_ca_file: mutual TLS is only supported by grpc", server))
+	case c.ClientCAFile != "" && !c.Enabled():
+		errs = append(errs, fmt.Errorf("%s.tls.client_ca_file: requires cert_file", server))
+	}
+	if len(c.Principals) > 0 && c.ClientCAFile == "" {
+		errs = append(errs, fmt.Errorf("%s.tls.principals: requires client_ca_file", server))
+	}
+	if c.ReloadInterval < 0 {
+		errs = append(errs, fmt.Errorf("%s.tls.reload_interval: must not be negative", server))
+	}
+	return errs
+}
+
+type RepositoryConfig struct {
+	Backend string `yaml:"backend"`
+}
+
+// TracingConfig selects where spans are exported. File is used by the file
+// exporter and OTLPEndpoint, a host:port of a collector, by the otlp one.
+type TracingConfig struct {
+	Exporter     string `yaml:"exporter"`
+	ServiceName  string `yaml:"service_name"`
+	File         string `yaml:"file"`
+	OTLPEndpoint string `yaml:"otlp_endpoint"`
+	OTLPInsecure bool   `yaml:"otlp_insecure"`
+}
+
+// TimeoutsConfig bounds the App operations. Operations overrides Default
+// for single methods of app.App by name, e.g. CreateAd. Zero disables the
+// bound.
+type TimeoutsConfig struct {
+	Default    time.Duration            `yaml:"default"`
+	Operations map[string]time.Duration `yaml:"operations"`
+}
+
+// IdempotencyConfig sets how long responses are replayed for retries with
+// the same Idempotency-Key. The http and grpc APIs store the responses
+// separately, each for TTL.
+type IdempotencyConfig struct {
+	TTL time.Duration `yaml:"ttl"`
+}
+
+// WebhooksConfig sets how events are delivered to webhooks. A failed
+// attempt is retried after Backoff, doubled after every attempt up to
+// MaxBackoff, until MaxAttempts are made. Timeout bounds a single attempt.
+// Deliveries go only to public addresses unless AllowPrivateAddresses is
+// set.
+type WebhooksConfig struct {
+	Workers               int           `yaml:"workers"`
+	MaxAttempts           int           `yaml:"max_attempts"`
+	Backoff               time.Duration `yaml:"backoff"`
+	MaxBackoff            time.Duration `yaml:"max_backoff"`
+	Timeout               time.Duration `yaml:"timeout"`
+	AllowPrivateAddresses bool          `yaml:"allow_private_addresses"`
+}
+
+// CacheConfig bounds the read-through cache of the repositories. Size is
+// the number of entries per repository, zero disables the cache.
+type CacheConfig struct {
+	Size int           `yaml:"size"`
+	TTL  time.Duration `yaml:"ttl"`
+}
+
+// AnalyticsConfig sets how views are counted. Repeated views of an ad by
+// the same viewer within ViewWindow are counted once.
+type AnalyticsConfig struct {
+	ViewWindow time.Duration `yaml:"view_window"`
+}
+
+// AdminConfig enables the admin console at /admin of the http server when
+// StaffFile is set. The file has a "login:bcrypt hash" line per staff
+// member, as made by `adsd hash-password` or `htpasswd -B`. It is not part
+// of the YAML file, where the environment variables would be expanded in
+// the hashes. A session lasts SessionTTL, and all sessions end when the
+// server restarts.
+type AdminConfig struct {
+	StaffFile  string        `yaml:"staff_file"`
+	SessionTTL time.Duration `yaml:"session_ttl"`
+}
+
+// Enabled reports whether the admin console is served.
+func (c AdminConfig) Enabled() bool {
+	return c.StaffFile != ""
+}
+
+// Default returns the configuration used when nothing is overridden.
+func Default() Config {
+	return Config{
+		HTTP:            ServerConfig{Addr: defaultHTTPAddr, TLS: TLSConfig{ReloadInterval: defaultTLSReload}},
+		GRPC:            ServerConfig{Addr: defaultGRPCAddr, TLS: TLSConfig{ReloadInterval: defaultTLSReload}},
+		Metrics:         ServerConfig{Addr: defaultMetricsAddr, TLS: TLSConfig{ReloadInterval: defaultTLSReload}},
+		LogLevel:        defaultLogLevel,
+		LogFormat:       defaultLogFormat,
+		GinMode:         defaultGinMode,
+		ShutdownTimeout: defaultShutdownTimeout,
+		Repository:      RepositoryConfig{Backend: defaultBackend},
+		Tracing: TracingConfig{
+			Exporter:     defaultExporter,
+			ServiceName:  defaultServiceName,
+			OTLPEndpoint: defaultOTLPEndpoint,
+		},
+		Timeouts:    TimeoutsConfig{Default: defaultTimeout},
+		Idempotency: IdempotencyConfig{TTL: defaultIdempotencyTTL},
+		Webhooks: WebhooksConfig{
+			Workers:     defaultWebhookWorkers,
+			MaxAttempts: defaultWebhookAttempts,
+			Backoff:     defaultWebhookBackoff,
+			MaxBackoff:  defaultWebhookMaxWait,
+			Timeout:     defaultWebhookTimeout,
+		},
+		Cache:     CacheConfig{Size: defaultCacheSize, TTL: defaultCacheTTL},
+		Analytics: AnalyticsConfig{ViewWindow: defaultViewWindow},
+		Admin:     AdminConfig{SessionTTL: defaultAdminSession},
+	}
+}
+
+// Load builds the configuration in layers: defaults, then the YAML file given
+// by -config or ADS_CONFIG, then ADS_* environment variables, then flags.
+// The result is validated.
+func Load(args []string) (Config, error) {
+	cfg := Default()
+
+	fs := flag.NewFlagSet("ads", flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("ADS_CONFIG"), "application configuration file")
+	httpAddr := fs.String("http-addr", "", "http listen address")
+	grpcAddr := fs.String("grpc-addr", "", "grpc listen address")
+	metricsAddr := fs.String("metrics-addr", "", "prometheus metrics listen address")
+	httpCert := fs.String("http-tls-cert", "", "certificate file of the http server, enables TLS")
+	httpKey := fs.String("http-tls-key", "", "private key file of the http server")
+	grpcCert := fs.String("grpc-tls-cert", "", "certificate file of the grpc server, enables TLS")
+	grpcKey := fs.String("grpc-tls-key", "", "private key file of the grpc server")
+	grpcClientCA := fs.String("grpc-tls-client-ca", "", "CA file of the client certificates, enables mutual TLS on grpc")
+	adminStaff := fs.String("admin-staff-file", "", "file of the staff logins and password hashes, enables the admin console")
+	logLevel := fs.String("log-level", "", "log level: debug, info, warn or error")
+	logFormat := fs.String("log-format", "", "log format: json or text")
+	ginMode := fs.String("gin-mode", "", "gin mode: debug, release or test")
+	shutdownTimeout := fs.Duration("shutdown-timeout", 0, "graceful shutdown timeout")
+	drainDelay := fs.Duration("drain-delay", 0, "time between reporting not ready and stopping the servers")
+	timeout := fs.Duration("timeout", 0, "default timeout of an operation, 0 disables it")
+	idempotencyTTL := fs.Duration("idempotency-ttl", 0, "how long responses are kept for idempotency keys")
+	cacheSize := fs.Int("cache-size", 0, "entries of the repository cache, 0 disables it")
+	cacheTTL := fs.Duration("cache-ttl", 0, "how long repository cache entries are served")
+	viewWindow := fs.Duration("view-window", 0, "time in which repeated views by a viewer are counted once")
+	webhookWorkers := fs.Int("webhook-workers", 0, "number of concurrent webhook deliveries")
+	backend := fs.String("repository-backend", "", "repository backend")
+	exporter := fs.String("tracing-exporter", "", "span exporter: none, stdout, file or otlp")
+	traceFile := fs.String("tracing-file", "", "file for the file span exporter")
+	otlpEndpoint := fs.String("tracing-otlp-endpoint", "", "host:port of the OTLP collector")
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	if *configFile != "" {
+		if err := cfg.loadFile(*configFile); err != nil {
+			return Config{}, err
+		}
+	}
+
+	if err := cfg.loadEnv(); err != nil {
+		return Config{}, err
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "http-addr":
+			cfg.HTTP.Addr = *httpAddr
+		case "grpc-addr":
+			cfg.GRPC.Addr = *grpcAddr
+		case "metrics-addr":
+			cfg.Metrics.Addr = *metricsAddr
+		case "http-tls-cert":
+			cfg.HTTP.TLS.CertFile = *httpCert
+		case "http-tls-key":
+			cfg.HTTP.TLS.KeyFile = *httpKey
+		case "grpc-tls-cert":
+			cfg.GRPC.TLS.CertFile = *grpcCert
+		case "grpc-tls-key":
+			cfg.GRPC.TLS.KeyFile = *grpcKey
+		case "grpc-tls-client-ca":
+			cfg.GRPC.TLS.ClientCAFile = *grpcClientCA
+		case "admin-staff-file":
+			cfg.Admin.StaffFile = *adminStaff
+		case "log-level":
+			cfg.LogLevel = LogLevel(*logLevel)
+		case "log-format":
+			cfg.LogFormat = *logFormat
+		case "gin-mode":
+			cfg.GinMode = *ginMode
+		case "shutdown-timeout":
+			cfg.ShutdownTimeout = *shutdownTimeout
+		case "drain-delay":
+			cfg.DrainDelay = *drainDelay
+		case "timeout":
+			cfg.Timeouts.Default = *timeout
+		case "idempotency-ttl":
+			cfg.Idempotency.TTL = *idempotencyTTL
+		case "cache-size":
+			cfg.Cache.Size = *cacheSize
+		case "cache-ttl":
+			cfg.Cache.TTL = *cacheTTL
+		case "view-window":
+			cfg.Analytics.ViewWindow = *viewWindow
+		case "webhook-workers":
+			cfg.Webhooks.Workers = *webhookWorkers
+		case "repository-backend":
+			cfg.Repository.Backend = *backend
+		case "tracing-exporter":
+			cfg.Tracing.Exporter = *exporter
+		case "tracing-file":
+			cfg.Tracing.File = *traceFile
+		case "tracing-otlp-endpoint":
+			cfg.Tracing.OTLPEndpoint = *otlpEndpoint
+		}
+	})
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file error: %w", err)
+	}
+
+	// expand envs
+	bytes = []byte(os.ExpandEnv(string(bytes)))
+
+	if err := yaml.Unmarshal(bytes, c); err != nil {
+		return fmt.Errorf("broken config file: %w", err)
+	}
+	return nil
+}
+
+func (c *Config) loadEnv() error {
+	if v, ok := os.LookupEnv("ADS_HTTP_ADDR"); ok {
+		c.HTTP.Addr = v
+	}
+	if v, ok := os.LookupEnv("ADS_GRPC_ADDR"); ok {
+		c.GRPC.Addr = v
+	}
+	if v, ok := os.LookupEnv("ADS_METRICS_ADDR"); ok {
+		c.Metrics.Addr = v
+	}
+	if v, ok := os.LookupEnv("ADS_HTTP_TLS_CERT"); ok {
+		c.HTTP.TLS.CertFile = v
+	}
+	if v, ok := os.LookupEnv("ADS_HTTP_TLS_KEY"); ok {
+		c.HTTP.TLS.KeyFile = v
+	}
+	if v, ok := os.LookupEnv("ADS_GRPC_TLS_CERT"); ok {
+		c.GRPC.TLS.CertFile = v
+	}
+	if v, ok := os.LookupEnv("ADS_GRPC_TLS_KEY"); ok {
+		c.GRPC.TLS.KeyFile = v
+	}
+	if v, ok := os.LookupEnv("ADS_GRPC_TLS_CLIENT_CA"); ok {
+		c.GRPC.TLS.ClientCAFile = v
+	}
+	if v, ok := os.LookupEnv("ADS_ADMIN_STAFF_FILE"); ok {
+		c.Admin.StaffFile = v
+	}
+	if v, ok := os.LookupEnv("ADS_LOG_LEVEL"); ok {
+		c.LogLevel = LogLevel(v)
+	}
+	if v, ok := os.LookupEnv("ADS_LOG_FORMAT"); ok {
+		c.LogFormat = v
+	}
+	if v, ok := os.LookupEnv("ADS_GIN_MODE"); ok {
+		c.GinMode = v
+	}
+	if v, ok := os.LookupEnv("ADS_SHUTDOWN_TIMEOUT"); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("ADS_SHUTDOWN_TIMEOUT: %w", err)
+		}
+		c.ShutdownTimeout = d
+	}
+	if v, ok := os.LookupEnv("ADS_DRAIN_DELAY"); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("ADS_DRAIN_DELAY: %w", err)
+		}
+		c.DrainDelay = d
+	}
+	if v, ok := os.LookupEnv("ADS_TIMEOUT"); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("ADS_TIMEOUT: %w", err)
+		}
+		c.Timeouts.Default = d
+	}
+	if v, ok := os.LookupEnv("ADS_IDEMPOTENCY_TTL"); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("ADS_IDEMPOTENCY_TTL: %w", err)
+		}
+		c.Idempotency.TTL = d
+	}
+	if v, ok := os.LookupEnv("ADS_CACHE_SIZE"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("ADS_CACHE_SIZE: %w", err)
+		}
+		c.Cache.Size = n
+	}
+	if v, ok := os.LookupEnv("ADS_CACHE_TTL"); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("ADS_CACHE_TTL: %w", err)
+		}
+		c.Cache.TTL = d
+	}
+	if v, ok := os.LookupEnv("ADS_VIEW_WINDOW"); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("ADS_VIEW_WINDOW: %w", err)
+		}
+		c.Analytics.ViewWindow = d
+	}
+	if v, ok := os.LookupEnv("ADS_WEBHOOK_WORKERS"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("ADS_WEBHOOK_WORKERS: %w", err)
+		}
+		c.Webhooks.Workers = n
+	}
+	if v, ok := os.LookupEnv("ADS_REPOSITORY_BACKEND"); ok {
+		c.Repository.Backend = v
+	}
+	if v, ok := os.LookupEnv("ADS_TRACING_EXPORTER"); ok {
+		c.Tracing.Exporter = v
+	}
+	if v, ok := os.LookupEnv("ADS_TRACING_FILE"); ok {
+		c.Tracing.File = v
+	}
+	if v, ok := os.LookupEnv("ADS_TRACING_OTLP_ENDPOINT"); ok {
+		c.Tracing.OTLPEndpoint = v
+	}
+	if v, ok := os.LookupEnv("ADS_TRACING_OTLP_INSECURE"); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("ADS_TRACING_OTLP_INSECURE: %w", err)
+		}
+		c.Tracing.OTLPInsecure = b
+	}
+	return nil
+}
+
+// Validate reports all problems of the configuration at once.
+func (c Config) Validate() error {
+	var errs []error
+	if _, _, err := net.SplitHostPort(c.HTTP.Addr); err != nil {
+		errs = append(errs, fmt.Errorf("http.addr: %w", err))
+	}
+	if _, _, err := net.SplitHostPort(c.GRPC.Addr); err != nil {
+		errs = append(errs, fmt.Errorf("grpc.addr: %w", err))
+	}
+	if _, _, err := net.SplitHostPort(c.Metrics.Addr); err != nil {
+		errs = append(errs, fmt.Errorf("metrics.addr: %w", err))
+	}
+	errs = append(errs, c.HTTP.TLS.validate("http", false)...)
+	errs = append(errs, c.GRPC.TLS.validate("grpc", true)...)
+	errs = append(errs, c.Metrics.TLS.validate("metrics", false)...)
+	for _, p := range c.HTTP.TrustedProxies {
+		if _, _, err := net.ParseCIDR(p); err != nil && net.ParseIP(p) == nil {
+			errs = append(errs, fmt.Errorf("http.trusted_proxies: invalid IP or CIDR %q", p))
+		}
+	}
+	if len(c.GRPC.TrustedProxies) > 0 {
+		errs = append(errs, errors.New("grpc.trusted_proxies: only supported by http"))
+	}
+	if len(c.Metrics.TrustedProxies) > 0 {
+		errs = append(errs, errors.New("metrics.trusted_proxies: only supported by http"))
+	}
+	switch c.LogLevel {
+	case LevelDebug, LevelInfo, LevelWarn, LevelError:
+	default:
+		errs = append(errs, fmt.Errorf("log_level: unknown level %q", c.LogLevel))
+	}
+	switch c.LogFormat {
+	case FormatJSON, FormatText:
+	default:
+		errs = append(errs, fmt.Errorf("log_format: unknown format %q", c.LogFormat))
+	}
+	switch c.GinMode {
+	case "debug", "release", "test":
+	default:
+		errs = append(errs, fmt.Errorf("gin_mode: unknown mode %q", c.GinMode))
+	}
+	if c.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("shutdown_timeout: must be positive"))
+	}
+	if c.DrainDelay < 0 {
+		errs = append(errs, errors.New("drain_delay: must not be negative"))
+	}
+	if c.Timeouts.Default < 0 {
+		errs = append(errs, errors.New("timeouts.default: must not be negative"))
+	}
+	for op, d := range c.Timeouts.Operations {
+		if d < 0 {
+			errs = append(errs, fmt.Errorf("timeouts.operations.%s: must not be negative", op))
+		}
+	}
+	if c.Idempotency.TTL <= 0 {
+		errs = append(errs, errors.New("idempotency.ttl: must be positive"))
+	}
+	if c.Cache.Size < 0 {
+		errs = append(errs, errors.New("cache.size: must not be negative"))
+	}
+	if c.Cache.Size > 0 && c.Cache.TTL <= 0 {
+		errs = append(errs, errors.New("cache.ttl: must be positive"))
+	}
+	if c.Analytics.ViewWindow <= 0 {
+		errs = append(errs, errors.New("analytics.view_window: must be positive"))
+	}
+	if c.Admin.SessionTTL <= 0 {
+		errs = append(errs, errors.New("admin.session_ttl: must be positive"))
+	}
+	if c.Webhooks.Workers <= 0 {
+		errs = append(errs, errors.New("webhooks.workers: must be positive"))
+	}
+	if c.Webhooks.MaxAttempts <= 0 {
+		errs = append(errs, errors.New("webhooks.max_attempts: must be positive"))
+	}
+	if c.Webhooks.Backoff <= 0 {
+		errs = append(errs, errors.New("webhooks.backoff: must be positive"))
+	}
+	if c.Webhooks.MaxBackoff < c.Webhooks.Backoff {
+		errs = append(errs, errors.New("webhooks.max_backoff: must not be less than webhooks.backoff"))
+	}
+	if c.Webhooks.Timeout <= 0 {
+		errs = append(errs, errors.New("webhooks.timeout: must be positive"))
+	}
+	if c.Repository.Backend != BackendMemory {
+		errs = append(errs, fmt.Errorf("repository.backend: unknown backend %q", c.Repository.Backend))
+	}
+	switch c.Tracing.Exporter {
+	case ExporterNone, ExporterStdout:
+	case ExporterFile:
+		if c.Tracing.File == "" {
+			errs = append(errs, errors.New("tracing.file: required by the file exporter"))
+		}
+	case ExporterOTLP:
+		if _, _, err := net.SplitHostPort(c.Tracing.OTLPEndpoint); err != nil {
+			errs = append(errs, fmt.Errorf("tracing.otlp_endpoint: %w", err))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("tracing.exporter: unknown exporter %q", c.Tracing.Exporter))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,144 @@
+// Package engagement keeps the favorites and the messages of ads, the
+// interest of buyers beyond views. A user favorites an ad once, messages are
+// sent by buyers to the author of the ad. Only the latest messages of every
+// ad are kept, the counts include the dropped ones.
+package engagement
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultLimit is the number of messages kept per ad.
+const DefaultLimit = 1000
+
+// Message is a message to the author of an ad.
+type Message struct {
+	ID       int64     `json:"id"`
+	AdID     int64     `json:"ad_id"`
+	SenderID int64     `json:"sender_id"`
+	Text     string    `json:"text"`
+	Time     time.Time `json:"time"`
+}
+
+// SellerEngagement sums the favorites and messages of the ads of a seller.
+type SellerEngagement struct {
+	Favorites int64
+	Messages  int64
+}
+
+type adState struct {
+	authorID  int64
+	favorites map[int64]bool
+	sent      int64
+	messages  []Message
+}
+
+// Store is safe for concurrent use.
+type Store struct {
+	limit int
+
+	mtx    sync.RWMutex
+	nextID int64
+	ads    map[int64]*adState
+}
+
+type Option func(*Store)
+
+// WithLimit sets the number of messages kept per ad.
+func WithLimit(n int) Option {
+	return func(s *Store) {
+		s.limit = n
+	}
+}
+
+func New(opts ...Option) *Store {
+	s := &Store{limit: DefaultLimit, nextID: 1, ads: map[int64]*adState{}}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *Store) ad(adID int64, authorID int64) *adState {
+	st, ok := s.ads[adID]
+	if !ok {
+		st = &adState{authorID: authorID, favorites: map[int64]bool{}}
+		s.ads[adID] = st
+	}
+	return st
+}
+
+// Favorite adds the ad of authorID to the favorites of userID. It reports
+// whether the ad was not a favorite of the user yet.
+func (s *Store) Favorite(adID int64, authorID int64, userID int64) bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	st := s.ad(adID, authorID)
+	if st.favorites[userID] {
+		return false
+	}
+	st.favorites[userID] = true
+	return true
+}
+
+// Unfavorite removes the ad from the favorites of userID. It reports whether
+// the ad was a favorite of the user.
+func (s *Store) Unfavorite(adID int64, userID int64) bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	st, ok := s.ads[adID]
+	if !ok || !st.favorites[userID] {
+		return false
+	}
+	delete(st.favorites, userID)
+	return true
+}
+
+// Send stores m to the author of its ad and returns it with its ID. The
+// oldest message of the ad is dropped over the limit.
+func (s *Store) Send(m Message, authorID int64) Message {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	m.ID = s.nextID
+	s.nextID++
+	st := s.ad(m.AdID, authorID)
+	st.sent++
+	st.messages = append(st.messages, m)
+	if len(st.messages) > s.limit {
+		st.messages = append(st.messages[:0], st.messages[len(st.messages)-s.limit:]...)
+	}
+	return m
+}
+
+// Messages returns the kept messages of an ad, the oldest first.
+func (s *Store) Messages(adID int64) []Message {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	st, ok := s.ads[adID]
+	if !ok {
+		return []Message{}
+	}
+	return append([]Message{}, st.messages...)
+}
+
+// Seller sums the favorites and messages of the ads of authorID.
+func (s *Store) Seller(authorID int64) SellerEngagement {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	var res SellerEngagement
+	for _, st := range s.ads {
+		if st.authorID == authorID {
+			res.Favorites += int64(len(st.favorites))
+			res.Messages += st.sent
+		}
+	}
+	return res
+}
+
+// Forget drops the favorites and messages of a deleted ad.
+func (s *Store) Forget(adID int64) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	delete(s.ads, adID)
+}
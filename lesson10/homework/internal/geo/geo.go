@@ -0,0 +1,103 @@
+// Package geo has the geometry of ad locations: great-circle distances,
+// bounding boxes and a grid index of points for area queries.
+package geo
+
+import "math"
+
+// EarthRadius is the mean radius of the Earth in kilometers.
+const EarthRadius = 6371.0
+
+// Point is a position in degrees.
+type Point struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// Valid reports whether the latitude is from -90 to 90 and the longitude
+// from -180 to 180.
+func (p Point) Valid() bool {
+	return p.Lat >= -90 && p.Lat <= 90 && p.Lon >= -180 && p.Lon <= 180
+}
+
+func radians(deg float64) float64 { return deg * math.Pi / 180 }
+func degrees(rad float64) float64 { return rad * 180 / math.Pi }
+
+// Distance returns the haversine distance between a and b in kilometers.
+func Distance(a, b Point) float64 {
+	dLat := radians(b.Lat - a.Lat)
+	dLon := radians(b.Lon - a.Lon)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(radians(a.Lat))*math.Cos(radians(b.Lat))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * EarthRadius * math.Asin(math.Sqrt(math.Min(1, h)))
+}
+
+// Box is an area between two latitudes and two longitudes. A box with
+// MinLon greater than MaxLon crosses the antimeridian.
+type Box struct {
+	MinLat float64 `json:"min_lat"`
+	MinLon float64 `json:"min_lon"`
+	MaxLat float64 `json:"max_lat"`
+	MaxLon float64 `json:"max_lon"`
+}
+
+// Valid reports whether both corners are valid and MinLat is not greater
+// than MaxLat.
+func (b Box) Valid() bool {
+	return Point{b.MinLat, b.MinLon}.Valid() && Point{b.MaxLat, b.MaxLon}.Valid() && b.MinLat <= b.MaxLat
+}
+
+func (b Box) Contains(p Point) bool {
+	if p.Lat < b.MinLat || p.Lat > b.MaxLat {
+		return false
+	}
+	if b.MinLon <= b.MaxLon {
+		return p.Lon >= b.MinLon && p.Lon <= b.MaxLon
+	}
+	return p.Lon >= b.MinLon || p.Lon <= b.MaxLon
+}
+
+// Center is the middle of the box, across the antimeridian if the box
+// crosses it.
+func (b Box) Center() Point {
+	lon := (b.MinLon + b.MaxLon) / 2
+	if b.MinLon > b.MaxLon {
+		lon += 180
+		if lon > 180 {
+			lon -= 360
+		}
+	}
+	return Point{Lat: (b.MinLat + b.MaxLat) / 2, Lon: lon}
+}
+
+// Circle is the area within Radius kilometers of Center.
+type Circle struct {
+	Center Point   `json:"center"`
+	Radius float64 `json:"radius_km"`
+}
+
+func (c Circle) Contains(p Point) bool {
+	return Distance(c.Center, p) <= c.Radius
+}
+
+// Bounds returns the smallest box containing the circle. The box covers
+// all longitudes when the circle contains a pole.
+func (c Circle) Bounds() Box {
+	d := c.Radius / EarthRadius
+	lat := radians(c.Center.Lat)
+	minLat, maxLat := lat-d, lat+d
+	if minLat <= -math.Pi/2 || maxLat >= math.Pi/2 {
+		return Box{
+			MinLat: math.Max(-90, degrees(minLat)), MinLon: -180,
+			MaxLat: math.Min(90, degrees(maxLat)), MaxLon: 180,
+		}
+	}
+	dLon := degrees(math.Asin(math.Sin(d) / math.Cos(lat)))
+	minLon, maxLon := c.Center.Lon-dLon, c.Center.Lon+dLon
+	if minLon < -180 {
+		minLon += 360
+	}
+	if maxLon > 180 {
+		maxLon -= 360
+	}
+	return Box{MinLat: degrees(minLat), MinLon: minLon, MaxLat: degrees(maxLat), MaxLon: maxLon}
+}
@@ -0,0 +1,131 @@
+package geo
+
+import (
+	"maps"
+	"math"
+)
+
+// DefaultCellSize is the side of a grid cell in degrees, about 11 km of
+// latitude.
+const DefaultCellSize = 0.1
+
+type cell struct {
+	row, col int
+}
+
+// Grid indexes points by ID in cells of equal size in degrees. It is not
+// safe for concurrent use, the owner locks it with its storage.
+type Grid struct {
+	size  float64
+	rows  int
+	cols  int
+	cells map[cell]map[int64]Point
+}
+
+// NewGrid returns an empty grid with cells of size degrees, DefaultCellSize
+// if size is not positive.
+func NewGrid(size float64) *Grid {
+	if size <= 0 {
+		size = DefaultCellSize
+	}
+	return &Grid{
+		size:  size,
+		rows:  int(math.Ceil(180 / size)),
+		cols:  int(math.Ceil(360 / size)),
+		cells: map[cell]map[int64]Point{},
+	}
+}
+
+func (g *Grid) row(lat float64) int {
+	return min(int((lat+90)/g.size), g.rows-1)
+}
+
+func (g *Grid) col(lon float64) int {
+	return min(int((lon+180)/g.size), g.cols-1)
+}
+
+func (g *Grid) cellOf(p Point) cell {
+	return cell{row: g.row(p.Lat), col: g.col(p.Lon)}
+}
+
+// Insert adds the point of id. The previous point of id must be removed
+// first.
+func (g *Grid) Insert(id int64, p Point) {
+	c := g.cellOf(p)
+	points, ok := g.cells[c]
+	if !ok {
+		points = map[int64]Point{}
+		g.cells[c] = points
+	}
+	points[id] = p
+}
+
+// Remove drops the point p of id.
+func (g *Grid) Remove(id int64, p Point) {
+	c := g.cellOf(p)
+	delete(g.cells[c], id)
+	if len(g.cells[c]) == 0 {
+		delete(g.cells, c)
+	}
+}
+
+// Search calls fn with every point in box. The order is unspecified.
+func (g *Grid) Search(box Box, fn func(id int64, p Point)) {
+	r0, r1 := g.row(box.MinLat), g.row(box.MaxLat)
+	type span struct{ from, to int }
+	spans := []span{{g.col(box.MinLon), g.col(box.MaxLon)}}
+	if box.MinLon > box.MaxLon {
+		spans = []span{{g.col(box.MinLon), g.cols - 1}, {0, g.col(box.MaxLon)}}
+	}
+	visit := func(c cell) {
+		for id, p := range g.cells[c] {
+			if box.Contains(p) {
+				fn(id, p)
+			}
+		}
+	}
+
+	covered := 0
+	for _, s := range spans {
+		covered += (r1 - r0 + 1) * (s.to - s.from + 1)
+	}
+	// a large box covers more cells than are in use, walk the used ones
+	if covered > len(g.cells) {
+		for c := range g.cells {
+			if c.row < r0 || c.row > r1 {
+				continue
+			}
+			for _, s := range spans {
+				if c.col >= s.from && c.col <= s.to {
+					visit(c)
+				}
+			}
+		}
+		return
+	}
+	for r := r0; r <= r1; r++ {
+		for _, s := range spans {
+			for col := s.from; col <= s.to; col++ {
+				visit(cell{row: r, col: col})
+			}
+		}
+	}
+}
+
+// Len returns the number of points.
+func (g *Grid) Len() int {
+	n := 0
+	for _, points := range g.cells {
+		n += len(points)
+	}
+	return n
+}
+
+// Clone returns a deep copy of the grid.
+func (g *Grid) Clone() *Grid {
+	res := &Grid{size: g.size, rows: g.rows, cols: g.cols, cells: make(map[cell]map[int64]Point, len(g.cells))}
+	for c, points := range g.cells {
+		res.cells[c] = maps.Clone(points)
+	}
+	return res
+}
@@ -0,0 +1,152 @@
+// Package health keeps the readiness of the service and the health checks of
+// its dependencies, and serves them over HTTP and grpc.health.v1.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// Service is the name of the ads service in grpc.health.v1.
+const Service = "ad.AdService"
+
+// checkTimeout bounds a readiness probe, so one stuck dependency can't hang it.
+const checkTimeout = 2 * time.Second
+
+// Checker reports whether a dependency can serve requests. Repository
+// backends implement it to take part in readiness.
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+type CheckerFunc func(ctx context.Context) error
+
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+type Health struct {
+	mtx      sync.RWMutex
+	ready    bool
+	checkers map[string]Checker
+	grpc     *health.Server
+}
+
+// New returns a not ready Health, call SetReady when the servers listen.
+func New() *Health {
+	h := &Health{checkers: map[string]Checker{}, grpc: health.NewServer()}
+	h.SetReady(false)
+	return h
+}
+
+func (h *Health) Register(name string, c Checker) {
+	h.mtx.Lock()
+	h.checkers[name] = c
+	h.mtx.Unlock()
+}
+
+// SetReady switches readiness. It is turned off at the start of graceful
+// shutdown so load balancers stop sending traffic before the servers stop.
+func (h *Health) SetReady(ready bool) {
+	h.mtx.Lock()
+	h.ready = ready
+	h.mtx.Unlock()
+	st := healthpb.HealthCheckResponse_NOT_SERVING
+	if ready {
+		st = healthpb.HealthCheckResponse_SERVING
+	}
+	h.grpc.SetServingStatus("", st)
+	h.grpc.SetServingStatus(Service, st)
+}
+
+// Report is the result of a readiness probe.
+type Report struct {
+	Ready  bool              `json:"ready"`
+	Checks map[string]string `json:"checks"`
+}
+
+// Check runs all checkers. The service is ready when readiness is on and no
+// checker fails.
+func (h *Health) Check(ctx context.Context) Report {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	h.mtx.RLock()
+	ready := h.ready
+	names := make([]string, 0, len(h.checkers))
+	for name := range h.checkers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	checkers := make([]Checker, len(names))
+	for i, name := range names {
+		checkers[i] = h.checkers[name]
+	}
+	h.mtx.RUnlock()
+
+	r := Report{Ready: ready, Checks: make(map[string]string, len(names))}
+	if !ready {
+		r.Checks["server"] = "shutting down or not started"
+	}
+	for i, c := range checkers {
+		if err := c.Check(ctx); err != nil {
+			r.Ready = false
+			r.Checks[names[i]] = err.Error()
+		} else {
+			r.Checks[names[i]] = "ok"
+		}
+	}
+	return r
+}
+
+// Liveness answers 200 while the process can serve HTTP.
+func (h *Health) Liveness(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// Readiness answers 200 when Check reports ready and 503 otherwise.
+func (h *Health) Readiness(w http.ResponseWriter, r *http.Request) {
+	report := h.Check(r.Context())
+	code := http.StatusOK
+	if !report.Ready {
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, report)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// GRPC returns the grpc.health.v1 implementation. Check runs the checkers,
+// Watch streams the readiness switches.
+func (h *Health) GRPC() healthpb.HealthServer {
+	return grpcHealth{Server: h.grpc, h: h}
+}
+
+type grpcHealth struct {
+	*health.Server
+	h *Health
+}
+
+func (g grpcHealth) Check(ctx context.Context, in *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	if in.GetService() != "" && in.GetService() != Service {
+		return nil, status.Errorf(codes.NotFound, "unknown service %q", in.GetService())
+	}
+	if !g.h.Check(ctx).Ready {
+		return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_NOT_SERVING}, nil
+	}
+	return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, nil
+}
@@ -0,0 +1,142 @@
+// Package idempotency remembers the responses of requests sent with an
+// idempotency key, so that retries of a request are answered with the first
+// response instead of being executed again.
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"homework10/internal/app"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Header is the HTTP header carrying the key. Its lower case form is the gRPC
+// metadata key.
+const Header = "Idempotency-Key"
+
+// ReplayedHeader marks responses that were replayed from the store.
+const ReplayedHeader = "Idempotent-Replayed"
+
+var (
+	// ErrKeyReused is returned when a key is sent again with another request.
+	ErrKeyReused = &app.Error{Code: app.CodeUnprocessable, Message: "idempotency key was used with a different request"}
+	// ErrInProgress is returned while the first request with the key runs.
+	ErrInProgress = &app.Error{Code: app.CodeConflict, Message: "request with this idempotency key is in progress", Retryable: true}
+)
+
+type entry struct {
+	fingerprint string
+	done        bool
+	response    any
+	expires     time.Time
+}
+
+// Store keeps the responses for a TTL after they were completed. Pending
+// keys never expire, they are completed or released by their request.
+type Store struct {
+	mtx       sync.Mutex
+	ttl       time.Duration
+	now       func() time.Time
+	nextSweep time.Time
+	entries   map[string]*entry
+}
+
+type Option func(*Store)
+
+// WithClock replaces time.Now, so tests can expire responses.
+func WithClock(now func() time.Time) Option {
+	return func(s *Store) {
+		s.now = now
+	}
+}
+
+func New(ttl time.Duration, opts ...Option) *Store {
+	s := &Store{ttl: ttl, now: time.Now, entries: map[string]*entry{}}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Key scopes a client key, e.g. by the method and the user, so that equal
+// keys of different users don't collide.
+func Key(key string, scope ...string) string {
+	return strings.Join(append(scope, key), "\x00")
+}
+
+// Fingerprint identifies the request payload. JSON payloads are compacted
+// first, so reformatting the body doesn't count as another request.
+func Fingerprint(payload []byte) string {
+	var buf bytes.Buffer
+	if json.Compact(&buf, payload) == nil {
+		payload = buf.Bytes()
+	}
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// Begin returns the stored response of key with found set. Otherwise it
+// reserves key for the caller, who must call Complete or Release. It fails
+// with ErrKeyReused when fingerprint differs from the one the key was
+// reserved with and with ErrInProgress while the key is reserved.
+func (s *Store) Begin(key string, fingerprint string) (response any, found bool, err error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	now := s.now()
+	s.sweep(now)
+
+	e, ok := s.entries[key]
+	if ok && e.done && !now.Before(e.expires) {
+		delete(s.entries, key)
+		ok = false
+	}
+	if !ok {
+		s.entries[key] = &entry{fingerprint: fingerprint}
+		return nil, false, nil
+	}
+	if e.fingerprint != fingerprint {
+		return nil, false, ErrKeyReused
+	}
+	if !e.done {
+		return nil, false, ErrInProgress
+	}
+	return e.response, true, nil
+}
+
+// Complete stores the response of a key reserved by Begin.
+func (s *Store) Complete(key string, response any) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if e, ok := s.entries[key]; ok && !e.done {
+		e.done = true
+		e.response = response
+		e.expires = s.now().Add(s.ttl)
+	}
+}
+
+// Release drops a key reserved by Begin without storing a response, so the
+// request can be retried.
+func (s *Store) Release(key string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if e, ok := s.entries[key]; ok && !e.done {
+		delete(s.entries, key)
+	}
+}
+
+// sweep drops the expired responses at most once per TTL.
+func (s *Store) sweep(now time.Time) {
+	if now.Before(s.nextSweep) {
+		return
+	}
+	for key, e := range s.entries {
+		if e.done && !now.Before(e.expires) {
+			delete(s.entries, key)
+		}
+	}
+	s.nextSweep = now.Add(s.ttl)
+}
@@ -0,0 +1,244 @@
+// Package lifecycle starts the components of the service in order, reports
+// when all of them are ready and stops them in the reverse order within a
+// deadline. A component failing at any time shuts the others down, and the
+// failure is returned to the caller instead of ending the process.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const defaultTimeout = 10 * time.Second
+
+// Component is a part of the service, like a server or a background worker.
+type Component interface {
+	// Start returns once the component is ready, ctx bounds only the
+	// start. An error ending the component later is passed to fail, which
+	// shuts the manager down.
+	Start(ctx context.Context, fail func(error)) error
+	// Stop ends the component, waiting for the work in progress until ctx
+	// is done.
+	Stop(ctx context.Context) error
+}
+
+// Phase is the part of the lifetime of a component in which it failed.
+type Phase string
+
+const (
+	PhaseStart Phase = "start"
+	PhaseRun   Phase = "run"
+	PhaseStop  Phase = "stop"
+)
+
+// Error is the failure of a named component.
+type Error struct {
+	Component string
+	Phase     Phase
+	Err       error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s: %v", e.Component, e.Phase, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ErrAlreadyRun is returned by Run of a manager that has been run before.
+var ErrAlreadyRun = errors.New("lifecycle: manager already run")
+
+type entry struct {
+	name string
+	c    Component
+}
+
+// Manager runs components. It is meant to be run once.
+type Manager struct {
+	logger    *slog.Logger
+	timeout   time.Duration
+	drain     time.Duration
+	readiness func(bool)
+	signals   []os.Signal
+
+	components []entry
+	ran        atomic.Bool
+	ready      chan struct{}
+	done       chan struct{}
+	stop       chan struct{}
+	stopOnce   sync.Once
+	failed     chan error
+}
+
+type Option func(*Manager)
+
+// WithLogger sets the logger of the starts and stops.
+func WithLogger(logger *slog.Logger) Option {
+	return func(m *Manager) {
+		m.logger = logger
+	}
+}
+
+// WithShutdownTimeout bounds stopping all components together, 10 seconds
+// by default.
+func WithShutdownTimeout(d time.Duration) Option {
+	return func(m *Manager) {
+		m.timeout = d
+	}
+}
+
+// WithDrain sets how long the components keep running after readiness is
+// turned off, so load balancers can drain traffic. A failed component
+// skips the drain.
+func WithDrain(d time.Duration) Option {
+	return func(m *Manager) {
+		m.drain = d
+	}
+}
+
+// WithReadiness sets the function told when all components are started and
+// when the shutdown begins.
+func WithReadiness(set func(ready bool)) Option {
+	return func(m *Manager) {
+		m.readiness = set
+	}
+}
+
+// WithSignals shuts the manager down when one of signals arrives.
+func WithSignals(signals ...os.Signal) Option {
+	return func(m *Manager) {
+		m.signals = signals
+	}
+}
+
+func New(opts ...Option) *Manager {
+	m := &Manager{
+		logger:    slog.New(discardHandler{}),
+		timeout:   defaultTimeout,
+		readiness: func(bool) {},
+		ready:     make(chan struct{}),
+		done:      make(chan struct{}),
+		stop:      make(chan struct{}),
+		failed:    make(chan error, 1),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Add appends a component. Components start in the order they are added and
+// stop in the reverse order. Add must not be called after Run.
+func (m *Manager) Add(name string, c Component) {
+	m.components = append(m.components, entry{name: name, c: c})
+}
+
+// Ready is closed when all components are started.
+func (m *Manager) Ready() <-chan struct{} {
+	return m.ready
+}
+
+// Done is closed when Run returns.
+func (m *Manager) Done() <-chan struct{} {
+	return m.done
+}
+
+// Shutdown makes Run stop the components and return. It doesn't wait, see
+// Done.
+func (m *Manager) Shutdown() {
+	m.stopOnce.Do(func() { close(m.stop) })
+}
+
+func (m *Manager) fail(name string) func(error) {
+	return func(err error) {
+		select {
+		case m.failed <- &Error{Component: name, Phase: PhaseRun, Err: err}:
+		default:
+		}
+	}
+}
+
+// Run starts the components and stops them when ctx is done, Shutdown is
+// called, a signal arrives or a component fails. A failed start stops the
+// components started before. The errors are *Error values, joined when
+// there are several. A shutdown that isn't caused by a failure returns nil
+// unless a component fails to stop.
+func (m *Manager) Run(ctx context.Context) error {
+	if !m.ran.CompareAndSwap(false, true) {
+		return ErrAlreadyRun
+	}
+	defer close(m.done)
+
+	for i, e := range m.components {
+		if err := e.c.Start(ctx, m.fail(e.name)); err != nil {
+			m.logger.Error("component failed to start", "component", e.name, "error", err)
+			startErr := &Error{Component: e.name, Phase: PhaseStart, Err: err}
+			return errors.Join(startErr, m.stopAll(m.components[:i]))
+		}
+		m.logger.Info("component started", "component", e.name)
+	}
+	m.readiness(true)
+	close(m.ready)
+
+	sig := make(chan os.Signal, 1)
+	if len(m.signals) > 0 {
+		signal.Notify(sig, m.signals...)
+		defer signal.Stop(sig)
+	}
+	var cause error
+	select {
+	case <-ctx.Done():
+	case <-m.stop:
+	case s := <-sig:
+		m.logger.Info("captured signal", "signal", s.String())
+	case cause = <-m.failed:
+		m.logger.Error("component failed", "error", cause)
+	}
+
+	m.readiness(false)
+	if cause == nil && m.drain > 0 {
+		m.logger.Info("not ready, draining", "delay", m.drain)
+		time.Sleep(m.drain)
+	}
+	return errors.Join(cause, m.stopAll(m.components))
+}
+
+// stopAll stops the components in the reverse order within the shutdown
+// timeout.
+func (m *Manager) stopAll(components []entry) error {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+	var errs []error
+	for i := len(components) - 1; i >= 0; i-- {
+		e := components[i]
+		if err := e.c.Stop(ctx); err != nil {
+			m.logger.Error("component failed to stop", "component", e.name, "error", err)
+			errs = append(errs, &Error{Component: e.name, Phase: PhaseStop, Err: err})
+			continue
+		}
+		m.logger.Info("component stopped", "component", e.name)
+	}
+	return errors.Join(errs...)
+}
+
+// OnStop is a component that only stops, e.g. by flushing buffers.
+type OnStop func(ctx context.Context) error
+
+func (OnStop) Start(context.Context, func(error)) error { return nil }
+
+func (f OnStop) Stop(ctx context.Context) error { return f(ctx) }
+
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
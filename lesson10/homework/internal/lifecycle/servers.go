@@ -0,0 +1,72 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+
+	"google.golang.org/grpc"
+)
+
+type httpServer struct {
+	srv *http.Server
+	lis net.Listener
+}
+
+// HTTPServer serves srv on lis. Stop shuts srv down gracefully.
+func HTTPServer(srv *http.Server, lis net.Listener) Component {
+	return &httpServer{srv: srv, lis: lis}
+}
+
+func (s *httpServer) Start(_ context.Context, fail func(error)) error {
+	go func() {
+		if err := s.srv.Serve(s.lis); !errors.Is(err, http.ErrServerClosed) {
+			fail(err)
+		}
+	}()
+	return nil
+}
+
+func (s *httpServer) Stop(ctx context.Context) error {
+	err := s.srv.Shutdown(ctx)
+	// Shutdown doesn't know lis when Serve hasn't started yet
+	_ = s.lis.Close()
+	return err
+}
+
+type grpcServer struct {
+	srv *grpc.Server
+	lis net.Listener
+}
+
+// GRPCServer serves srv on lis. Stop waits for the running calls until its
+// ctx is done and then closes the connections, because streams such as
+// health watches last until the client goes away.
+func GRPCServer(srv *grpc.Server, lis net.Listener) Component {
+	return &grpcServer{srv: srv, lis: lis}
+}
+
+func (s *grpcServer) Start(_ context.Context, fail func(error)) error {
+	go func() {
+		if err := s.srv.Serve(s.lis); err != nil {
+			fail(err)
+		}
+	}()
+	return nil
+}
+
+func (s *grpcServer) Stop(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.srv.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.srv.Stop()
+		return ctx.Err()
+	}
+}
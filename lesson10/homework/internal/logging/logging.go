@@ -0,0 +1,105 @@
+// Package logging builds the structured logger of the service and keeps the
+// request ID and the calling service in context.Context.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"homework10/internal/config"
+	"io"
+	"log/slog"
+)
+
+// RequestIDHeader is the HTTP header and, lowercased, the gRPC metadata key
+// that carries the request ID.
+const RequestIDHeader = "X-Request-ID"
+
+const (
+	requestIDKey = "request_id"
+	principalKey = "principal"
+)
+
+type ctxKey struct{}
+
+type principalCtxKey struct{}
+
+// WithRequestID returns a copy of ctx that carries id.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// RequestID returns the request ID stored in ctx or an empty string.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(ctxKey{}).(string)
+	return id
+}
+
+// WithPrincipal returns a copy of ctx that carries the name of the
+// authenticated service calling.
+func WithPrincipal(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, principalCtxKey{}, name)
+}
+
+// Principal returns the name of the service calling stored in ctx or an
+// empty string.
+func Principal(ctx context.Context) string {
+	name, _ := ctx.Value(principalCtxKey{}).(string)
+	return name
+}
+
+// NewRequestID generates a random request ID.
+func NewRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// New creates a logger writing to w in the configured format. Every record
+// logged with a context gets the request ID of that context.
+func New(cfg config.Config, w io.Writer) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: Level(cfg.LogLevel)}
+	var h slog.Handler
+	if cfg.LogFormat == config.FormatText {
+		h = slog.NewTextHandler(w, opts)
+	} else {
+		h = slog.NewJSONHandler(w, opts)
+	}
+	return slog.New(contextHandler{h})
+}
+
+// Level converts the configured level to slog.Level.
+func Level(l config.LogLevel) slog.Level {
+	switch l {
+	case config.LevelDebug:
+		return slog.LevelDebug
+	case config.LevelWarn:
+		return slog.LevelWarn
+	case config.LevelError:
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String(requestIDKey, id))
+	}
+	if name := Principal(ctx); name != "" {
+		r.AddAttrs(slog.String(principalKey, name))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
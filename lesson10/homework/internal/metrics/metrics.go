@@ -0,0 +1,204 @@
+// Package metrics collects the Prometheus metrics of the service.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "ads"
+
+// Metrics owns a registry, so several servers can live in one process, e.g.
+// in tests. It implements app.Metrics.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+	grpcRequests *prometheus.CounterVec
+	grpcDuration *prometheus.HistogramVec
+
+	ads          prometheus.Gauge
+	publishedAds prometheus.Gauge
+	users        prometheus.Gauge
+	adsCreated   prometheus.Counter
+	lockWait     *prometheus.HistogramVec
+	caches       *cacheCollector
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by method, route and status code.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by method and route.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		grpcRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "grpc_requests_total",
+			Help:      "gRPC calls by method and status code.",
+		}, []string{"method", "code"}),
+		grpcDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "grpc_request_duration_seconds",
+			Help:      "gRPC call latency by method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method"}),
+		ads: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "ads",
+			Help:      "Ads in the repository.",
+		}),
+		publishedAds: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "published_ads",
+			Help:      "Published ads in the repository.",
+		}),
+		users: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "users",
+			Help:      "Users in the repository.",
+		}),
+		adsCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "ads_created_total",
+			Help:      "Created ads, ads per minute is rate(ads_ads_created_total[1m]) * 60.",
+		}),
+		lockWait: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "repository_lock_wait_seconds",
+			Help:      "Time spent waiting for the repository lock.",
+			Buckets:   []float64{.00001, .0001, .001, .01, .1, 1},
+		}, []string{"repository"}),
+		caches: &cacheCollector{caches: map[string]func() CacheStats{}},
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests, m.httpDuration, m.grpcRequests, m.grpcDuration,
+		m.ads, m.publishedAds, m.users, m.adsCreated, m.lockWait, m.caches,
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+func (m *Metrics) ObserveHTTP(method string, route string, status int, d time.Duration) {
+	m.httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	m.httpDuration.WithLabelValues(method, route).Observe(d.Seconds())
+}
+
+func (m *Metrics) ObserveGRPC(method string, code string, d time.Duration) {
+	m.grpcRequests.WithLabelValues(method, code).Inc()
+	m.grpcDuration.WithLabelValues(method).Observe(d.Seconds())
+}
+
+// LockWait returns an observer for the lock wait time of a repository.
+func (m *Metrics) LockWait(repository string) func(time.Duration) {
+	h := m.lockWait.WithLabelValues(repository)
+	return func(d time.Duration) {
+		h.Observe(d.Seconds())
+	}
+}
+
+func (m *Metrics) AdCreated() {
+	m.ads.Inc()
+	m.adsCreated.Inc()
+}
+
+func (m *Metrics) AdDeleted(published bool) {
+	m.ads.Dec()
+	if published {
+		m.publishedAds.Dec()
+	}
+}
+
+func (m *Metrics) AdStatusChanged(published bool) {
+	if published {
+		m.publishedAds.Inc()
+	} else {
+		m.publishedAds.Dec()
+	}
+}
+
+func (m *Metrics) UserCreated() {
+	m.users.Inc()
+}
+
+func (m *Metrics) UserDeleted() {
+	m.users.Dec()
+}
+
+// CacheStats are the counters of a repository cache.
+type CacheStats struct {
+	Hits          uint64
+	Misses        uint64
+	Evictions     uint64
+	Invalidations uint64
+	Entries       int
+}
+
+var (
+	cacheHitsDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "cache", "hits_total"),
+		"Lookups answered by the repository cache.", []string{"cache"}, nil)
+	cacheMissesDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "cache", "misses_total"),
+		"Lookups the repository cache passed to the backend.", []string{"cache"}, nil)
+	cacheEvictionsDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "cache", "evictions_total"),
+		"Entries evicted to keep the repository cache within its size.", []string{"cache"}, nil)
+	cacheInvalidationsDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "cache", "invalidations_total"),
+		"Invalidations of the repository cache by writes.", []string{"cache"}, nil)
+	cacheEntriesDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "cache", "entries"),
+		"Entries in the repository cache.", []string{"cache"}, nil)
+)
+
+// cacheCollector reads the counters of the registered caches on every
+// scrape.
+type cacheCollector struct {
+	mtx    sync.Mutex
+	caches map[string]func() CacheStats
+}
+
+func (c *cacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- cacheHitsDesc
+	ch <- cacheMissesDesc
+	ch <- cacheEvictionsDesc
+	ch <- cacheInvalidationsDesc
+	ch <- cacheEntriesDesc
+}
+
+func (c *cacheCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	for name, stats := range c.caches {
+		s := stats()
+		ch <- prometheus.MustNewConstMetric(cacheHitsDesc, prometheus.CounterValue, float64(s.Hits), name)
+		ch <- prometheus.MustNewConstMetric(cacheMissesDesc, prometheus.CounterValue, float64(s.Misses), name)
+		ch <- prometheus.MustNewConstMetric(cacheEvictionsDesc, prometheus.CounterValue, float64(s.Evictions), name)
+		ch <- prometheus.MustNewConstMetric(cacheInvalidationsDesc, prometheus.CounterValue, float64(s.Invalidations), name)
+		ch <- prometheus.MustNewConstMetric(cacheEntriesDesc, prometheus.GaugeValue, float64(s.Entries), name)
+	}
+}
+
+// RegisterCache exports the counters of the named cache, replacing a cache
+// registered with the same name. stats is called on every scrape.
+func (m *Metrics) RegisterCache(name string, stats func() CacheStats) {
+	m.caches.mtx.Lock()
+	defer m.caches.mtx.Unlock()
+	m.caches.caches[name] = stats
+}
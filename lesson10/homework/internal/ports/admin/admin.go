@@ -0,0 +1,139 @@
+// Package admin serves the admin console, server-rendered pages for the
+// support staff to search ads and users, see the revisions of ads and
+// unpublish or delete them. The templates and the stylesheet are embedded
+// into the binary.
+//
+// Staff log in with the passwords configured in config.AdminConfig. Every
+// form sends a CSRF token bound to the session, and the cookies are sent
+// only with requests from the console.
+package admin
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"homework10/internal/app"
+	"homework10/internal/config"
+	"html/template"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// basePath is where the console is served.
+const basePath = "/admin"
+
+//go:embed templates/*.html
+var templateFiles embed.FS
+
+//go:embed static
+var staticFiles embed.FS
+
+var funcs = template.FuncMap{
+	"datetime": func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.UTC().Format("2006-01-02 15:04:05 UTC")
+	},
+}
+
+// pages are the templates of the console by file name, each together with
+// the layout.
+type pages map[string]*template.Template
+
+func parsePages() (pages, error) {
+	files, err := fs.Glob(templateFiles, "templates/*.html")
+	if err != nil {
+		return nil, err
+	}
+	res := pages{}
+	for _, f := range files {
+		name := f[len("templates/"):]
+		if name == "layout.html" {
+			continue
+		}
+		t, err := template.New(name).Funcs(funcs).ParseFS(templateFiles, "templates/layout.html", f)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", f, err)
+		}
+		res[name] = t
+	}
+	return res, nil
+}
+
+// view is the data every page gets, Data is the one of the page itself.
+type view struct {
+	Title string
+	Staff string
+	CSRF  string
+	// Flash is a message about the last action
+	Flash string
+	Error string
+	Data  any
+}
+
+// render executes the page into a buffer, so a failing template does not
+// leave a half written page.
+func (p pages) render(c *gin.Context, status int, page string, v view) {
+	var buf bytes.Buffer
+	if err := p[page].ExecuteTemplate(&buf, "layout.html", v); err != nil {
+		_ = c.Error(err)
+		c.String(http.StatusInternalServerError, "can't render the page")
+		return
+	}
+	c.Data(status, "text/html; charset=utf-8", buf.Bytes())
+}
+
+// securityHeaders keeps the pages of the console out of frames and caches
+// and allows only its own scripts and styles.
+func securityHeaders(c *gin.Context) {
+	h := c.Writer.Header()
+	h.Set("Content-Security-Policy", "default-src 'none'; style-src 'self'; img-src 'self'; form-action 'self'; frame-ancestors 'none'")
+	h.Set("X-Frame-Options", "DENY")
+	h.Set("X-Content-Type-Options", "nosniff")
+	h.Set("Referrer-Policy", "same-origin")
+	h.Set("Cache-Control", "no-store")
+	c.Next()
+}
+
+// Register serves the console at /admin of e. It must be called only when
+// cfg is enabled.
+func Register(e *gin.Engine, a app.App, cfg config.AdminConfig, logger *slog.Logger) error {
+	p, err := parsePages()
+	if err != nil {
+		return err
+	}
+	staff, err := LoadStaff(cfg.StaffFile)
+	if err != nil {
+		return err
+	}
+	au, err := newAuth(staff, cfg.SessionTTL)
+	if err != nil {
+		return err
+	}
+	static, err := fs.Sub(staticFiles, "static")
+	if err != nil {
+		return err
+	}
+	h := &handlers{app: a, auth: au, pages: p, logger: logger}
+
+	g := e.Group(basePath, securityHeaders)
+	g.StaticFS("/static", http.FS(static))
+	g.GET("/login", h.loginForm)
+	g.POST("/login", h.login)
+
+	authed := g.Group("", h.requireStaff)
+	authed.POST("/logout", h.logout)
+	authed.GET("", func(c *gin.Context) { c.Redirect(http.StatusFound, basePath+"/ads") })
+	authed.GET("/ads", h.searchAds)
+	authed.GET("/ads/:id", h.ad)
+	authed.POST("/ads/:id/unpublish", h.unpublishAd)
+	authed.POST("/ads/:id/delete", h.deleteAd)
+	authed.GET("/users", h.searchUsers)
+	authed.GET("/users/:id", h.user)
+	return nil
+}
@@ -0,0 +1,190 @@
+package admin
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	sessionCookie = "ads_admin_session"
+	// loginCookie carries the nonce of the CSRF token of the login form,
+	// before there is a session
+	loginCookie = "ads_admin_login"
+	loginTTL    = 15 * time.Minute
+
+	csrfField = "csrf_token"
+)
+
+// LoadStaff reads a file of "login:bcrypt hash" lines and returns the hashes
+// by login. Empty lines and lines starting with # are skipped.
+func LoadStaff(file string) (map[string]string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read staff file: %w", err)
+	}
+	staff := map[string]string{}
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		login, hash, ok := strings.Cut(line, ":")
+		if !ok || login == "" {
+			return nil, fmt.Errorf("%s:%d: want login:hash", file, i+1)
+		}
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return nil, fmt.Errorf("%s:%d: not a bcrypt hash: %w", file, i+1, err)
+		}
+		if _, dup := staff[login]; dup {
+			return nil, fmt.Errorf("%s:%d: duplicate login %q", file, i+1, login)
+		}
+		staff[login] = hash
+	}
+	if len(staff) == 0 {
+		return nil, fmt.Errorf("%s: no staff", file)
+	}
+	return staff, nil
+}
+
+var errBadSession = errors.New("invalid session")
+
+// session is the payload of a signed cookie. Staff is empty in the cookie
+// of the login form.
+type session struct {
+	Staff   string `json:"s,omitempty"`
+	Nonce   string `json:"n"`
+	Expires int64  `json:"e"`
+}
+
+// auth checks passwords and signs the cookies of the console. The key is
+// made at start, so a restart ends all sessions.
+type auth struct {
+	staff map[string]string
+	ttl   time.Duration
+	key   []byte
+	now   func() time.Time
+	// dummyHash is compared with the password of an unknown login, so a
+	// login attempt takes the same time whether the login exists or not
+	dummyHash []byte
+}
+
+func newAuth(staff map[string]string, ttl time.Duration) (*auth, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	dummy, err := bcrypt.GenerateFromPassword(key, bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	return &auth{staff: staff, ttl: ttl, key: key, now: time.Now, dummyHash: dummy}, nil
+}
+
+// login reports whether password is the one of the staff member.
+func (a *auth) login(staff, password string) bool {
+	hash, ok := a.staff[staff]
+	if !ok {
+		_ = bcrypt.CompareHashAndPassword(a.dummyHash, []byte(password))
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+func (a *auth) mac(data string) []byte {
+	m := hmac.New(sha256.New, a.key)
+	m.Write([]byte(data))
+	return m.Sum(nil)
+}
+
+// newSession returns a session of staff, or the one of the login form if
+// staff is empty, with a fresh nonce.
+func (a *auth) newSession(staff string, ttl time.Duration) (session, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return session{}, err
+	}
+	return session{Staff: staff, Nonce: hex.EncodeToString(nonce), Expires: a.now().Add(ttl).Unix()}, nil
+}
+
+func (a *auth) encode(s session) string {
+	payload, _ := json.Marshal(s)
+	data := base64.RawURLEncoding.EncodeToString(payload)
+	return data + "." + base64.RawURLEncoding.EncodeToString(a.mac(data))
+}
+
+// decode verifies the signature and the expiry of a cookie value.
+func (a *auth) decode(value string) (session, error) {
+	data, sig, ok := strings.Cut(value, ".")
+	if !ok {
+		return session{}, errBadSession
+	}
+	got, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(got, a.mac(data)) {
+		return session{}, errBadSession
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(data)
+	if err != nil {
+		return session{}, errBadSession
+	}
+	var s session
+	if err := json.Unmarshal(payload, &s); err != nil {
+		return session{}, errBadSession
+	}
+	if a.now().Unix() >= s.Expires {
+		return session{}, errBadSession
+	}
+	return s, nil
+}
+
+// staffSession returns the session of a staff member still in the
+// configuration.
+func (a *auth) staffSession(r *http.Request) (session, error) {
+	c, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return session{}, errBadSession
+	}
+	s, err := a.decode(c.Value)
+	if err != nil {
+		return session{}, err
+	}
+	if _, ok := a.staff[s.Staff]; !ok {
+		return session{}, errBadSession
+	}
+	return s, nil
+}
+
+// csrfToken returns the token that forms of the session must send. It is
+// bound to the nonce, so it changes with every login.
+func (a *auth) csrfToken(s session) string {
+	return base64.RawURLEncoding.EncodeToString(a.mac("csrf:" + s.Nonce))
+}
+
+func (a *auth) checkCSRF(s session, token string) bool {
+	return hmac.Equal([]byte(token), []byte(a.csrfToken(s)))
+}
+
+// setCookie sets a cookie of the console, limited to its path and sent
+// only with requests from the console itself.
+func setCookie(w http.ResponseWriter, r *http.Request, name, value string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     basePath,
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
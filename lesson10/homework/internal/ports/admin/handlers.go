@@ -0,0 +1,321 @@
+package admin
+
+import (
+	"errors"
+	"homework10/internal/ads"
+	"homework10/internal/app"
+	"homework10/internal/logging"
+	"homework10/internal/ports/httpgin"
+	"homework10/internal/revisions"
+	"homework10/internal/users"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxResults limits the rows of a search page.
+const maxResults = 100
+
+const sessionKey = "admin_session"
+
+// flashes are the messages shown after the actions, by the done parameter
+// of the redirect.
+var flashes = map[string]string{
+	"unpublished": "The ad was unpublished.",
+	"deleted":     "The ad was deleted.",
+}
+
+type handlers struct {
+	app    app.App
+	auth   *auth
+	pages  pages
+	logger *slog.Logger
+}
+
+// requireStaff lets only staff with a session in, the others are sent to
+// the login form. Forms must carry the CSRF token of the session.
+func (h *handlers) requireStaff(c *gin.Context) {
+	s, err := h.auth.staffSession(c.Request)
+	if err != nil {
+		if c.Request.Method != http.MethodGet {
+			h.renderError(c, http.StatusForbidden, "Your session has expired, log in again.")
+			c.Abort()
+			return
+		}
+		c.Redirect(http.StatusFound, basePath+"/login?next="+url.QueryEscape(c.Request.URL.RequestURI()))
+		c.Abort()
+		return
+	}
+	if c.Request.Method == http.MethodPost && !h.auth.checkCSRF(s, c.PostForm(csrfField)) {
+		h.logger.WarnContext(c.Request.Context(), "admin request with invalid CSRF token", "staff", s.Staff, "path", c.Request.URL.Path)
+		h.renderError(c, http.StatusForbidden, "The form is invalid, reload the page and try again.")
+		c.Abort()
+		return
+	}
+	c.Set(sessionKey, s)
+	c.Request = c.Request.WithContext(logging.WithPrincipal(c.Request.Context(), "staff:"+s.Staff))
+	c.Next()
+}
+
+func (h *handlers) session(c *gin.Context) session {
+	s, _ := c.Get(sessionKey)
+	return s.(session)
+}
+
+// render renders a page of a staff member.
+func (h *handlers) render(c *gin.Context, page, title string, data any) {
+	s := h.session(c)
+	h.pages.render(c, http.StatusOK, page, view{
+		Title: title,
+		Staff: s.Staff,
+		CSRF:  h.auth.csrfToken(s),
+		Flash: flashes[c.Query("done")],
+		Data:  data,
+	})
+}
+
+func (h *handlers) renderError(c *gin.Context, status int, msg string) {
+	v := view{Title: http.StatusText(status), Error: msg}
+	if s, ok := c.Get(sessionKey); ok {
+		v.Staff = s.(session).Staff
+		v.CSRF = h.auth.csrfToken(s.(session))
+	}
+	h.pages.render(c, status, "error.html", v)
+}
+
+// renderAppError shows an error of App, the details of internal errors are
+// only logged.
+func (h *handlers) renderAppError(c *gin.Context, err error) {
+	e := app.FromError(err)
+	msg := e.Error()
+	if e.Code == app.CodeInternal {
+		h.logger.ErrorContext(c.Request.Context(), "admin request failed", "path", c.Request.URL.Path, "error", err)
+		msg = "Something went wrong."
+	}
+	h.renderError(c, httpgin.HTTPStatus(e.Code), msg)
+}
+
+// nextPage returns where to go after the login, only pages of the console
+// are allowed.
+func nextPage(next string) string {
+	if !strings.HasPrefix(next, basePath+"/") || strings.HasPrefix(next, basePath+"/login") {
+		return basePath + "/ads"
+	}
+	return next
+}
+
+type loginData struct {
+	Login string
+	Next  string
+}
+
+// renderLogin shows the login form with a fresh CSRF token.
+func (h *handlers) renderLogin(c *gin.Context, status int, data loginData, msg string) {
+	s, err := h.auth.newSession("", loginTTL)
+	if err != nil {
+		h.renderAppError(c, err)
+		return
+	}
+	setCookie(c.Writer, c.Request, loginCookie, h.auth.encode(s), int(loginTTL.Seconds()))
+	h.pages.render(c, status, "login.html", view{Title: "Log in", CSRF: h.auth.csrfToken(s), Error: msg, Data: data})
+}
+
+func (h *handlers) loginForm(c *gin.Context) {
+	if _, err := h.auth.staffSession(c.Request); err == nil {
+		c.Redirect(http.StatusFound, nextPage(c.Query("next")))
+		return
+	}
+	h.renderLogin(c, http.StatusOK, loginData{Next: nextPage(c.Query("next"))}, "")
+}
+
+func (h *handlers) login(c *gin.Context) {
+	data := loginData{Login: c.PostForm("login"), Next: nextPage(c.PostForm("next"))}
+	cookie, err := c.Request.Cookie(loginCookie)
+	var form session
+	if err == nil {
+		form, err = h.auth.decode(cookie.Value)
+	}
+	if err != nil || !h.auth.checkCSRF(form, c.PostForm(csrfField)) {
+		h.renderLogin(c, http.StatusForbidden, data, "The form has expired, try again.")
+		return
+	}
+	if !h.auth.login(data.Login, c.PostForm("password")) {
+		h.logger.WarnContext(c.Request.Context(), "admin login failed", "staff", data.Login, "remote_addr", c.ClientIP())
+		h.renderLogin(c, http.StatusUnauthorized, data, "Wrong login or password.")
+		return
+	}
+	s, err := h.auth.newSession(data.Login, h.auth.ttl)
+	if err != nil {
+		h.renderAppError(c, err)
+		return
+	}
+	h.logger.InfoContext(c.Request.Context(), "admin logged in", "staff", data.Login, "remote_addr", c.ClientIP())
+	setCookie(c.Writer, c.Request, loginCookie, "", -1)
+	setCookie(c.Writer, c.Request, sessionCookie, h.auth.encode(s), int(h.auth.ttl.Seconds()))
+	c.Redirect(http.StatusSeeOther, data.Next)
+}
+
+func (h *handlers) logout(c *gin.Context) {
+	setCookie(c.Writer, c.Request, sessionCookie, "", -1)
+	c.Redirect(http.StatusSeeOther, basePath+"/login")
+}
+
+// pathID parses the id parameter, rendering an error when it is not a
+// number.
+func (h *handlers) pathID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		h.renderError(c, http.StatusNotFound, "There is no such page.")
+		return 0, false
+	}
+	return id, true
+}
+
+type adsData struct {
+	Title  string
+	Author string
+	Ads    []ads.Ad
+	Total  int
+}
+
+// searchAds finds ads by a part of the title, of an author if given. A
+// number in the ad field opens that ad.
+func (h *handlers) searchAds(c *gin.Context) {
+	if id := strings.TrimSpace(c.Query("id")); id != "" {
+		c.Redirect(http.StatusFound, basePath+"/ads/"+url.PathEscape(id))
+		return
+	}
+	data := adsData{Title: c.Query("title"), Author: strings.TrimSpace(c.Query("author"))}
+	ctx := c.Request.Context()
+	var (
+		found []ads.Ad
+		err   error
+	)
+	if data.Author != "" {
+		authorID, perr := strconv.ParseInt(data.Author, 10, 64)
+		if perr != nil {
+			h.renderError(c, http.StatusBadRequest, "The author must be a user ID.")
+			return
+		}
+		found, err = h.app.SelectByAuthor(ctx, authorID)
+		found = filterTitle(found, data.Title)
+	} else {
+		found, err = h.app.FindByTitle(ctx, data.Title)
+	}
+	if err != nil {
+		h.renderAppError(c, err)
+		return
+	}
+	data.Total = len(found)
+	data.Ads = found[:min(len(found), maxResults)]
+	h.render(c, "ads.html", "Ads", data)
+}
+
+func filterTitle(arr []ads.Ad, title string) []ads.Ad {
+	res := arr[:0]
+	for _, ad := range arr {
+		if strings.Contains(ad.Title, title) {
+			res = append(res, ad)
+		}
+	}
+	return res
+}
+
+type adData struct {
+	ID        int64
+	Ad        *ads.Ad
+	Revisions []revisions.Revision
+}
+
+// ad shows an ad with its revisions. A deleted ad is shown by its
+// revisions, Ad is nil then.
+func (h *handlers) ad(c *gin.Context) {
+	id, ok := h.pathID(c)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	data := adData{ID: id}
+	ad, err := h.app.GetAdByID(ctx, id)
+	if err != nil && !errors.Is(err, app.ErrNotFound) {
+		h.renderAppError(c, err)
+		return
+	}
+	data.Ad = ad
+	data.Revisions, err = h.app.AdRevisions(ctx, id)
+	if err != nil {
+		h.renderAppError(c, err)
+		return
+	}
+	h.render(c, "ad.html", "Ad "+strconv.FormatInt(id, 10), data)
+}
+
+func (h *handlers) unpublishAd(c *gin.Context) {
+	id, ok := h.pathID(c)
+	if !ok {
+		return
+	}
+	if _, err := h.app.UnpublishAdAsStaff(c.Request.Context(), id, h.session(c).Staff); err != nil {
+		h.renderAppError(c, err)
+		return
+	}
+	c.Redirect(http.StatusSeeOther, basePath+"/ads/"+strconv.FormatInt(id, 10)+"?done=unpublished")
+}
+
+func (h *handlers) deleteAd(c *gin.Context) {
+	id, ok := h.pathID(c)
+	if !ok {
+		return
+	}
+	if _, err := h.app.DeleteAdAsStaff(c.Request.Context(), id, h.session(c).Staff); err != nil {
+		h.renderAppError(c, err)
+		return
+	}
+	c.Redirect(http.StatusSeeOther, basePath+"/ads/"+strconv.FormatInt(id, 10)+"?done=deleted")
+}
+
+type usersData struct {
+	Query string
+	Users []users.User
+	Total int
+}
+
+func (h *handlers) searchUsers(c *gin.Context) {
+	data := usersData{Query: c.Query("q")}
+	found, err := h.app.FindUsers(c.Request.Context(), strings.TrimSpace(data.Query))
+	if err != nil {
+		h.renderAppError(c, err)
+		return
+	}
+	data.Total = len(found)
+	data.Users = found[:min(len(found), maxResults)]
+	h.render(c, "users.html", "Users", data)
+}
+
+type userData struct {
+	User *users.User
+	Ads  []ads.Ad
+}
+
+func (h *handlers) user(c *gin.Context) {
+	id, ok := h.pathID(c)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	usr, err := h.app.GetUserByID(ctx, id)
+	if err != nil {
+		h.renderAppError(c, err)
+		return
+	}
+	own, err := h.app.SelectByAuthor(ctx, id)
+	if err != nil {
+		h.renderAppError(c, err)
+		return
+	}
+	h.render(c, "user.html", usr.Nickname, userData{User: usr, Ads: own})
+}
@@ -0,0 +1,47 @@
+package ports
+
+import (
+	"context"
+	"homework10/internal/analytics"
+	"homework10/internal/app"
+	grpc_func "homework10/internal/ports/grpc"
+	"homework10/internal/ports/httpgin"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// NewGatewayHandler serves the HTTP rules declared in service.proto by calling
+// the gRPC service implementation in-process. The calls don't pass the gRPC
+// interceptors: the gateway is a part of the HTTP API and is guarded like
+// /api/v1, the client certificates and principals of the grpc TLS
+// configuration don't apply to it.
+func NewGatewayHandler(ctx context.Context, a app.App) (http.Handler, error) {
+	mux := runtime.NewServeMux(
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
+			MarshalOptions:   protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true},
+			UnmarshalOptions: protojson.UnmarshalOptions{DiscardUnknown: true},
+		}),
+		runtime.WithErrorHandler(gatewayErrorHandler),
+	)
+	service := &grpc_func.AdUserService{App: a}
+	if err := grpc_func.RegisterAdServiceHandlerServer(ctx, mux, service); err != nil {
+		return nil, err
+	}
+	return mux, nil
+}
+
+// gatewayViewer puts the client address into the request context. The
+// gateway calls the service without a gRPC peer, so GetAd counts the view by
+// it, like GET /api/v1/ads/{id}.
+func gatewayViewer(c *gin.Context) {
+	ctx := analytics.WithViewer(c.Request.Context(), analytics.AddrViewer(c.ClientIP()))
+	c.Request = c.Request.WithContext(ctx)
+	c.Next()
+}
+
+func gatewayErrorHandler(_ context.Context, _ *runtime.ServeMux, _ runtime.Marshaler, w http.ResponseWriter, r *http.Request, err error) {
+	httpgin.WriteProblem(w, r, grpc_func.FromStatus(err))
+}
@@ -0,0 +1,127 @@
+package grpc
+
+import (
+	"homework10/internal/app"
+	"strconv"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const errorDomain = "ads.homework10"
+
+func grpcCode(code app.Code) codes.Code {
+	switch code {
+	case app.CodeInvalidArgument:
+		return codes.InvalidArgument
+	case app.CodeNotFound:
+		return codes.NotFound
+	case app.CodePermissionDenied:
+		return codes.PermissionDenied
+	case app.CodeUnavailable:
+		return codes.Unavailable
+	case app.CodeDeadlineExceeded:
+		return codes.DeadlineExceeded
+	case app.CodeCanceled:
+		return codes.Canceled
+	case app.CodeConflict:
+		return codes.Aborted
+	case app.CodeUnprocessable:
+		return codes.FailedPrecondition
+	case app.CodeUnauthenticated:
+		return codes.Unauthenticated
+	case app.CodeUnimplemented:
+		return codes.Unimplemented
+	case app.CodeTooLarge:
+		return codes.ResourceExhausted
+	default:
+		return codes.Internal
+	}
+}
+
+// ToStatus converts an App error to a gRPC status error. The app code is sent
+// as ErrorInfo, field violations as BadRequest and retryability as RetryInfo.
+func ToStatus(err error) error {
+	e := app.FromError(err)
+	msg := e.Message
+	if e.Code != app.CodeInternal {
+		msg = e.Error()
+	}
+	st := status.New(grpcCode(e.Code), msg)
+
+	details := []proto.Message{&errdetails.ErrorInfo{
+		Reason:   string(e.Code),
+		Domain:   errorDomain,
+		Metadata: map[string]string{"retryable": strconv.FormatBool(e.Retryable)},
+	}}
+	if len(e.Fields) > 0 {
+		br := &errdetails.BadRequest{}
+		for _, f := range e.Fields {
+			br.FieldViolations = append(br.FieldViolations,
+				&errdetails.BadRequest_FieldViolation{Field: f.Field, Description: f.Description})
+		}
+		details = append(details, br)
+	}
+	if e.Retryable {
+		details = append(details, &errdetails.RetryInfo{})
+	}
+
+	withDetails, derr := st.WithDetails(details...)
+	if derr != nil {
+		return st.Err()
+	}
+	return withDetails.Err()
+}
+
+func appCode(code codes.Code) app.Code {
+	switch code {
+	case codes.InvalidArgument:
+		return app.CodeInvalidArgument
+	case codes.NotFound:
+		return app.CodeNotFound
+	case codes.PermissionDenied:
+		return app.CodePermissionDenied
+	case codes.Unavailable:
+		return app.CodeUnavailable
+	case codes.DeadlineExceeded:
+		return app.CodeDeadlineExceeded
+	case codes.Canceled:
+		return app.CodeCanceled
+	case codes.Aborted:
+		return app.CodeConflict
+	case codes.FailedPrecondition:
+		return app.CodeUnprocessable
+	case codes.Unauthenticated:
+		return app.CodeUnauthenticated
+	case codes.Unimplemented:
+		return app.CodeUnimplemented
+	case codes.ResourceExhausted:
+		return app.CodeTooLarge
+	default:
+		return app.CodeInternal
+	}
+}
+
+// FromStatus restores an App error from a status created by ToStatus.
+// Statuses without details are classified by their gRPC code only.
+func FromStatus(err error) *app.Error {
+	st := status.Convert(err)
+	e := &app.Error{Code: appCode(st.Code()), Message: st.Message()}
+	for _, d := range st.Details() {
+		switch d := d.(type) {
+		case *errdetails.ErrorInfo:
+			if d.GetDomain() == errorDomain {
+				e.Code = app.Code(d.GetReason())
+			}
+		case *errdetails.BadRequest:
+			for _, f := range d.GetFieldViolations() {
+				e.Fields = append(e.Fields, app.FieldViolation{Field: f.GetField(), Description: f.GetDescription()})
+			}
+		case *errdetails.RetryInfo:
+			e.Retryable = true
+		}
+	}
+	return e
+}
@@ -2,10 +2,15 @@ package grpc
 
 import (
 	context "context"
-	"errors"
 	"homework10/internal/ads"
+	"homework10/internal/analytics"
 	"homework10/internal/app"
+	"homework10/internal/catalog"
+	"homework10/internal/geo"
+	"io"
+	"net"
 
+	"google.golang.org/grpc/peer"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
@@ -14,41 +19,87 @@ type AdUserService struct {
 }
 
 func (serv *AdUserService) CreateAd(ctx context.Context, r *CreateAdRequest) (*AdResponse, error) {
-	ad, err := serv.App.CreateAd(r.Title, r.Text, r.UserId)
+	ad, err := serv.App.CreateAd(ctx, r.Title, r.Text, r.UserId, location(r.Location), offer(r.Offer))
 	if err != nil {
-		return &AdResponse{}, err
+		return &AdResponse{}, ToStatus(err)
 	}
-	return &AdResponse{Id: ad.ID, Title: ad.Title,
-		Text: ad.Text, AuthorId: ad.AuthorID, Published: ad.Published,
-		CreationDate: timestamppb.New(ad.CreationDate), UpdateTime: timestamppb.New(ad.UpdateTime)}, nil
+	return newAdResponse(*ad), nil
 }
 
 func (serv *AdUserService) ChangeAdStatus(ctx context.Context, r *ChangeAdStatusRequest) (*AdResponse, error) {
-	ad, err := serv.App.ChangeAdStatus(r.AdId, r.UserId, r.Published)
+	ad, err := serv.App.ChangeAdStatus(ctx, r.AdId, r.UserId, r.Published)
 	if err != nil {
-		return &AdResponse{}, err
+		return &AdResponse{}, ToStatus(err)
 	}
-	return &AdResponse{Id: ad.ID, Title: ad.Title,
-		Text: ad.Text, AuthorId: ad.AuthorID, Published: ad.Published,
-		CreationDate: timestamppb.New(ad.CreationDate), UpdateTime: timestamppb.New(ad.UpdateTime)}, nil
+	return newAdResponse(*ad), nil
 }
 
 func (serv *AdUserService) UpdateAd(ctx context.Context, r *UpdateAdRequest) (*AdResponse, error) {
-	ad, err := serv.App.UpdateAd(r.AdId, r.UserId, r.Title, r.Text)
+	ad, err := serv.App.UpdateAd(ctx, r.AdId, r.UserId, r.Title, r.Text, location(r.Location), offer(r.Offer))
+	if err != nil {
+		return &AdResponse{}, ToStatus(err)
+	}
+	return newAdResponse(*ad), nil
+}
+
+// GetAd counts the view by the address of the peer unless ctx carries a
+// viewer already, e.g. the client address set by the gateway.
+func (serv *AdUserService) GetAd(ctx context.Context, r *GetAdRequest) (*AdResponse, error) {
+	if analytics.Viewer(ctx) == "" {
+		if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+			ctx = analytics.WithViewer(ctx, analytics.AddrViewer(peerHost(p.Addr)))
+		}
+	}
+	ad, err := serv.App.GetAdByID(ctx, r.AdId)
 	if err != nil {
-		return &AdResponse{}, err
+		return &AdResponse{}, ToStatus(err)
 	}
-	return &AdResponse{Id: ad.ID, Title: ad.Title,
+	return newAdResponse(*ad), nil
+}
+
+// peerHost returns the IP of addr without the port, so the connections of
+// one client are one viewer.
+func peerHost(addr net.Addr) string {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
+
+func newAdResponse(ad ads.Ad) *AdResponse {
+	res := &AdResponse{Id: ad.ID, Title: ad.Title,
 		Text: ad.Text, AuthorId: ad.AuthorID, Published: ad.Published,
-		CreationDate: timestamppb.New(ad.CreationDate), UpdateTime: timestamppb.New(ad.UpdateTime)}, nil
+		CreationDate: timestamppb.New(ad.CreationDate), UpdateTime: timestamppb.New(ad.UpdateTime)}
+	if l := ad.Location; l != nil {
+		res.Location = &Location{Lat: l.Lat, Lon: l.Lon, City: l.City}
+	}
+	if o := ad.Offer; o != nil {
+		res.Offer = &Offer{Category: o.Category, Price: o.Price}
+	}
+	return res
+}
+
+// location returns nil for a missing location.
+func location(l *Location) *ads.Location {
+	if l == nil {
+		return nil
+	}
+	return &ads.Location{Lat: l.Lat, Lon: l.Lon, City: l.City}
+}
+
+// offer returns nil for a missing offer.
+func offer(o *Offer) *ads.Offer {
+	if o == nil {
+		return nil
+	}
+	return &ads.Offer{Category: o.Category, Price: o.Price}
 }
 
 func createListAdResponse(a []ads.Ad) *ListAdResponse {
 	var arr []*AdResponse
 	for _, ad := range a {
-		arr = append(arr, &AdResponse{Id: ad.ID, Title: ad.Title,
-			Text: ad.Text, AuthorId: ad.AuthorID, Published: ad.Published,
-			CreationDate: timestamppb.New(ad.CreationDate), UpdateTime: timestamppb.New(ad.UpdateTime)})
+		arr = append(arr, newAdResponse(ad))
 	}
 	return &ListAdResponse{List: arr}
 }
@@ -61,61 +112,201 @@ func (serv *AdUserService) ListAds(ctx context.Context, m *Mode) (*ListAdRespons
 	if mode == "ByAuthor" {
 		data, ok := m.Data.(*Mode_AuthorId)
 		if !ok {
-			return &ListAdResponse{}, errors.New("wrong parameters")
+			return &ListAdResponse{}, ToStatus(app.Errorf(app.CodeInvalidArgument, "wrong parameters for mode %s", mode))
 		}
-		arr, err = serv.App.SelectByAuthor(data.AuthorId)
+		arr, err = serv.App.SelectByAuthor(ctx, data.AuthorId)
 	} else if mode == "ByCreation" {
 		data, ok := m.Data.(*Mode_Time)
 		if !ok {
-			return &ListAdResponse{}, errors.New("wrong parameters")
+			return &ListAdResponse{}, ToStatus(app.Errorf(app.CodeInvalidArgument, "wrong parameters for mode %s", mode))
 		}
-		arr = serv.App.SelectByCreation(data.Time.AsTime())
+		arr, err = serv.App.SelectByCreation(ctx, data.Time.AsTime())
 	} else if mode == "All" {
-		arr = serv.App.SelectAll()
+		arr, err = serv.App.SelectAll(ctx)
 	} else if mode == "ByTitle" {
 		data, ok := m.Data.(*Mode_Title)
 		if !ok {
-			return &ListAdResponse{}, errors.New("wrong parameters")
+			return &ListAdResponse{}, ToStatus(app.Errorf(app.CodeInvalidArgument, "wrong parameters for mode %s", mode))
 		}
-		arr = serv.App.FindByTitle(data.Title)
+		arr, err = serv.App.FindByTitle(ctx, data.Title)
 	} else {
-		arr = serv.App.Select()
+		arr, err = serv.App.Select(ctx)
 	}
 	if err != nil {
-		return &ListAdResponse{}, err
+		return &ListAdResponse{}, ToStatus(err)
 	}
 	return createListAdResponse(arr), nil
 }
 
 func (serv *AdUserService) CreateUser(ctx context.Context, r *CreateUserRequest) (*UserResponse, error) {
-	usr := serv.App.CreateUser(r.Name, r.Email)
+	usr, err := serv.App.CreateUser(ctx, r.Name, r.Email)
+	if err != nil {
+		return &UserResponse{}, ToStatus(err)
+	}
 	return &UserResponse{Id: usr.ID, Name: usr.Nickname, Email: usr.Email}, nil
 }
 
+func (serv *AdUserService) ListUsers(ctx context.Context, _ *ListUsersRequest) (*ListUserResponse, error) {
+	arr, err := serv.App.ListUsers(ctx)
+	if err != nil {
+		return &ListUserResponse{}, ToStatus(err)
+	}
+	res := &ListUserResponse{}
+	for _, usr := range arr {
+		res.List = append(res.List, &UserResponse{Id: usr.ID, Name: usr.Nickname, Email: usr.Email})
+	}
+	return res, nil
+}
+
 func (serv *AdUserService) GetUser(ctx context.Context, r *GetUserRequest) (*UserResponse, error) {
-	usr, err := serv.App.GetUserByID(r.Id)
+	usr, err := serv.App.GetUserByID(ctx, r.Id)
 	if err != nil {
-		return &UserResponse{}, err
+		return &UserResponse{}, ToStatus(err)
 	}
 	return &UserResponse{Id: usr.ID, Name: usr.Nickname, Email: usr.Email}, nil
 }
 
 func (serv *AdUserService) DeleteUser(ctx context.Context, r *DeleteUserRequest) (*UserResponse, error) {
-	usr, err := serv.App.DeleteUser(r.Id)
+	usr, err := serv.App.DeleteUser(ctx, r.Id)
 	if err != nil {
-		return &UserResponse{}, err
+		return &UserResponse{}, ToStatus(err)
 	}
 	return &UserResponse{Id: usr.ID, Name: usr.Nickname, Email: usr.Email}, nil
 }
 
 func (serv *AdUserService) DeleteAd(ctx context.Context, r *DeleteAdRequest) (*AdResponse, error) {
-	ad, err := serv.App.DeleteAd(r.AdId, r.AuthorId)
+	ad, err := serv.App.DeleteAd(ctx, r.AdId, r.AuthorId)
 	if err != nil {
-		return &AdResponse{}, err
+		return &AdResponse{}, ToStatus(err)
 	}
-	return &AdResponse{Id: ad.ID, Title: ad.Title,
-		Text: ad.Text, AuthorId: ad.AuthorID, Published: ad.Published,
-		CreationDate: timestamppb.New(ad.CreationDate), UpdateTime: timestamppb.New(ad.UpdateTime)}, nil
+	return newAdResponse(*ad), nil
+}
+
+func (serv *AdUserService) SimilarAds(ctx context.Context, r *SimilarAdsRequest) (*SimilarAdsResponse, error) {
+	viewerID := int64(-1)
+	if r.ViewerId != nil {
+		viewerID = *r.ViewerId
+	}
+	limit := int(r.Limit)
+	if limit == 0 {
+		limit = 10
+	}
+	res, err := serv.App.SimilarAds(ctx, r.AdId, viewerID, limit)
+	if err != nil {
+		return &SimilarAdsResponse{}, ToStatus(err)
+	}
+	resp := &SimilarAdsResponse{}
+	for _, s := range res {
+		resp.List = append(resp.List, &SimilarAd{Score: s.Score, Ad: newAdResponse(s.Ad)})
+	}
+	return resp, nil
+}
+
+func (serv *AdUserService) SearchNearby(ctx context.Context, r *SearchNearbyRequest) (*SearchNearbyResponse, error) {
+	q := app.NearbyQuery{AuthorID: r.AuthorId, All: r.All, Title: r.Title,
+		SortByDistance: r.SortByDistance, Limit: int(r.Limit)}
+	if q.Limit == 0 {
+		q.Limit = 100
+	}
+	if r.CreatedAfter != nil {
+		q.CreatedAfter = r.CreatedAfter.AsTime()
+	}
+	switch area := r.Area.(type) {
+	case *SearchNearbyRequest_Circle:
+		q.Circle = &geo.Circle{Center: geo.Point{Lat: area.Circle.Lat, Lon: area.Circle.Lon}, Radius: area.Circle.RadiusKm}
+	case *SearchNearbyRequest_Box:
+		q.Box = &geo.Box{MinLat: area.Box.MinLat, MinLon: area.Box.MinLon, MaxLat: area.Box.MaxLat, MaxLon: area.Box.MaxLon}
+	}
+	res, err := serv.App.SearchNearby(ctx, q)
+	if err != nil {
+		return &SearchNearbyResponse{}, ToStatus(err)
+	}
+	resp := &SearchNearbyResponse{}
+	for _, n := range res {
+		resp.List = append(resp.List, &NearbyAd{Ad: newAdResponse(n.Ad), DistanceKm: n.Distance})
+	}
+	return resp, nil
 }
 
 func (serv *AdUserService) mustEmbedUnimplementedAdServiceServer() {}
+
+func newBulkAdError(e *app.Error) *BulkAdError {
+	be := &BulkAdError{Code: string(e.Code), Message: e.Error(), Retryable: e.Retryable}
+	for _, f := range e.Fields {
+		be.Fields = append(be.Fields, &FieldViolation{Field: f.Field, Description: f.Description})
+	}
+	return be
+}
+
+var bulkActions = map[BulkAction]app.BulkAction{
+	BulkAction_Publish:   app.BulkPublish,
+	BulkAction_Unpublish: app.BulkUnpublish,
+	BulkAction_Update:    app.BulkUpdate,
+	BulkAction_Delete:    app.BulkDelete,
+}
+
+func (serv *AdUserService) BulkAds(ctx context.Context, r *BulkAdsRequest) (*BulkAdsResponse, error) {
+	ops := make([]app.BulkOp, len(r.Operations))
+	for i, op := range r.Operations {
+		ops[i] = app.BulkOp{Action: bulkActions[op.Action], AdID: op.AdId, Title: op.Title, Text: op.Text}
+	}
+	results, err := serv.App.BulkAds(ctx, r.UserId, ops, r.Atomic)
+	if err != nil {
+		return &BulkAdsResponse{}, ToStatus(err)
+	}
+	resp := &BulkAdsResponse{Results: make([]*BulkAdResult, len(results))}
+	for i, res := range results {
+		item := &BulkAdResult{AdId: res.AdID}
+		if ad := res.Ad; ad != nil {
+			item.Ad = newAdResponse(*ad)
+		}
+		if res.Err != nil {
+			item.Error = newBulkAdError(res.Err)
+		}
+		resp.Results[i] = item
+	}
+	return resp, nil
+}
+
+func (serv *AdUserService) ImportAds(stream AdService_ImportAdsServer) error {
+	ctx := stream.Context()
+	first, err := stream.Recv()
+	if err == io.EOF {
+		return ToStatus(app.Errorf(app.CodeInvalidArgument, "import options are required"))
+	}
+	if err != nil {
+		return err
+	}
+	opts := first.GetOptions()
+	if opts == nil {
+		return ToStatus(app.Errorf(app.CodeInvalidArgument, "the first message must carry the import options"))
+	}
+	im, err := catalog.NewImporter(ctx, serv.App, opts.UserId, opts.DryRun)
+	if err != nil {
+		return ToStatus(err)
+	}
+	for line := 1; ; line++ {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		row := req.GetRow()
+		if row == nil {
+			im.Fail(line, app.Errorf(app.CodeInvalidArgument, "expected a row"))
+			continue
+		}
+		if err := im.Add(ctx, catalog.Row{Line: line, Title: row.Title, Text: row.Text}); err != nil {
+			return ToStatus(err)
+		}
+	}
+
+	report := im.Report()
+	resp := &ImportAdsResponse{Created: int64(report.Created), Failed: int64(report.Failed), DryRun: report.DryRun}
+	for _, e := range report.Errors {
+		resp.Errors = append(resp.Errors, &ImportLineError{Line: int64(e.Line), Error: newBulkAdError(e.Err)})
+	}
+	return stream.SendAndClose(resp)
+}
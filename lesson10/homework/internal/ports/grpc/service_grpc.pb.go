@@ -2,7 +2,7 @@
 // versions:
 // - protoc-gen-go-grpc v1.3.0
 // - protoc             (unknown)
-// source: lesson9/homework/internal/ports/grpc/service.proto
+// source: service.proto
 
 package grpc
 
@@ -22,11 +22,17 @@ const (
 	AdService_CreateAd_FullMethodName       = "/ad.AdService/CreateAd"
 	AdService_ChangeAdStatus_FullMethodName = "/ad.AdService/ChangeAdStatus"
 	AdService_UpdateAd_FullMethodName       = "/ad.AdService/UpdateAd"
+	AdService_GetAd_FullMethodName          = "/ad.AdService/GetAd"
 	AdService_ListAds_FullMethodName        = "/ad.AdService/ListAds"
 	AdService_CreateUser_FullMethodName     = "/ad.AdService/CreateUser"
+	AdService_ListUsers_FullMethodName      = "/ad.AdService/ListUsers"
 	AdService_GetUser_FullMethodName        = "/ad.AdService/GetUser"
 	AdService_DeleteUser_FullMethodName     = "/ad.AdService/DeleteUser"
 	AdService_DeleteAd_FullMethodName       = "/ad.AdService/DeleteAd"
+	AdService_BulkAds_FullMethodName        = "/ad.AdService/BulkAds"
+	AdService_SimilarAds_FullMethodName     = "/ad.AdService/SimilarAds"
+	AdService_SearchNearby_FullMethodName   = "/ad.AdService/SearchNearby"
+	AdService_ImportAds_FullMethodName      = "/ad.AdService/ImportAds"
 )
 
 // AdServiceClient is the client API for AdService service.
@@ -36,11 +42,21 @@ type AdServiceClient interface {
 	CreateAd(ctx context.Context, in *CreateAdRequest, opts ...grpc.CallOption) (*AdResponse, error)
 	ChangeAdStatus(ctx context.Context, in *ChangeAdStatusRequest, opts ...grpc.CallOption) (*AdResponse, error)
 	UpdateAd(ctx context.Context, in *UpdateAdRequest, opts ...grpc.CallOption) (*AdResponse, error)
+	// GetAd counts a view of a published ad by the address of the client.
+	GetAd(ctx context.Context, in *GetAdRequest, opts ...grpc.CallOption) (*AdResponse, error)
 	ListAds(ctx context.Context, in *Mode, opts ...grpc.CallOption) (*ListAdResponse, error)
 	CreateUser(ctx context.Context, in *CreateUserRequest, opts ...grpc.CallOption) (*UserResponse, error)
+	ListUsers(ctx context.Context, in *ListUsersRequest, opts ...grpc.CallOption) (*ListUserResponse, error)
 	GetUser(ctx context.Context, in *GetUserRequest, opts ...grpc.CallOption) (*UserResponse, error)
 	DeleteUser(ctx context.Context, in *DeleteUserRequest, opts ...grpc.CallOption) (*UserResponse, error)
 	DeleteAd(ctx context.Context, in *DeleteAdRequest, opts ...grpc.CallOption) (*AdResponse, error)
+	BulkAds(ctx context.Context, in *BulkAdsRequest, opts ...grpc.CallOption) (*BulkAdsResponse, error)
+	SimilarAds(ctx context.Context, in *SimilarAdsRequest, opts ...grpc.CallOption) (*SimilarAdsResponse, error)
+	// SearchNearby finds the ads located in a circle or a bounding box.
+	SearchNearby(ctx context.Context, in *SearchNearbyRequest, opts ...grpc.CallOption) (*SearchNearbyResponse, error)
+	// ImportAds takes the options in the first message and a row per message
+	// after it.
+	ImportAds(ctx context.Context, opts ...grpc.CallOption) (AdService_ImportAdsClient, error)
 }
 
 type adServiceClient struct {
@@ -78,6 +94,15 @@ func (c *adServiceClient) UpdateAd(ctx context.Context, in *UpdateAdRequest, opt
 	return out, nil
 }
 
+func (c *adServiceClient) GetAd(ctx context.Context, in *GetAdRequest, opts ...grpc.CallOption) (*AdResponse, error) {
+	out := new(AdResponse)
+	err := c.cc.Invoke(ctx, AdService_GetAd_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *adServiceClient) ListAds(ctx context.Context, in *Mode, opts ...grpc.CallOption) (*ListAdResponse, error) {
 	out := new(ListAdResponse)
 	err := c.cc.Invoke(ctx, AdService_ListAds_FullMethodName, in, out, opts...)
@@ -96,6 +121,15 @@ func (c *adServiceClient) CreateUser(ctx context.Context, in *CreateUserRequest,
 	return out, nil
 }
 
+func (c *adServiceClient) ListUsers(ctx context.Context, in *ListUsersRequest, opts ...grpc.CallOption) (*ListUserResponse, error) {
+	out := new(ListUserResponse)
+	err := c.cc.Invoke(ctx, AdService_ListUsers_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *adServiceClient) GetUser(ctx context.Context, in *GetUserRequest, opts ...grpc.CallOption) (*UserResponse, error) {
 	out := new(UserResponse)
 	err := c.cc.Invoke(ctx, AdService_GetUser_FullMethodName, in, out, opts...)
@@ -123,6 +157,67 @@ func (c *adServiceClient) DeleteAd(ctx context.Context, in *DeleteAdRequest, opt
 	return out, nil
 }
 
+func (c *adServiceClient) BulkAds(ctx context.Context, in *BulkAdsRequest, opts ...grpc.CallOption) (*BulkAdsResponse, error) {
+	out := new(BulkAdsResponse)
+	err := c.cc.Invoke(ctx, AdService_BulkAds_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) SimilarAds(ctx context.Context, in *SimilarAdsRequest, opts ...grpc.CallOption) (*SimilarAdsResponse, error) {
+	out := new(SimilarAdsResponse)
+	err := c.cc.Invoke(ctx, AdService_SimilarAds_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) SearchNearby(ctx context.Context, in *SearchNearbyRequest, opts ...grpc.CallOption) (*SearchNearbyResponse, error) {
+	out := new(SearchNearbyResponse)
+	err := c.cc.Invoke(ctx, AdService_SearchNearby_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) ImportAds(ctx context.Context, opts ...grpc.CallOption) (AdService_ImportAdsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &AdService_ServiceDesc.Streams[0], AdService_ImportAds_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &adServiceImportAdsClient{stream}
+	return x, nil
+}
+
+type AdService_ImportAdsClient interface {
+	Send(*ImportAdsRequest) error
+	CloseAndRecv() (*ImportAdsResponse, error)
+	grpc.ClientStream
+}
+
+type adServiceImportAdsClient struct {
+	grpc.ClientStream
+}
+
+func (x *adServiceImportAdsClient) Send(m *ImportAdsRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *adServiceImportAdsClient) CloseAndRecv() (*ImportAdsResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(ImportAdsResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // AdServiceServer is the server API for AdService service.
 // All implementations must embed UnimplementedAdServiceServer
 // for forward compatibility
@@ -130,11 +225,21 @@ type AdServiceServer interface {
 	CreateAd(context.Context, *CreateAdRequest) (*AdResponse, error)
 	ChangeAdStatus(context.Context, *ChangeAdStatusRequest) (*AdResponse, error)
 	UpdateAd(context.Context, *UpdateAdRequest) (*AdResponse, error)
+	// GetAd counts a view of a published ad by the address of the client.
+	GetAd(context.Context, *GetAdRequest) (*AdResponse, error)
 	ListAds(context.Context, *Mode) (*ListAdResponse, error)
 	CreateUser(context.Context, *CreateUserRequest) (*UserResponse, error)
+	ListUsers(context.Context, *ListUsersRequest) (*ListUserResponse, error)
 	GetUser(context.Context, *GetUserRequest) (*UserResponse, error)
 	DeleteUser(context.Context, *DeleteUserRequest) (*UserResponse, error)
 	DeleteAd(context.Context, *DeleteAdRequest) (*AdResponse, error)
+	BulkAds(context.Context, *BulkAdsRequest) (*BulkAdsResponse, error)
+	SimilarAds(context.Context, *SimilarAdsRequest) (*SimilarAdsResponse, error)
+	// SearchNearby finds the ads located in a circle or a bounding box.
+	SearchNearby(context.Context, *SearchNearbyRequest) (*SearchNearbyResponse, error)
+	// ImportAds takes the options in the first message and a row per message
+	// after it.
+	ImportAds(AdService_ImportAdsServer) error
 	mustEmbedUnimplementedAdServiceServer()
 }
 
@@ -151,12 +256,18 @@ func (UnimplementedAdServiceServer) ChangeAdStatus(context.Context, *ChangeAdSta
 func (UnimplementedAdServiceServer) UpdateAd(context.Context, *UpdateAdRequest) (*AdResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method UpdateAd not implemented")
 }
+func (UnimplementedAdServiceServer) GetAd(context.Context, *GetAdRequest) (*AdResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAd not implemented")
+}
 func (UnimplementedAdServiceServer) ListAds(context.Context, *Mode) (*ListAdResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ListAds not implemented")
 }
 func (UnimplementedAdServiceServer) CreateUser(context.Context, *CreateUserRequest) (*UserResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CreateUser not implemented")
 }
+func (UnimplementedAdServiceServer) ListUsers(context.Context, *ListUsersRequest) (*ListUserResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListUsers not implemented")
+}
 func (UnimplementedAdServiceServer) GetUser(context.Context, *GetUserRequest) (*UserResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetUser not implemented")
 }
@@ -166,6 +277,18 @@ func (UnimplementedAdServiceServer) DeleteUser(context.Context, *DeleteUserReque
 func (UnimplementedAdServiceServer) DeleteAd(context.Context, *DeleteAdRequest) (*AdResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method DeleteAd not implemented")
 }
+func (UnimplementedAdServiceServer) BulkAds(context.Context, *BulkAdsRequest) (*BulkAdsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BulkAds not implemented")
+}
+func (UnimplementedAdServiceServer) SimilarAds(context.Context, *SimilarAdsRequest) (*SimilarAdsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SimilarAds not implemented")
+}
+func (UnimplementedAdServiceServer) SearchNearby(context.Context, *SearchNearbyRequest) (*SearchNearbyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchNearby not implemented")
+}
+func (UnimplementedAdServiceServer) ImportAds(AdService_ImportAdsServer) error {
+	return status.Errorf(codes.Unimplemented, "method ImportAds not implemented")
+}
 func (UnimplementedAdServiceServer) mustEmbedUnimplementedAdServiceServer() {}
 
 // UnsafeAdServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -233,6 +356,24 @@ func _AdService_UpdateAd_Handler(srv interface{}, ctx context.Context, dec func(
 	return interceptor(ctx, in, info, handler)
 }
 
+func _AdService_GetAd_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAdRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).GetAd(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_GetAd_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).GetAd(ctx, req.(*GetAdRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _AdService_ListAds_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(Mode)
 	if err := dec(in); err != nil {
@@ -269,6 +410,24 @@ func _AdService_CreateUser_Handler(srv interface{}, ctx context.Context, dec fun
 	return interceptor(ctx, in, info, handler)
 }
 
+func _AdService_ListUsers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListUsersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).ListUsers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_ListUsers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).ListUsers(ctx, req.(*ListUsersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _AdService_GetUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(GetUserRequest)
 	if err := dec(in); err != nil {
@@ -323,6 +482,86 @@ func _AdService_DeleteAd_Handler(srv interface{}, ctx context.Context, dec func(
 	return interceptor(ctx, in, info, handler)
 }
 
+func _AdService_BulkAds_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BulkAdsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).BulkAds(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_BulkAds_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).BulkAds(ctx, req.(*BulkAdsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdService_SimilarAds_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SimilarAdsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).SimilarAds(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_SimilarAds_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).SimilarAds(ctx, req.(*SimilarAdsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdService_SearchNearby_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchNearbyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).SearchNearby(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_SearchNearby_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).SearchNearby(ctx, req.(*SearchNearbyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdService_ImportAds_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(AdServiceServer).ImportAds(&adServiceImportAdsServer{stream})
+}
+
+type AdService_ImportAdsServer interface {
+	SendAndClose(*ImportAdsResponse) error
+	Recv() (*ImportAdsRequest, error)
+	grpc.ServerStream
+}
+
+type adServiceImportAdsServer struct {
+	grpc.ServerStream
+}
+
+func (x *adServiceImportAdsServer) SendAndClose(m *ImportAdsResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *adServiceImportAdsServer) Recv() (*ImportAdsRequest, error) {
+	m := new(ImportAdsRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // AdService_ServiceDesc is the grpc.ServiceDesc for AdService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -342,6 +581,10 @@ var AdService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "UpdateAd",
 			Handler:    _AdService_UpdateAd_Handler,
 		},
+		{
+			MethodName: "GetAd",
+			Handler:    _AdService_GetAd_Handler,
+		},
 		{
 			MethodName: "ListAds",
 			Handler:    _AdService_ListAds_Handler,
@@ -350,6 +593,10 @@ var AdService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "CreateUser",
 			Handler:    _AdService_CreateUser_Handler,
 		},
+		{
+			MethodName: "ListUsers",
+			Handler:    _AdService_ListUsers_Handler,
+		},
 		{
 			MethodName: "GetUser",
 			Handler:    _AdService_GetUser_Handler,
@@ -362,7 +609,25 @@ var AdService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "DeleteAd",
 			Handler:    _AdService_DeleteAd_Handler,
 		},
+		{
+			MethodName: "BulkAds",
+			Handler:    _AdService_BulkAds_Handler,
+		},
+		{
+			MethodName: "SimilarAds",
+			Handler:    _AdService_SimilarAds_Handler,
+		},
+		{
+			MethodName: "SearchNearby",
+			Handler:    _AdService_SearchNearby_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ImportAds",
+			Handler:       _AdService_ImportAds_Handler,
+			ClientStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
-	Metadata: "lesson9/homework/internal/ports/grpc/service.proto",
+	Metadata: "service.proto",
 }
@@ -0,0 +1,172 @@
+package httpgin
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultCompressMinSize is the size in bytes below which responses are
+// sent uncompressed, compressing them saves less than it costs.
+const DefaultCompressMinSize = 1024
+
+// encoder is a compressing writer that can be reused.
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+type encoding struct {
+	name string
+	pool *sync.Pool
+}
+
+// encodings are in the order of preference for equal quality values.
+var encodings = []encoding{
+	{"br", &sync.Pool{New: func() any { return brotli.NewWriterLevel(nil, 5) }}},
+	{"gzip", &sync.Pool{New: func() any { return gzip.NewWriter(nil) }}},
+}
+
+// negotiate picks the encoding with the highest quality value in the
+// Accept-Encoding header, nil if the response must not be compressed.
+func negotiate(header string) *encoding {
+	quality := map[string]float64{}
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		quality[strings.ToLower(strings.TrimSpace(name))] = q
+	}
+	var best *encoding
+	bestQ := 0.0
+	for i, enc := range encodings {
+		q, ok := quality[enc.name]
+		if !ok {
+			q = quality["*"]
+		}
+		if q > bestQ {
+			best, bestQ = &encodings[i], q
+		}
+	}
+	return best
+}
+
+// Compress compresses responses with gzip or brotli, as negotiated from
+// the Accept-Encoding header. Bodies are buffered until minSize bytes are
+// written, smaller ones are sent as they are. A flush, as in streamed
+// exports, starts compressing at once. The entity tag of a compressed
+// response gets the encoding as a suffix, because the bytes differ.
+func Compress(minSize int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+		enc := negotiate(c.GetHeader("Accept-Encoding"))
+		if enc == nil || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+		w := &compressWriter{ResponseWriter: c.Writer, enc: enc, minSize: minSize}
+		c.Writer = w
+		defer func() {
+			w.close()
+			c.Writer = w.ResponseWriter
+		}()
+		c.Next()
+	}
+}
+
+// compressWriter buffers the body until it decides whether to compress it.
+type compressWriter struct {
+	gin.ResponseWriter
+	enc     *encoding
+	minSize int
+
+	buf     bytes.Buffer
+	decided bool
+	encoder encoder
+}
+
+// decide writes the buffered body, compressed if compress is set and the
+// response allows it.
+func (w *compressWriter) decide(compress bool) {
+	w.decided = true
+	h := w.Header()
+	status := w.Status()
+	if compress && h.Get("Content-Encoding") == "" && status != http.StatusNoContent && status != http.StatusNotModified {
+		h.Set("Content-Encoding", w.enc.name)
+		h.Del("Content-Length")
+		if etag := h.Get("ETag"); strings.HasSuffix(etag, `"`) {
+			h.Set("ETag", strings.TrimSuffix(etag, `"`)+"-"+w.enc.name+`"`)
+		}
+		w.encoder = w.enc.pool.Get().(encoder)
+		w.encoder.Reset(w.ResponseWriter)
+	}
+	if w.buf.Len() > 0 {
+		_, _ = w.write(w.buf.Bytes())
+		w.buf.Reset()
+	}
+}
+
+func (w *compressWriter) write(b []byte) (int, error) {
+	if w.encoder != nil {
+		return w.encoder.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if w.decided {
+		return w.write(b)
+	}
+	w.buf.Write(b)
+	if w.buf.Len() >= w.minSize {
+		w.decide(true)
+	}
+	return len(b), nil
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Written reports whether the body was started, even if it is still
+// buffered.
+func (w *compressWriter) Written() bool {
+	return w.buf.Len() > 0 || w.ResponseWriter.Written()
+}
+
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.decide(w.buf.Len() > 0)
+	}
+	if w.encoder != nil {
+		_ = w.encoder.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// close sends the rest of the body and returns the encoder to its pool.
+func (w *compressWriter) close() {
+	if !w.decided {
+		w.decide(false)
+	}
+	if w.encoder != nil {
+		_ = w.encoder.Close()
+		w.encoder.Reset(nil)
+		w.enc.pool.Put(w.encoder)
+		w.encoder = nil
+	}
+}
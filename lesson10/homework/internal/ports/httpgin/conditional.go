@@ -0,0 +1,76 @@
+package httpgin
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// etagOf returns a strong entity tag of a response body.
+func etagOf(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatch reports whether the If-None-Match header matches etag. The
+// comparison is weak, as RFC 9110 requires for If-None-Match, and ignores
+// the suffix that Compress adds to the tags of compressed responses.
+func etagMatch(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+		for _, enc := range encodings {
+			if tag == strings.TrimSuffix(etag, `"`)+"-"+enc.name+`"` {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// notModified reports whether the conditional headers of the request are
+// satisfied by a response with etag and modified. If-Modified-Since is only
+// checked without If-None-Match.
+func notModified(r *http.Request, etag string, modified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etagMatch(inm, etag)
+	}
+	if modified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	return !modified.Truncate(time.Second).After(since)
+}
+
+// writeCacheable renders body as JSON with its ETag and, unless zero,
+// modified as Last-Modified. A request whose If-None-Match or
+// If-Modified-Since is satisfied gets 304 without a body. Clients must
+// revalidate before reusing a stored response.
+func writeCacheable(c *gin.Context, modified time.Time, body any) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	etag := etagOf(data)
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "no-cache")
+	if !modified.IsZero() {
+		c.Header("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+	if notModified(c.Request, etag, modified) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", data)
+}
@@ -0,0 +1,37 @@
+// Package docs serves the OpenAPI document of the v1 HTTP API together with
+// Swagger UI. swagger.json and swagger.yaml are generated by swag from the
+// annotations in package httpgin.
+package docs
+
+import (
+	_ "embed"
+	"net/http"
+
+	swaggerFiles "github.com/swaggo/files/v2"
+)
+
+//go:generate swag init --v3.1 -g router.go -d ..,../../../ads,../../../users,../../../app,../../../webhooks,../../../analytics,../../../engagement --parseInternal -o . --ot json,yaml
+
+//go:embed swagger.json
+var OpenAPI []byte
+
+//go:embed swagger-initializer.js
+var initializer []byte
+
+// Handler serves the spec at /openapi.json and Swagger UI at /.
+// It expects the mount prefix to be stripped from the request path.
+func Handler() http.Handler {
+	files := http.FileServer(http.FS(swaggerFiles.FS))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/openapi.json":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write(OpenAPI)
+		case "/swagger-initializer.js":
+			w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+			_, _ = w.Write(initializer)
+		default:
+			files.ServeHTTP(w, r)
+		}
+	})
+}
@@ -0,0 +1,99 @@
+package httpgin
+
+import (
+	"encoding/json"
+	"homework10/internal/app"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const problemContentType = "application/problem+json"
+
+// statusClientClosedRequest is the nginx status for requests the client
+// canceled. Nobody reads the response, it is only seen in logs and metrics.
+const statusClientClosedRequest = 499
+
+// problem is an RFC 7807 problem details object extended with the fields
+// of app.Error.
+type problem struct {
+	Type          string               `json:"type"`
+	Title         string               `json:"title"`
+	Status        int                  `json:"status"`
+	Detail        string               `json:"detail,omitempty"`
+	Instance      string               `json:"instance,omitempty"`
+	Code          app.Code             `json:"code" enums:"invalid_argument,not_found,permission_denied,unavailable,deadline_exceeded,canceled,conflict,unprocessable,unauthenticated,unimplemented,too_large,internal"`
+	Retryable     bool                 `json:"retryable"`
+	InvalidParams []app.FieldViolation `json:"invalid_params,omitempty"`
+}
+
+// HTTPStatus returns the status of a response reporting an error with code.
+func HTTPStatus(code app.Code) int {
+	switch code {
+	case app.CodeInvalidArgument:
+		return http.StatusBadRequest
+	case app.CodeNotFound:
+		return http.StatusNotFound
+	case app.CodePermissionDenied:
+		return http.StatusForbidden
+	case app.CodeUnavailable:
+		return http.StatusServiceUnavailable
+	case app.CodeDeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case app.CodeCanceled:
+		return statusClientClosedRequest
+	case app.CodeConflict:
+		return http.StatusConflict
+	case app.CodeUnprocessable:
+		return http.StatusUnprocessableEntity
+	case app.CodeUnauthenticated:
+		return http.StatusUnauthorized
+	case app.CodeUnimplemented:
+		// the gateway reports a route with another method as unimplemented
+		return http.StatusMethodNotAllowed
+	case app.CodeTooLarge:
+		return http.StatusRequestEntityTooLarge
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// WriteProblem renders err as problem+json. It is shared by the gin handlers
+// and the gRPC gateway so both HTTP APIs report errors the same way.
+func WriteProblem(w http.ResponseWriter, r *http.Request, err error) {
+	e := app.FromError(err)
+	status := HTTPStatus(e.Code)
+	detail := e.Message
+	if e.Code != app.CodeInternal {
+		detail = e.Error()
+	}
+	p := problem{
+		Type:          "about:blank",
+		Title:         statusText(status),
+		Status:        status,
+		Detail:        detail,
+		Instance:      r.URL.Path,
+		Code:          e.Code,
+		Retryable:     e.Retryable,
+		InvalidParams: e.Fields,
+	}
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(p)
+}
+
+func statusText(status int) string {
+	if status == statusClientClosedRequest {
+		return "Client Closed Request"
+	}
+	return http.StatusText(status)
+}
+
+func writeError(c *gin.Context, err error) {
+	WriteProblem(c.Writer, c.Request, err)
+	c.Abort()
+}
+
+func badRequest(c *gin.Context, format string, args ...any) {
+	writeError(c, app.Errorf(app.CodeInvalidArgument, format, args...))
+}
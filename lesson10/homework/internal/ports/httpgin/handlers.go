@@ -2,52 +2,88 @@ package httpgin
 
 import (
 	"encoding/json"
+	"errors"
 	"homework10/internal/ads"
+	"homework10/internal/analytics"
 	"homework10/internal/app"
+	"homework10/internal/catalog"
+	"homework10/internal/geo"
+	"io"
 	"net/http"
+	"path"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// GetAdByID godoc
+//
+//	@Summary		Get ad
+//	@Description	Counts a view of a published ad, once per client address within a window, see http.trusted_proxies. Answers 304 when the ETag in If-None-Match or the time in If-Modified-Since is still current.
+//	@Tags			ads
+//	@Produce		json
+//	@Param			id					path		int		true	"Ad ID"
+//	@Param			If-None-Match		header		string	false	"ETag of a stored response"
+//	@Param			If-Modified-Since	header		string	false	"Last-Modified of a stored response"
+//	@Success		200					{object}	adResponse
+//	@Header			200					{string}	ETag			"Strong entity tag of the response"
+//	@Header			200					{string}	Last-Modified	"Time of the last update of the ad"
+//	@Failure		400					{object}	problem
+//	@Failure		404					{object}	problem
+//	@Success		304					"Not modified"
+//	@Router			/ads/{id} [get]
 func GetAdByID(a app.App) gin.HandlerFunc {
 	fn := func(c *gin.Context) {
 		id, err := strconv.Atoi(c.Param("id"))
 		if err != nil {
-			c.Status(http.StatusBadRequest)
+			badRequest(c, "invalid id %q", c.Param("id"))
 			return
 		}
-		ad, err := a.GetAdByID(int64(id))
+		// the API has no authenticated users, a user ID in the request
+		// could be anyone's
+		ctx := analytics.WithViewer(c.Request.Context(), analytics.AddrViewer(c.ClientIP()))
+		ad, err := a.GetAdByID(ctx, int64(id))
 		if err != nil {
-			c.Status(http.StatusNotFound)
+			writeError(c, err)
 			return
 		}
-		c.JSON(http.StatusOK, adResponse{*ad})
+		writeCacheable(c, ad.UpdateTime, adResponse{*ad})
 	}
 
 	return gin.HandlerFunc(fn)
 }
 
+// CreateAd godoc
+//
+//	@Summary	Create ad
+//	@Tags		ads
+//	@Accept		json
+//	@Produce	json
+//	@Param		request			body		createAdRequest	true	"New ad"
+//	@Param		Idempotency-Key	header		string			false	"Replays the first response to retries"
+//	@Success	200				{object}	adResponse
+//	@Failure	400				{object}	problem
+//	@Failure	404				{object}	problem
+//	@Failure	409				{object}	problem
+//	@Failure	422				{object}	problem
+//	@Router		/ads [post]
 func CreateAd(a app.App) gin.HandlerFunc {
 	fn := func(c *gin.Context) {
 		body, err := c.GetRawData()
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err})
+			badRequest(c, "can't read request body")
 			return
 		}
 		var data createAdRequest
 		err = json.Unmarshal(body, &data)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err})
+			badRequest(c, "malformed request body: %s", err.Error())
 			return
 		}
-		ad, err := a.CreateAd(data.Title, data.Text, data.UserID)
+		ad, err := a.CreateAd(c.Request.Context(), data.Title, data.Text, data.UserID, data.Location, data.Offer)
 		if err != nil {
-			if err == app.ErrBadRequest {
-				c.Status(http.StatusBadRequest)
-			} else {
-				c.Status(http.StatusNotFound)
-			}
+			writeError(c, err)
 			return
 		}
 		c.JSON(http.StatusOK, adResponse{*ad})
@@ -55,31 +91,40 @@ func CreateAd(a app.App) gin.HandlerFunc {
 	return gin.HandlerFunc(fn)
 }
 
+// ChangeAdStatus godoc
+//
+//	@Summary	Publish or unpublish ad
+//	@Tags		ads
+//	@Accept		json
+//	@Produce	json
+//	@Param		id		path		int						true	"Ad ID"
+//	@Param		request	body		changeAdStatusRequest	true	"New status"
+//	@Success	200		{object}	adResponse
+//	@Failure	400		{object}	problem
+//	@Failure	403		{object}	problem
+//	@Failure	404		{object}	problem
+//	@Router		/ads/{id}/status [put]
 func ChangeAdStatus(a app.App) gin.HandlerFunc {
 	fn := func(c *gin.Context) {
 		id, err := strconv.Atoi(c.Param("id"))
 		if err != nil {
-			c.Status(http.StatusBadRequest)
+			badRequest(c, "invalid id %q", c.Param("id"))
 			return
 		}
 		body, err := c.GetRawData()
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err})
+			badRequest(c, "can't read request body")
 			return
 		}
 		var data changeAdStatusRequest
 		err = json.Unmarshal(body, &data)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err})
+			badRequest(c, "malformed request body: %s", err.Error())
 			return
 		}
-		ad, err := a.ChangeAdStatus(int64(id), data.UserID, data.Published)
+		ad, err := a.ChangeAdStatus(c.Request.Context(), int64(id), data.UserID, data.Published)
 		if err != nil {
-			if err == app.ErrForbidden {
-				c.Status(http.StatusForbidden)
-			} else {
-				c.Status(http.StatusNotFound)
-			}
+			writeError(c, err)
 			return
 		}
 		c.JSON(http.StatusOK, adResponse{*ad})
@@ -87,33 +132,40 @@ func ChangeAdStatus(a app.App) gin.HandlerFunc {
 	return gin.HandlerFunc(fn)
 }
 
+// UpdateAd godoc
+//
+//	@Summary	Update ad title, text, location and offer
+//	@Tags		ads
+//	@Accept		json
+//	@Produce	json
+//	@Param		id		path		int				true	"Ad ID"
+//	@Param		request	body		updateAdRequest	true	"New title, text, location and offer"
+//	@Success	200		{object}	adResponse
+//	@Failure	400		{object}	problem
+//	@Failure	403		{object}	problem
+//	@Failure	404		{object}	problem
+//	@Router		/ads/{id} [put]
 func UpdateAd(a app.App) gin.HandlerFunc {
 	fn := func(c *gin.Context) {
 		id, err := strconv.Atoi(c.Param("id"))
 		if err != nil {
-			c.Status(http.StatusBadRequest)
+			badRequest(c, "invalid id %q", c.Param("id"))
 			return
 		}
 		body, err := c.GetRawData()
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err})
+			badRequest(c, "can't read request body")
 			return
 		}
 		var data updateAdRequest
 		err = json.Unmarshal(body, &data)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err})
+			badRequest(c, "malformed request body: %s", err.Error())
 			return
 		}
-		ad, err := a.UpdateAd(int64(id), data.UserID, data.Title, data.Text)
+		ad, err := a.UpdateAd(c.Request.Context(), int64(id), data.UserID, data.Title, data.Text, data.Location, data.Offer)
 		if err != nil {
-			if err == app.ErrForbidden {
-				c.Status(http.StatusForbidden)
-			} else if err == app.ErrBadRequest {
-				c.Status(http.StatusBadRequest)
-			} else {
-				c.Status(http.StatusNotFound)
-			}
+			writeError(c, err)
 			return
 		}
 		c.JSON(http.StatusOK, adResponse{*ad})
@@ -121,77 +173,119 @@ func UpdateAd(a app.App) gin.HandlerFunc {
 	return gin.HandlerFunc(fn)
 }
 
+// Select godoc
+//
+//	@Summary		List ads
+//	@Description	Without a body only published ads are listed. Answers 304 when the ETag in If-None-Match or the time in If-Modified-Since is still current.
+//	@Tags			ads
+//	@Accept			json
+//	@Produce		json
+//	@Param			request				body		selectAdRequest	false	"Filter"
+//	@Param			If-None-Match		header		string			false	"ETag of a stored response"
+//	@Param			If-Modified-Since	header		string			false	"Last-Modified of a stored response"
+//	@Success		200					{object}	adsResponse
+//	@Header			200					{string}	ETag			"Strong entity tag of the response"
+//	@Header			200					{string}	Last-Modified	"Time of the last change of any ad"
+//	@Failure		404					{object}	problem
+//	@Success		304					"Not modified"
+//	@Router			/ads [get]
 func Select(a app.App) gin.HandlerFunc {
 	fn := func(c *gin.Context) {
-		body, err := c.GetRawData()
-		if err != nil {
-			c.JSON(http.StatusOK, adsResponse{a.Select()})
-			return
-		}
+		// an unreadable or malformed body lists the published ads
 		var data selectAdRequest
-		err = json.Unmarshal(body, &data)
-		if err != nil {
-			c.JSON(http.StatusOK, adsResponse{a.Select()})
-			return
+		body, err := c.GetRawData()
+		if err == nil && json.Unmarshal(body, &data) != nil {
+			data = selectAdRequest{}
 		}
+		modified := a.AdsModified(c.Request.Context())
 		var arr []ads.Ad
 		if data.ByAuthor {
-			arr, err = a.SelectByAuthor(data.AuthorID)
+			arr, err = a.SelectByAuthor(c.Request.Context(), data.AuthorID)
 		} else if data.ByCreation {
-			arr = a.SelectByCreation(data.CreationTime)
+			arr, err = a.SelectByCreation(c.Request.Context(), data.CreationTime)
 		} else if data.All {
-			arr = a.SelectAll()
+			arr, err = a.SelectAll(c.Request.Context())
 		} else {
-			arr = a.Select()
+			arr, err = a.Select(c.Request.Context())
 		}
 		if err != nil {
-			c.Status(http.StatusBadRequest)
+			writeError(c, err)
+			return
 		}
-		c.JSON(http.StatusOK, adsResponse{arr})
+		writeCacheable(c, modified, adsResponse{arr})
 	}
 	return gin.HandlerFunc(fn)
 }
 
+// CreateUser godoc
+//
+//	@Summary	Create user
+//	@Tags		users
+//	@Accept		json
+//	@Produce	json
+//	@Param		request			body		createOrUpdateUser	true	"New user"
+//	@Param		Idempotency-Key	header		string				false	"Replays the first response to retries"
+//	@Success	200				{object}	userResponse
+//	@Failure	400				{object}	problem
+//	@Failure	409				{object}	problem
+//	@Failure	422				{object}	problem
+//	@Router		/users [post]
 func CreateUser(a app.App) gin.HandlerFunc {
 	fn := func(c *gin.Context) {
 		body, err := c.GetRawData()
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err})
+			badRequest(c, "can't read request body")
 			return
 		}
 		var data createOrUpdateUser
 		err = json.Unmarshal(body, &data)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err})
+			badRequest(c, "malformed request body: %s", err.Error())
+			return
+		}
+		usr, err := a.CreateUser(c.Request.Context(), data.Nickname, data.Email)
+		if err != nil {
+			writeError(c, err)
 			return
 		}
-		usr := a.CreateUser(data.Nickname, data.Email)
 		c.JSON(http.StatusOK, userResponse{*usr})
 	}
 	return gin.HandlerFunc(fn)
 }
 
+// UpdateUser godoc
+//
+//	@Summary	Update user
+//	@Tags		users
+//	@Accept		json
+//	@Produce	json
+//	@Param		id		path		int					true	"User ID"
+//	@Param		request	body		createOrUpdateUser	true	"New nickname and email"
+//	@Success	200		{object}	userResponse
+//	@Failure	400		{object}	problem
+//	@Failure	404		{object}	problem
+//	@Router		/users/{id} [put]
 func UpdateUser(a app.App) gin.HandlerFunc {
 	fn := func(c *gin.Context) {
 		id, err := strconv.Atoi(c.Param("id"))
 		if err != nil {
-			c.Status(http.StatusBadRequest)
+			badRequest(c, "invalid id %q", c.Param("id"))
 			return
 		}
 		body, err := c.GetRawData()
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err})
+			badRequest(c, "can't read request body")
 			return
 		}
 		var data createOrUpdateUser
 		err = json.Unmarshal(body, &data)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err})
+			badRequest(c, "malformed request body: %s", err.Error())
 			return
 		}
-		usr, err := a.UpdateUser(int64(id), data.Nickname, data.Email)
+		usr, err := a.UpdateUser(c.Request.Context(), int64(id), data.Nickname, data.Email)
 		if err != nil {
-			c.Status(http.StatusNotFound)
+			writeError(c, err)
 			return
 		}
 		c.JSON(http.StatusOK, userResponse{*usr})
@@ -199,29 +293,60 @@ func UpdateUser(a app.App) gin.HandlerFunc {
 	return gin.HandlerFunc(fn)
 }
 
+// FindAdByTitle godoc
+//
+//	@Summary		Find ads by title
+//	@Description	Answers 304 when the ETag in If-None-Match or the time in If-Modified-Since is still current.
+//	@Tags			ads
+//	@Produce		json
+//	@Param			title				query		string	true	"Substring of the title"
+//	@Param			If-None-Match		header		string	false	"ETag of a stored response"
+//	@Param			If-Modified-Since	header		string	false	"Last-Modified of a stored response"
+//	@Success		200					{object}	adsResponse
+//	@Header			200					{string}	ETag			"Strong entity tag of the response"
+//	@Header			200					{string}	Last-Modified	"Time of the last change of any ad"
+//	@Failure		400					{object}	problem
+//	@Success		304					"Not modified"
+//	@Router			/ads/title [get]
 func FindAdByTitle(a app.App) gin.HandlerFunc {
 	fn := func(c *gin.Context) {
 		title := c.Query("title")
 		if title == "" {
-			c.Status(http.StatusBadRequest)
+			badRequest(c, "query parameter title is required")
 			return
 		}
-		c.JSON(http.StatusOK, adsResponse{a.FindByTitle(title)})
+		modified := a.AdsModified(c.Request.Context())
+		arr, err := a.FindByTitle(c.Request.Context(), title)
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		writeCacheable(c, modified, adsResponse{arr})
 	}
 
 	return gin.HandlerFunc(fn)
 }
 
+// GetUserByID godoc
+//
+//	@Summary	Get user
+//	@Tags		users
+//	@Produce	json
+//	@Param		id	path		int	true	"User ID"
+//	@Success	200	{object}	userResponse
+//	@Failure	400	{object}	problem
+//	@Failure	404	{object}	problem
+//	@Router		/users/{id} [get]
 func GetUserByID(a app.App) gin.HandlerFunc {
 	fn := func(c *gin.Context) {
 		id, err := strconv.Atoi(c.Param("id"))
 		if err != nil {
-			c.Status(http.StatusBadRequest)
+			badRequest(c, "invalid id %q", c.Param("id"))
 			return
 		}
-		usr, err := a.GetUserByID(int64(id))
+		usr, err := a.GetUserByID(c.Request.Context(), int64(id))
 		if err != nil {
-			c.Status(http.StatusNotFound)
+			writeError(c, err)
 			return
 		}
 		c.JSON(http.StatusOK, userResponse{*usr})
@@ -230,16 +355,26 @@ func GetUserByID(a app.App) gin.HandlerFunc {
 	return gin.HandlerFunc(fn)
 }
 
+// DeleteUserByID godoc
+//
+//	@Summary	Delete user
+//	@Tags		users
+//	@Produce	json
+//	@Param		id	path		int	true	"User ID"
+//	@Success	200	{object}	userResponse
+//	@Failure	400	{object}	problem
+//	@Failure	404	{object}	problem
+//	@Router		/users/{id} [delete]
 func DeleteUserByID(a app.App) gin.HandlerFunc {
 	fn := func(c *gin.Context) {
 		id, err := strconv.Atoi(c.Param("id"))
 		if err != nil {
-			c.Status(http.StatusBadRequest)
+			badRequest(c, "invalid id %q", c.Param("id"))
 			return
 		}
-		usr, err := a.DeleteUser(int64(id))
+		usr, err := a.DeleteUser(c.Request.Context(), int64(id))
 		if err != nil {
-			c.Status(http.StatusNotFound)
+			writeError(c, err)
 			return
 		}
 		c.JSON(http.StatusOK, userResponse{*usr})
@@ -248,35 +383,772 @@ func DeleteUserByID(a app.App) gin.HandlerFunc {
 	return gin.HandlerFunc(fn)
 }
 
+// BulkAds godoc
+//
+//	@Summary		Apply operations to many ads
+//	@Description	Every operation gets its own result. With atomic set either all operations are applied or none.
+//	@Tags			ads
+//	@Accept			json
+//	@Produce		json
+//	@Param			request			body		bulkAdsRequest	true	"Operations of one author"
+//	@Param			Idempotency-Key	header		string			false	"Replays the first response to retries"
+//	@Success		200				{object}	bulkAdsResponse
+//	@Failure		400				{object}	problem
+//	@Failure		404				{object}	problem
+//	@Router			/ads/bulk [post]
+func BulkAds(a app.App) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		body, err := c.GetRawData()
+		if err != nil {
+			badRequest(c, "can't read request body")
+			return
+		}
+		var data bulkAdsRequest
+		err = json.Unmarshal(body, &data)
+		if err != nil {
+			badRequest(c, "malformed request body: %s", err.Error())
+			return
+		}
+		ops := make([]app.BulkOp, len(data.Operations))
+		for i, op := range data.Operations {
+			ops[i] = app.BulkOp{Action: op.Action, AdID: op.AdID, Title: op.Title, Text: op.Text}
+		}
+		results, err := a.BulkAds(c.Request.Context(), data.UserID, ops, data.Atomic)
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, newBulkAdsResponse(results))
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// DeleteAdByID godoc
+//
+//	@Summary	Delete ad
+//	@Tags		ads
+//	@Produce	json
+//	@Param		id		path		int	true	"Ad ID"
+//	@Param		author	query		int	true	"Author ID"
+//	@Success	200		{object}	adResponse
+//	@Failure	400		{object}	problem
+//	@Failure	403		{object}	problem
+//	@Failure	404		{object}	problem
+//	@Router		/ads/{id} [delete]
 func DeleteAdByID(a app.App) gin.HandlerFunc {
 	fn := func(c *gin.Context) {
 		id, err := strconv.Atoi(c.Param("id"))
 		if err != nil {
-			c.Status(http.StatusBadRequest)
+			badRequest(c, "invalid id %q", c.Param("id"))
 			return
 		}
 		author := c.Query("author")
 		if author == "" {
-			c.Status(http.StatusBadRequest)
+			badRequest(c, "query parameter author is required")
 			return
 		}
 		authorID, err := strconv.Atoi(author)
 		if err != nil {
-			c.Status(http.StatusBadRequest)
+			badRequest(c, "invalid author %q", author)
 			return
 		}
-		ad, err := a.DeleteAd(int64(id), int64(authorID))
+		ad, err := a.DeleteAd(c.Request.Context(), int64(id), int64(authorID))
 		if err != nil {
-			if err == app.ErrForbidden {
-				c.Status(http.StatusForbidden)
-			} else if err == app.ErrBadRequest {
-				c.Status(http.StatusBadRequest)
-			} else {
-				c.Status(http.StatusNotFound)
+			writeError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, adResponse{*ad})
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// exportFlushEvery is the number of ads written between flushes of an
+// export, so clients receive it while it is written.
+const exportFlushEvery = 100
+
+// ExportAds godoc
+//
+//	@Summary		Export ads
+//	@Description	Streams the ads as CSV or JSON Lines. Filters are applied in the order of the parameters, without filters only published ads are exported.
+//	@Tags			ads
+//	@Produce		text/csv,application/x-ndjson
+//	@Param			format			query		string	false	"csv or ndjson"	default(csv)
+//	@Param			author_id		query		int		false	"Ads of the author"
+//	@Param			created_after	query		string	false	"Ads created after the RFC 3339 time"
+//	@Param			all				query		bool	false	"Published and unpublished ads"
+//	@Param			title			query		string	false	"Ads with the substring in the title"
+//	@Success		200				{string}	string	"CSV or JSON Lines"
+//	@Failure		400				{object}	problem
+//	@Failure		404				{object}	problem
+//	@Router			/ads/export [get]
+func ExportAds(a app.App) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		format, err := catalog.ParseFormat(c.DefaultQuery("format", string(catalog.FormatCSV)))
+		if err != nil {
+			badRequest(c, "%s", err.Error())
+			return
+		}
+		ctx := c.Request.Context()
+		var arr []ads.Ad
+		if v, ok := c.GetQuery("author_id"); ok {
+			id, perr := strconv.ParseInt(v, 10, 64)
+			if perr != nil {
+				badRequest(c, "invalid author_id %q", v)
+				return
+			}
+			arr, err = a.SelectByAuthor(ctx, id)
+		} else if v, ok := c.GetQuery("created_after"); ok {
+			t, perr := time.Parse(time.RFC3339, v)
+			if perr != nil {
+				badRequest(c, "invalid created_after %q", v)
+				return
 			}
+			arr, err = a.SelectByCreation(ctx, t)
+		} else if c.Query("all") == "true" {
+			arr, err = a.SelectAll(ctx)
+		} else if v, ok := c.GetQuery("title"); ok {
+			arr, err = a.FindByTitle(ctx, v)
+		} else {
+			arr, err = a.Select(ctx)
+		}
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+
+		w, err := catalog.NewWriter(c.Writer, format)
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		c.Header("Content-Type", format.ContentType())
+		c.Header("Content-Disposition", `attachment; filename="ads.`+string(format)+`"`)
+		c.Status(http.StatusOK)
+		for i, ad := range arr {
+			if err := w.Write(ad); err != nil {
+				return
+			}
+			if (i+1)%exportFlushEvery == 0 {
+				if w.Flush() != nil {
+					return
+				}
+				c.Writer.Flush()
+			}
+		}
+		_ = w.Flush()
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// ImportAds godoc
+//
+//	@Summary		Import ads
+//	@Description	Creates an ad per row of a CSV or JSON Lines file. CSV files need a header with title and text columns. Rows are checked like single ads, failed rows are reported by line and don't stop the import. The form fields must precede the file.
+//	@Tags			ads
+//	@Accept			mpfd
+//	@Produce		json
+//	@Param			user_id	formData	int		true	"Author of the ads"
+//	@Param			dry_run	formData	bool	false	"Only check the rows"
+//	@Param			format	formData	string	false	"csv or ndjson, by default taken from the file extension"
+//	@Param			file	formData	file	true	"Ads"
+//	@Success		200		{object}	importResponse
+//	@Failure		400		{object}	problem
+//	@Failure		404		{object}	problem
+//	@Router			/ads/import [post]
+func ImportAds(a app.App) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		mr, err := c.Request.MultipartReader()
+		if err != nil {
+			badRequest(c, "multipart/form-data body is required")
+			return
+		}
+		var (
+			userID  int64
+			hasUser bool
+			dryRun  bool
+			format  string
+		)
+		for {
+			part, err := mr.NextPart()
+			if errors.Is(err, io.EOF) {
+				badRequest(c, "file is required")
+				return
+			}
+			if err != nil {
+				badRequest(c, "malformed multipart body: %s", err.Error())
+				return
+			}
+			if part.FormName() != "file" {
+				v, err := io.ReadAll(io.LimitReader(part, 1024))
+				if err != nil {
+					badRequest(c, "malformed multipart body: %s", err.Error())
+					return
+				}
+				switch part.FormName() {
+				case "user_id":
+					userID, err = strconv.ParseInt(string(v), 10, 64)
+					hasUser = err == nil
+				case "dry_run":
+					dryRun, err = strconv.ParseBool(string(v))
+				case "format":
+					format = string(v)
+				}
+				if err != nil {
+					badRequest(c, "invalid %s %q", part.FormName(), v)
+					return
+				}
+				continue
+			}
+
+			if !hasUser {
+				badRequest(c, "user_id must precede the file")
+				return
+			}
+			if format == "" {
+				format = path.Ext(part.FileName())
+			}
+			f, err := catalog.ParseFormat(format)
+			if err != nil {
+				badRequest(c, "%s", err.Error())
+				return
+			}
+			ctx := c.Request.Context()
+			im, err := catalog.NewImporter(ctx, a, userID, dryRun)
+			if err != nil {
+				writeError(c, err)
+				return
+			}
+			r, err := catalog.NewReader(part, f)
+			if err == nil {
+				err = im.ReadFrom(ctx, r)
+			}
+			if err != nil {
+				if ctx.Err() != nil {
+					writeError(c, err)
+				} else {
+					badRequest(c, "can't read file: %s", err.Error())
+				}
+				return
+			}
+			c.JSON(http.StatusOK, newImportResponse(im.Report()))
+			return
+		}
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// pathIDs parses the named path parameters as IDs. On failure it writes the
+// error and returns false.
+func pathIDs(c *gin.Context, names ...string) ([]int64, bool) {
+	ids := make([]int64, len(names))
+	for i, name := range names {
+		id, err := strconv.ParseInt(c.Param(name), 10, 64)
+		if err != nil {
+			badRequest(c, "invalid %s %q", name, c.Param(name))
+			return nil, false
+		}
+		ids[i] = id
+	}
+	return ids, true
+}
+
+// CreateWebhook godoc
+//
+//	@Summary		Subscribe to ad events
+//	@Description	The events of the ads of the user are POSTed to the URL, signed with HMAC-SHA256 of the secret in the X-Webhook-Signature header. Without a secret one is generated. The secret is shown only in this response.
+//	@Tags			webhooks
+//	@Accept			json
+//	@Produce		json
+//	@Param			id				path		int						true	"User ID"
+//	@Param			request			body		createWebhookRequest	true	"Subscription"
+//	@Param			Idempotency-Key	header		string					false	"Replays the first response to retries"
+//	@Success		200				{object}	createWebhookResponse
+//	@Failure		400				{object}	problem
+//	@Failure		404				{object}	problem
+//	@Router			/users/{id}/webhooks [post]
+func CreateWebhook(a app.App) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		ids, ok := pathIDs(c, "id")
+		if !ok {
+			return
+		}
+		body, err := c.GetRawData()
+		if err != nil {
+			badRequest(c, "can't read request body")
+			return
+		}
+		var data createWebhookRequest
+		err = json.Unmarshal(body, &data)
+		if err != nil {
+			badRequest(c, "malformed request body: %s", err.Error())
+			return
+		}
+		s, err := a.CreateWebhook(c.Request.Context(), ids[0], data.URL, data.Events, data.Secret)
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, createWebhookResponse{createdWebhook{*s, s.Secret}})
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// ListWebhooks godoc
+//
+//	@Summary	List webhooks of user
+//	@Tags		webhooks
+//	@Produce	json
+//	@Param		id	path		int	true	"User ID"
+//	@Success	200	{object}	webhooksResponse
+//	@Failure	400	{object}	problem
+//	@Failure	404	{object}	problem
+//	@Router		/users/{id}/webhooks [get]
+func ListWebhooks(a app.App) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		ids, ok := pathIDs(c, "id")
+		if !ok {
+			return
+		}
+		subs, err := a.ListWebhooks(c.Request.Context(), ids[0])
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, webhooksResponse{subs})
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// DeleteWebhook godoc
+//
+//	@Summary	Delete webhook
+//	@Tags		webhooks
+//	@Produce	json
+//	@Param		id			path		int	true	"User ID"
+//	@Param		webhook_id	path		int	true	"Webhook ID"
+//	@Success	200			{object}	webhookResponse
+//	@Failure	400			{object}	problem
+//	@Failure	403			{object}	problem
+//	@Failure	404			{object}	problem
+//	@Router		/users/{id}/webhooks/{webhook_id} [delete]
+func DeleteWebhook(a app.App) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		ids, ok := pathIDs(c, "id", "webhook_id")
+		if !ok {
+			return
+		}
+		s, err := a.DeleteWebhook(c.Request.Context(), ids[1], ids[0])
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, webhookResponse{*s})
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// ListDeliveries godoc
+//
+//	@Summary		List deliveries of webhook
+//	@Description	The delivery log, the oldest first. Only the latest deliveries are kept.
+//	@Tags			webhooks
+//	@Produce		json
+//	@Param			id			path		int	true	"User ID"
+//	@Param			webhook_id	path		int	true	"Webhook ID"
+//	@Success		200			{object}	deliveriesResponse
+//	@Failure		400			{object}	problem
+//	@Failure		403			{object}	problem
+//	@Failure		404			{object}	problem
+//	@Router			/users/{id}/webhooks/{webhook_id}/deliveries [get]
+func ListDeliveries(a app.App) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		ids, ok := pathIDs(c, "id", "webhook_id")
+		if !ok {
+			return
+		}
+		res, err := a.ListDeliveries(c.Request.Context(), ids[1], ids[0])
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, deliveriesResponse{res})
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// ReplayDelivery godoc
+//
+//	@Summary		Replay delivery
+//	@Description	Sends the event of a finished delivery again as a new delivery.
+//	@Tags			webhooks
+//	@Produce		json
+//	@Param			id			path		int	true	"User ID"
+//	@Param			webhook_id	path		int	true	"Webhook ID"
+//	@Param			delivery_id	path		int	true	"Delivery ID"
+//	@Success		200			{object}	deliveryResponse
+//	@Failure		400			{object}	problem
+//	@Failure		403			{object}	problem
+//	@Failure		404			{object}	problem
+//	@Failure		409			{object}	problem
+//	@Router			/users/{id}/webhooks/{webhook_id}/deliveries/{delivery_id}/replay [post]
+func ReplayDelivery(a app.App) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		ids, ok := pathIDs(c, "id", "webhook_id", "delivery_id")
+		if !ok {
+			return
+		}
+		d, err := a.ReplayDelivery(c.Request.Context(), ids[2], ids[1], ids[0])
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, deliveryResponse{*d})
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// SellerStats godoc
+//
+//	@Summary		Statistics of seller
+//	@Description	Ads by status, favorites and messages of the ads of the user and their views, hourly for the last 24 hours and daily for the last 30 days in UTC. Conversion is the share of the viewed ads that were unpublished since.
+//	@Tags			users
+//	@Produce		json
+//	@Param			id	path		int	true	"User ID"
+//	@Success		200	{object}	sellerStatsResponse
+//	@Failure		400	{object}	problem
+//	@Failure		404	{object}	problem
+//	@Router			/users/{id}/stats [get]
+func SellerStats(a app.App) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		ids, ok := pathIDs(c, "id")
+		if !ok {
+			return
+		}
+		stats, err := a.SellerStats(c.Request.Context(), ids[0])
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, newSellerStatsResponse(stats))
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// MostViewed godoc
+//
+//	@Summary		Most viewed ads today
+//	@Description	Answers 304 when the ETag in If-None-Match is still current.
+//	@Tags			ads
+//	@Produce		json
+//	@Param			limit			query		int		false	"Number of ads, from 1 to 100"	default(10)
+//	@Param			If-None-Match	header		string	false	"ETag of a stored response"
+//	@Success		200				{object}	viewedAdsResponse
+//	@Header			200				{string}	ETag	"Strong entity tag of the response"
+//	@Failure		400				{object}	problem
+//	@Success		304				"Not modified"
+//	@Router			/ads/most-viewed [get]
+func MostViewed(a app.App) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+		if err != nil {
+			badRequest(c, "invalid limit %q", c.Query("limit"))
+			return
+		}
+		res, err := a.MostViewed(c.Request.Context(), limit)
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		// views change the result without changing the ads
+		writeCacheable(c, time.Time{}, newViewedAdsResponse(res))
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// SimilarAds godoc
+//
+//	@Summary		Similar ads
+//	@Description	Published ads with similar title and text terms, category and price, the most similar first. The ads of the viewer are skipped. Answers 304 when the ETag in If-None-Match or the time in If-Modified-Since is still current.
+//	@Tags			ads
+//	@Produce		json
+//	@Param			id					path		int		true	"Ad ID"
+//	@Param			viewer_id			query		int		false	"User viewing the ad"
+//	@Param			limit				query		int		false	"Number of ads, from 1 to 50"	default(10)
+//	@Param			If-None-Match		header		string	false	"ETag of a stored response"
+//	@Param			If-Modified-Since	header		string	false	"Last-Modified of a stored response"
+//	@Success		200					{object}	similarAdsResponse
+//	@Header			200					{string}	ETag			"Strong entity tag of the response"
+//	@Header			200					{string}	Last-Modified	"Time of the last change of any ad"
+//	@Failure		400					{object}	problem
+//	@Failure		404					{object}	problem
+//	@Success		304					"Not modified"
+//	@Router			/ads/{id}/similar [get]
+func SimilarAds(a app.App) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		ids, ok := pathIDs(c, "id")
+		if !ok {
+			return
+		}
+		viewerID := int64(-1)
+		if v := c.Query("viewer_id"); v != "" {
+			id, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || id < 0 {
+				badRequest(c, "invalid viewer_id %q", v)
+				return
+			}
+			viewerID = id
+		}
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+		if err != nil {
+			badRequest(c, "invalid limit %q", c.Query("limit"))
+			return
+		}
+		modified := a.AdsModified(c.Request.Context())
+		res, err := a.SimilarAds(c.Request.Context(), ids[0], viewerID, limit)
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		writeCacheable(c, modified, newSimilarAdsResponse(res))
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// FavoriteAd godoc
+//
+//	@Summary		Add ad to favorites
+//	@Description	Adding a favorite again changes nothing. The ad must be published, the author can't favorite it.
+//	@Tags			ads
+//	@Produce		json
+//	@Param			id		path		int	true	"Ad ID"
+//	@Param			user_id	path		int	true	"User ID"
+//	@Success		200		{object}	adResponse
+//	@Failure		400		{object}	problem
+//	@Failure		403		{object}	problem
+//	@Failure		404		{object}	problem
+//	@Failure		409		{object}	problem
+//	@Router			/ads/{id}/favorites/{user_id} [put]
+func FavoriteAd(a app.App) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		ids, ok := pathIDs(c, "id", "user_id")
+		if !ok {
+			return
+		}
+		ad, err := a.FavoriteAd(c.Request.Context(), ids[0], ids[1])
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, adResponse{*ad})
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// UnfavoriteAd godoc
+//
+//	@Summary	Remove ad from favorites
+//	@Tags		ads
+//	@Produce	json
+//	@Param		id		path		int	true	"Ad ID"
+//	@Param		user_id	path		int	true	"User ID"
+//	@Success	200		{object}	adResponse
+//	@Failure	400		{object}	problem
+//	@Failure	404		{object}	problem
+//	@Router		/ads/{id}/favorites/{user_id} [delete]
+func UnfavoriteAd(a app.App) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		ids, ok := pathIDs(c, "id", "user_id")
+		if !ok {
+			return
+		}
+		ad, err := a.UnfavoriteAd(c.Request.Context(), ids[0], ids[1])
+		if err != nil {
+			writeError(c, err)
 			return
 		}
 		c.JSON(http.StatusOK, adResponse{*ad})
 	}
 	return gin.HandlerFunc(fn)
 }
+
+// SendMessage godoc
+//
+//	@Summary		Send message to author of ad
+//	@Description	The ad must be published, the author can't message it.
+//	@Tags			ads
+//	@Accept			json
+//	@Produce		json
+//	@Param			id				path		int					true	"Ad ID"
+//	@Param			request			body		sendMessageRequest	true	"Sender and text"
+//	@Param			Idempotency-Key	header		string				false	"Replays the first response to retries"
+//	@Success		200				{object}	messageResponse
+//	@Failure		400				{object}	problem
+//	@Failure		403				{object}	problem
+//	@Failure		404				{object}	problem
+//	@Failure		409				{object}	problem
+//	@Router			/ads/{id}/messages [post]
+func SendMessage(a app.App) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		ids, ok := pathIDs(c, "id")
+		if !ok {
+			return
+		}
+		body, err := c.GetRawData()
+		if err != nil {
+			badRequest(c, "can't read request body")
+			return
+		}
+		var data sendMessageRequest
+		err = json.Unmarshal(body, &data)
+		if err != nil {
+			badRequest(c, "malformed request body: %s", err.Error())
+			return
+		}
+		m, err := a.SendMessage(c.Request.Context(), ids[0], data.UserID, data.Text)
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, messageResponse{*m})
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// ListMessages godoc
+//
+//	@Summary		List messages about ad
+//	@Description	The messages sent to the author, the oldest first. Only the latest messages are kept.
+//	@Tags			ads
+//	@Produce		json
+//	@Param			id		path		int	true	"Ad ID"
+//	@Param			author	query		int	true	"Author ID"
+//	@Success		200		{object}	messagesResponse
+//	@Failure		400		{object}	problem
+//	@Failure		403		{object}	problem
+//	@Failure		404		{object}	problem
+//	@Router			/ads/{id}/messages [get]
+func ListMessages(a app.App) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		ids, ok := pathIDs(c, "id")
+		if !ok {
+			return
+		}
+		author := c.Query("author")
+		if author == "" {
+			badRequest(c, "query parameter author is required")
+			return
+		}
+		authorID, err := strconv.ParseInt(author, 10, 64)
+		if err != nil {
+			badRequest(c, "invalid author %q", author)
+			return
+		}
+		res, err := a.ListMessages(c.Request.Context(), ids[0], authorID)
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, messagesResponse{res})
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// SearchNearby godoc
+//
+//	@Summary		Search ads by location
+//	@Description	Finds the located ads within radius_km of lat and lon, or in the box of min_lat, min_lon, max_lat and max_lon. A box with min_lon greater than max_lon crosses the antimeridian. Only published ads are found unless all is true. Answers 304 when the ETag in If-None-Match or the time in If-Modified-Since is still current.
+//	@Tags			ads
+//	@Produce		json
+//	@Param			lat					query		number	false	"Latitude of the center"
+//	@Param			lon					query		number	false	"Longitude of the center"
+//	@Param			radius_km			query		number	false	"Radius, up to 1000 km"
+//	@Param			min_lat				query		number	false	"South edge of the box"
+//	@Param			min_lon				query		number	false	"West edge of the box"
+//	@Param			max_lat				query		number	false	"North edge of the box"
+//	@Param			max_lon				query		number	false	"East edge of the box"
+//	@Param			author_id			query		int		false	"Author of the ads"
+//	@Param			created_after		query		string	false	"Creation time, RFC 3339"
+//	@Param			title				query		string	false	"Part of the title"
+//	@Param			all					query		bool	false	"Find unpublished ads too"
+//	@Param			sort				query		string	false	"Order, by ID when omitted"		Enums(distance)
+//	@Param			limit				query		int		false	"Number of ads, from 1 to 1000"	default(100)
+//	@Param			If-None-Match		header		string	false	"ETag of a stored response"
+//	@Param			If-Modified-Since	header		string	false	"Last-Modified of a stored response"
+//	@Success		200					{object}	nearbyAdsResponse
+//	@Header			200					{string}	ETag			"Strong entity tag of the response"
+//	@Header			200					{string}	Last-Modified	"Time of the last change of any ad"
+//	@Failure		400					{object}	problem
+//	@Failure		404					{object}	problem
+//	@Success		304					"Not modified"
+//	@Router			/ads/nearby [get]
+func SearchNearby(a app.App) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		var failed bool
+		// float reads an optional number, has reports whether it was given
+		float := func(name string) (v float64, has bool) {
+			s, ok := c.GetQuery(name)
+			if !ok || failed {
+				return 0, false
+			}
+			v, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				badRequest(c, "invalid %s %q", name, s)
+				failed = true
+			}
+			return v, true
+		}
+		lat, hasLat := float("lat")
+		lon, hasLon := float("lon")
+		radius, hasRadius := float("radius_km")
+		minLat, hasMinLat := float("min_lat")
+		minLon, hasMinLon := float("min_lon")
+		maxLat, hasMaxLat := float("max_lat")
+		maxLon, hasMaxLon := float("max_lon")
+		if failed {
+			return
+		}
+		if s := c.Query("sort"); s != "" && s != "distance" {
+			badRequest(c, "invalid sort %q", s)
+			return
+		}
+
+		q := app.NearbyQuery{Title: c.Query("title"), All: c.Query("all") == "true", SortByDistance: c.Query("sort") == "distance"}
+		if hasLat || hasLon || hasRadius {
+			q.Circle = &geo.Circle{Center: geo.Point{Lat: lat, Lon: lon}, Radius: radius}
+		}
+		if hasMinLat || hasMinLon || hasMaxLat || hasMaxLon {
+			q.Box = &geo.Box{MinLat: minLat, MinLon: minLon, MaxLat: maxLat, MaxLon: maxLon}
+		}
+		if s := c.Query("author_id"); s != "" {
+			id, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				badRequest(c, "invalid author_id %q", s)
+				return
+			}
+			q.AuthorID = &id
+		}
+		if s := c.Query("created_after"); s != "" {
+			t, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				badRequest(c, "invalid created_after %q", s)
+				return
+			}
+			q.CreatedAfter = t
+		}
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+		if err != nil {
+			badRequest(c, "invalid limit %q", c.Query("limit"))
+			return
+		}
+		q.Limit = limit
+
+		modified := a.AdsModified(c.Request.Context())
+		res, err := a.SearchNearby(c.Request.Context(), q)
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		writeCacheable(c, modified, newNearbyAdsResponse(res))
+	}
+	return gin.HandlerFunc(fn)
+}
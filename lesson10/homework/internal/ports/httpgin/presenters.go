@@ -2,14 +2,21 @@ package httpgin
 
 import (
 	"homework10/internal/ads"
+	"homework10/internal/analytics"
+	"homework10/internal/app"
+	"homework10/internal/catalog"
+	"homework10/internal/engagement"
 	"homework10/internal/users"
+	"homework10/internal/webhooks"
 	"time"
 )
 
 type createAdRequest struct {
-	Title  string `json:"title"`
-	Text   string `json:"text"`
-	UserID int64  `json:"user_id"`
+	Title    string        `json:"title"`
+	Text     string        `json:"text"`
+	UserID   int64         `json:"user_id"`
+	Location *ads.Location `json:"location,omitempty"`
+	Offer    *ads.Offer    `json:"offer,omitempty"`
 }
 
 type changeAdStatusRequest struct {
@@ -21,6 +28,9 @@ type updateAdRequest struct {
 	Title  string `json:"title"`
 	Text   string `json:"text"`
 	UserID int64  `json:"user_id"`
+	// Location and Offer are kept when omitted.
+	Location *ads.Location `json:"location,omitempty"`
+	Offer    *ads.Offer    `json:"offer,omitempty"`
 }
 
 type selectAdRequest struct {
@@ -31,6 +41,201 @@ type selectAdRequest struct {
 	All          bool      `json:"all"`
 }
 
+type bulkOperation struct {
+	Action app.BulkAction `json:"action" enums:"publish,unpublish,update,delete"`
+	AdID   int64          `json:"ad_id"`
+	Title  string         `json:"title,omitempty"`
+	Text   string         `json:"text,omitempty"`
+}
+
+type bulkAdsRequest struct {
+	UserID     int64           `json:"user_id"`
+	Atomic     bool            `json:"atomic"`
+	Operations []bulkOperation `json:"operations"`
+}
+
+type bulkError struct {
+	Code          app.Code             `json:"code" enums:"invalid_argument,not_found,permission_denied,unavailable,deadline_exceeded,canceled,conflict,unprocessable,unauthenticated,unimplemented,too_large,internal"`
+	Detail        string               `json:"detail"`
+	Retryable     bool                 `json:"retryable"`
+	InvalidParams []app.FieldViolation `json:"invalid_params,omitempty"`
+}
+
+type bulkResult struct {
+	AdID  int64      `json:"ad_id"`
+	Ad    *ads.Ad    `json:"ad,omitempty"`
+	Error *bulkError `json:"error,omitempty"`
+}
+
+type bulkAdsResponse struct {
+	Data []bulkResult `json:"data"`
+}
+
+func newBulkAdsResponse(results []app.BulkResult) bulkAdsResponse {
+	resp := bulkAdsResponse{Data: make([]bulkResult, len(results))}
+	for i, r := range results {
+		resp.Data[i] = bulkResult{AdID: r.AdID, Ad: r.Ad}
+		if r.Err != nil {
+			resp.Data[i].Error = newBulkError(r.Err)
+		}
+	}
+	return resp
+}
+
+type importError struct {
+	Line int `json:"line"`
+	bulkError
+}
+
+type importReport struct {
+	Created int           `json:"created"`
+	Failed  int           `json:"failed"`
+	DryRun  bool          `json:"dry_run"`
+	Errors  []importError `json:"errors"`
+}
+
+type importResponse struct {
+	Data importReport `json:"data"`
+}
+
+func newBulkError(e *app.Error) *bulkError {
+	return &bulkError{Code: e.Code, Detail: e.Error(), Retryable: e.Retryable, InvalidParams: e.Fields}
+}
+
+func newImportResponse(r catalog.Report) importResponse {
+	resp := importResponse{Data: importReport{Created: r.Created, Failed: r.Failed, DryRun: r.DryRun, Errors: make([]importError, len(r.Errors))}}
+	for i, e := range r.Errors {
+		resp.Data.Errors[i] = importError{Line: e.Line, bulkError: *newBulkError(e.Err)}
+	}
+	return resp
+}
+
+type createWebhookRequest struct {
+	URL    string               `json:"url"`
+	Events []webhooks.EventType `json:"events" enums:"ad.created,ad.published,ad.unpublished,ad.updated,ad.deleted"`
+	Secret string               `json:"secret,omitempty"`
+}
+
+// createdWebhook shows the secret, that is hidden in other responses.
+type createdWebhook struct {
+	webhooks.Subscription
+	Secret string `json:"secret"`
+}
+
+type createWebhookResponse struct {
+	Data createdWebhook `json:"data"`
+}
+
+type webhookResponse struct {
+	Data webhooks.Subscription `json:"data"`
+}
+
+type webhooksResponse struct {
+	Data []webhooks.Subscription `json:"data"`
+}
+
+type deliveryResponse struct {
+	Data webhooks.Delivery `json:"data"`
+}
+
+type deliveriesResponse struct {
+	Data []webhooks.Delivery `json:"data"`
+}
+
+type sellerStats struct {
+	UserID      int64                 `json:"user_id"`
+	Ads         int                   `json:"ads"`
+	Published   int                   `json:"published"`
+	Unpublished int                   `json:"unpublished"`
+	ViewedAds   int                   `json:"viewed_ads"`
+	Conversion  float64               `json:"conversion"`
+	Favorites   int64                 `json:"favorites"`
+	Messages    int64                 `json:"messages"`
+	Views       analytics.SellerViews `json:"views"`
+}
+
+type sellerStatsResponse struct {
+	Data sellerStats `json:"data"`
+}
+
+func newSellerStatsResponse(s *app.SellerStats) sellerStatsResponse {
+	return sellerStatsResponse{sellerStats{
+		UserID:      s.UserID,
+		Ads:         s.Ads,
+		Published:   s.Published,
+		Unpublished: s.Unpublished,
+		ViewedAds:   s.ViewedAds,
+		Conversion:  s.Conversion,
+		Favorites:   s.Favorites,
+		Messages:    s.Messages,
+		Views:       s.Views,
+	}}
+}
+
+type viewedAd struct {
+	ads.Ad
+	Views int64 `json:"views"`
+}
+
+type viewedAdsResponse struct {
+	Data []viewedAd `json:"data"`
+}
+
+func newViewedAdsResponse(res []app.ViewedAd) viewedAdsResponse {
+	resp := viewedAdsResponse{Data: make([]viewedAd, len(res))}
+	for i, v := range res {
+		resp.Data[i] = viewedAd{Ad: v.Ad, Views: v.Views}
+	}
+	return resp
+}
+
+type similarAd struct {
+	ads.Ad
+	Score float64 `json:"score"`
+}
+
+type similarAdsResponse struct {
+	Data []similarAd `json:"data"`
+}
+
+func newSimilarAdsResponse(res []app.SimilarAd) similarAdsResponse {
+	resp := similarAdsResponse{Data: make([]similarAd, len(res))}
+	for i, s := range res {
+		resp.Data[i] = similarAd{Ad: s.Ad, Score: s.Score}
+	}
+	return resp
+}
+
+type nearbyAd struct {
+	ads.Ad
+	Distance float64 `json:"distance_km"`
+}
+
+type nearbyAdsResponse struct {
+	Data []nearbyAd `json:"data"`
+}
+
+func newNearbyAdsResponse(res []app.NearbyAd) nearbyAdsResponse {
+	resp := nearbyAdsResponse{Data: make([]nearbyAd, len(res))}
+	for i, n := range res {
+		resp.Data[i] = nearbyAd{Ad: n.Ad, Distance: n.Distance}
+	}
+	return resp
+}
+
+type sendMessageRequest struct {
+	UserID int64  `json:"user_id"`
+	Text   string `json:"text"`
+}
+
+type messageResponse struct {
+	Data engagement.Message `json:"data"`
+}
+
+type messagesResponse struct {
+	Data []engagement.Message `json:"data"`
+}
+
 type createOrUpdateUser struct {
 	Nickname string `json:"nickname"`
 	Email    string `json:"email"`
@@ -1,48 +1,238 @@
 package httpgin
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"homework10/internal/app"
-	"log"
+	"homework10/internal/idempotency"
+	"homework10/internal/logging"
+	"homework10/internal/metrics"
+	"homework10/internal/tracing"
+	"io"
+	"log/slog"
 	"net/http"
 	"runtime"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
-func AppRouter(r *gin.RouterGroup, a app.App) {
-
-	r.Use(gin.CustomRecovery(CustomPanicRecover))
-	r.Use(CustomLogger)
+// AppRouter registers the v1 API. Every route must be documented with swag
+// annotations, run `go generate ./...` after changing them. Panics are
+// recovered by the engine, see PanicRecover. Responses are compressed, see
+// Compress. The middleware runs inside Compress and sees the uncompressed
+// responses, so Idempotency must be passed here rather than used on r.
+//
+//	@title			Ads API
+//	@version		1.0
+//	@description	Ads and users of the bulletin board.
+//	@servers.url	/api/v1
+func AppRouter(r *gin.RouterGroup, a app.App, middleware ...gin.HandlerFunc) {
+	r.Use(Compress(DefaultCompressMinSize))
+	r.Use(middleware...)
 
 	r.GET("/ads/:id", GetAdByID(a))
 	r.POST("/ads", CreateAd(a))
+	r.POST("/ads/bulk", BulkAds(a))
+	r.GET("/ads/export", ExportAds(a))
+	r.POST("/ads/import", ImportAds(a))
 	r.PUT("/ads/:id/status", ChangeAdStatus(a))
 	r.PUT("/ads/:id", UpdateAd(a))
 	r.GET("/ads", Select(a))
 	r.GET("/ads/title", FindAdByTitle(a))
+	r.GET("/ads/most-viewed", MostViewed(a))
+	r.GET("/ads/nearby", SearchNearby(a))
+	r.GET("/ads/:id/similar", SimilarAds(a))
+	r.PUT("/ads/:id/favorites/:user_id", FavoriteAd(a))
+	r.DELETE("/ads/:id/favorites/:user_id", UnfavoriteAd(a))
+	r.POST("/ads/:id/messages", SendMessage(a))
+	r.GET("/ads/:id/messages", ListMessages(a))
 	r.DELETE("/ads/:id", DeleteAdByID(a))
 
 	r.POST("/users", CreateUser(a))
 	r.PUT("/users/:id", UpdateUser(a))
 	r.GET("/users/:id", GetUserByID(a))
 	r.DELETE("/users/:id", DeleteUserByID(a))
+	r.GET("/users/:id/stats", SellerStats(a))
+
+	r.POST("/users/:id/webhooks", CreateWebhook(a))
+	r.GET("/users/:id/webhooks", ListWebhooks(a))
+	r.DELETE("/users/:id/webhooks/:webhook_id", DeleteWebhook(a))
+	r.GET("/users/:id/webhooks/:webhook_id/deliveries", ListDeliveries(a))
+	r.POST("/users/:id/webhooks/:webhook_id/deliveries/:delivery_id/replay", ReplayDelivery(a))
 
 }
 
-func CustomLogger(c *gin.Context) {
-	t := time.Now().UTC()
+// RequestID takes the request ID from the X-Request-ID header or generates
+// one, returns it in the response and puts it into the request context.
+func RequestID(c *gin.Context) {
+	id := c.GetHeader(logging.RequestIDHeader)
+	if id == "" {
+		id = logging.NewRequestID()
+	}
+	c.Header(logging.RequestIDHeader, id)
+	c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
 	c.Next()
-	latency := time.Since(t)
-	status := c.Writer.Status()
+}
+
+// Tracing starts a server span per request. It continues the trace given in
+// the traceparent header, if any.
+func Tracing(tp trace.TracerProvider) gin.HandlerFunc {
+	tracer := tp.Tracer("homework10/internal/ports/httpgin")
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		ctx := tracing.Propagator.Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		ctx, span := tracer.Start(ctx, c.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.method", c.Request.Method),
+				attribute.String("http.route", route),
+				attribute.String("http.target", c.Request.URL.Path),
+			),
+		)
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+		status := c.Writer.Status()
+		span.SetAttributes(attribute.Int("http.status_code", status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
+
+// Logger writes an access log record per request. It must be installed after
+// RequestID.
+func Logger(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		t := time.Now()
+		c.Next()
+		logger.InfoContext(c.Request.Context(), "http request",
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"status", c.Writer.Status(),
+			"latency", time.Since(t),
+		)
+	}
+}
+
+// Metrics records the request count and latency per route. Requests that
+// match no route are recorded with the "unmatched" route.
+func Metrics(m *metrics.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		t := time.Now()
+		c.Next()
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		m.ObserveHTTP(c.Request.Method, route, c.Writer.Status(), time.Since(t))
+	}
+}
+
+// PanicRecover logs the panic with its stack and answers with 500.
+func PanicRecover(logger *slog.Logger) gin.RecoveryFunc {
+	return func(c *gin.Context, err any) {
+		buf := make([]byte, 2048)
+		n := runtime.Stack(buf, false)
+		logger.ErrorContext(c.Request.Context(), "panic recovered", "panic", err, "stack", string(buf[:n]))
+		writeError(c, fmt.Errorf("panic: %v", err))
+	}
+}
 
-	log.Println("latency", latency, "method", c.Request.Method, "path", c.Request.URL.Path, "status", status)
+// recordedResponse is a response stored for an idempotency key. The body
+// is stored uncompressed, a replay is compressed for the retried request.
+type recordedResponse struct {
+	status      int
+	contentType string
+	body        []byte
 }
 
-func CustomPanicRecover(c *gin.Context, err any) {
-	log.Println("panic: " + err.(error).Error())
-	buf := make([]byte, 2048)
-	n := runtime.Stack(buf, false)
-	log.Println(string(buf[:n]))
-	c.AbortWithStatusJSON(http.StatusInternalServerError, err.(error).Error())
+// responseRecorder keeps a copy of the body written by the handlers.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// IdempotencyMaxBody limits the body of a request with an Idempotency-Key,
+// which is buffered to be fingerprinted.
+const IdempotencyMaxBody = 1 << 20
+
+// Idempotency replays the first response to POST requests with the same
+// Idempotency-Key header, path and user_id of the body. A key sent with
+// another body is rejected with 422, a body over IdempotencyMaxBody with
+// 413. Multipart bodies, like the streamed imports, are not buffered and the
+// key is ignored for them. Responses with status 499 and 5xx are not stored,
+// so such requests are executed again on retry. s is not shared with the
+// grpc API, a key is replayed to retries over the API it was first sent to.
+func Idempotency(s *idempotency.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(idempotency.Header)
+		if key == "" || c.Request.Method != http.MethodPost || strings.HasPrefix(c.ContentType(), "multipart/") {
+			c.Next()
+			return
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, IdempotencyMaxBody))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(c, app.Errorf(app.CodeTooLarge, "request body with %s is larger than %d bytes", idempotency.Header, tooLarge.Limit))
+			return
+		}
+		if err != nil {
+			badRequest(c, "can't read request body")
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		var owner struct {
+			UserID json.Number `json:"user_id"`
+		}
+		_ = json.Unmarshal(body, &owner)
+		key = idempotency.Key(key, c.Request.URL.Path, owner.UserID.String())
+
+		stored, found, err := s.Begin(key, idempotency.Fingerprint(body))
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		if found {
+			r := stored.(recordedResponse)
+			c.Header(idempotency.ReplayedHeader, "true")
+			c.Data(r.status, r.contentType, r.body)
+			c.Abort()
+			return
+		}
+
+		rec := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = rec
+		defer s.Release(key)
+		c.Next()
+		if status := rec.Status(); status < http.StatusInternalServerError && status != statusClientClosedRequest {
+			s.Complete(key, recordedResponse{
+				status:      status,
+				contentType: rec.Header().Get("Content-Type"),
+				body:        rec.body.Bytes(),
+			})
+		}
+	}
 }
@@ -0,0 +1,206 @@
+package ports
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"homework10/internal/app"
+	"homework10/internal/certs"
+	"homework10/internal/config"
+	"homework10/internal/lifecycle"
+	"homework10/internal/webhooks"
+	"net"
+	"net/http"
+	"os"
+
+	grpc "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// Server is the http, grpc and metrics servers of the application together
+// with its telemetry.
+type Server struct {
+	HTTP    *http.Server
+	GRPC    *grpc.Server
+	Metrics *http.Server
+
+	httpLis    net.Listener
+	grpcLis    net.Listener
+	metricsLis net.Listener
+	signals    []os.Signal
+	hooks      *webhooks.Dispatcher
+	manager    *lifecycle.Manager
+}
+
+type ServerOption func(*Server)
+
+// WithHTTPListener serves the http API on lis instead of cfg.HTTP.Addr.
+func WithHTTPListener(lis net.Listener) ServerOption {
+	return func(s *Server) {
+		s.httpLis = lis
+	}
+}
+
+// WithGRPCListener serves the grpc API on lis instead of cfg.GRPC.Addr.
+func WithGRPCListener(lis net.Listener) ServerOption {
+	return func(s *Server) {
+		s.grpcLis = lis
+	}
+}
+
+// WithMetricsListener serves the metrics on lis instead of
+// cfg.Metrics.Addr.
+func WithMetricsListener(lis net.Listener) ServerOption {
+	return func(s *Server) {
+		s.metricsLis = lis
+	}
+}
+
+// WithSignals shuts the server down when one of signals arrives.
+func WithSignals(signals ...os.Signal) ServerOption {
+	return func(s *Server) {
+		s.signals = signals
+	}
+}
+
+// WithWebhooks closes hooks, the dispatcher of the app, after the servers
+// stop, so the events of the last requests are still delivered.
+func WithWebhooks(hooks *webhooks.Dispatcher) ServerOption {
+	return func(s *Server) {
+		s.hooks = hooks
+	}
+}
+
+// NewServer binds the addresses in cfg that are not replaced by listeners
+// in opts, ":0" binds a free port. The servers with cfg TLS enabled load
+// their certificates. tel should be the one a reports to, see NewApp, it is
+// shut down after the servers.
+func NewServer(cfg config.Config, a app.App, tel Telemetry, opts ...ServerOption) (*Server, error) {
+	s := &Server{}
+	for _, opt := range opts {
+		opt(s)
+	}
+	httpTLS, err := serverTLS("http", cfg.HTTP.TLS, tel)
+	if err != nil {
+		return nil, err
+	}
+	grpcTLS, err := serverTLS("grpc", cfg.GRPC.TLS, tel)
+	if err != nil {
+		return nil, err
+	}
+	metricsTLS, err := serverTLS("metrics", cfg.Metrics.TLS, tel)
+	if err != nil {
+		return nil, err
+	}
+	if s.HTTP, err = NewHTTPServer(cfg, a, tel); err != nil {
+		return nil, fmt.Errorf("http server: %w", err)
+	}
+
+	bind := []struct {
+		name string
+		addr string
+		lis  *net.Listener
+	}{
+		{"grpc", cfg.GRPC.Addr, &s.grpcLis},
+		{"http", cfg.HTTP.Addr, &s.httpLis},
+		{"metrics", cfg.Metrics.Addr, &s.metricsLis},
+	}
+	var bound []net.Listener
+	for _, b := range bind {
+		if *b.lis != nil {
+			continue
+		}
+		lis, err := net.Listen("tcp", b.addr)
+		if err != nil {
+			for _, l := range bound {
+				_ = l.Close()
+			}
+			return nil, fmt.Errorf("%s server: %w", b.name, err)
+		}
+		*b.lis = lis
+		bound = append(bound, lis)
+	}
+
+	var grpcOpts []grpc.ServerOption
+	if grpcTLS != nil {
+		grpcOpts = append(grpcOpts, grpc.Creds(credentials.NewTLS(grpcTLS)))
+	}
+	s.GRPC = NewGRPCServer(cfg, a, tel, grpcOpts...)
+	s.Metrics = NewMetricsServer(cfg, tel.Metrics)
+	httpLis, metricsLis := s.httpLis, s.metricsLis
+	if httpTLS != nil {
+		httpLis = tls.NewListener(httpLis, httpTLS)
+	}
+	if metricsTLS != nil {
+		metricsLis = tls.NewListener(metricsLis, metricsTLS)
+	}
+	s.manager = lifecycle.New(
+		lifecycle.WithLogger(tel.Logger),
+		lifecycle.WithShutdownTimeout(cfg.ShutdownTimeout),
+		lifecycle.WithDrain(cfg.DrainDelay),
+		lifecycle.WithReadiness(tel.Health.SetReady),
+		lifecycle.WithSignals(s.signals...),
+	)
+	s.manager.Add("telemetry", lifecycle.OnStop(tel.Shutdown))
+	if s.hooks != nil {
+		s.manager.Add("webhooks", lifecycle.OnStop(s.hooks.Close))
+	}
+	s.manager.Add("metrics", lifecycle.HTTPServer(s.Metrics, metricsLis))
+	s.manager.Add("grpc", lifecycle.GRPCServer(s.GRPC, s.grpcLis))
+	s.manager.Add("http", lifecycle.HTTPServer(s.HTTP, httpLis))
+	return s, nil
+}
+
+// serverTLS returns the TLS configuration of a server, nil if TLS is not
+// enabled.
+func serverTLS(name string, c config.TLSConfig, tel Telemetry) (*tls.Config, error) {
+	if !c.Enabled() {
+		return nil, nil
+	}
+	r, err := certs.NewReloader(c.CertFile, c.KeyFile, c.ClientCAFile, c.ReloadInterval, tel.Logger)
+	if err != nil {
+		return nil, fmt.Errorf("%s server: %w", name, err)
+	}
+	return r.ServerConfig(), nil
+}
+
+// HTTPAddr returns the bound address of the http API.
+func (s *Server) HTTPAddr() net.Addr {
+	return s.httpLis.Addr()
+}
+
+// GRPCAddr returns the bound address of the grpc API.
+func (s *Server) GRPCAddr() net.Addr {
+	return s.grpcLis.Addr()
+}
+
+// MetricsAddr returns the bound address of the metrics.
+func (s *Server) MetricsAddr() net.Addr {
+	return s.metricsLis.Addr()
+}
+
+// Run serves until ctx is done, Shutdown is called, a signal set by
+// WithSignals arrives or a server fails. Readiness is on while all servers
+// run. At the start of shutdown readiness is turned off and the servers keep
+// serving for cfg.DrainDelay, so load balancers can drain traffic. Then the
+// http, grpc and metrics servers and the telemetry are stopped in this
+// order, all within cfg.ShutdownTimeout. The errors are *lifecycle.Error
+// values.
+func (s *Server) Run(ctx context.Context) error {
+	return s.manager.Run(ctx)
+}
+
+// Ready is closed when the servers are started.
+func (s *Server) Ready() <-chan struct{} {
+	return s.manager.Ready()
+}
+
+// Done is closed when Run returns.
+func (s *Server) Done() <-chan struct{} {
+	return s.manager.Done()
+}
+
+// Shutdown makes Run stop the servers.
+func (s *Server) Shutdown() {
+	s.manager.Shutdown()
+}
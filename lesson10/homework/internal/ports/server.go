@@ -2,162 +2,449 @@ package ports
 
 import (
 	"context"
-	"errors"
+	"crypto/x509"
 	"fmt"
 	"homework10/internal/adapters/adrepo"
 	"homework10/internal/adapters/userrepo"
+	"homework10/internal/analytics"
 	"homework10/internal/app"
+	"homework10/internal/cache"
+	"homework10/internal/config"
+	"homework10/internal/engagement"
+	"homework10/internal/health"
+	"homework10/internal/idempotency"
+	"homework10/internal/logging"
+	"homework10/internal/metrics"
+	"homework10/internal/ports/admin"
 	grpc_func "homework10/internal/ports/grpc"
 	"homework10/internal/ports/httpgin"
+	"homework10/internal/ports/httpgin/docs"
+	"homework10/internal/revisions"
+	"homework10/internal/similar"
+	"homework10/internal/tracing"
+	"homework10/internal/webhooks"
 	"log"
-	"net"
+	"log/slog"
 	"net/http"
-	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	grpc_recovery "github.com/grpc-ecosystem/go-grpc-middleware/recovery"
-	"golang.org/x/sync/errgroup"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	grpc "google.golang.org/grpc"
 	codes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 	status "google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
-func NewHTTPServer(port string, a app.App) *http.Server {
-	gin.SetMode(gin.ReleaseMode)
+// NewHTTPServer creates the HTTP server of a. It fails when the gateway or
+// the admin console can't be registered, e.g. the staff file is missing.
+func NewHTTPServer(cfg config.Config, a app.App, tel Telemetry) (*http.Server, error) {
+	gin.SetMode(cfg.GinMode)
 	handler := gin.New()
-	api := handler.Group("/api/v1")
-	httpgin.AppRouter(api, a)
-	s := &http.Server{Addr: port, Handler: handler}
-	return s
+	// the client address counts views, it is only taken from the headers
+	// of known proxies
+	if err := handler.SetTrustedProxies(cfg.HTTP.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("http.trusted_proxies: %w", err)
+	}
+	handler.Use(
+		httpgin.RequestID,
+		httpgin.Tracing(tel.Tracer),
+		httpgin.Logger(tel.Logger),
+		httpgin.Metrics(tel.Metrics),
+		gin.CustomRecovery(httpgin.PanicRecover(tel.Logger)),
+	)
+	handler.GET("/healthz", gin.WrapF(tel.Health.Liveness))
+	handler.GET("/readyz", gin.WrapF(tel.Health.Readiness))
+	keys := idempotency.New(cfg.Idempotency.TTL)
+	httpgin.AppRouter(handler.Group("/api/v1"), a, httpgin.Idempotency(keys))
+	gateway, err := NewGatewayHandler(context.Background(), a)
+	if err != nil {
+		return nil, fmt.Errorf("register gateway: %w", err)
+	}
+	handler.Any("/api/v2/*path", httpgin.Idempotency(keys), gatewayViewer, gin.WrapH(gateway))
+	handler.GET("/api/docs/*path", gin.WrapH(http.StripPrefix("/api/docs", docs.Handler())))
+	if cfg.Admin.Enabled() {
+		if err := admin.Register(handler, a, cfg.Admin, tel.Logger); err != nil {
+			return nil, fmt.Errorf("register admin console: %w", err)
+		}
+	}
+	return &http.Server{Addr: cfg.HTTP.Addr, Handler: handler}, nil
 }
 
-func NewGRPCServer(port string, a app.App) *grpc.Server {
+// NewGRPCServer creates the grpc server of a. opts are appended to the
+// server options, e.g. the transport credentials.
+func NewGRPCServer(cfg config.Config, a app.App, tel Telemetry, opts ...grpc.ServerOption) *grpc.Server {
 	customFunc := func(p interface{}) (err error) {
-		return status.Errorf(codes.Unknown, "panic triggered: %v", p)
+		tel.Logger.Error("panic recovered", "panic", p)
+		return status.Errorf(codes.Internal, "panic triggered: %v", p)
 	}
-	opts := []grpc_recovery.Option{
+	recoveryOpts := []grpc_recovery.Option{
 		grpc_recovery.WithRecoveryHandler(customFunc),
 	}
+	interceptors := []grpc.UnaryServerInterceptor{TracingInterceptor(tel.Tracer)}
+	streamInterceptors := []grpc.StreamServerInterceptor{StreamTracingInterceptor(tel.Tracer)}
+	if cfg.GRPC.TLS.ClientCAFile != "" {
+		interceptors = append(interceptors, PrincipalInterceptor(tel.Logger, cfg.GRPC.TLS.Principals))
+		streamInterceptors = append(streamInterceptors, StreamPrincipalInterceptor(tel.Logger, cfg.GRPC.TLS.Principals))
+	}
+	interceptors = append(interceptors,
+		UnaryServerInterceptor(tel.Logger),
+		MetricsInterceptor(tel.Metrics),
+		IdempotencyInterceptor(idempotency.New(cfg.Idempotency.TTL)),
+		grpc_recovery.UnaryServerInterceptor(recoveryOpts...),
+	)
+	streamInterceptors = append(streamInterceptors,
+		StreamServerInterceptor(tel.Logger),
+		StreamMetricsInterceptor(tel.Metrics),
+		grpc_recovery.StreamServerInterceptor(recoveryOpts...),
+	)
 	service := &grpc_func.AdUserService{App: a}
-	server := grpc.NewServer(grpc.ChainUnaryInterceptor(UnaryServerInterceptor),
-		grpc.ChainUnaryInterceptor(grpc_recovery.UnaryServerInterceptor(opts...)))
+	opts = append(opts, grpc.ChainUnaryInterceptor(interceptors...), grpc.ChainStreamInterceptor(streamInterceptors...))
+	server := grpc.NewServer(opts...)
 	grpc_func.RegisterAdServiceServer(server, service)
+	healthpb.RegisterHealthServer(server, tel.Health.GRPC())
 	return server
 }
 
-func UnaryServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-	log.Println(time.Now().GoString() + ": " + info.FullMethod)
+// UnaryServerInterceptor takes the request ID from the x-request-id metadata
+// or generates one, returns it in the response header, puts it into the
+// context and logs the call.
+func UnaryServerInterceptor(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx = withRequestID(ctx)
+		t := time.Now()
+		resp, err := handler(ctx, req)
+		logCall(ctx, logger, info.FullMethod, err, t)
+		return resp, err
+	}
+}
 
-	return handler(ctx, req)
+// StreamServerInterceptor is UnaryServerInterceptor for streaming calls.
+func StreamServerInterceptor(logger *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := withRequestID(ss.Context())
+		t := time.Now()
+		err := handler(srv, withContext(ss, ctx))
+		logCall(ctx, logger, info.FullMethod, err, t)
+		return err
+	}
 }
 
-const (
-	grpcPort = ":50054"
-	httpPort = ":18080"
-)
+func withRequestID(ctx context.Context) context.Context {
+	key := strings.ToLower(logging.RequestIDHeader)
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(key); len(v) > 0 {
+			id = v[0]
+		}
+	}
+	if id == "" {
+		id = logging.NewRequestID()
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs(key, id))
+	return logging.WithRequestID(ctx, id)
+}
 
-func CreateServer(ctx context.Context, ch chan int) (*http.Server, *grpc.Server) {
-	a := app.NewApp(adrepo.New(), userrepo.New())
-	return CreateServerWithExternalApp(ctx, ch, a)
+func logCall(ctx context.Context, logger *slog.Logger, method string, err error, start time.Time) {
+	logger.InfoContext(ctx, "grpc request",
+		"method", method,
+		"code", status.Code(err).String(),
+		"latency", time.Since(start),
+	)
 }
 
-func CreateServerWithExternalApp(ctx context.Context, ch chan int, a app.App) (*http.Server, *grpc.Server) {
+// withContext returns ss with ctx as its context.
+func withContext(ss grpc.ServerStream, ctx context.Context) grpc.ServerStream {
+	w := grpc_middleware.WrapServerStream(ss)
+	w.WrappedContext = ctx
+	return w
+}
 
-	lis, err := net.Listen("tcp", grpcPort)
-	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
+// PrincipalInterceptor puts the name of the service calling into the
+// context, see config.TLSConfig.Principals. The name is taken from the
+// subject of the client certificate, which is verified during the
+// handshake. Calls without a certificate fail with Unauthenticated, calls
+// with an unknown subject with PermissionDenied.
+func PrincipalInterceptor(logger *slog.Logger, principals map[string]string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := withPrincipal(ctx, logger, principals, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
 	}
+}
 
-	httpServer := NewHTTPServer(httpPort, a)
-	grpcServer := NewGRPCServer(grpcPort, a)
+// StreamPrincipalInterceptor is PrincipalInterceptor for streaming calls,
+// it checks the certificate before the handler reads the stream.
+func StreamPrincipalInterceptor(logger *slog.Logger, principals map[string]string) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := withPrincipal(ss.Context(), logger, principals, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, withContext(ss, ctx))
+	}
+}
 
-	eg, ctx := errgroup.WithContext(ctx)
+func withPrincipal(ctx context.Context, logger *slog.Logger, principals map[string]string, method string) (context.Context, error) {
+	var certs []*x509.Certificate
+	if p, ok := peer.FromContext(ctx); ok {
+		if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			certs = tlsInfo.State.PeerCertificates
+		}
+	}
+	if len(certs) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "client certificate required")
+	}
+	subject := certs[0].Subject
+	name := subject.CommonName
+	if len(principals) > 0 {
+		name = principals[subject.String()]
+	}
+	if name == "" {
+		logger.WarnContext(ctx, "unknown client certificate", "method", method, "subject", subject.String())
+		return nil, status.Errorf(codes.PermissionDenied, "unknown client certificate subject %q", subject.String())
+	}
+	return logging.WithPrincipal(ctx, name), nil
+}
 
-	sigQuit := make(chan os.Signal, 1)
-	signal.Ignore(syscall.SIGHUP, syscall.SIGPIPE)
-	signal.Notify(sigQuit, syscall.SIGINT, syscall.SIGTERM)
+// MetricsInterceptor records the call count and latency per method.
+func MetricsInterceptor(m *metrics.Metrics) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		t := time.Now()
+		resp, err := handler(ctx, req)
+		m.ObserveGRPC(info.FullMethod, status.Code(err).String(), time.Since(t))
+		return resp, err
+	}
+}
 
-	go func() {
-		eg.Go(func() error {
-			select {
-			case s := <-sigQuit:
-				log.Printf("captured signal: %v\n", s)
-				return fmt.Errorf("captured signal: %v", s)
-			case <-ctx.Done():
-				return nil
-			}
-		})
+// StreamMetricsInterceptor records the count and duration of streaming
+// calls per method.
+func StreamMetricsInterceptor(m *metrics.Metrics) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		t := time.Now()
+		err := handler(srv, ss)
+		m.ObserveGRPC(info.FullMethod, status.Code(err).String(), time.Since(t))
+		return err
+	}
+}
 
-		// run grpc server
-		eg.Go(func() error {
-			log.Printf("starting grpc server, listening on %s\n", grpcPort)
-			defer log.Printf("close grpc server listening on %s\n", grpcPort)
+// storedCall is a result of a call stored for an idempotency key.
+type storedCall struct {
+	resp proto.Message
+	err  error
+}
 
-			errCh := make(chan error)
+// IdempotencyInterceptor replays the first result of calls with the same
+// idempotency-key metadata, method and user_id of the request. A key sent
+// with another request fails with FailedPrecondition. Results that can be
+// fixed by retrying, like Unavailable or DeadlineExceeded, are not stored.
+// The http API keeps its own store, see httpgin.Idempotency.
+func IdempotencyInterceptor(s *idempotency.Store) grpc.UnaryServerInterceptor {
+	mdKey := strings.ToLower(idempotency.Header)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		msg, ok := req.(proto.Message)
+		if len(md.Get(mdKey)) == 0 || !ok {
+			return handler(ctx, req)
+		}
+		payload, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+		if err != nil {
+			return handler(ctx, req)
+		}
+		var user string
+		if r, ok := req.(interface{ GetUserId() int64 }); ok {
+			user = strconv.FormatInt(r.GetUserId(), 10)
+		}
+		key := idempotency.Key(md.Get(mdKey)[0], info.FullMethod, user)
 
-			defer func() {
-				grpcServer.GracefulStop()
-				_ = lis.Close()
+		stored, found, err := s.Begin(key, idempotency.Fingerprint(payload))
+		if err != nil {
+			return nil, grpc_func.ToStatus(err)
+		}
+		if found {
+			call := stored.(storedCall)
+			_ = grpc.SetHeader(ctx, metadata.Pairs(strings.ToLower(idempotency.ReplayedHeader), "true"))
+			if call.err != nil {
+				return nil, call.err
+			}
+			return proto.Clone(call.resp), nil
+		}
 
-				close(errCh)
-			}()
+		defer s.Release(key)
+		resp, err := handler(ctx, req)
+		switch status.Code(err) {
+		case codes.Unknown, codes.Internal, codes.Unavailable, codes.DeadlineExceeded, codes.Canceled, codes.Aborted, codes.ResourceExhausted:
+			return resp, err
+		}
+		if m, ok := resp.(proto.Message); ok || err != nil {
+			s.Complete(key, storedCall{resp: m, err: err})
+		}
+		return resp, err
+	}
+}
 
-			go func() {
-				if err := grpcServer.Serve(lis); err != nil {
-					errCh <- err
-				}
-			}()
+// TracingInterceptor starts a server span per call. It continues the trace
+// given in the traceparent metadata, if any.
+func TracingInterceptor(tp trace.TracerProvider) grpc.UnaryServerInterceptor {
+	tracer := tp.Tracer("homework10/internal/ports")
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, span := startSpan(ctx, tracer, info.FullMethod)
+		defer span.End()
+		resp, err := handler(ctx, req)
+		endSpan(span, err)
+		return resp, err
+	}
+}
 
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			case err := <-errCh:
-				return fmt.Errorf("grpc server can't listen and serve requests: %w", err)
-			}
-		})
+// StreamTracingInterceptor is TracingInterceptor for streaming calls, the
+// span covers the whole stream.
+func StreamTracingInterceptor(tp trace.TracerProvider) grpc.StreamServerInterceptor {
+	tracer := tp.Tracer("homework10/internal/ports")
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, span := startSpan(ss.Context(), tracer, info.FullMethod)
+		defer span.End()
+		err := handler(srv, withContext(ss, ctx))
+		endSpan(span, err)
+		return err
+	}
+}
 
-		eg.Go(func() error {
-			log.Printf("starting http server, listening on %s\n", httpServer.Addr)
-			defer log.Printf("close http server listening on %s\n", httpServer.Addr)
+func startSpan(ctx context.Context, tracer trace.Tracer, method string) (context.Context, trace.Span) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	ctx = tracing.Propagator.Extract(ctx, tracing.MetadataCarrier(md))
+	return tracer.Start(ctx, strings.TrimPrefix(method, "/"),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("rpc.system", "grpc"),
+			attribute.String("rpc.method", method),
+		),
+	)
+}
 
-			errCh := make(chan error)
+func endSpan(span trace.Span, err error) {
+	code := status.Code(err)
+	span.SetAttributes(attribute.String("rpc.grpc.status_code", code.String()))
+	if code != codes.OK {
+		span.SetStatus(otelcodes.Error, code.String())
+	}
+}
 
-			defer func() {
-				shCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-				defer cancel()
+// NewMetricsServer serves the Prometheus metrics on its own address, so they
+// are not exposed together with the public API.
+func NewMetricsServer(cfg config.Config, m *metrics.Metrics) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m.Handler())
+	return &http.Server{Addr: cfg.Metrics.Addr, Handler: mux}
+}
 
-				if err := httpServer.Shutdown(shCtx); err != nil {
-					log.Printf("can't close http server listening on %s: %s", httpServer.Addr, err.Error())
-				}
+// NewApp creates the application on top of the repository backend selected
+// in cfg, bounding its operations by cfg.Timeouts. Lookups by ID are cached
+// as set in cfg.Cache. The events are published to hooks, nil disables the
+// webhooks, see NewWebhooks.
+func NewApp(cfg config.Config, tel Telemetry, hooks *webhooks.Dispatcher) (app.App, error) {
+	for op := range cfg.Timeouts.Operations {
+		if !app.IsOperation(op) {
+			return nil, fmt.Errorf("timeouts.operations: unknown operation %q", op)
+		}
+	}
+	switch cfg.Repository.Backend {
+	case config.BackendMemory:
+		ads := adrepo.New(adrepo.WithLockWait(tel.Metrics.LockWait("ads")))
+		usrs := userrepo.New(userrepo.WithLockWait(tel.Metrics.LockWait("users")))
+		registerChecker(tel.Health, "ads_repository", ads)
+		registerChecker(tel.Health, "users_repository", usrs)
+		opts := []app.Option{
+			app.WithLogger(tel.Logger),
+			app.WithMetrics(tel.Metrics),
+			app.WithTimeouts(cfg.Timeouts.Default, cfg.Timeouts.Operations),
+			app.WithViews(analytics.New(analytics.WithWindow(cfg.Analytics.ViewWindow))),
+			app.WithEngagement(engagement.New()),
+			app.WithSimilarities(similar.New()),
+			app.WithRevisions(revisions.New()),
+		}
+		if hooks != nil {
+			opts = append(opts, app.WithWebhooks(hooks))
+		}
+		var adRepo app.AdRepository = tracing.AdRepository(ads, tel.Tracer)
+		var userRepo app.UserRepository = tracing.UserRepository(usrs, tel.Tracer)
+		if cfg.Cache.Size > 0 {
+			adCache := cache.NewAdRepository(adRepo, cache.WithSize(cfg.Cache.Size), cache.WithTTL(cfg.Cache.TTL))
+			userCache := cache.NewUserRepository(userRepo, cache.WithSize(cfg.Cache.Size), cache.WithTTL(cfg.Cache.TTL))
+			tel.Metrics.RegisterCache("ads", func() metrics.CacheStats { return metrics.CacheStats(adCache.Stats()) })
+			tel.Metrics.RegisterCache("users", func() metrics.CacheStats { return metrics.CacheStats(userCache.Stats()) })
+			adRepo, userRepo = adCache, userCache
+		}
+		a := app.NewApp(adRepo, userRepo, opts...)
+		return tracing.App(a, tel.Tracer), nil
+	default:
+		return nil, fmt.Errorf("unknown repository backend %q", cfg.Repository.Backend)
+	}
+}
 
-				close(errCh)
-			}()
+// registerChecker adds the health check of a repository that implements
+// health.Checker.
+func registerChecker(h *health.Health, name string, repo any) {
+	if c, ok := repo.(health.Checker); ok {
+		h.Register(name, c)
+	}
+}
 
-			go func() {
-				if err := httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
-					errCh <- err
-				}
-			}()
+// CreateServer runs the servers with the default configuration, see
+// CreateServerWithExternalApp.
+//
+// Deprecated: use NewServer, which returns the errors instead of exiting.
+func CreateServer(ctx context.Context, ch chan int) (*http.Server, *grpc.Server) {
+	cfg := config.Default()
+	tel, err := NewTelemetry(ctx, cfg)
+	if err != nil {
+		log.Fatalf("failed to create telemetry: %v", err)
+	}
+	hooks := NewWebhooks(cfg, tel.Logger)
+	a, err := NewApp(cfg, tel, hooks)
+	if err != nil {
+		log.Fatalf("failed to create app: %v", err)
+	}
+	return runServer(ctx, ch, a, cfg, tel, WithWebhooks(hooks))
+}
 
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			case err := <-errCh:
-				return fmt.Errorf("http server can't listen and serve requests: %w", err)
-			}
-		})
+// CreateServerWithExternalApp runs the http, grpc and metrics servers until
+// ctx is done or a termination signal arrives, then sends to ch. The
+// listeners are bound and readiness is on when it returns.
+//
+// Deprecated: use NewServer, which returns the errors instead of exiting.
+func CreateServerWithExternalApp(ctx context.Context, ch chan int, a app.App, cfg config.Config, tel Telemetry) (*http.Server, *grpc.Server) {
+	return runServer(ctx, ch, a, cfg, tel)
+}
 
-		if err := eg.Wait(); err != nil {
-			log.Printf("gracefully shutting down the servers: %s\n", err.Error())
+func runServer(ctx context.Context, ch chan int, a app.App, cfg config.Config, tel Telemetry, opts ...ServerOption) (*http.Server, *grpc.Server) {
+	signal.Ignore(syscall.SIGHUP, syscall.SIGPIPE)
+	srv, err := NewServer(cfg, a, tel, append(opts, WithSignals(syscall.SIGINT, syscall.SIGTERM))...)
+	if err != nil {
+		log.Fatalf("failed to listen: %v", err)
+	}
+	go func() {
+		if err := srv.Run(ctx); err != nil {
+			tel.Logger.Error("server failed", "error", err)
 		}
-
-		log.Println("servers were successfully shutdown")
-
 		ch <- 0
 	}()
-	time.Sleep(time.Millisecond * 30)
-	return httpServer, grpcServer
+	select {
+	case <-srv.Ready():
+	case <-srv.Done():
+	}
+	return srv.HTTP, srv.GRPC
 }
@@ -0,0 +1,57 @@
+package ports
+
+import (
+	"context"
+	"homework10/internal/config"
+	"homework10/internal/health"
+	"homework10/internal/logging"
+	"homework10/internal/metrics"
+	"homework10/internal/tracing"
+	"homework10/internal/webhooks"
+	"log/slog"
+	"os"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Telemetry is shared by the application and the servers, so both report to
+// the same logger, metrics registry, tracer provider and health.
+type Telemetry struct {
+	Logger  *slog.Logger
+	Metrics *metrics.Metrics
+	Tracer  *sdktrace.TracerProvider
+	Health  *health.Health
+}
+
+// NewTelemetry creates the telemetry configured in cfg.
+func NewTelemetry(ctx context.Context, cfg config.Config) (Telemetry, error) {
+	tp, err := tracing.NewProvider(ctx, cfg.Tracing)
+	if err != nil {
+		return Telemetry{}, err
+	}
+	logger := logging.New(cfg, os.Stderr)
+	return Telemetry{
+		Logger:  logger,
+		Metrics: metrics.New(),
+		Tracer:  tp,
+		Health:  health.New(),
+	}, nil
+}
+
+// Shutdown flushes the spans that were not exported yet.
+func (t Telemetry) Shutdown(ctx context.Context) error {
+	return t.Tracer.Shutdown(ctx)
+}
+
+// NewWebhooks creates the dispatcher of the webhooks configured in cfg. It
+// is passed to NewApp, which publishes the events, and to NewServer, which
+// closes it after the servers stop.
+func NewWebhooks(cfg config.Config, logger *slog.Logger) *webhooks.Dispatcher {
+	return webhooks.New(
+		webhooks.WithWorkers(cfg.Webhooks.Workers),
+		webhooks.WithRetries(cfg.Webhooks.MaxAttempts, cfg.Webhooks.Backoff, cfg.Webhooks.MaxBackoff),
+		webhooks.WithTimeout(cfg.Webhooks.Timeout),
+		webhooks.WithPrivateAddresses(cfg.Webhooks.AllowPrivateAddresses),
+		webhooks.WithLogger(logger),
+	)
+}
@@ -0,0 +1,89 @@
+// Package revisions keeps the past versions of ads, so staff can see who
+// changed an ad and how. The history outlives the ad, a deleted ad keeps its
+// revisions. Only the latest revisions of every ad are kept.
+package revisions
+
+import (
+	"homework10/internal/ads"
+	"homework10/internal/webhooks"
+	"sync"
+	"time"
+)
+
+// DefaultLimit is the number of revisions kept per ad.
+const DefaultLimit = 50
+
+// Revision is the state of an ad after a change. Staff is the staff member
+// who made the change, empty when it was the author.
+type Revision struct {
+	Number int
+	Time   time.Time
+	Event  webhooks.EventType
+	Staff  string
+	Ad     ads.Ad
+}
+
+type history struct {
+	// next is the number of the next revision, older ones may be dropped
+	next int
+	revs []Revision
+}
+
+// Store is safe for concurrent use.
+type Store struct {
+	limit int
+
+	mtx sync.RWMutex
+	ads map[int64]*history
+}
+
+type Option func(*Store)
+
+// WithLimit sets the number of revisions kept per ad.
+func WithLimit(n int) Option {
+	return func(s *Store) {
+		s.limit = n
+	}
+}
+
+func New(opts ...Option) *Store {
+	s := &Store{limit: DefaultLimit, ads: map[int64]*history{}}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Record appends r to the history of its ad and numbers it, the first
+// revision of an ad is 1. The oldest revision is dropped over the limit.
+func (s *Store) Record(r Revision) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	h, ok := s.ads[r.Ad.ID]
+	if !ok {
+		h = &history{next: 1}
+		s.ads[r.Ad.ID] = h
+	}
+	r.Number = h.next
+	h.next++
+	h.revs = append(h.revs, r)
+	if len(h.revs) > s.limit {
+		h.revs = append(h.revs[:0], h.revs[len(h.revs)-s.limit:]...)
+	}
+}
+
+// History returns the kept revisions of an ad, the latest first, or nil
+// if it has none.
+func (s *Store) History(adID int64) []Revision {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	h, ok := s.ads[adID]
+	if !ok {
+		return nil
+	}
+	res := make([]Revision, len(h.revs))
+	for i, r := range h.revs {
+		res[len(res)-1-i] = r
+	}
+	return res
+}
@@ -0,0 +1,250 @@
+// Package similar finds ads related to a given one by the TF-IDF cosine of
+// their title and text terms, blended with the match of their categories
+// and the proximity of their prices. The index holds only published ads and
+// is updated on every change of an ad, so queries never scan the repository.
+package similar
+
+import (
+	"homework10/internal/ads"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+const (
+	// titleWeight is how many times a term of the title counts more than a
+	// term of the text.
+	titleWeight = 2
+	minTermLen  = 2
+
+	// textShare, categoryShare and priceShare are the parts of the score
+	// of an ad with an offer. Without an offer the score is the cosine of
+	// the terms alone.
+	textShare     = 0.6
+	categoryShare = 0.25
+	priceShare    = 0.15
+)
+
+// Match is an indexed ad with its similarity to the query, from 0 to 1.
+type Match struct {
+	AdID  int64
+	Score float64
+}
+
+type doc struct {
+	authorID int64
+	terms    map[string]int
+	offer    *ads.Offer
+}
+
+// Index is safe for concurrent use.
+type Index struct {
+	mtx  sync.RWMutex
+	docs map[int64]doc
+	// postings maps a term to the ads containing it, its length is the
+	// document frequency of the term
+	postings map[string]map[int64]int
+	// categories maps a category to the ads in it
+	categories map[string]map[int64]struct{}
+}
+
+func New() *Index {
+	return &Index{docs: map[int64]doc{}, postings: map[string]map[int64]int{}, categories: map[string]map[int64]struct{}{}}
+}
+
+// category returns the key of the category of o, empty without an offer.
+func category(o *ads.Offer) string {
+	if o == nil {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(o.Category))
+}
+
+// Terms splits s into lower case words of letters and digits. Words shorter
+// than two characters are dropped.
+func Terms(s string) []string {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	res := words[:0]
+	for _, w := range words {
+		if len([]rune(w)) >= minTermLen {
+			res = append(res, w)
+		}
+	}
+	return res
+}
+
+func termCounts(ad ads.Ad) map[string]int {
+	counts := map[string]int{}
+	for _, t := range Terms(ad.Title) {
+		counts[t] += titleWeight
+	}
+	for _, t := range Terms(ad.Text) {
+		counts[t]++
+	}
+	return counts
+}
+
+// Put indexes a published ad or removes an unpublished one.
+func (x *Index) Put(ad ads.Ad) {
+	x.mtx.Lock()
+	defer x.mtx.Unlock()
+	x.remove(ad.ID)
+	if !ad.Published {
+		return
+	}
+	d := doc{authorID: ad.AuthorID, terms: termCounts(ad), offer: ad.Offer}
+	for t, n := range d.terms {
+		p, ok := x.postings[t]
+		if !ok {
+			p = map[int64]int{}
+			x.postings[t] = p
+		}
+		p[ad.ID] = n
+	}
+	if c := category(d.offer); c != "" {
+		ids, ok := x.categories[c]
+		if !ok {
+			ids = map[int64]struct{}{}
+			x.categories[c] = ids
+		}
+		ids[ad.ID] = struct{}{}
+	}
+	x.docs[ad.ID] = d
+}
+
+// Remove drops an ad from the index.
+func (x *Index) Remove(adID int64) {
+	x.mtx.Lock()
+	defer x.mtx.Unlock()
+	x.remove(adID)
+}
+
+func (x *Index) remove(adID int64) {
+	d, ok := x.docs[adID]
+	if !ok {
+		return
+	}
+	for t := range d.terms {
+		delete(x.postings[t], adID)
+		if len(x.postings[t]) == 0 {
+			delete(x.postings, t)
+		}
+	}
+	if c := category(d.offer); c != "" {
+		delete(x.categories[c], adID)
+		if len(x.categories[c]) == 0 {
+			delete(x.categories, c)
+		}
+	}
+	delete(x.docs, adID)
+}
+
+// Len returns the number of indexed ads.
+func (x *Index) Len() int {
+	x.mtx.RLock()
+	defer x.mtx.RUnlock()
+	return len(x.docs)
+}
+
+// idf is smoothed, so a term of every ad still has a weight.
+func (x *Index) idf(t string) float64 {
+	n := float64(len(x.docs))
+	return math.Log((1+n)/(1+float64(len(x.postings[t])))) + 1
+}
+
+func weight(count int, idf float64) float64 {
+	return (1 + math.Log(float64(count))) * idf
+}
+
+// priceProximity is the ratio of the lower price to the higher one, 1 for
+// equal prices.
+func priceProximity(a, b int64) float64 {
+	if a == b {
+		return 1
+	}
+	return float64(min(a, b)) / float64(max(a, b))
+}
+
+// score blends the cosine of the terms of two ads with the match of their
+// offers q and o. Without q it is the cosine, an ad without o gets only the
+// share of the text.
+func score(cosine float64, q, o *ads.Offer) float64 {
+	if q == nil {
+		return cosine
+	}
+	s := textShare * cosine
+	if o != nil {
+		if category(q) == category(o) {
+			s += categoryShare
+		}
+		s += priceShare * priceProximity(q.Price, o.Price)
+	}
+	return s
+}
+
+// Similar returns up to limit indexed ads sharing terms or the category
+// with ad, the most similar first. The ad itself and the ads of viewerID
+// are skipped, a negative viewerID skips none.
+func (x *Index) Similar(ad ads.Ad, limit int, viewerID int64) []Match {
+	x.mtx.RLock()
+	defer x.mtx.RUnlock()
+
+	skip := func(id int64) bool {
+		return id == ad.ID || (viewerID >= 0 && x.docs[id].authorID == viewerID)
+	}
+	query := map[string]float64{}
+	var queryNorm float64
+	for t, n := range termCounts(ad) {
+		if _, ok := x.postings[t]; !ok {
+			continue
+		}
+		w := weight(n, x.idf(t))
+		query[t] = w
+		queryNorm += w * w
+	}
+
+	dots := map[int64]float64{}
+	for t, qw := range query {
+		idf := x.idf(t)
+		for id, n := range x.postings[t] {
+			if !skip(id) {
+				dots[id] += qw * weight(n, idf)
+			}
+		}
+	}
+	if c := category(ad.Offer); c != "" {
+		for id := range x.categories[c] {
+			if _, ok := dots[id]; !ok && !skip(id) {
+				dots[id] = 0
+			}
+		}
+	}
+
+	res := make([]Match, 0, len(dots))
+	for id, dot := range dots {
+		var cosine float64
+		if dot > 0 {
+			var norm float64
+			for t, n := range x.docs[id].terms {
+				w := weight(n, x.idf(t))
+				norm += w * w
+			}
+			cosine = dot / math.Sqrt(norm*queryNorm)
+		}
+		res = append(res, Match{AdID: id, Score: score(cosine, ad.Offer, x.docs[id].offer)})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Score != res[j].Score {
+			return res[i].Score > res[j].Score
+		}
+		return res[i].AdID < res[j].AdID
+	})
+	if len(res) > limit {
+		res = res[:limit]
+	}
+	return res
+}
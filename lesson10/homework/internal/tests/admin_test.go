@@ -0,0 +1,321 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"homework10/internal/adapters/adrepo"
+	"homework10/internal/adapters/userrepo"
+	"homework10/internal/ads"
+	"homework10/internal/app"
+	"homework10/internal/ports/admin"
+	"homework10/internal/revisions"
+	"homework10/internal/webhooks"
+	"homework10/pkg/adsclient"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var csrfInput = regexp.MustCompile(`name="csrf_token" value="([^"]+)"`)
+
+// browser is a client of the admin console that keeps cookies and doesn't
+// follow redirects.
+type browser struct {
+	t      *testing.T
+	base   string
+	client *http.Client
+}
+
+func newBrowser(t *testing.T, base string) *browser {
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+	return &browser{t: t, base: base, client: &http.Client{
+		Jar:           jar,
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}}
+}
+
+func (b *browser) do(req *http.Request) (*http.Response, string) {
+	resp, err := b.client.Do(req)
+	require.NoError(b.t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(b.t, err)
+	return resp, string(body)
+}
+
+func (b *browser) get(path string) (*http.Response, string) {
+	req, err := http.NewRequest(http.MethodGet, b.base+path, nil)
+	require.NoError(b.t, err)
+	return b.do(req)
+}
+
+func (b *browser) post(path string, form url.Values) (*http.Response, string) {
+	req, err := http.NewRequest(http.MethodPost, b.base+path, strings.NewReader(form.Encode()))
+	require.NoError(b.t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return b.do(req)
+}
+
+// csrf returns the CSRF token of the forms of page.
+func (b *browser) csrf(page string) string {
+	_, body := b.get(page)
+	m := csrfInput.FindStringSubmatch(body)
+	require.NotNil(b.t, m, "no CSRF token in %s", page)
+	return m[1]
+}
+
+func (b *browser) login(password string) *http.Response {
+	resp, _ := b.post("/admin/login", url.Values{
+		"login":      {"alice"},
+		"password":   {password},
+		"csrf_token": {b.csrf("/admin/login")},
+	})
+	return resp
+}
+
+// runAdmin starts a server with the console for the staff member alice
+// with the password "secret".
+func runAdmin(t *testing.T) (*adsclient.Client, *browser) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+	cfg := ephemeralConfig()
+	cfg.Admin.StaffFile = writeFile(t, filepath.Join(t.TempDir(), "staff"), []byte("# staff\nalice:"+string(hash)+"\n"))
+	srv := runServer(t, cfg)
+	return srv.api, newBrowser(t, srv.baseURL)
+}
+
+func TestAdminLogin(t *testing.T) {
+	t.Parallel()
+	_, b := runAdmin(t)
+
+	resp, _ := b.get("/admin/ads?title=bike")
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+	assert.Equal(t, "/admin/login?next=%2Fadmin%2Fads%3Ftitle%3Dbike", resp.Header.Get("Location"))
+
+	resp, body := b.get("/admin/login")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, body, `type="password"`)
+	assert.Equal(t, "DENY", resp.Header.Get("X-Frame-Options"))
+	assert.Contains(t, resp.Header.Get("Content-Security-Policy"), "frame-ancestors 'none'")
+	assert.Equal(t, "no-store", resp.Header.Get("Cache-Control"))
+	for _, c := range resp.Cookies() {
+		assert.True(t, c.HttpOnly)
+		assert.Equal(t, http.SameSiteStrictMode, c.SameSite)
+		assert.Equal(t, "/admin", c.Path)
+	}
+
+	resp = b.login("wrong")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// the login form needs its token too
+	resp, _ = b.post("/admin/login", url.Values{"login": {"alice"}, "password": {"secret"}})
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	resp, _ = b.post("/admin/login", url.Values{"login": {"alice"}, "password": {"secret"}, "csrf_token": {"forged"}})
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp = b.login("secret")
+	require.Equal(t, http.StatusSeeOther, resp.StatusCode)
+	assert.Equal(t, "/admin/ads", resp.Header.Get("Location"))
+	resp, body = b.get("/admin/ads")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, body, "alice")
+
+	// a forged session is refused
+	other := newBrowser(t, b.base)
+	u, err := url.Parse(b.base + "/admin/")
+	require.NoError(t, err)
+	for _, c := range b.client.Jar.Cookies(u) {
+		c.Value = strings.Replace(c.Value, ".", "x.", 1)
+		other.client.Jar.SetCookies(u, []*http.Cookie{c})
+	}
+	resp, _ = other.get("/admin/ads")
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+
+	// logging out needs the token of the session
+	resp, _ = b.post("/admin/logout", url.Values{})
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	resp, _ = b.post("/admin/logout", url.Values{"csrf_token": {b.csrf("/admin/ads")}})
+	assert.Equal(t, http.StatusSeeOther, resp.StatusCode)
+	resp, _ = b.get("/admin/ads")
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+}
+
+func TestAdminRedirectAfterLogin(t *testing.T) {
+	t.Parallel()
+	_, first := runAdmin(t)
+	for _, tc := range []struct{ next, want string }{
+		{"/admin/users?q=a", "/admin/users?q=a"},
+		{"https://evil.example.com/admin/", "/admin/ads"},
+		{"//evil.example.com", "/admin/ads"},
+	} {
+		b := newBrowser(t, first.base)
+		resp, body := b.get("/admin/login?next=" + url.QueryEscape(tc.next))
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		resp, _ = b.post("/admin/login", url.Values{
+			"login":      {"alice"},
+			"password":   {"secret"},
+			"next":       {tc.next},
+			"csrf_token": {csrfInput.FindStringSubmatch(body)[1]},
+		})
+		require.Equal(t, http.StatusSeeOther, resp.StatusCode)
+		assert.Equal(t, tc.want, resp.Header.Get("Location"))
+	}
+}
+
+func TestAdminModeration(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	api, b := runAdmin(t)
+	seller, err := api.CreateUser(ctx, "Seller", "seller@example.com")
+	require.NoError(t, err)
+	_, err = api.CreateUser(ctx, "buyer", "buyer@example.com")
+	require.NoError(t, err)
+	ad, err := api.CreateAd(ctx, adsclient.CreateAdRequest{UserID: seller.ID, Title: "bike <script>", Text: "red"})
+	require.NoError(t, err)
+	_, err = api.ChangeAdStatus(ctx, ad.ID, seller.ID, true)
+	require.NoError(t, err)
+	_, err = api.UpdateAd(ctx, ad.ID, adsclient.UpdateAdRequest{UserID: seller.ID, Title: "bike <script>", Text: "blue"})
+	require.NoError(t, err)
+	adPage := fmt.Sprintf("/admin/ads/%d", ad.ID)
+
+	require.Equal(t, http.StatusSeeOther, b.login("secret").StatusCode)
+
+	// search
+	_, body := b.get("/admin/ads?title=bike")
+	assert.Contains(t, body, "bike &lt;script&gt;")
+	assert.NotContains(t, body, "<script>")
+	_, body = b.get(fmt.Sprintf("/admin/ads?author=%d&title=car", seller.ID))
+	assert.Contains(t, body, "No ads found.")
+	resp, _ := b.get("/admin/ads?author=seller")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp, _ = b.get(fmt.Sprintf("/admin/ads?id=%d", ad.ID))
+	assert.Equal(t, adPage, resp.Header.Get("Location"))
+	_, body = b.get("/admin/users?q=SELLER")
+	assert.Contains(t, body, "seller@example.com")
+	assert.NotContains(t, body, "buyer@example.com")
+	_, body = b.get(fmt.Sprintf("/admin/users/%d", seller.ID))
+	assert.Contains(t, body, adPage)
+
+	// revisions
+	_, body = b.get(adPage)
+	assert.Contains(t, body, "ad.created")
+	assert.Contains(t, body, "ad.published")
+	assert.Contains(t, body, "ad.updated")
+	assert.Contains(t, body, "blue")
+
+	// unpublish, a form without the token is refused
+	resp, _ = b.post(adPage+"/unpublish", url.Values{})
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	got, err := api.GetAd(ctx, ad.ID)
+	require.NoError(t, err)
+	assert.True(t, got.Published)
+
+	resp, _ = b.post(adPage+"/unpublish", url.Values{"csrf_token": {b.csrf(adPage)}})
+	require.Equal(t, http.StatusSeeOther, resp.StatusCode)
+	assert.Equal(t, adPage+"?done=unpublished", resp.Header.Get("Location"))
+	got, err = api.GetAd(ctx, ad.ID)
+	require.NoError(t, err)
+	assert.False(t, got.Published)
+	_, body = b.get(adPage + "?done=unpublished")
+	assert.Contains(t, body, "The ad was unpublished.")
+	assert.Contains(t, body, "staff alice")
+
+	// delete
+	resp, _ = b.post(adPage+"/delete", url.Values{"csrf_token": {b.csrf(adPage)}})
+	require.Equal(t, http.StatusSeeOther, resp.StatusCode)
+	_, err = api.GetAd(ctx, ad.ID)
+	assert.ErrorIs(t, err, adsclient.ErrNotFound)
+	resp, body = b.get(adPage)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, body, "The ad is deleted")
+	assert.Contains(t, body, "ad.deleted")
+
+	resp, _ = b.post(adPage+"/delete", url.Values{"csrf_token": {b.csrf("/admin/ads")}})
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp, _ = b.get("/admin/ads/1000")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp, _ = b.get("/admin/ads/bike")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestAdminStaticFiles(t *testing.T) {
+	t.Parallel()
+	_, b := runAdmin(t)
+	resp, body := b.get("/admin/static/admin.css")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Content-Type"), "text/css")
+	assert.NotEmpty(t, body)
+}
+
+func TestAdminDisabled(t *testing.T) {
+	t.Parallel()
+	srv := runServer(t, ephemeralConfig())
+	resp, _ := newBrowser(t, srv.baseURL).get("/admin/login")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestLoadStaff(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+	dir := t.TempDir()
+	staff, err := admin.LoadStaff(writeFile(t, filepath.Join(dir, "ok"), []byte("\n# comment\nalice:"+string(hash)+"\nbob:"+string(hash))))
+	require.NoError(t, err)
+	assert.Len(t, staff, 2)
+
+	for name, content := range map[string]string{
+		"empty":     "# nobody\n",
+		"no hash":   "alice\n",
+		"plaintext": "alice:secret\n",
+		"duplicate": "alice:" + string(hash) + "\nalice:" + string(hash) + "\n",
+	} {
+		_, err := admin.LoadStaff(writeFile(t, filepath.Join(dir, name), []byte(content)))
+		assert.Error(t, err, name)
+	}
+	_, err = admin.LoadStaff(filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+}
+
+func TestRevisionsLimit(t *testing.T) {
+	s := revisions.New(revisions.WithLimit(3))
+	for i := 0; i < 5; i++ {
+		s.Record(revisions.Revision{Event: webhooks.AdUpdated, Ad: ads.Ad{ID: 1, Text: strconv.Itoa(i)}})
+	}
+	s.Record(revisions.Revision{Event: webhooks.AdCreated, Ad: ads.Ad{ID: 2}})
+
+	revs := s.History(1)
+	require.Len(t, revs, 3)
+	for i, r := range revs {
+		assert.Equal(t, 5-i, r.Number)
+		assert.Equal(t, strconv.Itoa(4-i), r.Ad.Text)
+	}
+	assert.Len(t, s.History(2), 1)
+	assert.Nil(t, s.History(3))
+}
+
+func TestAdRevisionsWithoutStore(t *testing.T) {
+	ctx := context.Background()
+	a := app.NewApp(adrepo.New(), userrepo.New())
+	usr, err := a.CreateUser(ctx, "seller", "seller@example.com")
+	require.NoError(t, err)
+	ad, err := a.CreateAd(ctx, "bike", "red", usr.ID, nil, nil)
+	require.NoError(t, err)
+
+	revs, err := a.AdRevisions(ctx, ad.ID)
+	require.NoError(t, err)
+	assert.Empty(t, revs)
+	_, err = a.AdRevisions(ctx, ad.ID+1)
+	assert.ErrorIs(t, err, app.ErrNotFound)
+	_, err = a.DeleteAdAsStaff(ctx, ad.ID, "")
+	assert.Error(t, err)
+}
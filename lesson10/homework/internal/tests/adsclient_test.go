@@ -0,0 +1,153 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"homework10/pkg/adsclient"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyServer answers the first failures requests with status and the
+// following ones with a user. It counts the requests and remembers the
+// last Idempotency-Key header.
+type flakyServer struct {
+	*httptest.Server
+	failures int64
+	status   int
+	requests atomic.Int64
+	key      atomic.Value
+}
+
+func newFlakyServer(t *testing.T, failures int64, status int) *flakyServer {
+	s := &flakyServer{failures: failures, status: status}
+	s.key.Store("")
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := s.requests.Add(1)
+		s.key.Store(r.Header.Get(adsclient.IdempotencyKeyHeader))
+		if n <= s.failures {
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(s.status)
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"title": http.StatusText(s.status), "status": s.status, "instance": r.URL.Path,
+				"code": "unavailable", "retryable": s.status == http.StatusServiceUnavailable,
+			})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"id": 1, "nickname": "Oleg", "path": r.URL.Path}})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *flakyServer) client(t *testing.T, opts ...adsclient.Option) *adsclient.Client {
+	opts = append([]adsclient.Option{adsclient.WithBackoff(time.Millisecond, 5*time.Millisecond)}, opts...)
+	c, err := adsclient.New(s.URL, opts...)
+	require.NoError(t, err)
+	return c
+}
+
+func TestClientRetriesIdempotentRequests(t *testing.T) {
+	ctx := context.Background()
+	srv := newFlakyServer(t, 2, http.StatusServiceUnavailable)
+	usr, err := srv.client(t).GetUser(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "Oleg", usr.Nickname)
+	assert.EqualValues(t, 3, srv.requests.Load())
+
+	srv = newFlakyServer(t, 10, http.StatusServiceUnavailable)
+	_, err = srv.client(t, adsclient.WithRetries(2)).GetUser(ctx, 1)
+	assert.ErrorIs(t, err, adsclient.ErrUnavailable)
+	assert.EqualValues(t, 3, srv.requests.Load())
+
+	// errors of the client are not retried
+	srv = newFlakyServer(t, 1, http.StatusNotFound)
+	_, err = srv.client(t).GetUser(ctx, 1)
+	var apiErr *adsclient.Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusNotFound, apiErr.Status)
+	assert.Equal(t, "/api/v1/users/1", apiErr.Instance)
+	assert.EqualValues(t, 1, srv.requests.Load())
+}
+
+func TestClientRetriesPostWithIdempotencyKey(t *testing.T) {
+	ctx := context.Background()
+	srv := newFlakyServer(t, 1, http.StatusServiceUnavailable)
+	_, err := srv.client(t).CreateUser(ctx, "Oleg", "")
+	assert.ErrorIs(t, err, adsclient.ErrUnavailable)
+	assert.EqualValues(t, 1, srv.requests.Load())
+	assert.Empty(t, srv.key.Load())
+
+	srv = newFlakyServer(t, 1, http.StatusServiceUnavailable)
+	usr, err := srv.client(t).CreateUser(adsclient.WithIdempotencyKey(ctx, "key-1"), "Oleg", "")
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, usr.ID)
+	assert.EqualValues(t, 2, srv.requests.Load())
+	assert.Equal(t, "key-1", srv.key.Load())
+
+	// the key is only sent with POST requests
+	_, err = srv.client(t).GetUser(adsclient.WithIdempotencyKey(ctx, "key-2"), 1)
+	require.NoError(t, err)
+	assert.Empty(t, srv.key.Load())
+}
+
+func TestClientRetryStopsWithContext(t *testing.T) {
+	srv := newFlakyServer(t, 10, http.StatusServiceUnavailable)
+	c := srv.client(t, adsclient.WithBackoff(time.Minute, time.Minute))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := c.GetUser(ctx, 1)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.EqualValues(t, 1, srv.requests.Load())
+}
+
+func TestClientErrorWithoutProblemDetails(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "upstream is down", http.StatusBadGateway)
+	}))
+	defer srv.Close()
+	c, err := adsclient.New(srv.URL, adsclient.WithRetries(0))
+	require.NoError(t, err)
+
+	_, err = c.ListAds(context.Background())
+	var apiErr *adsclient.Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusBadGateway, apiErr.Status)
+	assert.Equal(t, adsclient.CodeUnavailable, apiErr.Code)
+	assert.Equal(t, "Bad Gateway", apiErr.Title)
+	assert.Equal(t, "upstream is down", apiErr.Detail)
+}
+
+func TestClientBaseURL(t *testing.T) {
+	for _, u := range []string{"", "localhost:8080", "/api/v1", "ftp://localhost"} {
+		_, err := adsclient.New(u)
+		assert.Error(t, err, u)
+	}
+
+	var path string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		_, _ = w.Write([]byte(`{"data": {"id": 1}}`))
+	}))
+	defer srv.Close()
+	for base, want := range map[string]string{
+		srv.URL:                 "/api/v1/users/1",
+		srv.URL + "/":           "/api/v1/users/1",
+		srv.URL + "/ads/api/v1": "/ads/api/v1/users/1",
+	} {
+		c, err := adsclient.New(base)
+		require.NoError(t, err)
+		_, err = c.GetUser(context.Background(), 1)
+		require.NoError(t, err)
+		assert.Equal(t, want, path, base)
+	}
+}
@@ -0,0 +1,270 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"homework10/internal/analytics"
+	grpcPort "homework10/internal/ports/grpc"
+	"homework10/pkg/adsclient"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func TestTrackerDeduplicatesViews(t *testing.T) {
+	now := time.Date(2026, 3, 1, 10, 30, 0, 0, time.UTC)
+	tr := analytics.New(analytics.WithWindow(time.Hour), analytics.WithClock(func() time.Time { return now }))
+	anna := analytics.WithViewer(context.Background(), analytics.AddrViewer("10.0.0.2"))
+	guest := analytics.WithViewer(context.Background(), analytics.AddrViewer("10.0.0.1"))
+
+	assert.True(t, tr.View(anna, 1, 7))
+	assert.False(t, tr.View(anna, 1, 7))
+	assert.True(t, tr.View(guest, 1, 7))
+	assert.True(t, tr.View(anna, 2, 7))
+	// viewers without identity are not counted
+	assert.False(t, tr.View(context.Background(), 1, 7))
+
+	now = now.Add(time.Hour)
+	assert.True(t, tr.View(anna, 1, 7))
+
+	s := tr.Seller(7)
+	assert.Equal(t, int64(4), s.Total)
+	assert.Equal(t, int64(4), s.Today)
+	assert.Equal(t, []analytics.AdViews{{AdID: 1, Views: 3}, {AdID: 2, Views: 1}}, s.PerAd)
+	require.Len(t, s.Hourly, analytics.HourlyBuckets)
+	assert.Equal(t, analytics.Bucket{Start: time.Date(2026, 3, 1, 11, 0, 0, 0, time.UTC), Views: 1}, s.Hourly[analytics.HourlyBuckets-1])
+	assert.Equal(t, analytics.Bucket{Start: time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC), Views: 3}, s.Hourly[analytics.HourlyBuckets-2])
+	require.Len(t, s.Daily, analytics.DailyBuckets)
+	assert.Equal(t, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), s.Daily[analytics.DailyBuckets-1].Start)
+
+	// a day later the views are history
+	now = now.Add(24 * time.Hour)
+	s = tr.Seller(7)
+	assert.Equal(t, int64(4), s.Total)
+	assert.Zero(t, s.Today)
+	assert.Equal(t, int64(4), s.Daily[analytics.DailyBuckets-2].Views)
+	for _, b := range s.Hourly {
+		assert.Zero(t, b.Views)
+	}
+	assert.Empty(t, tr.Today())
+
+	tr.Forget(1)
+	assert.Equal(t, int64(1), tr.Seller(7).Total)
+}
+
+func TestSellerStatsAndMostViewed(t *testing.T) {
+	ctx := context.Background()
+	cfg := ephemeralConfig()
+	// the test client is a proxy of the viewers
+	cfg.HTTP.TrustedProxies = []string{"127.0.0.1", "::1"}
+	_, srv := startServer(t, cfg)
+	client := srv.api
+
+	seller, err := client.CreateUser(ctx, "Oleg", "")
+	require.NoError(t, err)
+	buyer, err := client.CreateUser(ctx, "Anna", "")
+	require.NoError(t, err)
+	sellerID := seller.ID
+
+	var ids []int64
+	for _, title := range []string{"bike", "car", "boat"} {
+		ad, err := client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: sellerID, Title: title, Text: "for sale"})
+		require.NoError(t, err)
+		_, err = client.ChangeAdStatus(ctx, ad.ID, sellerID, true)
+		require.NoError(t, err)
+		ids = append(ids, ad.ID)
+	}
+	bike, car, boat := ids[0], ids[1], ids[2]
+
+	view := func(ID int64, query string, addr string) {
+		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/api/v1/ads/%d%s", srv.baseURL, ID, query), nil)
+		require.NoError(t, err)
+		req.Header.Set("X-Forwarded-For", addr)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+	view(bike, "", "10.0.0.1")
+	view(bike, "", "10.0.0.1") // same address, not counted
+	// the user ID is not trusted, not counted
+	view(bike, fmt.Sprintf("?viewer_id=%d", buyer.ID), "10.0.0.1")
+	view(bike, "", "10.0.0.2")
+	view(car, "", "10.0.0.1")
+	view(boat, "", "10.0.0.2")
+
+	// the boat was sold
+	_, err = client.ChangeAdStatus(ctx, boat, sellerID, false)
+	require.NoError(t, err)
+
+	stats, err := client.UserStats(ctx, sellerID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, stats.Ads)
+	assert.Equal(t, 2, stats.Published)
+	assert.Equal(t, 1, stats.Unpublished)
+	assert.Equal(t, 3, stats.ViewedAds)
+	assert.InDelta(t, 1.0/3, stats.Conversion, 1e-9)
+	assert.Equal(t, int64(4), stats.Views.Total)
+	assert.Equal(t, int64(4), stats.Views.Today)
+	assert.Len(t, stats.Views.Hourly, analytics.HourlyBuckets)
+	assert.Equal(t, adsclient.AdViews{AdID: bike, Views: 2}, stats.Views.PerAd[0])
+
+	top, err := client.MostViewedAds(ctx, 5)
+	require.NoError(t, err)
+	require.Len(t, top, 2)
+	assert.Equal(t, bike, top[0].ID)
+	assert.Equal(t, int64(2), top[0].Views)
+	assert.Equal(t, car, top[1].ID)
+
+	top, err = client.MostViewedAds(ctx, 1)
+	require.NoError(t, err)
+	assert.Len(t, top, 1)
+
+	// deleted ads leave the statistics
+	_, err = client.DeleteAd(ctx, bike, sellerID)
+	require.NoError(t, err)
+	stats, err = client.UserStats(ctx, sellerID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), stats.Views.Total)
+
+	for _, limit := range []int{-1, 101} {
+		_, err = client.MostViewedAds(ctx, limit)
+		assert.ErrorIs(t, err, adsclient.ErrInvalidArgument, limit)
+	}
+	_, err = client.UserStats(ctx, 100)
+	assert.ErrorIs(t, err, adsclient.ErrNotFound)
+}
+
+// TestViewerAddressFromUntrustedPeer checks that without trusted proxies
+// the forwarding headers can't make one client many viewers.
+func TestViewerAddressFromUntrustedPeer(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	srv := runServer(t, ephemeralConfig())
+	seller, err := srv.api.CreateUser(ctx, "Oleg", "")
+	require.NoError(t, err)
+	ad, err := srv.api.CreateAd(ctx, adsclient.CreateAdRequest{UserID: seller.ID, Title: "bike", Text: "for sale"})
+	require.NoError(t, err)
+	_, err = srv.api.ChangeAdStatus(ctx, ad.ID, seller.ID, true)
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		resp, _ := srv.get(t, fmt.Sprintf("/api/v1/ads/%d?viewer_id=%d", ad.ID, 100+i), map[string]string{
+			"X-Forwarded-For": fmt.Sprintf("10.0.0.%d", i),
+			"X-Real-IP":       fmt.Sprintf("10.0.1.%d", i),
+		})
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+	stats, err := srv.api.UserStats(ctx, seller.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stats.Views.Total)
+}
+
+func TestFavoritesAndMessagesInSellerStats(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	srv := runServer(t, ephemeralConfig())
+	client := srv.api
+
+	seller, err := client.CreateUser(ctx, "Oleg", "")
+	require.NoError(t, err)
+	anna, err := client.CreateUser(ctx, "Anna", "")
+	require.NoError(t, err)
+	ivan, err := client.CreateUser(ctx, "Ivan", "")
+	require.NoError(t, err)
+	bike, err := client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: seller.ID, Title: "bike", Text: "for sale"})
+	require.NoError(t, err)
+
+	// unpublished ads can't be favorited or messaged
+	_, err = client.FavoriteAd(ctx, bike.ID, anna.ID)
+	assert.ErrorIs(t, err, adsclient.ErrConflict)
+	_, err = client.ChangeAdStatus(ctx, bike.ID, seller.ID, true)
+	require.NoError(t, err)
+
+	_, err = client.FavoriteAd(ctx, bike.ID, anna.ID)
+	require.NoError(t, err)
+	_, err = client.FavoriteAd(ctx, bike.ID, anna.ID) // once per user
+	require.NoError(t, err)
+	_, err = client.FavoriteAd(ctx, bike.ID, ivan.ID)
+	require.NoError(t, err)
+	_, err = client.UnfavoriteAd(ctx, bike.ID, ivan.ID)
+	require.NoError(t, err)
+	_, err = client.FavoriteAd(ctx, bike.ID, seller.ID)
+	assert.ErrorIs(t, err, adsclient.ErrPermissionDenied)
+	_, err = client.FavoriteAd(ctx, bike.ID, 100)
+	assert.ErrorIs(t, err, adsclient.ErrNotFound)
+
+	m, err := client.SendMessage(ctx, bike.ID, anna.ID, "is it still available?")
+	require.NoError(t, err)
+	assert.Equal(t, anna.ID, m.SenderID)
+	_, err = client.SendMessage(ctx, bike.ID, ivan.ID, "what size?")
+	require.NoError(t, err)
+	_, err = client.SendMessage(ctx, bike.ID, anna.ID, "")
+	assert.ErrorIs(t, err, adsclient.ErrInvalidArgument)
+	_, err = client.SendMessage(ctx, bike.ID, seller.ID, "bump")
+	assert.ErrorIs(t, err, adsclient.ErrPermissionDenied)
+
+	msgs, err := client.AdMessages(ctx, bike.ID, seller.ID)
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+	assert.Equal(t, "is it still available?", msgs[0].Text)
+	assert.Equal(t, ivan.ID, msgs[1].SenderID)
+	_, err = client.AdMessages(ctx, bike.ID, anna.ID)
+	assert.ErrorIs(t, err, adsclient.ErrPermissionDenied)
+
+	stats, err := client.UserStats(ctx, seller.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stats.Favorites)
+	assert.Equal(t, int64(2), stats.Messages)
+
+	// deleted ads leave the statistics
+	_, err = client.DeleteAd(ctx, bike.ID, seller.ID)
+	require.NoError(t, err)
+	stats, err = client.UserStats(ctx, seller.ID)
+	require.NoError(t, err)
+	assert.Zero(t, stats.Favorites)
+	assert.Zero(t, stats.Messages)
+}
+
+// TestViewsOverGRPCAndGateway checks that GetAd counts views by the address
+// of the gRPC peer and of the gateway client.
+func TestViewsOverGRPCAndGateway(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	cfg := ephemeralConfig()
+	cfg.HTTP.TrustedProxies = []string{"127.0.0.1", "::1"}
+	srv := runServer(t, cfg)
+	seller, err := srv.api.CreateUser(ctx, "Oleg", "")
+	require.NoError(t, err)
+	ad, err := srv.api.CreateAd(ctx, adsclient.CreateAdRequest{UserID: seller.ID, Title: "bike", Text: "for sale"})
+	require.NoError(t, err)
+	_, err = srv.api.ChangeAdStatus(ctx, ad.ID, seller.ID, true)
+	require.NoError(t, err)
+
+	conn, err := grpc.DialContext(ctx, srv.grpcAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	client := grpcPort.NewAdServiceClient(conn)
+	for i := 0; i < 2; i++ {
+		res, err := client.GetAd(ctx, &grpcPort.GetAdRequest{AdId: ad.ID})
+		require.NoError(t, err)
+		assert.Equal(t, "bike", res.Title)
+	}
+	_, err = client.GetAd(ctx, &grpcPort.GetAdRequest{AdId: 100})
+	assert.Error(t, err)
+
+	for _, addr := range []string{"10.0.0.1", "10.0.0.1", "10.0.0.2"} {
+		resp, _ := srv.get(t, fmt.Sprintf("/api/v2/ads/%d", ad.ID), map[string]string{"X-Forwarded-For": addr})
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	stats, err := srv.api.UserStats(ctx, seller.ID)
+	require.NoError(t, err)
+	// the gRPC peer once, the gateway clients once each
+	assert.Equal(t, int64(3), stats.Views.Total)
+}
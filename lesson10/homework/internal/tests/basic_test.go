@@ -2,34 +2,25 @@ package tests
 
 import (
 	"context"
-	"net/http"
+	"homework10/pkg/adsclient"
 	"testing"
 
-	"homework10/internal/ports"
-
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 )
 
 type BasicTestSuite struct {
 	suite.Suite
-	hsrv *http.Server
-	cf   context.CancelFunc
-	ch   chan int
-	t    *testing.T
+	srv *testServer
+	t   *testing.T
 }
 
 func (suite *BasicTestSuite) SetupTest() {
-	ctx, cf := context.WithCancel(context.Background())
-	suite.cf = cf
-	endChan := make(chan int)
-	suite.ch = endChan
-	suite.hsrv, _ = ports.CreateServer(ctx, endChan)
+	suite.srv = runServer(suite.t, ephemeralConfig())
 }
 
 func (suite *BasicTestSuite) TearDownTest() {
-	suite.cf()
-	<-suite.ch
+	suite.srv.stop()
 }
 
 func TestBasicTestSuite(t *testing.T) {
@@ -39,243 +30,257 @@ func TestBasicTestSuite(t *testing.T) {
 }
 
 func (suite *BasicTestSuite) TestCreateUser() {
-	client := getTestClient(suite.hsrv.Addr)
-	response, err := client.createUser("Alice", "alice.doe@gmail.com")
+	ctx := context.Background()
+	client := suite.srv.api
+	response, err := client.CreateUser(ctx, "Alice", "alice.doe@gmail.com")
 	assert.NoError(suite.t, err)
-	assert.Equal(suite.t, response.Data.ID, int64(0))
-	assert.Equal(suite.t, response.Data.Nickname, "Alice")
-	assert.Equal(suite.t, response.Data.Email, "alice.doe@gmail.com")
-	response, err = client.createUser("Bob", "bob.doe@gmail.com")
+	assert.Equal(suite.t, response.ID, int64(0))
+	assert.Equal(suite.t, response.Nickname, "Alice")
+	assert.Equal(suite.t, response.Email, "alice.doe@gmail.com")
+	response, err = client.CreateUser(ctx, "Bob", "bob.doe@gmail.com")
 	assert.NoError(suite.t, err)
-	assert.Equal(suite.t, response.Data.ID, int64(1))
+	assert.Equal(suite.t, response.ID, int64(1))
 }
 
 func (suite *BasicTestSuite) TestGetUser() {
-	client := getTestClient(suite.hsrv.Addr)
+	ctx := context.Background()
+	client := suite.srv.api
 
-	resp, err := client.createUser("David", "david.doe@gmail.com")
+	resp, err := client.CreateUser(ctx, "David", "david.doe@gmail.com")
 	assert.NoError(suite.t, err)
-	response, err := client.GetUserByID(resp.Data.ID)
+	response, err := client.GetUser(ctx, resp.ID)
 	assert.NoError(suite.t, err)
-	assert.Equal(suite.t, response.Data.ID, resp.Data.ID)
-	assert.Equal(suite.t, response.Data.Nickname, resp.Data.Nickname)
-	assert.Equal(suite.t, response.Data.Email, resp.Data.Email)
+	assert.Equal(suite.t, response.ID, resp.ID)
+	assert.Equal(suite.t, response.Nickname, resp.Nickname)
+	assert.Equal(suite.t, response.Email, resp.Email)
 }
 
 func (suite *BasicTestSuite) TestDeleteUser() {
-	client := getTestClient(suite.hsrv.Addr)
+	ctx := context.Background()
+	client := suite.srv.api
 
-	resp, err := client.createUser("Harry", "harry.doe@gmail.com")
+	resp, err := client.CreateUser(ctx, "Harry", "harry.doe@gmail.com")
 	assert.NoError(suite.t, err)
-	response, err := client.DeleteUser(resp.Data.ID)
+	response, err := client.DeleteUser(ctx, resp.ID)
 	assert.NoError(suite.t, err)
-	assert.Equal(suite.t, response.Data.ID, resp.Data.ID)
-	assert.Equal(suite.t, response.Data.Nickname, resp.Data.Nickname)
-	assert.Equal(suite.t, response.Data.Email, resp.Data.Email)
-	response, err = client.DeleteUser(resp.Data.ID)
-	assert.ErrorIs(suite.t, err, ErrNotFound)
+	assert.Equal(suite.t, response.ID, resp.ID)
+	assert.Equal(suite.t, response.Nickname, resp.Nickname)
+	assert.Equal(suite.t, response.Email, resp.Email)
+	response, err = client.DeleteUser(ctx, resp.ID)
+	assert.ErrorIs(suite.t, err, adsclient.ErrNotFound)
 }
 
 func (suite *BasicTestSuite) TestDeleteAd() {
-	client := getTestClient(suite.hsrv.Addr)
+	ctx := context.Background()
+	client := suite.srv.api
 
-	usr, err := client.createUser("Carol", "carol.doe@gmail.com")
+	usr, err := client.CreateUser(ctx, "Carol", "carol.doe@gmail.com")
 	assert.NoError(suite.t, err)
-	resp2, err := client.createAd(usr.Data.ID, "hello", "world")
+	resp2, err := client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: usr.ID, Title: "hello", Text: "world"})
 	assert.NoError(suite.t, err)
-	resp3, err := client.DeleteAd(resp2.Data.ID, resp2.Data.AuthorID)
+	resp3, err := client.DeleteAd(ctx, resp2.ID, resp2.AuthorID)
 	assert.NoError(suite.t, err)
-	assert.Equal(suite.t, resp2.Data.ID, resp3.Data.ID)
-	assert.Equal(suite.t, resp2.Data.AuthorID, resp3.Data.AuthorID)
-	_, err = client.DeleteAd(resp2.Data.ID, resp2.Data.AuthorID)
-	assert.ErrorIs(suite.t, err, ErrNotFound)
+	assert.Equal(suite.t, resp2.ID, resp3.ID)
+	assert.Equal(suite.t, resp2.AuthorID, resp3.AuthorID)
+	_, err = client.DeleteAd(ctx, resp2.ID, resp2.AuthorID)
+	assert.ErrorIs(suite.t, err, adsclient.ErrNotFound)
 }
 
 func (suite *BasicTestSuite) TestUpdateUser() {
-	client := getTestClient(suite.hsrv.Addr)
+	ctx := context.Background()
+	client := suite.srv.api
 
-	response_old, err := client.createUser("Eva", "eva.doe@gmail.com")
+	response_old, err := client.CreateUser(ctx, "Eva", "eva.doe@gmail.com")
 	assert.NoError(suite.t, err)
-	response, err := client.updateUser(response_old.Data.ID, "", "new.mail@yandex.ru")
+	response, err := client.UpdateUser(ctx, response_old.ID, "", "new.mail@yandex.ru")
 	assert.NoError(suite.t, err)
-	assert.Equal(suite.t, response.Data.ID, response_old.Data.ID)
-	assert.Equal(suite.t, response.Data.Nickname, "Eva")
-	assert.Equal(suite.t, response.Data.Email, "new.mail@yandex.ru")
+	assert.Equal(suite.t, response.ID, response_old.ID)
+	assert.Equal(suite.t, response.Nickname, "Eva")
+	assert.Equal(suite.t, response.Email, "new.mail@yandex.ru")
 
-	response, err = client.updateUser(response_old.Data.ID, "NewEva", "new.mail@yandex.ru")
+	response, err = client.UpdateUser(ctx, response_old.ID, "NewEva", "new.mail@yandex.ru")
 	assert.NoError(suite.t, err)
-	assert.Equal(suite.t, response.Data.ID, response_old.Data.ID)
-	assert.Equal(suite.t, response.Data.Nickname, "NewEva")
+	assert.Equal(suite.t, response.ID, response_old.ID)
+	assert.Equal(suite.t, response.Nickname, "NewEva")
 }
 
 func (suite *BasicTestSuite) TestCreateAd() {
-	client := getTestClient(suite.hsrv.Addr)
+	ctx := context.Background()
+	client := suite.srv.api
 
-	_, err := client.createUser("Franc", "franc.doe@gmail.com")
+	_, err := client.CreateUser(ctx, "Franc", "franc.doe@gmail.com")
 	assert.NoError(suite.t, err)
-	usr, err := client.createUser("Georg", "georg.doe@gmail.com")
+	usr, err := client.CreateUser(ctx, "Georg", "georg.doe@gmail.com")
 	assert.NoError(suite.t, err)
 
-	response, err := client.createAd(1, "hello", "world")
+	response, err := client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: 1, Title: "hello", Text: "world"})
 	assert.NoError(suite.t, err)
-	assert.Zero(suite.t, response.Data.ID)
-	assert.Equal(suite.t, response.Data.Title, "hello")
-	assert.Equal(suite.t, response.Data.Text, "world")
-	assert.Equal(suite.t, response.Data.AuthorID, usr.Data.ID)
-	assert.False(suite.t, response.Data.Published)
+	assert.Zero(suite.t, response.ID)
+	assert.Equal(suite.t, response.Title, "hello")
+	assert.Equal(suite.t, response.Text, "world")
+	assert.Equal(suite.t, response.AuthorID, usr.ID)
+	assert.False(suite.t, response.Published)
 }
 
 func (suite *BasicTestSuite) TestChangeAdStatus() {
-	client := getTestClient(suite.hsrv.Addr)
+	ctx := context.Background()
+	client := suite.srv.api
 
-	usr, err := client.createUser("Irma", "irma.doe@gmail.com")
+	usr, err := client.CreateUser(ctx, "Irma", "irma.doe@gmail.com")
 	assert.NoError(suite.t, err)
 
-	response, err := client.createAd(usr.Data.ID, "hello", "world")
+	response, err := client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: usr.ID, Title: "hello", Text: "world"})
 	assert.NoError(suite.t, err)
 
-	response, err = client.changeAdStatus(usr.Data.ID, response.Data.ID, true)
+	response, err = client.ChangeAdStatus(ctx, response.ID, usr.ID, true)
 	assert.NoError(suite.t, err)
-	assert.True(suite.t, response.Data.Published)
+	assert.True(suite.t, response.Published)
 
-	response, err = client.changeAdStatus(usr.Data.ID, response.Data.ID, false)
+	response, err = client.ChangeAdStatus(ctx, response.ID, usr.ID, false)
 	assert.NoError(suite.t, err)
-	assert.False(suite.t, response.Data.Published)
+	assert.False(suite.t, response.Published)
 
-	response, err = client.changeAdStatus(usr.Data.ID, response.Data.ID, false)
+	response, err = client.ChangeAdStatus(ctx, response.ID, usr.ID, false)
 	assert.NoError(suite.t, err)
-	assert.False(suite.t, response.Data.Published)
+	assert.False(suite.t, response.Published)
 }
 
 func (suite *BasicTestSuite) TestUpdateAd() {
-	client := getTestClient(suite.hsrv.Addr)
+	ctx := context.Background()
+	client := suite.srv.api
 
-	usr, err := client.createUser("Jane", "john.doe@gmail.com")
+	usr, err := client.CreateUser(ctx, "Jane", "john.doe@gmail.com")
 	assert.NoError(suite.t, err)
 
-	response, err := client.createAd(usr.Data.ID, "hello", "world")
+	response, err := client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: usr.ID, Title: "hello", Text: "world"})
 	assert.NoError(suite.t, err)
 
-	response, err = client.updateAd(usr.Data.ID, response.Data.ID, "привет", "мир")
+	response, err = client.UpdateAd(ctx, response.ID, adsclient.UpdateAdRequest{UserID: usr.ID, Title: "привет", Text: "мир"})
 	assert.NoError(suite.t, err)
-	assert.Equal(suite.t, response.Data.Title, "привет")
-	assert.Equal(suite.t, response.Data.Text, "мир")
+	assert.Equal(suite.t, response.Title, "привет")
+	assert.Equal(suite.t, response.Text, "мир")
 }
 
 func (suite *BasicTestSuite) TestListAds() {
-	client := getTestClient(suite.hsrv.Addr)
+	ctx := context.Background()
+	client := suite.srv.api
 
-	usr, err := client.createUser("Kate", "kate.doe@gmail.com")
+	usr, err := client.CreateUser(ctx, "Kate", "kate.doe@gmail.com")
 	assert.NoError(suite.t, err)
 
-	response, err := client.createAd(usr.Data.ID, "hello", "world")
+	response, err := client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: usr.ID, Title: "hello", Text: "world"})
 	assert.NoError(suite.t, err)
 
-	publishedAd, err := client.changeAdStatus(usr.Data.ID, response.Data.ID, true)
+	publishedAd, err := client.ChangeAdStatus(ctx, response.ID, usr.ID, true)
 	assert.NoError(suite.t, err)
 
-	_, err = client.createAd(usr.Data.ID, "best cat", "not for sale")
+	_, err = client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: usr.ID, Title: "best cat", Text: "not for sale"})
 	assert.NoError(suite.t, err)
 
-	ads, err := client.listAds(nil)
+	ads, err := client.ListAds(ctx)
 	assert.NoError(suite.t, err)
-	assert.Len(suite.t, ads.Data, int(1))
-	assert.Equal(suite.t, ads.Data[0].ID, publishedAd.Data.ID)
-	assert.Equal(suite.t, ads.Data[0].Title, publishedAd.Data.Title)
-	assert.Equal(suite.t, ads.Data[0].Text, publishedAd.Data.Text)
-	assert.Equal(suite.t, ads.Data[0].AuthorID, publishedAd.Data.AuthorID)
-	assert.True(suite.t, ads.Data[0].Published)
+	assert.Len(suite.t, ads, int(1))
+	assert.Equal(suite.t, ads[0].ID, publishedAd.ID)
+	assert.Equal(suite.t, ads[0].Title, publishedAd.Title)
+	assert.Equal(suite.t, ads[0].Text, publishedAd.Text)
+	assert.Equal(suite.t, ads[0].AuthorID, publishedAd.AuthorID)
+	assert.True(suite.t, ads[0].Published)
 }
 
 func (suite *BasicTestSuite) TestListAdsByAuthor() {
-	client := getTestClient(suite.hsrv.Addr)
+	ctx := context.Background()
+	client := suite.srv.api
 
-	usr1, err := client.createUser("Lisa", "lisa.doe@gmail.com")
+	usr1, err := client.CreateUser(ctx, "Lisa", "lisa.doe@gmail.com")
 	assert.NoError(suite.t, err)
-	usr2, err := client.createUser("Mary", "mary.doe@gmail.com")
+	usr2, err := client.CreateUser(ctx, "Mary", "mary.doe@gmail.com")
 	assert.NoError(suite.t, err)
 
-	ad, _ := client.createAd(usr2.Data.ID, "hello", "world")
-	_, err = client.createAd(usr1.Data.ID, "hello", "world")
+	ad, _ := client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: usr2.ID, Title: "hello", Text: "world"})
+	_, err = client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: usr1.ID, Title: "hello", Text: "world"})
 	assert.NoError(suite.t, err)
-	_, err = client.createAd(usr2.Data.ID, "world", "hello")
+	_, err = client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: usr2.ID, Title: "world", Text: "hello"})
 	assert.NoError(suite.t, err)
 
-	ads, err := client.listAdsByAuthor(usr2.Data.ID)
+	ads, err := client.ListAdsByAuthor(ctx, usr2.ID)
 	assert.NoError(suite.t, err)
-	assert.Len(suite.t, ads.Data, 2)
-	assert.Equal(suite.t, ads.Data[0].AuthorID, ad.Data.AuthorID)
-	assert.Equal(suite.t, ads.Data[1].AuthorID, ad.Data.AuthorID)
+	assert.Len(suite.t, ads, 2)
+	assert.Equal(suite.t, ads[0].AuthorID, ad.AuthorID)
+	assert.Equal(suite.t, ads[1].AuthorID, ad.AuthorID)
 }
 
 func (suite *BasicTestSuite) TestListAdsByTime() {
-	client := getTestClient(suite.hsrv.Addr)
+	ctx := context.Background()
+	client := suite.srv.api
 
-	usr1, err := client.createUser("Nansy", "nansy.doe@gmail.com")
+	usr1, err := client.CreateUser(ctx, "Nansy", "nansy.doe@gmail.com")
 	assert.NoError(suite.t, err)
-	usr2, err := client.createUser("Olga", "olga.doe@gmail.com")
+	usr2, err := client.CreateUser(ctx, "Olga", "olga.doe@gmail.com")
 	assert.NoError(suite.t, err)
 
-	ad1, err := client.createAd(usr2.Data.ID, "hello", "world")
+	ad1, err := client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: usr2.ID, Title: "hello", Text: "world"})
 	assert.NoError(suite.t, err)
-	ad2, err := client.createAd(usr1.Data.ID, "hello", "world")
+	ad2, err := client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: usr1.ID, Title: "hello", Text: "world"})
 	assert.NoError(suite.t, err)
 
-	ads, err := client.listAdsByTime(ad1.Data.CreationTime)
+	ads, err := client.ListAdsCreatedAfter(ctx, ad1.CreationTime)
 	assert.NoError(suite.t, err)
-	assert.Len(suite.t, ads.Data, 1)
-	assert.Equal(suite.t, ads.Data[0].ID, ad2.Data.ID)
-	assert.Equal(suite.t, ads.Data[0].Title, ad2.Data.Title)
-	assert.Equal(suite.t, ads.Data[0].Text, ad2.Data.Text)
-	assert.Equal(suite.t, ads.Data[0].AuthorID, ad2.Data.AuthorID)
+	assert.Len(suite.t, ads, 1)
+	assert.Equal(suite.t, ads[0].ID, ad2.ID)
+	assert.Equal(suite.t, ads[0].Title, ad2.Title)
+	assert.Equal(suite.t, ads[0].Text, ad2.Text)
+	assert.Equal(suite.t, ads[0].AuthorID, ad2.AuthorID)
 }
 
 func (suite *BasicTestSuite) TestListAll() {
-	client := getTestClient(suite.hsrv.Addr)
+	ctx := context.Background()
+	client := suite.srv.api
 
-	usr1, err := client.createUser("Peter", "peter.doe@gmail.com")
+	usr1, err := client.CreateUser(ctx, "Peter", "peter.doe@gmail.com")
 	assert.NoError(suite.t, err)
-	usr2, err := client.createUser("Rosa", "rosa.doe@gmail.com")
+	usr2, err := client.CreateUser(ctx, "Rosa", "rosa.doe@gmail.com")
 	assert.NoError(suite.t, err)
 
-	_, err = client.createAd(usr2.Data.ID, "hello", "world")
+	_, err = client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: usr2.ID, Title: "hello", Text: "world"})
 	assert.NoError(suite.t, err)
-	_, err = client.createAd(usr1.Data.ID, "hello", "world")
+	_, err = client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: usr1.ID, Title: "hello", Text: "world"})
 	assert.NoError(suite.t, err)
 
-	ads, err := client.listAll()
+	ads, err := client.ListAllAds(ctx)
 	assert.NoError(suite.t, err)
-	assert.Len(suite.t, ads.Data, 2)
+	assert.Len(suite.t, ads, 2)
 }
 
 func (suite *BasicTestSuite) TestGetAdById() {
-	client := getTestClient(suite.hsrv.Addr)
+	ctx := context.Background()
+	client := suite.srv.api
 
-	usr, err := client.createUser("Sally", "sally.doe@gmail.com")
+	usr, err := client.CreateUser(ctx, "Sally", "sally.doe@gmail.com")
 	assert.NoError(suite.t, err)
 
-	ad, err := client.createAd(usr.Data.ID, "hello", "world")
+	ad, err := client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: usr.ID, Title: "hello", Text: "world"})
 	assert.NoError(suite.t, err)
-	adAgain, err := client.getAd(ad.Data.ID)
+	adAgain, err := client.GetAd(ctx, ad.ID)
 	assert.NoError(suite.t, err)
-	assert.Equal(suite.t, ad.Data.ID, adAgain.Data.ID)
+	assert.Equal(suite.t, ad.ID, adAgain.ID)
 }
 
 func (suite *BasicTestSuite) TestFindByName() {
-	client := getTestClient(suite.hsrv.Addr)
+	ctx := context.Background()
+	client := suite.srv.api
 
-	usr, err := client.createUser("Tuomas", "tuomas.doe@gmail.com")
+	usr, err := client.CreateUser(ctx, "Tuomas", "tuomas.doe@gmail.com")
 	assert.NoError(suite.t, err)
 
-	ad, err := client.createAd(usr.Data.ID, "hello", "world")
+	ad, err := client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: usr.ID, Title: "hello", Text: "world"})
 	assert.NoError(suite.t, err)
-	_, err = client.createAd(usr.Data.ID, "hello", "mir")
+	_, err = client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: usr.ID, Title: "hello", Text: "mir"})
 	assert.NoError(suite.t, err)
-	_, err = client.createAd(usr.Data.ID, "hallo", "welt")
+	_, err = client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: usr.ID, Title: "hallo", Text: "welt"})
 	assert.NoError(suite.t, err)
-	arr, err := client.findAdByTitle(ad.Data.Title)
+	arr, err := client.FindAdsByTitle(ctx, ad.Title)
 	assert.NoError(suite.t, err)
-	assert.Len(suite.t, arr.Data, 2)
-	assert.Equal(suite.t, arr.Data[0].Title, ad.Data.Title)
-	assert.Equal(suite.t, arr.Data[1].Title, ad.Data.Title)
+	assert.Len(suite.t, arr, 2)
+	assert.Equal(suite.t, arr[0].Title, ad.Title)
+	assert.Equal(suite.t, arr[1].Title, ad.Title)
 }
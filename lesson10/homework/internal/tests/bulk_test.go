@@ -0,0 +1,195 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"homework10/internal/adapters/adrepo"
+	"homework10/internal/ads"
+	"homework10/internal/app"
+	grpcPort "homework10/internal/ports/grpc"
+	"homework10/pkg/adsclient"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+type BulkTestSuite struct {
+	suite.Suite
+	srv    *testServer
+	client *adsclient.Client
+}
+
+func (suite *BulkTestSuite) SetupTest() {
+	suite.srv = runServer(suite.T(), ephemeralConfig())
+	suite.client = suite.srv.api
+}
+
+func (suite *BulkTestSuite) TearDownTest() {
+	suite.srv.stop()
+}
+
+func TestBulkTestSuite(t *testing.T) {
+	suite.Run(t, new(BulkTestSuite))
+}
+
+// createAds creates a user with n ads and returns the user ID.
+func (suite *BulkTestSuite) createAds(n int) int64 {
+	ctx := context.Background()
+	usr, err := suite.client.CreateUser(ctx, "Seller", "seller@mail.org")
+	suite.Require().NoError(err)
+	for i := 0; i < n; i++ {
+		_, err := suite.client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: usr.ID, Title: "title", Text: "text"})
+		suite.Require().NoError(err)
+	}
+	return usr.ID
+}
+
+func (suite *BulkTestSuite) TestPartialFailure() {
+	ctx := context.Background()
+	userID := suite.createAds(3)
+
+	resp, err := suite.client.BulkAds(ctx, adsclient.BulkRequest{UserID: userID, Atomic: false, Operations: []adsclient.BulkOperation{
+		{Action: adsclient.BulkPublish, AdID: 0},
+		{Action: adsclient.BulkUpdate, AdID: 1, Title: "", Text: "new text"},
+		{Action: adsclient.BulkDelete, AdID: 2},
+		{Action: adsclient.BulkPublish, AdID: 99},
+		{Action: "archive", AdID: 0},
+	}})
+	suite.Require().NoError(err)
+	suite.Require().Len(resp, 5)
+
+	suite.Nil(resp[0].Error)
+	suite.True(resp[0].Ad.Published)
+	suite.Equal(adsclient.CodeInvalidArgument, resp[1].Error.Code)
+	suite.Equal([]adsclient.FieldViolation{{Field: "title", Description: "must be from 1 to 99 characters long"}}, resp[1].Error.InvalidParams)
+	suite.Nil(resp[2].Error)
+	suite.Equal(int64(2), resp[2].Ad.ID)
+	suite.Equal(adsclient.CodeNotFound, resp[3].Error.Code)
+	suite.Equal("action", resp[4].Error.InvalidParams[0].Field)
+
+	ad, err := suite.client.GetAd(ctx, 0)
+	suite.NoError(err)
+	suite.True(ad.Published)
+	_, err = suite.client.GetAd(ctx, 2)
+	suite.ErrorIs(err, adsclient.ErrNotFound)
+}
+
+func (suite *BulkTestSuite) TestAtomicRollback() {
+	ctx := context.Background()
+	userID := suite.createAds(2)
+
+	resp, err := suite.client.BulkAds(ctx, adsclient.BulkRequest{UserID: userID, Atomic: true, Operations: []adsclient.BulkOperation{
+		{Action: adsclient.BulkPublish, AdID: 0},
+		{Action: adsclient.BulkDelete, AdID: 1},
+		{Action: adsclient.BulkUpdate, AdID: 1, Title: "title", Text: "text"},
+	}})
+	suite.Require().NoError(err)
+	suite.Equal(adsclient.CodeConflict, resp[0].Error.Code)
+	suite.Equal(adsclient.CodeConflict, resp[1].Error.Code)
+	// the ad was deleted by the previous operation of the batch
+	suite.Equal(adsclient.CodeNotFound, resp[2].Error.Code)
+
+	ad, err := suite.client.GetAd(ctx, 0)
+	suite.NoError(err)
+	suite.False(ad.Published)
+	_, err = suite.client.GetAd(ctx, 1)
+	suite.NoError(err)
+}
+
+func (suite *BulkTestSuite) TestAtomicCommit() {
+	ctx := context.Background()
+	userID := suite.createAds(2)
+
+	resp, err := suite.client.BulkAds(ctx, adsclient.BulkRequest{UserID: userID, Atomic: true, Operations: []adsclient.BulkOperation{
+		{Action: adsclient.BulkPublish, AdID: 0},
+		{Action: adsclient.BulkUpdate, AdID: 1, Title: "bike", Text: "red"},
+		{Action: adsclient.BulkUnpublish, AdID: 0},
+	}})
+	suite.Require().NoError(err)
+	for _, r := range resp {
+		suite.Nil(r.Error)
+	}
+
+	ad, err := suite.client.GetAd(ctx, 1)
+	suite.NoError(err)
+	suite.Equal("bike", ad.Title)
+	ad, err = suite.client.GetAd(ctx, 0)
+	suite.NoError(err)
+	suite.False(ad.Published)
+}
+
+func (suite *BulkTestSuite) TestForeignAds() {
+	ctx := context.Background()
+	suite.createAds(1)
+	other, err := suite.client.CreateUser(ctx, "Other", "other@mail.org")
+	suite.Require().NoError(err)
+
+	resp, err := suite.client.BulkAds(ctx, adsclient.BulkRequest{UserID: other.ID, Atomic: false, Operations: []adsclient.BulkOperation{{Action: adsclient.BulkDelete, AdID: 0}}})
+	suite.Require().NoError(err)
+	suite.Equal(adsclient.CodePermissionDenied, resp[0].Error.Code)
+}
+
+func (suite *BulkTestSuite) TestInvalidBatch() {
+	ctx := context.Background()
+	userID := suite.createAds(0)
+
+	_, err := suite.client.BulkAds(ctx, adsclient.BulkRequest{UserID: userID, Atomic: false, Operations: nil})
+	suite.ErrorIs(err, adsclient.ErrInvalidArgument)
+	_, err = suite.client.BulkAds(ctx, adsclient.BulkRequest{UserID: userID, Atomic: false, Operations: make([]adsclient.BulkOperation, app.MaxBulkOperations+1)})
+	suite.ErrorIs(err, adsclient.ErrInvalidArgument)
+	_, err = suite.client.BulkAds(ctx, adsclient.BulkRequest{UserID: 42, Atomic: false, Operations: []adsclient.BulkOperation{{Action: adsclient.BulkPublish}}})
+	suite.ErrorIs(err, adsclient.ErrNotFound)
+}
+
+func (suite *BulkTestSuite) TestGRPCBulkAds() {
+	userID := suite.createAds(2)
+	conn, err := grpc.DialContext(context.Background(), suite.srv.grpcAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	suite.Require().NoError(err)
+	defer conn.Close()
+
+	res, err := grpcPort.NewAdServiceClient(conn).BulkAds(context.Background(), &grpcPort.BulkAdsRequest{
+		UserId: userID,
+		Atomic: true,
+		Operations: []*grpcPort.BulkAdOperation{
+			{Action: grpcPort.BulkAction_Publish, AdId: 0},
+			{Action: grpcPort.BulkAction_Update, AdId: 1, Title: "bike", Text: "red"},
+		},
+	})
+	suite.Require().NoError(err)
+	suite.Require().Len(res.GetResults(), 2)
+	suite.True(res.GetResults()[0].GetAd().GetPublished())
+	suite.Equal("bike", res.GetResults()[1].GetAd().GetTitle())
+	suite.Nil(res.GetResults()[1].GetError())
+}
+
+func TestAdRepositoryTransaction(t *testing.T) {
+	ctx := context.Background()
+	repo := adrepo.New()
+	_, err := repo.AppendAd(ctx, "title", "text", 0, nil, nil)
+	require.NoError(t, err)
+
+	errStop := errors.New("stop")
+	err = repo.Transaction(ctx, func(tx app.AdRepository) error {
+		_, err := tx.AppendAd(ctx, "title", "text", 0, nil, nil)
+		require.NoError(t, err)
+		require.NoError(t, tx.ChangeAdStatus(ctx, 0, true))
+		return errStop
+	})
+	assert.ErrorIs(t, err, errStop)
+	all, err := repo.Select(ctx, func(ads.Ad) bool { return true })
+	require.NoError(t, err)
+	assert.Len(t, all, 1)
+	assert.False(t, all[0].Published)
+
+	require.NoError(t, repo.Transaction(ctx, func(tx app.AdRepository) error {
+		_, err := tx.AppendAd(ctx, "title", "text", 0, nil, nil)
+		return err
+	}))
+	ad, err := repo.AppendAd(ctx, "title", "text", 0, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), ad.ID)
+}
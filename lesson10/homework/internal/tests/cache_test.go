@@ -0,0 +1,193 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"homework10/internal/adapters/adrepo"
+	"homework10/internal/ads"
+	"homework10/internal/app"
+	"homework10/internal/cache"
+	"homework10/internal/tests/mocks"
+	"homework10/internal/users"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdCacheReadThrough(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	repo := mocks.NewMockAdRepository(mockCtrl)
+
+	ad := &ads.Ad{ID: 1, Title: "bike", Text: "red"}
+	repo.EXPECT().GetAdByID(gomock.Any(), int64(1)).Return(ad, nil).Times(2)
+	repo.EXPECT().UpdateAd(gomock.Any(), int64(1), "blue", "bike", nil, nil).Return(nil)
+	repo.EXPECT().GetAdByID(gomock.Any(), int64(2)).Return(nil, app.ErrNotFound).Times(2)
+
+	ctx := context.Background()
+	c := cache.NewAdRepository(repo)
+	for i := 0; i < 3; i++ {
+		got, err := c.GetAdByID(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, "red", got.Text)
+		// callers get copies
+		got.Text = "changed"
+	}
+
+	require.NoError(t, c.UpdateAd(ctx, 1, "blue", "bike", nil, nil))
+	_, err := c.GetAdByID(ctx, 1)
+	require.NoError(t, err)
+
+	// errors are not cached
+	for i := 0; i < 2; i++ {
+		_, err := c.GetAdByID(ctx, 2)
+		assert.ErrorIs(t, err, app.ErrNotFound)
+	}
+
+	assert.Equal(t, cache.Stats{Hits: 2, Misses: 4, Invalidations: 1, Entries: 1}, c.Stats())
+}
+
+func TestCacheTTLAndEviction(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	repo := mocks.NewMockUserRepository(mockCtrl)
+	for id := int64(1); id <= 3; id++ {
+		repo.EXPECT().GetUserByID(gomock.Any(), id).Return(&users.User{ID: id}, nil).AnyTimes()
+	}
+
+	now := time.Now()
+	c := cache.NewUserRepository(repo, cache.WithSize(2), cache.WithTTL(time.Minute),
+		cache.WithClock(func() time.Time { return now }))
+	get := func(id int64) {
+		_, err := c.GetUserByID(context.Background(), id)
+		require.NoError(t, err)
+	}
+
+	get(1)
+	get(2)
+	get(1) // hit, 2 is the least recently used now
+	get(3) // evicts 2
+	get(1) // hit
+	get(2) // miss
+	assert.Equal(t, cache.Stats{Hits: 2, Misses: 4, Evictions: 2, Entries: 2}, c.Stats())
+
+	now = now.Add(time.Minute)
+	get(2)
+	assert.Equal(t, uint64(5), c.Stats().Misses)
+}
+
+// gatedUsers blocks GetUserByID until release is closed and counts the calls.
+type gatedUsers struct {
+	app.UserRepository
+	release chan struct{}
+	mtx     sync.Mutex
+	calls   int
+}
+
+func (g *gatedUsers) GetUserByID(ctx context.Context, ID int64) (*users.User, error) {
+	g.mtx.Lock()
+	g.calls++
+	g.mtx.Unlock()
+	select {
+	case <-g.release:
+		return &users.User{ID: ID, Nickname: "Oleg"}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestCacheCollapsesConcurrentMisses(t *testing.T) {
+	backend := &gatedUsers{release: make(chan struct{})}
+	c := cache.NewUserRepository(backend)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			usr, err := c.GetUserByID(context.Background(), 7)
+			if err == nil && usr.Nickname != "Oleg" {
+				err = errors.New("unexpected user")
+			}
+			errs <- err
+		}()
+	}
+	require.Eventually(t, func() bool { return c.Stats().Misses == 10 }, time.Second, time.Millisecond)
+	close(backend.release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 1, backend.calls)
+}
+
+func TestCacheWaiterIsNotCanceledByOthers(t *testing.T) {
+	backend := &gatedUsers{release: make(chan struct{})}
+	c := cache.NewUserRepository(backend)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := c.GetUserByID(ctx, 7)
+		first <- err
+	}()
+	require.Eventually(t, func() bool { return c.Stats().Misses == 1 }, time.Second, time.Millisecond)
+
+	second := make(chan error, 1)
+	go func() {
+		_, err := c.GetUserByID(context.Background(), 7)
+		second <- err
+	}()
+	require.Eventually(t, func() bool { return c.Stats().Misses == 2 }, time.Second, time.Millisecond)
+
+	cancel()
+	assert.ErrorIs(t, <-first, context.Canceled)
+	close(backend.release)
+	assert.NoError(t, <-second)
+}
+
+func TestCacheTransactionInvalidates(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewAdRepository(adrepo.New())
+	ad, err := c.AppendAd(ctx, "bike", "red", 0, nil, nil)
+	require.NoError(t, err)
+	_, err = c.GetAdByID(ctx, ad.ID)
+	require.NoError(t, err)
+
+	err = c.Transaction(ctx, func(tx app.AdRepository) error {
+		return tx.ChangeAdStatus(ctx, ad.ID, true)
+	})
+	require.NoError(t, err)
+	got, err := c.GetAdByID(ctx, ad.ID)
+	require.NoError(t, err)
+	assert.True(t, got.Published)
+}
+
+func TestCacheMetrics(t *testing.T) {
+	_, srv := startServer(t, ephemeralConfig())
+	client := srv.api
+	ctx := context.Background()
+	usr, err := client.CreateUser(ctx, "Oleg", "")
+	require.NoError(t, err)
+	_, err = client.GetUser(ctx, usr.ID)
+	require.NoError(t, err)
+	_, err = client.GetUser(ctx, usr.ID)
+	require.NoError(t, err)
+
+	resp, err := http.Get("http://" + srv.metricsAddr + "/metrics")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `ads_cache_hits_total{cache="users"} 1`)
+	assert.Contains(t, string(body), `ads_cache_misses_total{cache="users"} 1`)
+	assert.Contains(t, string(body), `ads_cache_entries{cache="ads"} 0`)
+}
@@ -0,0 +1,252 @@
+package tests
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	grpcPort "homework10/internal/ports/grpc"
+	"homework10/pkg/adsclient"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+type importErrorData struct {
+	Line int
+	Code adsclient.Code
+}
+
+// importErrors returns the lines and the codes of the failed rows.
+func importErrors(r *adsclient.ImportReport) []importErrorData {
+	var res []importErrorData
+	for _, e := range r.Errors {
+		res = append(res, importErrorData{Line: e.Line, Code: e.Code})
+	}
+	return res
+}
+
+type CatalogTestSuite struct {
+	suite.Suite
+	baseURL string
+	srv     *testServer
+	client  *adsclient.Client
+}
+
+func (suite *CatalogTestSuite) SetupTest() {
+	suite.srv = runServer(suite.T(), ephemeralConfig())
+	suite.baseURL = suite.srv.baseURL
+	suite.client = suite.srv.api
+}
+
+func (suite *CatalogTestSuite) TearDownTest() {
+	suite.srv.stop()
+}
+
+func TestCatalogTestSuite(t *testing.T) {
+	suite.Run(t, new(CatalogTestSuite))
+}
+
+func (suite *CatalogTestSuite) export(q adsclient.ExportQuery) ([]byte, error) {
+	r, err := suite.client.ExportAds(context.Background(), q)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	body, err := io.ReadAll(r)
+	suite.Require().NoError(err)
+	return body, nil
+}
+
+func (suite *CatalogTestSuite) importFile(r adsclient.ImportRequest, content string) (*adsclient.ImportReport, error) {
+	r.File = strings.NewReader(content)
+	return suite.client.ImportAds(context.Background(), r)
+}
+
+func (suite *CatalogTestSuite) TestExportCSV() {
+	ctx := context.Background()
+	usr, err := suite.client.CreateUser(ctx, "Seller", "seller@mail.org")
+	suite.Require().NoError(err)
+	for _, title := range []string{"bike", "car, red"} {
+		_, err := suite.client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: usr.ID, Title: title, Text: "text"})
+		suite.Require().NoError(err)
+	}
+	_, err = suite.client.ChangeAdStatus(ctx, 0, usr.ID, true)
+	suite.Require().NoError(err)
+
+	resp, err := http.Get(suite.baseURL + "/api/v1/ads/export?format=csv&all=true")
+	suite.Require().NoError(err)
+	resp.Body.Close()
+	suite.Equal(http.StatusOK, resp.StatusCode)
+	suite.Equal("text/csv; charset=utf-8", resp.Header.Get("Content-Type"))
+	suite.Contains(resp.Header.Get("Content-Disposition"), `filename="ads.csv"`)
+
+	body, err := suite.export(adsclient.ExportQuery{Format: adsclient.FormatCSV, All: true})
+	suite.Require().NoError(err)
+	records, err := csv.NewReader(bytes.NewReader(body)).ReadAll()
+	suite.Require().NoError(err)
+	suite.Require().Len(records, 3)
+	suite.Equal([]string{"id", "title", "text", "author_id", "published", "creation_time", "update_time"}, records[0])
+	suite.Equal("car, red", records[2][1])
+
+	// without filters only published ads are exported
+	body, err = suite.export(adsclient.ExportQuery{})
+	suite.Require().NoError(err)
+	records, err = csv.NewReader(bytes.NewReader(body)).ReadAll()
+	suite.Require().NoError(err)
+	suite.Len(records, 2)
+}
+
+func (suite *CatalogTestSuite) TestExportNDJSONByAuthor() {
+	ctx := context.Background()
+	for _, name := range []string{"Alice", "Bob"} {
+		usr, err := suite.client.CreateUser(ctx, name, "")
+		suite.Require().NoError(err)
+		_, err = suite.client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: usr.ID, Title: "ad of " + name, Text: "text"})
+		suite.Require().NoError(err)
+	}
+
+	bob := int64(1)
+	body, err := suite.export(adsclient.ExportQuery{Format: adsclient.FormatNDJSON, AuthorID: &bob})
+	suite.Require().NoError(err)
+	var lines []adsclient.Ad
+	sc := bufio.NewScanner(bytes.NewReader(body))
+	for sc.Scan() {
+		var ad adsclient.Ad
+		suite.Require().NoError(json.Unmarshal(sc.Bytes(), &ad))
+		lines = append(lines, ad)
+	}
+	suite.Require().Len(lines, 1)
+	suite.Equal("ad of Bob", lines[0].Title)
+
+	_, err = suite.export(adsclient.ExportQuery{Format: "xml"})
+	suite.ErrorIs(err, adsclient.ErrInvalidArgument)
+	nobody := int64(42)
+	_, err = suite.export(adsclient.ExportQuery{AuthorID: &nobody})
+	suite.ErrorIs(err, adsclient.ErrNotFound)
+}
+
+func (suite *CatalogTestSuite) TestImportCSV() {
+	ctx := context.Background()
+	usr, err := suite.client.CreateUser(ctx, "Seller", "seller@mail.org")
+	suite.Require().NoError(err)
+
+	file := "title,text\nbike,red\n,no title\n\"broken,row\ncar,blue\n"
+	report, err := suite.importFile(adsclient.ImportRequest{UserID: usr.ID, FileName: "ads.csv"}, file)
+	suite.Require().NoError(err)
+	suite.Equal(1, report.Created)
+	suite.Equal(2, report.Failed)
+	suite.Equal([]importErrorData{{Line: 3, Code: adsclient.CodeInvalidArgument}, {Line: 4, Code: adsclient.CodeInvalidArgument}}, importErrors(report))
+
+	ads, err := suite.client.ListAdsByAuthor(ctx, usr.ID)
+	suite.NoError(err)
+	suite.Len(ads, 1)
+}
+
+func (suite *CatalogTestSuite) TestImportDryRun() {
+	ctx := context.Background()
+	usr, err := suite.client.CreateUser(ctx, "Seller", "seller@mail.org")
+	suite.Require().NoError(err)
+
+	file := "{\"title\": \"bike\", \"text\": \"red\"}\n\n{\"title\": \"" + strings.Repeat("x", 100) + "\", \"text\": \"long\"}\nnot json\n"
+	report, err := suite.importFile(adsclient.ImportRequest{UserID: 0, DryRun: true, FileName: "ads.jsonl"}, file)
+	suite.Require().NoError(err)
+	suite.True(report.DryRun)
+	suite.Equal(1, report.Created)
+	suite.Equal([]importErrorData{{Line: 3, Code: adsclient.CodeInvalidArgument}, {Line: 4, Code: adsclient.CodeInvalidArgument}}, importErrors(report))
+
+	ads, err := suite.client.ListAdsByAuthor(ctx, usr.ID)
+	suite.NoError(err)
+	suite.Empty(ads)
+}
+
+func (suite *CatalogTestSuite) TestExportImportRoundTrip() {
+	ctx := context.Background()
+	for _, name := range []string{"Alice", "Bob"} {
+		_, err := suite.client.CreateUser(ctx, name, "")
+		suite.Require().NoError(err)
+	}
+	for _, title := range []string{"bike", "car"} {
+		_, err := suite.client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: 0, Title: title, Text: "text"})
+		suite.Require().NoError(err)
+	}
+
+	alice := int64(0)
+	for _, format := range []adsclient.Format{adsclient.FormatCSV, adsclient.FormatNDJSON} {
+		body, err := suite.export(adsclient.ExportQuery{Format: format, AuthorID: &alice})
+		suite.Require().NoError(err)
+		report, err := suite.importFile(adsclient.ImportRequest{UserID: 1, Format: format, FileName: "backup"}, string(body))
+		suite.Require().NoError(err)
+		suite.Equal(2, report.Created, format)
+		suite.Empty(report.Errors)
+	}
+	ads, err := suite.client.ListAdsByAuthor(ctx, 1)
+	suite.NoError(err)
+	suite.Len(ads, 4)
+}
+
+func (suite *CatalogTestSuite) TestImportBadRequests() {
+	ctx := context.Background()
+	_, err := suite.client.CreateUser(ctx, "Seller", "seller@mail.org")
+	suite.Require().NoError(err)
+
+	// the client always sends user_id, the form without it is made by hand
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	fw, err := mw.CreateFormFile("file", "ads.csv")
+	suite.Require().NoError(err)
+	_, err = io.WriteString(fw, "title,text\n")
+	suite.Require().NoError(err)
+	suite.Require().NoError(mw.Close())
+	resp, err := http.Post(suite.baseURL+"/api/v1/ads/import", mw.FormDataContentType(), &buf)
+	suite.Require().NoError(err)
+	resp.Body.Close()
+	suite.Equal(http.StatusBadRequest, resp.StatusCode)
+
+	_, err = suite.importFile(adsclient.ImportRequest{UserID: 0, FileName: "ads.txt"}, "title,text\n")
+	suite.ErrorIs(err, adsclient.ErrInvalidArgument)
+	_, err = suite.importFile(adsclient.ImportRequest{UserID: 0, FileName: "ads.csv"}, "name,description\n")
+	suite.ErrorIs(err, adsclient.ErrInvalidArgument)
+	_, err = suite.importFile(adsclient.ImportRequest{UserID: 42, FileName: "ads.csv"}, "title,text\n")
+	suite.ErrorIs(err, adsclient.ErrNotFound)
+}
+
+func (suite *CatalogTestSuite) TestGRPCImportAds() {
+	ctx := context.Background()
+	usr, err := suite.client.CreateUser(ctx, "Seller", "seller@mail.org")
+	suite.Require().NoError(err)
+	conn, err := grpc.DialContext(context.Background(), suite.srv.grpcAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	suite.Require().NoError(err)
+	defer conn.Close()
+
+	stream, err := grpcPort.NewAdServiceClient(conn).ImportAds(context.Background())
+	suite.Require().NoError(err)
+	reqs := []*grpcPort.ImportAdsRequest{
+		{Item: &grpcPort.ImportAdsRequest_Options{Options: &grpcPort.ImportOptions{UserId: usr.ID}}},
+		{Item: &grpcPort.ImportAdsRequest_Row{Row: &grpcPort.ImportRow{Title: "bike", Text: "red"}}},
+		{Item: &grpcPort.ImportAdsRequest_Row{Row: &grpcPort.ImportRow{Title: "", Text: "no title"}}},
+		{Item: &grpcPort.ImportAdsRequest_Row{Row: &grpcPort.ImportRow{Title: "car", Text: "blue"}}},
+	}
+	for _, r := range reqs {
+		suite.Require().NoError(stream.Send(r))
+	}
+	res, err := stream.CloseAndRecv()
+	suite.Require().NoError(err)
+	suite.Equal(int64(2), res.GetCreated())
+	suite.Equal(int64(1), res.GetFailed())
+	suite.Require().Len(res.GetErrors(), 1)
+	suite.Equal(int64(2), res.GetErrors()[0].GetLine())
+	suite.Equal("invalid_argument", res.GetErrors()[0].GetError().GetCode())
+	suite.Equal("title", res.GetErrors()[0].GetError().GetFields()[0].GetField())
+
+	ads, err := suite.client.ListAdsByAuthor(ctx, usr.ID)
+	suite.NoError(err)
+	suite.Len(ads, 2)
+}
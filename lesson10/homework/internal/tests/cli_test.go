@@ -0,0 +1,143 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"homework10/internal/app"
+	"homework10/internal/cli/adsctl"
+	"homework10/internal/cli/adsd"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type CLITestSuite struct {
+	suite.Suite
+	srv *testServer
+	t   *testing.T
+}
+
+func (suite *CLITestSuite) SetupTest() {
+	suite.srv = runServer(suite.t, ephemeralConfig())
+}
+
+func (suite *CLITestSuite) TearDownTest() {
+	suite.srv.stop()
+}
+
+func TestCLITestSuite(t *testing.T) {
+	cts := new(CLITestSuite)
+	cts.t = t
+	suite.Run(t, cts)
+}
+
+func (suite *CLITestSuite) adsctl(args ...string) (string, error) {
+	var out bytes.Buffer
+	err := adsctl.Run(context.Background(), append([]string{"-addr", suite.srv.grpcAddr}, args...), &out)
+	return out.String(), err
+}
+
+func (suite *CLITestSuite) TestSeedAndList() {
+	file := filepath.Join(suite.t.TempDir(), "seed.yaml")
+	assert.NoError(suite.t, os.WriteFile(file, []byte(`
+users:
+  - nickname: Alice
+    email: alice@example.com
+    ads:
+      - title: Bicycle
+        text: City bike
+        published: true
+      - title: Lamp
+        text: Desk lamp
+`), 0o600))
+
+	var out bytes.Buffer
+	err := adsd.Run(context.Background(), []string{"seed", "-addr", suite.srv.grpcAddr, "-file", file}, &out)
+	assert.NoError(suite.t, err)
+	assert.Equal(suite.t, "seeded 1 users and 2 ads\n", out.String())
+
+	res, err := suite.adsctl("-o", "json", "ads", "list", "-all")
+	assert.NoError(suite.t, err)
+	var list []map[string]any
+	assert.NoError(suite.t, json.Unmarshal([]byte(res), &list))
+	assert.Len(suite.t, list, 2)
+
+	res, err = suite.adsctl("ads", "list")
+	assert.NoError(suite.t, err)
+	lines := strings.Split(strings.TrimSpace(res), "\n")
+	assert.Len(suite.t, lines, 2)
+	assert.True(suite.t, strings.HasPrefix(lines[0], "ID"))
+	assert.Contains(suite.t, lines[1], "Bicycle")
+}
+
+func (suite *CLITestSuite) TestManageAdsAndUsers() {
+	res, err := suite.adsctl("-o", "json", "users", "create", "-name", "Carol", "-email", "carol@example.com")
+	assert.NoError(suite.t, err)
+	var users []map[string]any
+	assert.NoError(suite.t, json.Unmarshal([]byte(res), &users))
+	assert.Equal(suite.t, "Carol", users[0]["name"])
+
+	_, err = suite.adsctl("ads", "create", "-user", "0", "-title", "Chair", "-text", "Wooden chair")
+	assert.NoError(suite.t, err)
+	res, err = suite.adsctl("ads", "publish", "-id", "0", "-user", "0")
+	assert.NoError(suite.t, err)
+	assert.Contains(suite.t, res, "true")
+	res, err = suite.adsctl("ads", "unpublish", "-id", "0", "-user", "0")
+	assert.NoError(suite.t, err)
+	assert.Contains(suite.t, res, "false")
+
+	_, err = suite.adsctl("ads", "delete", "-id", "0", "-author", "1")
+	assert.ErrorIs(suite.t, err, app.ErrNotFound)
+	_, err = suite.adsctl("ads", "delete", "-id", "0", "-author", "0")
+	assert.NoError(suite.t, err)
+
+	_, err = suite.adsctl("users", "delete", "-id", "0")
+	assert.NoError(suite.t, err)
+	_, err = suite.adsctl("users", "get", "-id", "0")
+	assert.ErrorIs(suite.t, err, app.ErrNotFound)
+}
+
+func (suite *CLITestSuite) TestListUsers() {
+	for _, name := range []string{"Dave", "Erin"} {
+		_, err := suite.adsctl("users", "create", "-name", name, "-email", strings.ToLower(name)+"@example.com")
+		assert.NoError(suite.t, err)
+	}
+
+	res, err := suite.adsctl("users", "list")
+	assert.NoError(suite.t, err)
+	lines := strings.Split(strings.TrimSpace(res), "\n")
+	assert.Len(suite.t, lines, 3)
+	assert.True(suite.t, strings.HasPrefix(lines[0], "ID"))
+	assert.Contains(suite.t, lines[1], "Dave")
+	assert.Contains(suite.t, lines[2], "Erin")
+
+	res, err = suite.adsctl("-o", "json", "users", "list")
+	assert.NoError(suite.t, err)
+	var users []map[string]any
+	assert.NoError(suite.t, json.Unmarshal([]byte(res), &users))
+	assert.Len(suite.t, users, 2)
+	assert.Equal(suite.t, "erin@example.com", users[1]["email"])
+}
+
+func (suite *CLITestSuite) TestUsage() {
+	_, err := suite.adsctl("ads")
+	assert.ErrorIs(suite.t, err, adsctl.ErrUsage)
+	_, err = suite.adsctl("-o", "xml", "ads", "list")
+	assert.ErrorIs(suite.t, err, adsctl.ErrUsage)
+	_, err = suite.adsctl("ads", "sell")
+	assert.ErrorIs(suite.t, err, adsctl.ErrUsage)
+
+	var out bytes.Buffer
+	assert.ErrorIs(suite.t, adsd.Run(context.Background(), nil, &out), adsd.ErrUsage)
+	out.Reset()
+	assert.NoError(suite.t, adsd.Run(context.Background(), []string{"version"}, &out))
+	assert.True(suite.t, strings.HasPrefix(out.String(), "adsd dev"))
+	out.Reset()
+	assert.NoError(suite.t, adsd.Run(context.Background(), []string{"migrate"}, &out))
+	assert.Contains(suite.t, out.String(), "nothing to migrate")
+}
@@ -0,0 +1,357 @@
+package tests
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"homework10/internal/app"
+	"homework10/internal/config"
+	"homework10/internal/idempotency"
+	"homework10/internal/ports"
+	"homework10/pkg/adsclient"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// testServer is a running server with clients of its addresses.
+type testServer struct {
+	baseURL     string
+	grpcAddr    string
+	metricsAddr string
+	api         *adsclient.Client
+	// client doesn't ask for compression by itself
+	client *http.Client
+	stop   func()
+}
+
+// runServer starts a server with cfg that is stopped at the end of the
+// test.
+func runServer(t testing.TB, cfg config.Config) *testServer {
+	srv, err := newServer(t, cfg)
+	require.NoError(t, err)
+	return serve(t, srv)
+}
+
+// runAppServer is runServer for a, e.g. a mock, that reports to tel.
+func runAppServer(t testing.TB, cfg config.Config, a app.App, tel ports.Telemetry) *testServer {
+	srv, err := ports.NewServer(cfg, a, tel)
+	require.NoError(t, err)
+	return serve(t, srv)
+}
+
+// serve runs srv until stop is called or the test ends.
+func serve(t testing.TB, srv *ports.Server) *testServer {
+	go func() { _ = srv.Run(context.Background()) }()
+	select {
+	case <-srv.Ready():
+	case <-srv.Done():
+		t.Fatal("server stopped before it was ready")
+	}
+	var once sync.Once
+	ts := &testServer{
+		baseURL:     "http://" + srv.HTTPAddr().String(),
+		grpcAddr:    srv.GRPCAddr().String(),
+		metricsAddr: srv.MetricsAddr().String(),
+		client:      &http.Client{Transport: &http.Transport{DisableCompression: true}},
+		stop: func() {
+			once.Do(func() {
+				srv.Shutdown()
+				<-srv.Done()
+			})
+		},
+	}
+	ts.api = newTestClient(ts.baseURL, ts.client)
+	t.Cleanup(ts.stop)
+	return ts
+}
+
+// dial returns a plaintext connection to the grpc address, closed at the
+// end of the test.
+func (ts *testServer) dial(t testing.TB) *grpc.ClientConn {
+	conn, err := grpc.DialContext(context.Background(), ts.grpcAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// get sends a GET with the given headers and returns the response with its
+// body read.
+func (ts *testServer) get(t *testing.T, path string, header map[string]string) (*http.Response, []byte) {
+	req, err := http.NewRequest(http.MethodGet, ts.baseURL+path, nil)
+	require.NoError(t, err)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := ts.client.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp, body
+}
+
+func TestAdETag(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	srv := runServer(t, ephemeralConfig())
+	client := srv.api
+	user, err := client.CreateUser(ctx, "seller", "seller@example.com")
+	require.NoError(t, err)
+	ad, err := client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: user.ID, Title: "bike", Text: "red"})
+	require.NoError(t, err)
+	path := fmt.Sprintf("/api/v1/ads/%d", ad.ID)
+
+	resp, body := srv.get(t, path, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	etag := resp.Header.Get("ETag")
+	require.NotEmpty(t, etag)
+	assert.Equal(t, "no-cache", resp.Header.Get("Cache-Control"))
+	lastModified := resp.Header.Get("Last-Modified")
+	require.NotEmpty(t, lastModified)
+
+	resp, body2 := srv.get(t, path, nil)
+	assert.Equal(t, etag, resp.Header.Get("ETag"))
+	assert.Equal(t, body, body2)
+
+	for _, inm := range []string{etag, "W/" + etag, `"other", ` + etag, "*"} {
+		resp, body = srv.get(t, path, map[string]string{"If-None-Match": inm})
+		assert.Equal(t, http.StatusNotModified, resp.StatusCode, inm)
+		assert.Empty(t, body)
+		assert.Equal(t, etag, resp.Header.Get("ETag"))
+	}
+	resp, _ = srv.get(t, path, map[string]string{"If-None-Match": `"other"`})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, _ = srv.get(t, path, map[string]string{"If-Modified-Since": lastModified})
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+	before := time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)
+	resp, _ = srv.get(t, path, map[string]string{"If-Modified-Since": before})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	// If-None-Match takes precedence
+	resp, _ = srv.get(t, path, map[string]string{"If-None-Match": `"other"`, "If-Modified-Since": lastModified})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	_, err = client.UpdateAd(ctx, ad.ID, adsclient.UpdateAdRequest{UserID: user.ID, Title: "bike", Text: "blue"})
+	require.NoError(t, err)
+	resp, _ = srv.get(t, path, map[string]string{"If-None-Match": etag})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NotEqual(t, etag, resp.Header.Get("ETag"))
+}
+
+// TestAdPublishIsModification checks that a publish invalidates both
+// validators of the ad.
+func TestAdPublishIsModification(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	srv := runServer(t, ephemeralConfig())
+	client := srv.api
+	user, err := client.CreateUser(ctx, "seller", "seller@example.com")
+	require.NoError(t, err)
+	ad, err := client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: user.ID, Title: "bike", Text: "red"})
+	require.NoError(t, err)
+	path := fmt.Sprintf("/api/v1/ads/%d", ad.ID)
+
+	resp, _ := srv.get(t, path, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	etag := resp.Header.Get("ETag")
+	lastModified := resp.Header.Get("Last-Modified")
+
+	// Last-Modified has a precision of a second
+	time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second)))
+	published, err := client.ChangeAdStatus(ctx, ad.ID, user.ID, true)
+	require.NoError(t, err)
+	assert.True(t, published.UpdateTime.After(ad.UpdateTime))
+
+	resp, _ = srv.get(t, path, map[string]string{"If-Modified-Since": lastModified})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NotEqual(t, lastModified, resp.Header.Get("Last-Modified"))
+	resp, body := srv.get(t, path, map[string]string{"If-None-Match": etag})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NotEqual(t, etag, resp.Header.Get("ETag"))
+	assert.Contains(t, string(body), `"published":true`)
+}
+
+func TestListingETag(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	srv := runServer(t, ephemeralConfig())
+	client := srv.api
+	user, err := client.CreateUser(ctx, "seller", "seller@example.com")
+	require.NoError(t, err)
+	var ids []int64
+	for i := 0; i < 5; i++ {
+		ad, err := client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: user.ID, Title: fmt.Sprintf("bike %d", i), Text: "red"})
+		require.NoError(t, err)
+		_, err = client.ChangeAdStatus(ctx, ad.ID, user.ID, true)
+		require.NoError(t, err)
+		ids = append(ids, ad.ID)
+	}
+
+	// the listing is ordered, so its tag is stable
+	resp, _ := srv.get(t, "/api/v1/ads", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	etag := resp.Header.Get("ETag")
+	require.NotEmpty(t, resp.Header.Get("Last-Modified"))
+	for i := 0; i < 10; i++ {
+		resp, _ = srv.get(t, "/api/v1/ads", map[string]string{"If-None-Match": etag})
+		require.Equal(t, http.StatusNotModified, resp.StatusCode)
+	}
+
+	// a deleted ad changes the tag and the modification time
+	lastModified := resp.Header.Get("Last-Modified")
+	time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second)))
+	_, err = client.DeleteAd(ctx, ids[2], user.ID)
+	require.NoError(t, err)
+	resp, body := srv.get(t, "/api/v1/ads", map[string]string{"If-None-Match": etag})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NotEqual(t, etag, resp.Header.Get("ETag"))
+	var listed struct {
+		Data []adsclient.Ad `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(body, &listed))
+	assert.Len(t, listed.Data, 4)
+	resp, _ = srv.get(t, "/api/v1/ads", map[string]string{"If-Modified-Since": lastModified})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestCompression(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	srv := runServer(t, ephemeralConfig())
+	client := srv.api
+	user, err := client.CreateUser(ctx, "seller", "seller@example.com")
+	require.NoError(t, err)
+	small, err := client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: user.ID, Title: "bike", Text: "red"})
+	require.NoError(t, err)
+	for i := 0; i < 20; i++ {
+		ad, err := client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: user.ID, Title: fmt.Sprintf("bike %d", i), Text: strings.Repeat("a fast red bike ", 5)})
+		require.NoError(t, err)
+		_, err = client.ChangeAdStatus(ctx, ad.ID, user.ID, true)
+		require.NoError(t, err)
+	}
+	plainResp, plain := srv.get(t, "/api/v1/ads", nil)
+	require.Equal(t, http.StatusOK, plainResp.StatusCode)
+	require.Greater(t, len(plain), 1024)
+	assert.Empty(t, plainResp.Header.Get("Content-Encoding"))
+	assert.Contains(t, plainResp.Header.Values("Vary"), "Accept-Encoding")
+	etag := plainResp.Header.Get("ETag")
+
+	decoders := map[string]func(io.Reader) (io.Reader, error){
+		"gzip": func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		"br":   func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil },
+	}
+	for _, tc := range []struct {
+		accept   string
+		encoding string
+	}{
+		{"gzip", "gzip"},
+		{"br", "br"},
+		{"gzip, deflate, br", "br"},
+		{"br;q=0.5, gzip", "gzip"},
+		{"*", "br"},
+		{"br;q=0, *;q=0.1", "gzip"},
+		{"identity", ""},
+		{"gzip;q=0", ""},
+	} {
+		t.Run(tc.accept, func(t *testing.T) {
+			resp, body := srv.get(t, "/api/v1/ads", map[string]string{"Accept-Encoding": tc.accept})
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, tc.encoding, resp.Header.Get("Content-Encoding"))
+			if tc.encoding == "" {
+				assert.Equal(t, plain, body)
+				assert.Equal(t, etag, resp.Header.Get("ETag"))
+				return
+			}
+			assert.Less(t, len(body), len(plain))
+			r, err := decoders[tc.encoding](strings.NewReader(string(body)))
+			require.NoError(t, err)
+			decoded, err := io.ReadAll(r)
+			require.NoError(t, err)
+			assert.Equal(t, plain, decoded)
+
+			// the tag of the encoding is revalidated
+			encTag := resp.Header.Get("ETag")
+			assert.Equal(t, strings.TrimSuffix(etag, `"`)+"-"+tc.encoding+`"`, encTag)
+			resp, body = srv.get(t, "/api/v1/ads", map[string]string{"Accept-Encoding": tc.accept, "If-None-Match": encTag})
+			assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+			assert.Empty(t, resp.Header.Get("Content-Encoding"))
+			assert.Empty(t, body)
+		})
+	}
+
+	// small responses are sent as they are
+	resp, _ := srv.get(t, fmt.Sprintf("/api/v1/ads/%d", small.ID), map[string]string{"Accept-Encoding": "gzip, br"})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get("Content-Encoding"))
+	assert.Contains(t, resp.Header.Values("Vary"), "Accept-Encoding")
+}
+
+// TestIdempotentReplayIsCompressedPerRequest retries a request with a large
+// response asking for other encodings. The stored response is encoded for
+// every retry as it asks.
+func TestIdempotentReplayIsCompressedPerRequest(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	srv := runServer(t, ephemeralConfig())
+	user, err := srv.api.CreateUser(ctx, "seller", "seller@example.com")
+	require.NoError(t, err)
+	// the ads don't exist, every operation fails with its own error
+	var ops []string
+	for i := 0; i < 20; i++ {
+		ops = append(ops, fmt.Sprintf(`{"action": "delete", "ad_id": %d}`, 100+i))
+	}
+	payload := fmt.Sprintf(`{"user_id": %d, "atomic": false, "operations": [%s]}`, user.ID, strings.Join(ops, ","))
+
+	post := func(accept string) (*http.Response, []byte) {
+		req, err := http.NewRequest(http.MethodPost, srv.baseURL+"/api/v1/ads/bulk", strings.NewReader(payload))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(idempotency.Header, "bulk-1")
+		req.Header.Set("Accept-Encoding", accept)
+		resp, err := srv.client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var r io.Reader = resp.Body
+		switch resp.Header.Get("Content-Encoding") {
+		case "gzip":
+			r, err = gzip.NewReader(resp.Body)
+			require.NoError(t, err)
+		case "br":
+			r = brotli.NewReader(resp.Body)
+		}
+		body, err := io.ReadAll(r)
+		require.NoError(t, err)
+		return resp, body
+	}
+
+	resp, first := post("gzip")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.GreaterOrEqual(t, len(first), 1024)
+	assert.Equal(t, "gzip", resp.Header.Get("Content-Encoding"))
+
+	for _, tc := range []struct {
+		accept   string
+		encoding string
+	}{
+		{"identity", ""},
+		{"br", "br"},
+		{"gzip", "gzip"},
+	} {
+		resp, body := post(tc.accept)
+		require.Equal(t, http.StatusOK, resp.StatusCode, tc.accept)
+		assert.Equal(t, "true", resp.Header.Get(idempotency.ReplayedHeader), tc.accept)
+		assert.Equal(t, tc.encoding, resp.Header.Get("Content-Encoding"), tc.accept)
+		assert.Equal(t, first, body, tc.accept)
+	}
+}
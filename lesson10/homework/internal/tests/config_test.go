@@ -0,0 +1,122 @@
+package tests
+
+import (
+	"homework10/internal/config"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestConfigDefaults(t *testing.T) {
+	cfg, err := config.Load(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, config.Default(), cfg)
+}
+
+func TestConfigLayers(t *testing.T) {
+	path := writeConfig(t, `
+http:
+  addr: ":8081"
+grpc:
+  addr: ":9091"
+log_level: ${TEST_LOG_LEVEL}
+shutdown_timeout: 5s
+`)
+	t.Setenv("TEST_LOG_LEVEL", "debug")
+	t.Setenv("ADS_GRPC_ADDR", ":9092")
+	t.Setenv("ADS_SHUTDOWN_TIMEOUT", "7s")
+
+	cfg, err := config.Load([]string{"-config", path, "-shutdown-timeout", "9s"})
+	assert.NoError(t, err)
+	assert.Equal(t, ":8081", cfg.HTTP.Addr)
+	assert.Equal(t, ":9092", cfg.GRPC.Addr)
+	assert.Equal(t, config.LevelDebug, cfg.LogLevel)
+	assert.Equal(t, 9*time.Second, cfg.ShutdownTimeout)
+	assert.Equal(t, "release", cfg.GinMode)
+	assert.Equal(t, config.BackendMemory, cfg.Repository.Backend)
+}
+
+func TestConfigFileFromEnv(t *testing.T) {
+	t.Setenv("ADS_CONFIG", writeConfig(t, "gin_mode: test\n"))
+
+	cfg, err := config.Load(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "test", cfg.GinMode)
+}
+
+func TestConfigTimeouts(t *testing.T) {
+	path := writeConfig(t, `
+timeouts:
+  default: 2s
+  operations:
+    SelectAll: 1m
+`)
+	t.Setenv("ADS_TIMEOUT", "3s")
+
+	cfg, err := config.Load([]string{"-config", path})
+	assert.NoError(t, err)
+	assert.Equal(t, 3*time.Second, cfg.Timeouts.Default)
+	assert.Equal(t, map[string]time.Duration{"SelectAll": time.Minute}, cfg.Timeouts.Operations)
+
+	_, err = config.Load([]string{"-config", writeConfig(t, "timeouts:\n  operations:\n    CreateAd: -1s\n")})
+	assert.Error(t, err)
+}
+
+func TestConfigValidation(t *testing.T) {
+	var tests = []struct {
+		name string
+		args []string
+	}{
+		{"bad http addr", []string{"-http-addr", "18080"}},
+		{"bad grpc addr", []string{"-grpc-addr", ""}},
+		{"bad metrics addr", []string{"-metrics-addr", "metrics"}},
+		{"unknown log level", []string{"-log-level", "trace"}},
+		{"unknown log format", []string{"-log-format", "logfmt"}},
+		{"unknown gin mode", []string{"-gin-mode", "prod"}},
+		{"zero timeout", []string{"-shutdown-timeout", "0s"}},
+		{"negative drain delay", []string{"-drain-delay", "-1s"}},
+		{"negative timeout", []string{"-timeout", "-1s"}},
+		{"zero idempotency ttl", []string{"-idempotency-ttl", "0s"}},
+		{"negative cache size", []string{"-cache-size", "-1"}},
+		{"zero cache ttl", []string{"-cache-ttl", "0s"}},
+		{"zero view window", []string{"-view-window", "0s"}},
+		{"unknown backend", []string{"-repository-backend", "postgres"}},
+		{"unknown exporter", []string{"-tracing-exporter", "jaeger"}},
+		{"file exporter without file", []string{"-tracing-exporter", "file"}},
+		{"tls cert without key", []string{"-http-tls-cert", "server.crt"}},
+		{"client CA without cert", []string{"-grpc-tls-client-ca", "clients.crt"}},
+		{"unknown flag", []string{"-port", "1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := config.Load(tt.args)
+			assert.Error(t, err)
+		})
+	}
+
+	_, err := config.Load([]string{"-config", writeConfig(t, "http: [")})
+	assert.Error(t, err)
+	_, err = config.Load([]string{"-config", writeConfig(t, "http:\n  tls:\n    cert_file: a\n    key_file: b\n    client_ca_file: c\n")})
+	assert.ErrorContains(t, err, "only supported by grpc")
+	_, err = config.Load([]string{"-config", writeConfig(t, "grpc:\n  tls:\n    cert_file: a\n    key_file: b\n    principals:\n      CN=billing: billing\n")})
+	assert.ErrorContains(t, err, "requires client_ca_file")
+	_, err = config.Load([]string{"-config", writeConfig(t, "admin:\n  session_ttl: 0s\n")})
+	assert.ErrorContains(t, err, "admin.session_ttl")
+	_, err = config.Load([]string{"-config", writeConfig(t, "http:\n  trusted_proxies: [10.0.0.0/33]\n")})
+	assert.ErrorContains(t, err, "http.trusted_proxies")
+	_, err = config.Load([]string{"-config", writeConfig(t, "grpc:\n  trusted_proxies: [10.0.0.1]\n")})
+	assert.ErrorContains(t, err, "only supported by http")
+	cfg, err := config.Load([]string{"-config", writeConfig(t, "http:\n  trusted_proxies: [10.0.0.1, 192.168.0.0/16]\n")})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1", "192.168.0.0/16"}, cfg.HTTP.TrustedProxies)
+}
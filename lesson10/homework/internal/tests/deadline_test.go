@@ -0,0 +1,83 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"homework10/internal/app"
+	"homework10/internal/config"
+	"homework10/internal/ports"
+	grpcPort "homework10/internal/ports/grpc"
+	"homework10/internal/tests/mocks"
+	"homework10/internal/users"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// blockingUsers returns a user repository whose lookups wait until ctx is done.
+func blockingUsers(t *testing.T) *mocks.MockUserRepository {
+	usrrepo := mocks.NewMockUserRepository(gomock.NewController(t))
+	usrrepo.EXPECT().GetUserByID(gomock.Any(), gomock.Any()).AnyTimes().DoAndReturn(
+		func(ctx context.Context, ID int64) (*users.User, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+	return usrrepo
+}
+
+func TestOperationTimeout(t *testing.T) {
+	adrepo := mocks.NewMockAdRepository(gomock.NewController(t))
+	a := app.NewApp(adrepo, blockingUsers(t), app.WithTimeouts(time.Hour, map[string]time.Duration{"GetUserByID": 50 * time.Millisecond}))
+
+	ctx := context.Background()
+	cfg := ephemeralConfig()
+	tel, err := ports.NewTelemetry(ctx, cfg)
+	require.NoError(t, err)
+	srv := runAppServer(t, cfg, a, tel)
+
+	resp, err := srv.client.Get(srv.baseURL + "/api/v1/users/1")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusGatewayTimeout, resp.StatusCode)
+	var p struct {
+		Code      app.Code `json:"code"`
+		Retryable bool     `json:"retryable"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&p))
+	assert.Equal(t, app.CodeDeadlineExceeded, p.Code)
+	assert.True(t, p.Retryable)
+
+	_, err = grpcPort.NewAdServiceClient(srv.dial(t)).GetUser(ctx, &grpcPort.GetUserRequest{Id: 1})
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+}
+
+func TestClientDeadline(t *testing.T) {
+	adrepo := mocks.NewMockAdRepository(gomock.NewController(t))
+	a := app.NewApp(adrepo, blockingUsers(t))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := a.GetUserByID(ctx, 1)
+	assert.Equal(t, app.CodeDeadlineExceeded, app.FromError(err).Code)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	_, err = a.GetUserByID(ctx, 1)
+	assert.Equal(t, app.CodeCanceled, app.FromError(err).Code)
+}
+
+func TestUnknownOperationTimeout(t *testing.T) {
+	cfg := config.Default()
+	cfg.Timeouts.Operations = map[string]time.Duration{"CreateAdvert": time.Second}
+	tel, err := ports.NewTelemetry(context.Background(), cfg)
+	require.NoError(t, err)
+	_, err = ports.NewApp(cfg, tel, nil)
+	assert.Error(t, err)
+	assert.True(t, app.IsOperation("CreateAd"))
+}
@@ -2,7 +2,8 @@ package tests
 
 import (
 	"context"
-	"homework10/internal/ports"
+	"fmt"
+	"homework10/pkg/adsclient"
 	"net/http"
 	"testing"
 
@@ -12,23 +13,16 @@ import (
 
 type DomainTestSuite struct {
 	suite.Suite
-	hsrv *http.Server
-	cf   context.CancelFunc
-	ch   chan int
-	t    *testing.T
+	srv *testServer
+	t   *testing.T
 }
 
 func (suite *DomainTestSuite) SetupTest() {
-	ctx, cf := context.WithCancel(context.Background())
-	suite.cf = cf
-	endChan := make(chan int)
-	suite.ch = endChan
-	suite.hsrv, _ = ports.CreateServer(ctx, endChan)
+	suite.srv = runServer(suite.t, ephemeralConfig())
 }
 
 func (suite *DomainTestSuite) TearDownTest() {
-	suite.cf()
-	<-suite.ch
+	suite.srv.stop()
 }
 
 func TestDomainTestSuite(t *testing.T) {
@@ -38,74 +32,108 @@ func TestDomainTestSuite(t *testing.T) {
 }
 
 func (suite *DomainTestSuite) TestChangeStatusAdOfAnotherUser() {
-	client := getTestClient(suite.hsrv.Addr)
+	ctx := context.Background()
+	client := suite.srv.api
 
-	usr1, err := client.createUser("Uma", "uma.doe@gmail.com")
+	usr1, err := client.CreateUser(ctx, "Uma", "uma.doe@gmail.com")
 	assert.NoError(suite.t, err)
-	usr2, err := client.createUser("Victor", "jane.doe@gmail.com")
+	usr2, err := client.CreateUser(ctx, "Victor", "jane.doe@gmail.com")
 	assert.NoError(suite.t, err)
 
-	resp, err := client.createAd(usr1.Data.ID, "hello", "world")
+	resp, err := client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: usr1.ID, Title: "hello", Text: "world"})
 	assert.NoError(suite.t, err)
 
-	_, err = client.changeAdStatus(usr2.Data.ID, resp.Data.ID, true)
-	assert.ErrorIs(suite.t, err, ErrForbidden)
+	_, err = client.ChangeAdStatus(ctx, resp.ID, usr2.ID, true)
+	assert.ErrorIs(suite.t, err, adsclient.ErrPermissionDenied)
 }
 
 func (suite *DomainTestSuite) TestUpdateAdOfAnotherUser() {
-	client := getTestClient(suite.hsrv.Addr)
+	ctx := context.Background()
+	client := suite.srv.api
 
-	usr1, err := client.createUser("Wolfgang", "wolfgang.doe@gmail.com")
+	usr1, err := client.CreateUser(ctx, "Wolfgang", "wolfgang.doe@gmail.com")
 	assert.NoError(suite.t, err)
-	usr2, err := client.createUser("Xandria", "xandria.doe@gmail.com")
+	usr2, err := client.CreateUser(ctx, "Xandria", "xandria.doe@gmail.com")
 	assert.NoError(suite.t, err)
 
-	resp, err := client.createAd(usr1.Data.ID, "hello", "world")
+	resp, err := client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: usr1.ID, Title: "hello", Text: "world"})
 	assert.NoError(suite.t, err)
 
-	_, err = client.updateAd(usr2.Data.ID, resp.Data.ID, "title", "text")
-	assert.ErrorIs(suite.t, err, ErrForbidden)
+	_, err = client.UpdateAd(ctx, resp.ID, adsclient.UpdateAdRequest{UserID: usr2.ID, Title: "title", Text: "text"})
+	assert.ErrorIs(suite.t, err, adsclient.ErrPermissionDenied)
 }
 
 func (suite *DomainTestSuite) TestCreateAd_ID() {
-	client := getTestClient(suite.hsrv.Addr)
+	ctx := context.Background()
+	client := suite.srv.api
 
-	usr1, err := client.createUser("Yan", "yan.doe@gmail.com")
+	usr1, err := client.CreateUser(ctx, "Yan", "yan.doe@gmail.com")
 	assert.NoError(suite.t, err)
-	usr2, err := client.createUser("Zigfrid", "zigfrid.doe@gmail.com")
+	usr2, err := client.CreateUser(ctx, "Zigfrid", "zigfrid.doe@gmail.com")
 	assert.NoError(suite.t, err)
 
-	resp, err := client.createAd(usr1.Data.ID, "hello", "world")
+	resp, err := client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: usr1.ID, Title: "hello", Text: "world"})
 	assert.NoError(suite.t, err)
-	assert.Equal(suite.t, resp.Data.ID, int64(0))
+	assert.Equal(suite.t, resp.ID, int64(0))
 
-	resp, err = client.createAd(usr2.Data.ID, "hello", "world")
+	resp, err = client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: usr2.ID, Title: "hello", Text: "world"})
 	assert.NoError(suite.t, err)
-	assert.Equal(suite.t, resp.Data.ID, int64(1))
+	assert.Equal(suite.t, resp.ID, int64(1))
 
-	resp, err = client.createAd(usr1.Data.ID, "hello", "world")
+	resp, err = client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: usr1.ID, Title: "hello", Text: "world"})
 	assert.NoError(suite.t, err)
-	assert.Equal(suite.t, resp.Data.ID, int64(2))
+	assert.Equal(suite.t, resp.ID, int64(2))
 }
 
 func (suite *DomainTestSuite) TestCreateAdWithoutUser() {
-	client := getTestClient(suite.hsrv.Addr)
+	ctx := context.Background()
+	client := suite.srv.api
 
-	_, err := client.createAd(124, "hello", "world")
-	assert.ErrorIs(suite.t, err, ErrNotFound)
+	_, err := client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: 124, Title: "hello", Text: "world"})
+	assert.ErrorIs(suite.t, err, adsclient.ErrNotFound)
 }
 
 func (suite *DomainTestSuite) TestDeleteAdWithWrongUser() {
-	client := getTestClient(suite.hsrv.Addr)
+	ctx := context.Background()
+	client := suite.srv.api
 
-	usr1, err := client.createUser("Quark", "quark.doe@gmail.com")
+	usr1, err := client.CreateUser(ctx, "Quark", "quark.doe@gmail.com")
 	assert.NoError(suite.t, err)
-	usr2, err := client.createUser("Quel", "quel.doe@gmail.com")
+	usr2, err := client.CreateUser(ctx, "Quel", "quel.doe@gmail.com")
 	assert.NoError(suite.t, err)
 
-	resp, err := client.createAd(usr1.Data.ID, "hello", "world")
+	resp, err := client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: usr1.ID, Title: "hello", Text: "world"})
 	assert.NoError(suite.t, err)
 
-	_, err = client.DeleteAd(resp.Data.ID, usr2.Data.ID)
-	assert.ErrorIs(suite.t, err, ErrForbidden)
+	_, err = client.DeleteAd(ctx, resp.ID, usr2.ID)
+	assert.ErrorIs(suite.t, err, adsclient.ErrPermissionDenied)
+}
+
+func (suite *DomainTestSuite) TestErrorsAreProblemDetails() {
+	ctx := context.Background()
+	client := suite.srv.api
+
+	usr1, err := client.CreateUser(ctx, "Ramona", "ramona.doe@gmail.com")
+	assert.NoError(suite.t, err)
+	usr2, err := client.CreateUser(ctx, "Scott", "scott.doe@gmail.com")
+	assert.NoError(suite.t, err)
+
+	resp, err := client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: usr1.ID, Title: "hello", Text: "world"})
+	assert.NoError(suite.t, err)
+
+	_, err = client.DeleteAd(ctx, resp.ID, usr2.ID)
+	var apiErr *adsclient.Error
+	assert.ErrorAs(suite.t, err, &apiErr)
+	// the fields are decoded from application/problem+json only
+	assert.Equal(suite.t, "about:blank", apiErr.Type)
+	assert.Equal(suite.t, fmt.Sprintf("/api/v1/ads/%d", resp.ID), apiErr.Instance)
+	assert.Equal(suite.t, http.StatusForbidden, apiErr.Status)
+	assert.Equal(suite.t, adsclient.CodePermissionDenied, apiErr.Code)
+	assert.False(suite.t, apiErr.Retryable)
+
+	_, err = client.GetAd(ctx, resp.ID+100)
+	assert.ErrorAs(suite.t, err, &apiErr)
+	assert.Equal(suite.t, http.StatusNotFound, apiErr.Status)
+	assert.Equal(suite.t, adsclient.CodeNotFound, apiErr.Code)
+	assert.NotEmpty(suite.t, apiErr.Detail)
 }
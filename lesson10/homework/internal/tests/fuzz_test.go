@@ -2,7 +2,7 @@ package tests
 
 import (
 	"context"
-	"homework10/internal/ports"
+	"homework10/pkg/adsclient"
 	"strings"
 	"testing"
 
@@ -16,12 +16,10 @@ type validationStruct struct {
 }
 
 func FuzzTestServer(f *testing.F) {
-	ctx, cf := context.WithCancel(context.Background())
-	endChan := make(chan int)
-	hsrv, _ := ports.CreateServer(ctx, endChan)
-	httpclient := getTestClient(hsrv.Addr)
+	ctx := context.Background()
+	httpclient := runServer(f, ephemeralConfig()).api
 
-	usr, _ := httpclient.createUser("Admin", "mail@mail.com")
+	usr, _ := httpclient.CreateUser(ctx, "Admin", "mail@mail.com")
 
 	testcases := []string{"Hello, world", " ", "", "!12345", strings.Repeat("a", 101)}
 	for _, tc := range testcases {
@@ -29,7 +27,7 @@ func FuzzTestServer(f *testing.F) {
 	}
 	
 	f.Fuzz(func(t *testing.T, s string) {
-		_, err := httpclient.createAd(usr.Data.ID, s, "Test text")
+		_, err := httpclient.CreateAd(ctx, adsclient.CreateAdRequest{UserID: usr.ID, Title: s, Text: "Test text"})
 		validationErr := validation.Validate(validationStruct{Title: s, Text: "Test text"})
 		if validationErr == nil {
 			assert.NoError(t, err)
@@ -37,7 +35,4 @@ func FuzzTestServer(f *testing.F) {
 			assert.Error(t, err)
 		}
 	})
-
-	cf()
-	<-endChan
 }
\ No newline at end of file
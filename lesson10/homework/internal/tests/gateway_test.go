@@ -0,0 +1,125 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	grpcPort "homework10/internal/ports/grpc"
+	"homework10/internal/ports/httpgin"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type GatewayTestSuite struct {
+	suite.Suite
+	srv *testServer
+	t   *testing.T
+}
+
+func (suite *GatewayTestSuite) SetupTest() {
+	suite.srv = runServer(suite.t, ephemeralConfig())
+}
+
+func (suite *GatewayTestSuite) TearDownTest() {
+	suite.srv.stop()
+}
+
+func TestGatewayTestSuite(t *testing.T) {
+	gts := new(GatewayTestSuite)
+	gts.t = t
+	suite.Run(t, gts)
+}
+
+func (suite *GatewayTestSuite) do(method string, path string, body any) (*http.Response, map[string]any) {
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		assert.NoError(suite.t, err)
+		r = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, suite.srv.baseURL+path, r)
+	assert.NoError(suite.t, err)
+	resp, err := suite.srv.client.Do(req)
+	assert.NoError(suite.t, err)
+	defer resp.Body.Close()
+	var out map[string]any
+	assert.NoError(suite.t, json.NewDecoder(resp.Body).Decode(&out))
+	return resp, out
+}
+
+func (suite *GatewayTestSuite) TestUserLifecycle() {
+	resp, usr := suite.do(http.MethodPost, "/api/v2/users", map[string]any{"name": "Kim", "email": "kim@mail.com"})
+	assert.Equal(suite.t, http.StatusOK, resp.StatusCode)
+	assert.Equal(suite.t, "Kim", usr["name"])
+	id := usr["id"]
+
+	resp, got := suite.do(http.MethodGet, fmt.Sprintf("/api/v2/users/%v", id), nil)
+	assert.Equal(suite.t, http.StatusOK, resp.StatusCode)
+	assert.Equal(suite.t, usr, got)
+
+	resp, _ = suite.do(http.MethodDelete, fmt.Sprintf("/api/v2/users/%v", id), nil)
+	assert.Equal(suite.t, http.StatusOK, resp.StatusCode)
+
+	resp, p := suite.do(http.MethodGet, fmt.Sprintf("/api/v2/users/%v", id), nil)
+	assert.Equal(suite.t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(suite.t, "application/problem+json", resp.Header.Get("Content-Type"))
+	assert.Equal(suite.t, "not_found", p["code"])
+}
+
+func (suite *GatewayTestSuite) TestAdLifecycle() {
+	_, usr := suite.do(http.MethodPost, "/api/v2/users", map[string]any{"name": "Lee", "email": "lee@mail.com"})
+
+	resp, ad := suite.do(http.MethodPost, "/api/v2/ads", map[string]any{"user_id": usr["id"], "title": "hello", "text": "world"})
+	assert.Equal(suite.t, http.StatusOK, resp.StatusCode)
+	assert.Equal(suite.t, usr["id"], ad["author_id"])
+	assert.Contains(suite.t, ad, "creation_date")
+	assert.Contains(suite.t, ad, "update_time")
+	assert.Equal(suite.t, false, ad["published"])
+
+	resp, ad = suite.do(http.MethodPut, fmt.Sprintf("/api/v2/ads/%v/status", ad["id"]), map[string]any{"user_id": usr["id"], "published": true})
+	assert.Equal(suite.t, http.StatusOK, resp.StatusCode)
+	assert.Equal(suite.t, true, ad["published"])
+
+	resp, ad = suite.do(http.MethodPut, fmt.Sprintf("/api/v2/ads/%v", ad["id"]), map[string]any{"user_id": usr["id"], "title": "bye", "text": "world"})
+	assert.Equal(suite.t, http.StatusOK, resp.StatusCode)
+	assert.Equal(suite.t, "bye", ad["title"])
+
+	resp, list := suite.do(http.MethodGet, fmt.Sprintf("/api/v2/ads?mode=ByAuthor&author_id=%v", usr["id"]), nil)
+	assert.Equal(suite.t, http.StatusOK, resp.StatusCode)
+	assert.Len(suite.t, list["list"], 1)
+
+	resp, p := suite.do(http.MethodPost, "/api/v2/ads", map[string]any{"user_id": usr["id"], "title": "", "text": "world"})
+	assert.Equal(suite.t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(suite.t, "invalid_argument", p["code"])
+	assert.Len(suite.t, p["invalid_params"], 1)
+
+	resp, _ = suite.do(http.MethodDelete, fmt.Sprintf("/api/v2/ads/%v?author_id=%v", ad["id"], usr["id"]), nil)
+	assert.Equal(suite.t, http.StatusOK, resp.StatusCode)
+}
+
+func (suite *GatewayTestSuite) TestRoutingErrors() {
+	resp, p := suite.do(http.MethodPatch, "/api/v2/users", nil)
+	assert.Equal(suite.t, http.StatusMethodNotAllowed, resp.StatusCode)
+	assert.Equal(suite.t, "unimplemented", p["code"])
+
+	resp, p = suite.do(http.MethodGet, "/api/v2/nowhere", nil)
+	assert.Equal(suite.t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(suite.t, "not_found", p["code"])
+}
+
+func TestGatewayStatuses(t *testing.T) {
+	for code, want := range map[codes.Code]int{
+		codes.Unauthenticated:  http.StatusUnauthorized,
+		codes.Unimplemented:    http.StatusMethodNotAllowed,
+		codes.PermissionDenied: http.StatusForbidden,
+		codes.Unknown:          http.StatusInternalServerError,
+	} {
+		assert.Equal(t, want, httpgin.HTTPStatus(grpcPort.FromStatus(status.Error(code, "failed")).Code), code.String())
+	}
+}
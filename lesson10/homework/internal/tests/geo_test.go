@@ -0,0 +1,282 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"homework10/internal/adapters/adrepo"
+	"homework10/internal/ads"
+	"homework10/internal/app"
+	"homework10/internal/geo"
+	"homework10/pkg/adsclient"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	moscow     = geo.Point{Lat: 55.7558, Lon: 37.6173}
+	petersburg = geo.Point{Lat: 59.9343, Lon: 30.3351}
+	tver       = geo.Point{Lat: 56.8587, Lon: 35.9176}
+)
+
+func TestDistance(t *testing.T) {
+	assert.InDelta(t, 634, geo.Distance(moscow, petersburg), 2)
+	assert.InDelta(t, geo.Distance(moscow, petersburg), geo.Distance(petersburg, moscow), 1e-9)
+	assert.Zero(t, geo.Distance(moscow, moscow))
+	// across the antimeridian
+	assert.InDelta(t, geo.EarthRadius*0.5*math.Pi/180, geo.Distance(geo.Point{Lon: 179.75}, geo.Point{Lon: -179.75}), 0.01)
+}
+
+func TestCircleBounds(t *testing.T) {
+	c := geo.Circle{Center: moscow, Radius: 200}
+	b := c.Bounds()
+	for bearing := 0; bearing < 360; bearing += 10 {
+		// points just inside the circle are inside the bounds
+		p := destination(moscow, 199.9, float64(bearing))
+		require.True(t, c.Contains(p))
+		assert.True(t, b.Contains(p), "bearing %d", bearing)
+	}
+	assert.False(t, b.Contains(petersburg))
+
+	wrapped := geo.Circle{Center: geo.Point{Lat: 0, Lon: 179.9}, Radius: 100}.Bounds()
+	assert.Greater(t, wrapped.MinLon, wrapped.MaxLon)
+	assert.True(t, wrapped.Contains(geo.Point{Lat: 0, Lon: -179.9}))
+
+	polar := geo.Circle{Center: geo.Point{Lat: 89.5, Lon: 10}, Radius: 100}.Bounds()
+	assert.Equal(t, geo.Box{MinLat: polar.MinLat, MinLon: -180, MaxLat: 90, MaxLon: 180}, polar)
+}
+
+// destination moves d kilometers from p in the direction of bearing in
+// degrees.
+func destination(p geo.Point, d float64, bearing float64) geo.Point {
+	rad := func(x float64) float64 { return x * math.Pi / 180 }
+	deg := func(x float64) float64 { return x * 180 / math.Pi }
+	lat, lon, b, ang := rad(p.Lat), rad(p.Lon), rad(bearing), d/geo.EarthRadius
+	lat2 := math.Asin(math.Sin(lat)*math.Cos(ang) + math.Cos(lat)*math.Sin(ang)*math.Cos(b))
+	lon2 := lon + math.Atan2(math.Sin(b)*math.Sin(ang)*math.Cos(lat), math.Cos(ang)-math.Sin(lat)*math.Sin(lat2))
+	return geo.Point{Lat: deg(lat2), Lon: deg(lon2)}
+}
+
+func TestGridSearchMatchesScan(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	g := geo.NewGrid(1)
+	points := map[int64]geo.Point{}
+	for id := int64(0); id < 2000; id++ {
+		p := geo.Point{Lat: rnd.Float64()*180 - 90, Lon: rnd.Float64()*360 - 180}
+		points[id] = p
+		g.Insert(id, p)
+	}
+	for id := int64(0); id < 500; id++ {
+		g.Remove(id, points[id])
+		delete(points, id)
+	}
+	require.Equal(t, len(points), g.Len())
+
+	boxes := []geo.Box{
+		{MinLat: 10, MinLon: 10, MaxLat: 12.5, MaxLon: 13},
+		{MinLat: -90, MinLon: -180, MaxLat: 90, MaxLon: 180},
+		{MinLat: -30, MinLon: 170, MaxLat: 30, MaxLon: -170},
+		{MinLat: 89, MinLon: -180, MaxLat: 90, MaxLon: 180},
+	}
+	for i := 0; i < 50; i++ {
+		lat := []float64{rnd.Float64()*180 - 90, rnd.Float64()*180 - 90}
+		sort.Float64s(lat)
+		boxes = append(boxes, geo.Box{MinLat: lat[0], MinLon: rnd.Float64()*360 - 180, MaxLat: lat[1], MaxLon: rnd.Float64()*360 - 180})
+	}
+	for _, b := range boxes {
+		var want, got []int64
+		for id, p := range points {
+			if b.Contains(p) {
+				want = append(want, id)
+			}
+		}
+		g.Search(b, func(id int64, _ geo.Point) { got = append(got, id) })
+		assert.ElementsMatch(t, want, got, "%+v", b)
+	}
+}
+
+func TestRepositoryKeepsGridInSync(t *testing.T) {
+	ctx := context.Background()
+	repo := adrepo.New(adrepo.WithCellSize(0.5))
+	box := geo.Circle{Center: moscow, Radius: 50}.Bounds()
+	all := func(ads.Ad) bool { return true }
+	inBox := func() []int64 {
+		arr, err := repo.SelectArea(ctx, box, all)
+		require.NoError(t, err)
+		var ids []int64
+		for _, ad := range arr {
+			ids = append(ids, ad.ID)
+		}
+		return ids
+	}
+
+	ad, err := repo.AppendAd(ctx, "bike", "red", 0, &ads.Location{Lat: moscow.Lat, Lon: moscow.Lon, City: "Moscow"}, nil)
+	require.NoError(t, err)
+	_, err = repo.AppendAd(ctx, "car", "blue", 0, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{ad.ID}, inBox())
+
+	require.NoError(t, repo.UpdateAd(ctx, ad.ID, "", "", &ads.Location{Lat: petersburg.Lat, Lon: petersburg.Lon}, nil))
+	assert.Empty(t, inBox())
+	got, err := repo.GetAdByID(ctx, ad.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "bike", got.Title)
+	assert.Equal(t, petersburg.Lat, got.Location.Lat)
+
+	// a failed transaction leaves the grid alone
+	err = repo.Transaction(ctx, func(tx app.AdRepository) error {
+		if err := tx.UpdateAd(ctx, ad.ID, "", "", &ads.Location{Lat: moscow.Lat, Lon: moscow.Lon}, nil); err != nil {
+			return err
+		}
+		return errors.New("rollback")
+	})
+	require.Error(t, err)
+	assert.Empty(t, inBox())
+	err = repo.Transaction(ctx, func(tx app.AdRepository) error {
+		return tx.UpdateAd(ctx, ad.ID, "", "", &ads.Location{Lat: moscow.Lat, Lon: moscow.Lon}, nil)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{ad.ID}, inBox())
+
+	_, err = repo.DeleteAd(ctx, ad.ID)
+	require.NoError(t, err)
+	assert.Empty(t, inBox())
+}
+
+func TestSearchNearbyHTTP(t *testing.T) {
+	ctx := context.Background()
+	_, srv := startServer(t, ephemeralConfig())
+	client := srv.api
+
+	seller, err := client.CreateUser(ctx, "Oleg", "")
+	require.NoError(t, err)
+	other, err := client.CreateUser(ctx, "Anna", "")
+	require.NoError(t, err)
+	create := func(author int64, title string, p *geo.Point, publish bool) int64 {
+		r := adsclient.CreateAdRequest{UserID: author, Title: title, Text: "for sale"}
+		if p != nil {
+			r.Location = &adsclient.Location{Lat: p.Lat, Lon: p.Lon, City: title}
+		}
+		ad, err := client.CreateAd(ctx, r)
+		require.NoError(t, err)
+		if publish {
+			_, err := client.ChangeAdStatus(ctx, ad.ID, author, true)
+			require.NoError(t, err)
+		}
+		return ad.ID
+	}
+	inMoscow := create(seller.ID, "Moscow bike", &moscow, true)
+	inTver := create(other.ID, "Tver bike", &tver, true)
+	inPetersburg := create(seller.ID, "Petersburg bike", &petersburg, true)
+	draft := create(seller.ID, "Moscow draft", &moscow, false)
+	create(seller.ID, "nowhere", nil, true)
+
+	search := func(q adsclient.NearbyQuery) []adsclient.NearbyAd {
+		res, err := client.SearchNearby(ctx, q)
+		require.NoError(t, err)
+		return res
+	}
+	ids := func(arr []adsclient.NearbyAd) []int64 {
+		res := []int64{}
+		for _, ad := range arr {
+			res = append(res, ad.ID)
+		}
+		return res
+	}
+	around := func(p geo.Point, radius float64) adsclient.NearbyQuery {
+		return adsclient.NearbyQuery{Circle: &adsclient.Circle{Lat: p.Lat, Lon: p.Lon, RadiusKm: radius}}
+	}
+
+	res := search(around(moscow, 200))
+	assert.Equal(t, []int64{inMoscow, inTver}, ids(res))
+	assert.InDelta(t, 0, res[0].DistanceKm, 1e-6)
+	assert.Equal(t, "Moscow bike", res[0].Location.City)
+
+	q := around(tver, 700)
+	q.SortByDistance = true
+	assert.Equal(t, []int64{inTver, inMoscow, inPetersburg}, ids(search(q)))
+	q.AuthorID = &seller.ID
+	assert.Equal(t, []int64{inMoscow, inPetersburg}, ids(search(q)))
+	q.All = true
+	assert.Equal(t, []int64{inMoscow, draft, inPetersburg}, ids(search(q)))
+	q.Title = "draft"
+	assert.Equal(t, []int64{draft}, ids(search(q)))
+	q = around(tver, 700)
+	q.Limit = 1
+	assert.Len(t, search(q), 1)
+
+	box := adsclient.NearbyQuery{Box: &adsclient.Box{MinLat: 55, MinLon: 29, MaxLat: 60.5, MaxLon: 36}}
+	assert.Equal(t, []int64{inTver, inPetersburg}, ids(search(box)))
+
+	// moving an ad moves it in the index
+	updated, err := client.UpdateAd(ctx, inTver, adsclient.UpdateAdRequest{
+		UserID: other.ID, Title: "Tver bike", Text: "moved",
+		Location: &adsclient.Location{Lat: petersburg.Lat, Lon: petersburg.Lon, City: "Saint Petersburg"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Saint Petersburg", updated.Location.City)
+	assert.Equal(t, []int64{inMoscow}, ids(search(around(moscow, 200))))
+
+	// title and text updates keep the location
+	_, err = client.UpdateAd(ctx, inTver, adsclient.UpdateAdRequest{UserID: other.ID, Title: "Tver bike", Text: "kept"})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{inTver, inPetersburg}, ids(search(box)))
+
+	_, err = client.DeleteAd(ctx, inMoscow, seller.ID)
+	require.NoError(t, err)
+	assert.Empty(t, search(around(moscow, 10)))
+
+	bad := []url.Values{
+		{},
+		{"lat": {"55"}, "lon": {"37"}},
+		{"lat": {"91"}, "lon": {"37"}, "radius_km": {"10"}},
+		{"lat": {"55"}, "lon": {"37"}, "radius_km": {"1001"}},
+		{"lat": {"x"}, "lon": {"37"}, "radius_km": {"10"}},
+		{"min_lat": {"60"}, "min_lon": {"29"}, "max_lat": {"55"}, "max_lon": {"36"}},
+		{"lat": {"55"}, "lon": {"37"}, "radius_km": {"10"}, "min_lat": {"55"}},
+		{"lat": {"55"}, "lon": {"37"}, "radius_km": {"10"}, "sort": {"price"}},
+		{"lat": {"55"}, "lon": {"37"}, "radius_km": {"10"}, "created_after": {"yesterday"}},
+		{"lat": {"55"}, "lon": {"37"}, "radius_km": {"10"}, "limit": {"0"}},
+	}
+	// malformed parameters are sent by hand
+	for _, params := range bad {
+		resp, err := http.Get(srv.baseURL + "/api/v1/ads/nearby?" + params.Encode())
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, params.Encode())
+	}
+}
+
+func TestAdLocationValidation(t *testing.T) {
+	ctx := context.Background()
+	_, srv := startServer(t, ephemeralConfig())
+	client := srv.api
+	usr, err := client.CreateUser(ctx, "Oleg", "")
+	require.NoError(t, err)
+
+	_, err = client.CreateAd(ctx, adsclient.CreateAdRequest{
+		UserID: usr.ID, Title: "bike", Text: "red",
+		Location: &adsclient.Location{Lat: 91, Lon: -181, City: strings.Repeat("a", 100)},
+	})
+	var apiErr *adsclient.Error
+	require.ErrorAs(t, err, &apiErr)
+	var fields []string
+	for _, f := range apiErr.InvalidParams {
+		fields = append(fields, f.Field)
+	}
+	assert.ElementsMatch(t, []string{"location.lat", "location.lon", "location.city"}, fields)
+
+	ad, err := client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: usr.ID, Title: "bike", Text: "red"})
+	require.NoError(t, err)
+	_, err = client.UpdateAd(ctx, ad.ID, adsclient.UpdateAdRequest{
+		UserID: usr.ID, Title: "bike", Text: "red", Location: &adsclient.Location{Lat: -90.5, Lon: 0},
+	})
+	assert.ErrorIs(t, err, adsclient.ErrInvalidArgument)
+}
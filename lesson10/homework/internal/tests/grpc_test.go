@@ -5,34 +5,36 @@ import (
 	"log"
 	"testing"
 
+	"homework10/internal/ads"
 	"homework10/internal/ports"
 	grpcPort "homework10/internal/ports/grpc"
+	"homework10/internal/tests/mocks"
+	"homework10/internal/users"
 
+	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
 )
 
 type GrpcTestSuite struct {
 	suite.Suite
-	gsrv *grpc.Server
-	cf   context.CancelFunc
-	ch   chan int
-	t    *testing.T
+	srv *testServer
+	t   *testing.T
 }
 
 func (suite *GrpcTestSuite) SetupTest() {
-	ctx, cf := context.WithCancel(context.Background())
-	suite.cf = cf
-	endChan := make(chan int)
-	suite.ch = endChan
-	_, suite.gsrv = ports.CreateServer(ctx, endChan)
+	suite.srv = runServer(suite.t, ephemeralConfig())
 }
 
 func (suite *GrpcTestSuite) TearDownTest() {
-	suite.cf()
-	<-suite.ch
+	suite.srv.stop()
 }
 
 func TestGrpcTestSuite(t *testing.T) {
@@ -44,7 +46,7 @@ func TestGrpcTestSuite(t *testing.T) {
 func (suite *GrpcTestSuite) TestGRPCCreateUser() {
 
 	conn, err := grpc.DialContext(context.Background(),
-		"localhost:50054",
+		suite.srv.grpcAddr,
 		grpc.WithTransportCredentials(insecure.NewCredentials()))
 	if err != nil {
 		log.Fatal(err)
@@ -59,7 +61,7 @@ func (suite *GrpcTestSuite) TestGRPCCreateUser() {
 func (suite *GrpcTestSuite) TestGRPCGetUser() {
 
 	conn, err := grpc.DialContext(context.Background(),
-		"localhost:50054",
+		suite.srv.grpcAddr,
 		grpc.WithTransportCredentials(insecure.NewCredentials()))
 	if err != nil {
 		log.Fatal(err)
@@ -78,7 +80,7 @@ func (suite *GrpcTestSuite) TestGRPCGetUser() {
 func (suite *GrpcTestSuite) TestGRPCDeleteUser() {
 
 	conn, err := grpc.DialContext(context.Background(),
-		"localhost:50054",
+		suite.srv.grpcAddr,
 		grpc.WithTransportCredentials(insecure.NewCredentials()))
 	if err != nil {
 		log.Fatal(err)
@@ -92,14 +94,14 @@ func (suite *GrpcTestSuite) TestGRPCDeleteUser() {
 	assert.Equal(suite.t, res.GetName(), usr.Name)
 	assert.Equal(suite.t, res.GetEmail(), usr.Email)
 	_, err = client.DeleteUser(context.Background(), &grpcPort.DeleteUserRequest{Id: res.Id})
-	assert.Error(suite.t, err, ErrNotFound)
+	assert.Error(suite.t, err)
 
 }
 
 func (suite *GrpcTestSuite) TestGRPCCreateAd() {
 
 	conn, err := grpc.DialContext(context.Background(),
-		"localhost:50054",
+		suite.srv.grpcAddr,
 		grpc.WithTransportCredentials(insecure.NewCredentials()))
 	if err != nil {
 		log.Fatal(err)
@@ -116,7 +118,7 @@ func (suite *GrpcTestSuite) TestGRPCCreateAd() {
 func (suite *GrpcTestSuite) TestGRPCDeleteAd() {
 
 	conn, err := grpc.DialContext(context.Background(),
-		"localhost:50054",
+		suite.srv.grpcAddr,
 		grpc.WithTransportCredentials(insecure.NewCredentials()))
 	if err != nil {
 		log.Fatal(err)
@@ -135,7 +137,7 @@ func (suite *GrpcTestSuite) TestGRPCDeleteAd() {
 func (suite *GrpcTestSuite) TestGRPCChangeAdStatus() {
 
 	conn, err := grpc.DialContext(context.Background(),
-		"localhost:50054",
+		suite.srv.grpcAddr,
 		grpc.WithTransportCredentials(insecure.NewCredentials()))
 	if err != nil {
 		log.Fatal(err)
@@ -156,7 +158,7 @@ func (suite *GrpcTestSuite) TestGRPCChangeAdStatus() {
 func (suite *GrpcTestSuite) TestGRPCUpdateAd() {
 
 	conn, err := grpc.DialContext(context.Background(),
-		"localhost:50054",
+		suite.srv.grpcAddr,
 		grpc.WithTransportCredentials(insecure.NewCredentials()))
 	if err != nil {
 		log.Fatal(err)
@@ -184,7 +186,7 @@ func (suite *GrpcTestSuite) TestGRPCUpdateAd() {
 func (suite *GrpcTestSuite) TestGRPCListad() {
 
 	conn, err := grpc.DialContext(context.Background(),
-		"localhost:50054",
+		suite.srv.grpcAddr,
 		grpc.WithTransportCredentials(insecure.NewCredentials()))
 	if err != nil {
 		log.Fatal(err)
@@ -215,3 +217,165 @@ func (suite *GrpcTestSuite) TestGRPCListad() {
 	assert.Len(suite.t, ads.List, 1)
 
 }
+
+func (suite *GrpcTestSuite) TestGRPCErrorStatus() {
+
+	conn, err := grpc.DialContext(context.Background(),
+		suite.srv.grpcAddr,
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		log.Fatal(err)
+	}
+	client := grpcPort.NewAdServiceClient(conn)
+	_, err = client.GetUser(context.Background(), &grpcPort.GetUserRequest{Id: 1000})
+	assert.Equal(suite.t, codes.NotFound, status.Code(err))
+
+	res, err := client.CreateUser(context.Background(), &grpcPort.CreateUserRequest{Name: "Oleg"})
+	assert.NoError(suite.t, err)
+	_, err = client.CreateAd(context.Background(), &grpcPort.CreateAdRequest{UserId: res.Id, Title: "", Text: "world"})
+	st := status.Convert(err)
+	assert.Equal(suite.t, codes.InvalidArgument, st.Code())
+
+	var info *errdetails.ErrorInfo
+	var badRequest *errdetails.BadRequest
+	for _, d := range st.Details() {
+		switch d := d.(type) {
+		case *errdetails.ErrorInfo:
+			info = d
+		case *errdetails.BadRequest:
+			badRequest = d
+		}
+	}
+	assert.NotNil(suite.t, info)
+	assert.Equal(suite.t, "invalid_argument", info.GetReason())
+	assert.Equal(suite.t, "false", info.GetMetadata()["retryable"])
+	assert.NotNil(suite.t, badRequest)
+	assert.Len(suite.t, badRequest.GetFieldViolations(), 1)
+	assert.Equal(suite.t, "title", badRequest.GetFieldViolations()[0].GetField())
+}
+
+func (suite *GrpcTestSuite) TestGRPCSimilarAds() {
+
+	conn, err := grpc.DialContext(context.Background(),
+		suite.srv.grpcAddr,
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		log.Fatal(err)
+	}
+	client := grpcPort.NewAdServiceClient(conn)
+	seller, err := client.CreateUser(context.Background(), &grpcPort.CreateUserRequest{Name: "Oleg"})
+	assert.NoError(suite.t, err)
+	buyer, err := client.CreateUser(context.Background(), &grpcPort.CreateUserRequest{Name: "Anna"})
+	assert.NoError(suite.t, err)
+	var ids []int64
+	for _, title := range []string{"red bike", "blue bike", "garden chair"} {
+		ad, err := client.CreateAd(context.Background(), &grpcPort.CreateAdRequest{UserId: seller.Id, Title: title, Text: "for sale"})
+		assert.NoError(suite.t, err)
+		_, err = client.ChangeAdStatus(context.Background(), &grpcPort.ChangeAdStatusRequest{AdId: ad.Id, UserId: seller.Id, Published: true})
+		assert.NoError(suite.t, err)
+		ids = append(ids, ad.Id)
+	}
+
+	res, err := client.SimilarAds(context.Background(), &grpcPort.SimilarAdsRequest{AdId: ids[0], ViewerId: &buyer.Id})
+	assert.NoError(suite.t, err)
+	if assert.Len(suite.t, res.GetList(), 2) {
+		assert.Equal(suite.t, ids[1], res.GetList()[0].GetAd().GetId())
+		assert.Greater(suite.t, res.GetList()[0].GetScore(), res.GetList()[1].GetScore())
+	}
+
+	res, err = client.SimilarAds(context.Background(), &grpcPort.SimilarAdsRequest{AdId: ids[0], ViewerId: &seller.Id})
+	assert.NoError(suite.t, err)
+	assert.Empty(suite.t, res.GetList())
+
+	_, err = client.SimilarAds(context.Background(), &grpcPort.SimilarAdsRequest{AdId: ids[0], Limit: 51})
+	assert.Equal(suite.t, codes.InvalidArgument, status.Code(err))
+}
+
+func (suite *GrpcTestSuite) TestGRPCSearchNearby() {
+
+	conn, err := grpc.DialContext(context.Background(),
+		suite.srv.grpcAddr,
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		log.Fatal(err)
+	}
+	client := grpcPort.NewAdServiceClient(conn)
+	usr, err := client.CreateUser(context.Background(), &grpcPort.CreateUserRequest{Name: "Oleg"})
+	assert.NoError(suite.t, err)
+	var ids []int64
+	for _, loc := range []*grpcPort.Location{
+		{Lat: 55.7558, Lon: 37.6173, City: "Moscow"},
+		{Lat: 59.9343, Lon: 30.3351, City: "Saint Petersburg"},
+	} {
+		ad, err := client.CreateAd(context.Background(), &grpcPort.CreateAdRequest{UserId: usr.Id, Title: "bike", Text: "red", Location: loc})
+		assert.NoError(suite.t, err)
+		assert.Equal(suite.t, loc.City, ad.GetLocation().GetCity())
+		_, err = client.ChangeAdStatus(context.Background(), &grpcPort.ChangeAdStatusRequest{AdId: ad.Id, UserId: usr.Id, Published: true})
+		assert.NoError(suite.t, err)
+		ids = append(ids, ad.Id)
+	}
+
+	res, err := client.SearchNearby(context.Background(), &grpcPort.SearchNearbyRequest{
+		Area:           &grpcPort.SearchNearbyRequest_Circle{Circle: &grpcPort.Circle{Lat: 59, Lon: 31, RadiusKm: 1000}},
+		SortByDistance: true,
+	})
+	assert.NoError(suite.t, err)
+	if assert.Len(suite.t, res.GetList(), 2) {
+		assert.Equal(suite.t, ids[1], res.GetList()[0].GetAd().GetId())
+		assert.Less(suite.t, res.GetList()[0].GetDistanceKm(), res.GetList()[1].GetDistanceKm())
+	}
+
+	res, err = client.SearchNearby(context.Background(), &grpcPort.SearchNearbyRequest{
+		Area: &grpcPort.SearchNearbyRequest_Box{Box: &grpcPort.BoundingBox{MinLat: 55, MinLon: 37, MaxLat: 56, MaxLon: 38}},
+	})
+	assert.NoError(suite.t, err)
+	if assert.Len(suite.t, res.GetList(), 1) {
+		assert.Equal(suite.t, ids[0], res.GetList()[0].GetAd().GetId())
+	}
+
+	_, err = client.SearchNearby(context.Background(), &grpcPort.SearchNearbyRequest{})
+	assert.Equal(suite.t, codes.InvalidArgument, status.Code(err))
+	_, err = client.CreateAd(context.Background(), &grpcPort.CreateAdRequest{UserId: usr.Id, Title: "bike", Text: "red",
+		Location: &grpcPort.Location{Lat: 100}})
+	assert.Equal(suite.t, codes.InvalidArgument, status.Code(err))
+}
+
+// TestGRPCStreamPanicRecovered checks that a panic in a streaming handler
+// fails the call with Internal and leaves the server up.
+func TestGRPCStreamPanicRecovered(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	appmock := mocks.NewMockApp(mockCtrl)
+	appmock.EXPECT().GetUserByID(gomock.Any(), gomock.Any()).AnyTimes().Return(&users.User{ID: 1, Nickname: "Seller"}, nil)
+	appmock.EXPECT().CreateAd(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().DoAndReturn(
+		func(context.Context, string, string, int64, *ads.Location, *ads.Offer) (*ads.Ad, error) {
+			panic("broken repository")
+		})
+
+	cfg := ephemeralConfig()
+	tel, err := ports.NewTelemetry(context.Background(), cfg)
+	require.NoError(t, err)
+	srv, err := ports.NewServer(cfg, appmock, tel)
+	require.NoError(t, err)
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.Run(context.Background()) }()
+	<-srv.Ready()
+	defer func() {
+		srv.Shutdown()
+		require.NoError(t, <-errCh)
+	}()
+
+	conn, err := grpc.DialContext(context.Background(), srv.GRPCAddr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	stream, err := grpcPort.NewAdServiceClient(conn).ImportAds(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&grpcPort.ImportAdsRequest{Item: &grpcPort.ImportAdsRequest_Options{Options: &grpcPort.ImportOptions{UserId: 1}}}))
+	require.NoError(t, stream.Send(&grpcPort.ImportAdsRequest{Item: &grpcPort.ImportAdsRequest_Row{Row: &grpcPort.ImportRow{Title: "bike", Text: "red"}}}))
+	_, err = stream.CloseAndRecv()
+	assert.Equal(t, codes.Internal, status.Code(err))
+
+	res, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, res.Status)
+}
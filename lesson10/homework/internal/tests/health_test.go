@@ -0,0 +1,125 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"homework10/internal/config"
+	"homework10/internal/health"
+	"homework10/internal/ports"
+	"homework10/internal/webhooks"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+func getHealth(t *testing.T, url string) (int, health.Report) {
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var report health.Report
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+	return resp.StatusCode, report
+}
+
+func healthClient(t *testing.T, srv *testServer) healthpb.HealthClient {
+	return healthpb.NewHealthClient(srv.dial(t))
+}
+
+// startServer runs the servers with cfg and returns the telemetry, so tests
+// can register checkers.
+func startServer(t *testing.T, cfg config.Config) (ports.Telemetry, *testServer) {
+	tel, _, srv := startServerWithWebhooks(t, cfg)
+	return tel, srv
+}
+
+// startServerWithWebhooks is startServer that also returns the webhook
+// dispatcher of the app, which is closed with the servers.
+func startServerWithWebhooks(t *testing.T, cfg config.Config) (ports.Telemetry, *webhooks.Dispatcher, *testServer) {
+	tel, err := ports.NewTelemetry(context.Background(), cfg)
+	require.NoError(t, err)
+	hooks := ports.NewWebhooks(cfg, tel.Logger)
+	a, err := ports.NewApp(cfg, tel, hooks)
+	require.NoError(t, err)
+	srv, err := ports.NewServer(cfg, a, tel, ports.WithWebhooks(hooks))
+	require.NoError(t, err)
+	return tel, hooks, serve(t, srv)
+}
+
+func TestHealthEndpoints(t *testing.T) {
+	_, srv := startServer(t, ephemeralConfig())
+
+	resp, err := http.Get(srv.baseURL + "/healthz")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	code, report := getHealth(t, srv.baseURL+"/readyz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, report.Ready)
+	assert.Equal(t, map[string]string{"ads_repository": "ok", "users_repository": "ok"}, report.Checks)
+
+	client := healthClient(t, srv)
+	for _, service := range []string{"", health.Service} {
+		res, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+		require.NoError(t, err)
+		assert.Equal(t, healthpb.HealthCheckResponse_SERVING, res.GetStatus())
+	}
+	_, err = client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "unknown"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestReadinessFailingChecker(t *testing.T) {
+	tel, srv := startServer(t, ephemeralConfig())
+	tel.Health.Register("broken", health.CheckerFunc(func(ctx context.Context) error {
+		return errors.New("connection refused")
+	}))
+
+	code, report := getHealth(t, srv.baseURL+"/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.False(t, report.Ready)
+	assert.Equal(t, "connection refused", report.Checks["broken"])
+	assert.Equal(t, "ok", report.Checks["ads_repository"])
+
+	res, err := healthClient(t, srv).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, res.GetStatus())
+}
+
+func TestReadinessFlipsBeforeShutdown(t *testing.T) {
+	cfg := ephemeralConfig()
+	cfg.DrainDelay = 300 * time.Millisecond
+	_, srv := startServer(t, cfg)
+
+	watchCtx, cancelWatch := context.WithCancel(context.Background())
+	defer cancelWatch()
+	watch, err := healthClient(t, srv).Watch(watchCtx, &healthpb.HealthCheckRequest{Service: health.Service})
+	require.NoError(t, err)
+	res, err := watch.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, res.GetStatus())
+
+	stopped := make(chan struct{})
+	go func() {
+		srv.stop()
+		close(stopped)
+	}()
+
+	res, err = watch.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, res.GetStatus())
+	cancelWatch()
+
+	// the servers still answer while draining
+	code, report := getHealth(t, srv.baseURL+"/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.False(t, report.Ready)
+
+	<-stopped
+}
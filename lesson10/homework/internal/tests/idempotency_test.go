@@ -0,0 +1,245 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"homework10/internal/idempotency"
+	grpcPort "homework10/internal/ports/grpc"
+	"homework10/internal/ports/httpgin"
+	"homework10/pkg/adsclient"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type IdempotencyTestSuite struct {
+	suite.Suite
+	client  *adsclient.Client
+	baseURL string
+	hc      *http.Client
+	srv     *testServer
+}
+
+func (suite *IdempotencyTestSuite) SetupTest() {
+	suite.srv = runServer(suite.T(), ephemeralConfig())
+	suite.baseURL = suite.srv.baseURL
+	suite.hc = suite.srv.client
+	suite.client = suite.srv.api
+}
+
+func (suite *IdempotencyTestSuite) TearDownTest() {
+	suite.srv.stop()
+}
+
+func TestIdempotencyTestSuite(t *testing.T) {
+	suite.Run(t, new(IdempotencyTestSuite))
+}
+
+// adResponse is the envelope of a created ad.
+type adResponse struct {
+	Data adsclient.Ad `json:"data"`
+}
+
+// post sends body with the Idempotency-Key header and decodes the response
+// into out. The raw body lets the tests vary its formatting.
+func (suite *IdempotencyTestSuite) post(path string, key string, body string, out any) *http.Response {
+	req, err := http.NewRequest(http.MethodPost, suite.baseURL+path, strings.NewReader(body))
+	suite.Require().NoError(err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(idempotency.Header, key)
+	resp, err := suite.hc.Do(req)
+	suite.Require().NoError(err)
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	suite.Require().NoError(err)
+	suite.Require().NoError(json.Unmarshal(data, out))
+	return resp
+}
+
+func (suite *IdempotencyTestSuite) TestRetryReplaysResponse() {
+	ctx := context.Background()
+	usr, err := suite.client.CreateUser(ctx, "Alice", "alice@mail.org")
+	suite.Require().NoError(err)
+
+	body := `{"user_id": 0, "title": "bike", "text": "red"}`
+	var first, second adResponse
+	resp := suite.post("/api/v1/ads", "key-1", body, &first)
+	suite.Equal(http.StatusOK, resp.StatusCode)
+	suite.Empty(resp.Header.Get(idempotency.ReplayedHeader))
+
+	// the same payload formatted differently is the same request
+	resp = suite.post("/api/v1/ads", "key-1", `{"user_id":0,"title":"bike","text":"red"}`, &second)
+	suite.Equal(http.StatusOK, resp.StatusCode)
+	suite.Equal("true", resp.Header.Get(idempotency.ReplayedHeader))
+	suite.Equal(first, second)
+
+	ads, err := suite.client.ListAdsByAuthor(ctx, usr.ID)
+	suite.NoError(err)
+	suite.Len(ads, 1)
+}
+
+func (suite *IdempotencyTestSuite) TestKeyReusedWithDifferentPayload() {
+	ctx := context.Background()
+	_, err := suite.client.CreateUser(ctx, "Alice", "alice@mail.org")
+	suite.Require().NoError(err)
+
+	var ad adResponse
+	suite.post("/api/v1/ads", "key-1", `{"user_id": 0, "title": "bike", "text": "red"}`, &ad)
+
+	var p adsclient.Error
+	resp := suite.post("/api/v1/ads", "key-1", `{"user_id": 0, "title": "bike", "text": "blue"}`, &p)
+	suite.Equal(http.StatusUnprocessableEntity, resp.StatusCode)
+	suite.Equal(adsclient.CodeUnprocessable, p.Code)
+}
+
+func (suite *IdempotencyTestSuite) TestKeyIsScopedByUser() {
+	ctx := context.Background()
+	for _, name := range []string{"Alice", "Bob"} {
+		_, err := suite.client.CreateUser(ctx, name, "")
+		suite.Require().NoError(err)
+	}
+
+	var first, second adResponse
+	suite.post("/api/v1/ads", "key-1", `{"user_id": 0, "title": "bike", "text": "red"}`, &first)
+	resp := suite.post("/api/v1/ads", "key-1", `{"user_id": 1, "title": "bike", "text": "red"}`, &second)
+	suite.Equal(http.StatusOK, resp.StatusCode)
+	suite.NotEqual(first.Data.ID, second.Data.ID)
+}
+
+func (suite *IdempotencyTestSuite) TestClientIdempotencyKey() {
+	ctx := context.Background()
+	usr, err := suite.client.CreateUser(ctx, "Alice", "")
+	suite.Require().NoError(err)
+
+	r := adsclient.CreateAdRequest{UserID: usr.ID, Title: "bike", Text: "red"}
+	keyed := adsclient.WithIdempotencyKey(ctx, "key-1")
+	first, err := suite.client.CreateAd(keyed, r)
+	suite.Require().NoError(err)
+	second, err := suite.client.CreateAd(keyed, r)
+	suite.Require().NoError(err)
+	suite.Equal(first, second)
+
+	r.Text = "blue"
+	_, err = suite.client.CreateAd(keyed, r)
+	suite.ErrorIs(err, adsclient.ErrUnprocessable)
+	third, err := suite.client.CreateAd(ctx, r)
+	suite.Require().NoError(err)
+	suite.NotEqual(first.ID, third.ID)
+}
+
+func (suite *IdempotencyTestSuite) TestClientErrorIsReplayed() {
+	var p adsclient.Error
+	resp := suite.post("/api/v1/ads", "key-1", `{"user_id": 0, "title": "bike", "text": "red"}`, &p)
+	suite.Equal(http.StatusNotFound, resp.StatusCode)
+
+	// 4xx responses are stored, retries see the same result
+	resp = suite.post("/api/v1/ads", "key-1", `{"user_id": 0, "title": "bike", "text": "red"}`, &p)
+	suite.Equal(http.StatusNotFound, resp.StatusCode)
+	suite.Equal("true", resp.Header.Get(idempotency.ReplayedHeader))
+}
+
+func (suite *IdempotencyTestSuite) TestLargeAndMultipartBodies() {
+	ctx := context.Background()
+	usr, err := suite.client.CreateUser(ctx, "Alice", "alice@mail.org")
+	suite.Require().NoError(err)
+
+	var p adsclient.Error
+	body := `{"user_id": 0, "title": "bike", "text": "` + strings.Repeat("a", httpgin.IdempotencyMaxBody) + `"}`
+	resp := suite.post("/api/v1/ads", "key-1", body, &p)
+	suite.Equal(http.StatusRequestEntityTooLarge, resp.StatusCode)
+	suite.Equal(adsclient.CodeTooLarge, p.Code)
+
+	// multipart bodies are not buffered, every import is executed
+	for i := 0; i < 2; i++ {
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		suite.Require().NoError(mw.WriteField("user_id", strconv.FormatInt(usr.ID, 10)))
+		fw, err := mw.CreateFormFile("file", "ads.csv")
+		suite.Require().NoError(err)
+		_, err = io.WriteString(fw, "title,text\nbike,red\n")
+		suite.Require().NoError(err)
+		suite.Require().NoError(mw.Close())
+		req, err := http.NewRequest(http.MethodPost, suite.baseURL+"/api/v1/ads/import", &buf)
+		suite.Require().NoError(err)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		req.Header.Set(idempotency.Header, "key-2")
+		resp, err := suite.hc.Do(req)
+		suite.Require().NoError(err)
+		resp.Body.Close()
+		suite.Equal(http.StatusOK, resp.StatusCode)
+		suite.Empty(resp.Header.Get(idempotency.ReplayedHeader))
+	}
+	ads, err := suite.client.ListAdsByAuthor(ctx, usr.ID)
+	suite.NoError(err)
+	suite.Len(ads, 2)
+}
+
+func (suite *IdempotencyTestSuite) TestGRPCIdempotencyKey() {
+	conn, err := grpc.DialContext(context.Background(), suite.srv.grpcAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	suite.Require().NoError(err)
+	defer conn.Close()
+	client := grpcPort.NewAdServiceClient(conn)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "idempotency-key", "key-1")
+	first, err := client.CreateUser(ctx, &grpcPort.CreateUserRequest{Name: "Oleg"})
+	suite.Require().NoError(err)
+	var header metadata.MD
+	second, err := client.CreateUser(ctx, &grpcPort.CreateUserRequest{Name: "Oleg"}, grpc.Header(&header))
+	suite.Require().NoError(err)
+	suite.Equal(first.GetId(), second.GetId())
+	suite.Equal([]string{"true"}, header.Get("idempotent-replayed"))
+
+	_, err = client.CreateUser(ctx, &grpcPort.CreateUserRequest{Name: "Olga"})
+	suite.Equal(codes.FailedPrecondition, status.Code(err))
+
+	other, err := client.CreateUser(context.Background(), &grpcPort.CreateUserRequest{Name: "Oleg"})
+	suite.Require().NoError(err)
+	suite.NotEqual(first.GetId(), other.GetId())
+}
+
+func TestIdempotencyStore(t *testing.T) {
+	now := time.Now()
+	s := idempotency.New(time.Minute, idempotency.WithClock(func() time.Time { return now }))
+	key := idempotency.Key("key-1", "POST /ads", "0")
+	fp := idempotency.Fingerprint([]byte(`{"a": 1}`))
+
+	_, found, err := s.Begin(key, fp)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	_, _, err = s.Begin(key, fp)
+	assert.ErrorIs(t, err, idempotency.ErrInProgress)
+
+	s.Complete(key, "response")
+	resp, found, err := s.Begin(key, fp)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "response", resp)
+
+	_, _, err = s.Begin(key, idempotency.Fingerprint([]byte(`{"a": 2}`)))
+	assert.ErrorIs(t, err, idempotency.ErrKeyReused)
+
+	now = now.Add(time.Minute)
+	_, found, err = s.Begin(key, idempotency.Fingerprint([]byte(`{"a": 2}`)))
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	s.Release(key)
+	_, found, err = s.Begin(key, fp)
+	require.NoError(t, err)
+	assert.False(t, found)
+}
@@ -0,0 +1,265 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"homework10/internal/config"
+	"homework10/internal/lifecycle"
+	"homework10/internal/ports"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// recorder is a component writing its starts and stops to a shared log.
+type recorder struct {
+	name     string
+	mu       *sync.Mutex
+	log      *[]string
+	startErr error
+	// block makes Stop wait for its context
+	block bool
+	fail  func(error)
+}
+
+func (r *recorder) Start(_ context.Context, fail func(error)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	*r.log = append(*r.log, "start "+r.name)
+	r.fail = fail
+	return r.startErr
+}
+
+func (r *recorder) Stop(ctx context.Context) error {
+	if r.block {
+		<-ctx.Done()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	*r.log = append(*r.log, "stop "+r.name)
+	return ctx.Err()
+}
+
+type recorders struct {
+	mu  sync.Mutex
+	log []string
+}
+
+func (rs *recorders) new(name string) *recorder {
+	return &recorder{name: name, mu: &rs.mu, log: &rs.log}
+}
+
+func (rs *recorders) entries() []string {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	return append([]string(nil), rs.log...)
+}
+
+func runManager(m *lifecycle.Manager) chan error {
+	errCh := make(chan error, 1)
+	go func() { errCh <- m.Run(context.Background()) }()
+	return errCh
+}
+
+func TestManagerStopsInReverseOrder(t *testing.T) {
+	var rs recorders
+	var readiness []bool
+	m := lifecycle.New(lifecycle.WithReadiness(func(ready bool) { readiness = append(readiness, ready) }))
+	for _, name := range []string{"a", "b", "c"} {
+		m.Add(name, rs.new(name))
+	}
+	errCh := runManager(m)
+	<-m.Ready()
+	assert.Equal(t, []string{"start a", "start b", "start c"}, rs.entries())
+
+	m.Shutdown()
+	require.NoError(t, <-errCh)
+	<-m.Done()
+	assert.Equal(t, []string{"start a", "start b", "start c", "stop c", "stop b", "stop a"}, rs.entries())
+	assert.Equal(t, []bool{true, false}, readiness)
+	assert.ErrorIs(t, m.Run(context.Background()), lifecycle.ErrAlreadyRun)
+}
+
+func TestManagerStartFailure(t *testing.T) {
+	var rs recorders
+	boom := errors.New("boom")
+	ready := false
+	m := lifecycle.New(lifecycle.WithReadiness(func(r bool) { ready = r }))
+	m.Add("a", rs.new("a"))
+	b := rs.new("b")
+	b.startErr = boom
+	m.Add("b", b)
+	m.Add("c", rs.new("c"))
+
+	err := m.Run(context.Background())
+	assert.ErrorIs(t, err, boom)
+	var lerr *lifecycle.Error
+	require.ErrorAs(t, err, &lerr)
+	assert.Equal(t, "b", lerr.Component)
+	assert.Equal(t, lifecycle.PhaseStart, lerr.Phase)
+	assert.Equal(t, []string{"start a", "start b", "stop a"}, rs.entries())
+	assert.False(t, ready)
+	select {
+	case <-m.Ready():
+		t.Fatal("ready after a failed start")
+	default:
+	}
+}
+
+func TestManagerRunFailure(t *testing.T) {
+	var rs recorders
+	boom := errors.New("boom")
+	m := lifecycle.New(lifecycle.WithDrain(time.Hour))
+	a, b := rs.new("a"), rs.new("b")
+	m.Add("a", a)
+	m.Add("b", b)
+	errCh := runManager(m)
+	<-m.Ready()
+
+	// a failed component skips the drain
+	a.fail(boom)
+	select {
+	case err := <-errCh:
+		var lerr *lifecycle.Error
+		require.ErrorAs(t, err, &lerr)
+		assert.Equal(t, lifecycle.Error{Component: "a", Phase: lifecycle.PhaseRun, Err: boom}, *lerr)
+	case <-time.After(5 * time.Second):
+		t.Fatal("manager didn't stop after a failure")
+	}
+	assert.Equal(t, []string{"start a", "start b", "stop b", "stop a"}, rs.entries())
+}
+
+func TestManagerShutdownDeadline(t *testing.T) {
+	var rs recorders
+	m := lifecycle.New(lifecycle.WithShutdownTimeout(50 * time.Millisecond))
+	m.Add("a", rs.new("a"))
+	b := rs.new("b")
+	b.block = true
+	m.Add("b", b)
+	m.Add("c", rs.new("c"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() { errCh <- m.Run(ctx) }()
+	<-m.Ready()
+	cancel()
+
+	select {
+	case err := <-errCh:
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		var lerr *lifecycle.Error
+		require.ErrorAs(t, err, &lerr)
+		assert.Equal(t, "b", lerr.Component)
+		assert.Equal(t, lifecycle.PhaseStop, lerr.Phase)
+	case <-time.After(5 * time.Second):
+		t.Fatal("shutdown deadline not honored")
+	}
+	// the components after the late one are still stopped
+	assert.Equal(t, []string{"start a", "start b", "start c", "stop c", "stop b", "stop a"}, rs.entries())
+}
+
+// ephemeralConfig binds all servers to free ports.
+func ephemeralConfig() config.Config {
+	cfg := config.Default()
+	cfg.HTTP.Addr = "localhost:0"
+	cfg.GRPC.Addr = "localhost:0"
+	cfg.Metrics.Addr = "localhost:0"
+	return cfg
+}
+
+func newServer(t testing.TB, cfg config.Config, opts ...ports.ServerOption) (*ports.Server, error) {
+	tel, err := ports.NewTelemetry(context.Background(), cfg)
+	require.NoError(t, err)
+	hooks := ports.NewWebhooks(cfg, tel.Logger)
+	a, err := ports.NewApp(cfg, tel, hooks)
+	require.NoError(t, err)
+	return ports.NewServer(cfg, a, tel, append(opts, ports.WithWebhooks(hooks))...)
+}
+
+func TestServersOnEphemeralPorts(t *testing.T) {
+	t.Parallel()
+	for i := 0; i < 2; i++ {
+		t.Run("server", func(t *testing.T) {
+			t.Parallel()
+			srv, err := newServer(t, ephemeralConfig())
+			require.NoError(t, err)
+			errCh := make(chan error, 1)
+			go func() { errCh <- srv.Run(context.Background()) }()
+			<-srv.Ready()
+
+			code, report := getHealth(t, "http://"+srv.HTTPAddr().String()+"/readyz")
+			assert.Equal(t, http.StatusOK, code)
+			assert.True(t, report.Ready)
+
+			resp, err := http.Get("http://" + srv.MetricsAddr().String() + "/metrics")
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+			conn, err := grpc.DialContext(context.Background(), srv.GRPCAddr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+			require.NoError(t, err)
+			defer conn.Close()
+			res, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+			require.NoError(t, err)
+			assert.Equal(t, healthpb.HealthCheckResponse_SERVING, res.Status)
+
+			srv.Shutdown()
+			require.NoError(t, <-errCh)
+			_, err = http.Get("http://" + srv.HTTPAddr().String() + "/healthz")
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestServerWithListeners(t *testing.T) {
+	t.Parallel()
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	srv, err := newServer(t, ephemeralConfig(), ports.WithHTTPListener(lis))
+	require.NoError(t, err)
+	assert.Equal(t, lis.Addr(), srv.HTTPAddr())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.Run(ctx) }()
+	<-srv.Ready()
+	resp, err := http.Get("http://" + lis.Addr().String() + "/healthz")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	cancel()
+	require.NoError(t, <-errCh)
+}
+
+func TestServerAddressInUse(t *testing.T) {
+	t.Parallel()
+	taken, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	defer taken.Close()
+
+	cfg := ephemeralConfig()
+	cfg.HTTP.Addr = taken.Addr().String()
+	_, err = newServer(t, cfg)
+	assert.ErrorIs(t, err, syscall.EADDRINUSE)
+}
+
+func TestServerAdminConsoleError(t *testing.T) {
+	t.Parallel()
+	cfg := ephemeralConfig()
+	cfg.Admin.StaffFile = filepath.Join(t.TempDir(), "missing.json")
+	_, err := newServer(t, cfg)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	assert.Contains(t, err.Error(), "admin console")
+}
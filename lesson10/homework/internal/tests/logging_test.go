@@ -0,0 +1,92 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"homework10/internal/ads"
+	"homework10/internal/app"
+	"homework10/internal/logging"
+	"homework10/internal/ports"
+	grpcPort "homework10/internal/ports/grpc"
+	"homework10/internal/tests/mocks"
+	"homework10/internal/users"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestRequestIDReachesRepository(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var seen []string
+	record := func(ctx context.Context) { seen = append(seen, logging.RequestID(ctx)) }
+
+	adrepo := mocks.NewMockAdRepository(mockCtrl)
+	usrrepo := mocks.NewMockUserRepository(mockCtrl)
+	usrrepo.EXPECT().GetUserByID(gomock.Any(), gomock.Any()).AnyTimes().DoAndReturn(
+		func(ctx context.Context, ID int64) (*users.User, error) {
+			record(ctx)
+			return &users.User{ID: ID}, nil
+		})
+	adrepo.EXPECT().AppendAd(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().DoAndReturn(
+		func(ctx context.Context, Title string, Text string, AuthorID int64, Location *ads.Location, Offer *ads.Offer) (*ads.Ad, error) {
+			record(ctx)
+			return &ads.Ad{Title: Title, Text: Text, AuthorID: AuthorID}, nil
+		})
+
+	var logs bytes.Buffer
+	cfg := ephemeralConfig()
+	a := app.NewApp(adrepo, usrrepo, app.WithLogger(logging.New(cfg, &logs)))
+
+	tel, err := ports.NewTelemetry(context.Background(), cfg)
+	assert.NoError(t, err)
+	srv := runAppServer(t, cfg, a, tel)
+
+	body := strings.NewReader(`{"user_id": 1, "title": "title", "text": "text"}`)
+	req, err := http.NewRequest(http.MethodPost, srv.baseURL+"/api/v1/ads", body)
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(logging.RequestIDHeader, "req-42")
+	resp, err := srv.client.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "req-42", resp.Header.Get(logging.RequestIDHeader))
+	assert.Equal(t, []string{"req-42", "req-42"}, seen)
+
+	var entry map[string]any
+	assert.NoError(t, json.Unmarshal(logs.Bytes(), &entry))
+	assert.Equal(t, "ad created", entry["msg"])
+	assert.Equal(t, "req-42", entry["request_id"])
+}
+
+func TestRequestIDGenerated(t *testing.T) {
+	ctx := context.Background()
+	srv := runServer(t, ephemeralConfig())
+
+	resp, err := http.Get(srv.baseURL + "/api/v1/ads")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.NotEmpty(t, resp.Header.Get(logging.RequestIDHeader))
+
+	client := grpcPort.NewAdServiceClient(srv.dial(t))
+
+	var header metadata.MD
+	_, err = client.CreateUser(ctx, &grpcPort.CreateUserRequest{Name: "Oleg"}, grpc.Header(&header))
+	assert.NoError(t, err)
+	assert.Len(t, header.Get("x-request-id"), 1)
+	assert.NotEmpty(t, header.Get("x-request-id")[0])
+
+	mdCtx := metadata.AppendToOutgoingContext(ctx, "x-request-id", "grpc-7")
+	_, err = client.CreateUser(mdCtx, &grpcPort.CreateUserRequest{Name: "Oleg"}, grpc.Header(&header))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"grpc-7"}, header.Get("x-request-id"))
+}
@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"context"
+	"homework10/pkg/adsclient"
+	"io"
+	"net/http"
+	"testing"
+
+	grpcPort "homework10/internal/ports/grpc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+type MetricsTestSuite struct {
+	suite.Suite
+	srv    *testServer
+	client *adsclient.Client
+}
+
+func (suite *MetricsTestSuite) SetupTest() {
+	suite.srv = runServer(suite.T(), ephemeralConfig())
+	suite.client = suite.srv.api
+}
+
+func (suite *MetricsTestSuite) TearDownTest() {
+	suite.srv.stop()
+}
+
+func TestMetricsTestSuite(t *testing.T) {
+	suite.Run(t, new(MetricsTestSuite))
+}
+
+func (suite *MetricsTestSuite) scrape() string {
+	resp, err := http.Get("http://" + suite.srv.metricsAddr + "/metrics")
+	suite.Require().NoError(err)
+	defer resp.Body.Close()
+	suite.Equal(http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	suite.Require().NoError(err)
+	return string(body)
+}
+
+func (suite *MetricsTestSuite) TestRequestMetrics() {
+	ctx := context.Background()
+	usr, err := suite.client.CreateUser(ctx, "Oleg", "oleg@mail.ru")
+	suite.Require().NoError(err)
+	_, err = suite.client.GetAd(ctx, 42)
+	suite.ErrorIs(err, adsclient.ErrNotFound)
+
+	conn, err := grpc.DialContext(context.Background(), suite.srv.grpcAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	suite.Require().NoError(err)
+	defer conn.Close()
+	_, err = grpcPort.NewAdServiceClient(conn).GetUser(context.Background(), &grpcPort.GetUserRequest{Id: usr.ID})
+	suite.Require().NoError(err)
+
+	body := suite.scrape()
+	assert.Contains(suite.T(), body, `ads_http_requests_total{method="POST",route="/api/v1/users",status="200"} 1`)
+	assert.Contains(suite.T(), body, `ads_http_requests_total{method="GET",route="/api/v1/ads/:id",status="404"} 1`)
+	assert.Contains(suite.T(), body, `ads_http_request_duration_seconds_count{method="POST",route="/api/v1/users"} 1`)
+	assert.Contains(suite.T(), body, `ads_grpc_requests_total{code="OK",method="/ad.AdService/GetUser"} 1`)
+	assert.Contains(suite.T(), body, `ads_grpc_request_duration_seconds_count{method="/ad.AdService/GetUser"} 1`)
+}
+
+func (suite *MetricsTestSuite) TestDomainMetrics() {
+	ctx := context.Background()
+	usr, err := suite.client.CreateUser(ctx, "Oleg", "oleg@mail.ru")
+	suite.Require().NoError(err)
+	_, err = suite.client.CreateUser(ctx, "Anna", "anna@mail.ru")
+	suite.Require().NoError(err)
+	first, err := suite.client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: usr.ID, Title: "first", Text: "text"})
+	suite.Require().NoError(err)
+	second, err := suite.client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: usr.ID, Title: "second", Text: "text"})
+	suite.Require().NoError(err)
+	_, err = suite.client.ChangeAdStatus(ctx, first.ID, usr.ID, true)
+	suite.Require().NoError(err)
+	_, err = suite.client.ChangeAdStatus(ctx, first.ID, usr.ID, true)
+	suite.Require().NoError(err)
+	_, err = suite.client.ChangeAdStatus(ctx, second.ID, usr.ID, true)
+	suite.Require().NoError(err)
+	_, err = suite.client.DeleteAd(ctx, second.ID, usr.ID)
+	suite.Require().NoError(err)
+
+	body := suite.scrape()
+	assert.Contains(suite.T(), body, "\nads_users 2\n")
+	assert.Contains(suite.T(), body, "\nads_ads 1\n")
+	assert.Contains(suite.T(), body, "\nads_published_ads 1\n")
+	assert.Contains(suite.T(), body, "\nads_ads_created_total 2\n")
+	assert.Contains(suite.T(), body, `ads_repository_lock_wait_seconds_count{repository="ads"}`)
+	assert.Contains(suite.T(), body, `ads_repository_lock_wait_seconds_count{repository="users"}`)
+}
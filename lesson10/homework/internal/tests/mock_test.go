@@ -7,6 +7,7 @@ import (
 	"homework10/internal/ports"
 	"homework10/internal/tests/mocks"
 	"homework10/internal/users"
+	"homework10/pkg/adsclient"
 	"testing"
 	"time"
 
@@ -22,64 +23,67 @@ func TestAppWithRepoMock(t *testing.T) {
 	usrrepo := mocks.NewMockUserRepository(mockCtrl)
 
 	testad := &ads.Ad{Title: "Title", Text: "Text", AuthorID: 0}
-	adrepo.EXPECT().AppendAd(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().Return(testad)
-	adrepo.EXPECT().ChangeAdStatus(gomock.Any(), gomock.Any()).AnyTimes()
-	adrepo.EXPECT().GetAdByID(gomock.Any()).AnyTimes().Return(testad, nil)
-	adrepo.EXPECT().DeleteAd(gomock.Any()).Return(testad, nil).AnyTimes()
-	adrepo.EXPECT().UpdateAd(gomock.Any(), gomock.Any(), gomock.Any())
-	adrepo.EXPECT().Select(gomock.Any()).AnyTimes().Return([]ads.Ad{*testad})
+	adrepo.EXPECT().AppendAd(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().Return(testad, nil)
+	adrepo.EXPECT().ChangeAdStatus(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().Return(nil)
+	adrepo.EXPECT().GetAdByID(gomock.Any(), gomock.Any()).AnyTimes().Return(testad, nil)
+	adrepo.EXPECT().DeleteAd(gomock.Any(), gomock.Any()).Return(testad, nil).AnyTimes()
+	adrepo.EXPECT().UpdateAd(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+	adrepo.EXPECT().Select(gomock.Any(), gomock.Any()).AnyTimes().Return([]ads.Ad{*testad}, nil)
 
 	testusr := &users.User{ID: 0, Nickname: "Test Subject", Email: "glados@aparture.com"}
-	usrrepo.EXPECT().AppendUser(gomock.Any(), gomock.Any()).AnyTimes().Return(testusr)
-	usrrepo.EXPECT().DeleteUser(gomock.Any()).Return(testusr, nil)
-	usrrepo.EXPECT().GetUserByID(gomock.Any()).Return(testusr, nil).AnyTimes()
-	usrrepo.EXPECT().UpdateUser(gomock.Any(), gomock.Any(), gomock.Any())
+	usrrepo.EXPECT().AppendUser(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().Return(testusr, nil)
+	usrrepo.EXPECT().DeleteUser(gomock.Any(), gomock.Any()).Return(testusr, nil)
+	usrrepo.EXPECT().GetUserByID(gomock.Any(), gomock.Any()).Return(testusr, nil).AnyTimes()
+	usrrepo.EXPECT().UpdateUser(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
 
+	ctx := context.Background()
 	a := app.NewApp(adrepo, usrrepo)
-	usr := a.CreateUser("Chell", "chell@mail.org")
+	usr, err := a.CreateUser(ctx, "Chell", "chell@mail.org")
+	assert.NoError(t, err)
 	assert.Equal(t, usr.ID, testusr.ID)
 	assert.Equal(t, usr.Nickname, testusr.Nickname)
 	assert.Equal(t, usr.Email, testusr.Email)
 
-	usr, err := a.DeleteUser(usr.ID)
+	usr, err = a.DeleteUser(ctx, usr.ID)
 	assert.NoError(t, err)
 	assert.Equal(t, usr.ID, testusr.ID)
 
-	usr, err = a.GetUserByID(usr.ID)
+	usr, err = a.GetUserByID(ctx, usr.ID)
 	assert.NoError(t, err)
 	assert.Equal(t, usr.ID, testusr.ID)
 
-	usr, err = a.UpdateUser(usr.ID, "Jane", "Email")
+	usr, err = a.UpdateUser(ctx, usr.ID, "Jane", "Email")
 	assert.NoError(t, err)
 	assert.Equal(t, usr.ID, testusr.ID)
 	assert.Equal(t, usr.Nickname, testusr.Nickname)
 	assert.Equal(t, usr.Email, testusr.Email)
 
-	ad, err := a.CreateAd("NotTitle", "NotText", usr.ID)
+	ad, err := a.CreateAd(ctx, "NotTitle", "NotText", usr.ID, nil, nil)
 	assert.NoError(t, err)
 	assert.Equal(t, ad.ID, testad.ID)
 	assert.Equal(t, ad.Title, testad.Title)
 	assert.Equal(t, ad.Text, testad.Text)
 
-	ad, err = a.GetAdByID(ad.ID)
+	ad, err = a.GetAdByID(ctx, ad.ID)
 	assert.NoError(t, err)
 	assert.Equal(t, ad.ID, testad.ID)
 
-	adarr := a.Select()
+	adarr, err := a.Select(ctx)
+	assert.NoError(t, err)
 	assert.Len(t, adarr, 1)
 
-	_, err = a.DeleteAd(testad.ID, 9)
+	_, err = a.DeleteAd(ctx, testad.ID, 9)
 	assert.Error(t, err)
 
-	ad, err = a.DeleteAd(testad.ID, usr.ID)
+	ad, err = a.DeleteAd(ctx, testad.ID, usr.ID)
 	assert.NoError(t, err)
 	assert.Equal(t, ad.ID, testad.ID)
 
-	ad, err = a.UpdateAd(ad.ID, ad.AuthorID, "a", "b")
+	ad, err = a.UpdateAd(ctx, ad.ID, ad.AuthorID, "a", "b", nil, nil)
 	assert.NoError(t, err)
 	assert.Equal(t, ad.ID, testad.ID)
 
-	ad, err = a.ChangeAdStatus(ad.ID, ad.AuthorID, true)
+	ad, err = a.ChangeAdStatus(ctx, ad.ID, ad.AuthorID, true)
 	assert.NoError(t, err)
 	assert.Equal(t, ad.ID, testad.ID)
 }
@@ -92,99 +96,98 @@ func TestHandlerWithAppMock(t *testing.T) {
 	testusr := &users.User{ID: 0, Nickname: "Test Subject", Email: "glados@aparture.com"}
 
 	appmock := mocks.NewMockApp(mockCtrl)
-	appmock.EXPECT().ChangeAdStatus(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().DoAndReturn(
-		func(ID int64, AuthorID int64, status bool) (*ads.Ad, error) {
+	appmock.EXPECT().ChangeAdStatus(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().DoAndReturn(
+		func(_ context.Context, ID int64, AuthorID int64, status bool) (*ads.Ad, error) {
 			if AuthorID != 0 {
-				return &ads.Ad{}, ErrForbidden
+				return &ads.Ad{}, app.ErrForbidden
 			}
 			testad.Published = status
 			return testad, nil
 		})
-	appmock.EXPECT().CreateAd(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().Return(testad, nil)
-	appmock.EXPECT().CreateUser(gomock.Any(), gomock.Any()).AnyTimes().Return(testusr)
-	appmock.EXPECT().DeleteAd(gomock.Any(), gomock.Any()).AnyTimes().DoAndReturn(func(ID int64, AuthorID int64) (*ads.Ad, error) {
+	appmock.EXPECT().CreateAd(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().Return(testad, nil)
+	appmock.EXPECT().CreateUser(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().Return(testusr, nil)
+	appmock.EXPECT().DeleteAd(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().DoAndReturn(func(_ context.Context, ID int64, AuthorID int64) (*ads.Ad, error) {
 		if AuthorID != 0 {
-			return &ads.Ad{}, ErrForbidden
+			return &ads.Ad{}, app.ErrForbidden
 		}
 		return testad, nil
 	})
-	appmock.EXPECT().DeleteUser(gomock.Any()).AnyTimes().DoAndReturn(func(ID int64) (*users.User, error) {
+	appmock.EXPECT().DeleteUser(gomock.Any(), gomock.Any()).AnyTimes().DoAndReturn(func(_ context.Context, ID int64) (*users.User, error) {
 		if ID != testusr.ID {
-			return &users.User{}, ErrNotFound
+			return &users.User{}, app.ErrNotFound
 		}
 		return testusr, nil
 	})
-	appmock.EXPECT().GetAdByID(gomock.Any()).AnyTimes().Return(testad, nil)
-	appmock.EXPECT().GetUserByID(gomock.Any()).AnyTimes().Return(testusr, nil)
-	appmock.EXPECT().UpdateAd(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().Return(testad, nil)
-	appmock.EXPECT().UpdateUser(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().Return(testusr, nil)
-
-	appmock.EXPECT().Select().AnyTimes().Return([]ads.Ad{*testad})
-	appmock.EXPECT().SelectAll().AnyTimes().Return([]ads.Ad{*testad})
-	appmock.EXPECT().SelectByAuthor(gomock.Any()).AnyTimes().Return([]ads.Ad{*testad}, nil)
-	appmock.EXPECT().SelectByCreation(gomock.Any()).AnyTimes().Return([]ads.Ad{*testad})
+	appmock.EXPECT().GetAdByID(gomock.Any(), gomock.Any()).AnyTimes().Return(testad, nil)
+	appmock.EXPECT().GetUserByID(gomock.Any(), gomock.Any()).AnyTimes().Return(testusr, nil)
+	appmock.EXPECT().UpdateAd(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().Return(testad, nil)
+	appmock.EXPECT().UpdateUser(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().Return(testusr, nil)
 
-	ctx, cf := context.WithCancel(context.Background())
-	endChan := make(chan int)
-	hsrv, _ := ports.CreateServerWithExternalApp(ctx, endChan, appmock)
+	appmock.EXPECT().Select(gomock.Any()).AnyTimes().Return([]ads.Ad{*testad}, nil)
+	appmock.EXPECT().SelectAll(gomock.Any()).AnyTimes().Return([]ads.Ad{*testad}, nil)
+	appmock.EXPECT().SelectByAuthor(gomock.Any(), gomock.Any()).AnyTimes().Return([]ads.Ad{*testad}, nil)
+	appmock.EXPECT().SelectByCreation(gomock.Any(), gomock.Any()).AnyTimes().Return([]ads.Ad{*testad}, nil)
+	appmock.EXPECT().AdsModified(gomock.Any()).AnyTimes().Return(time.Now())
 
-	client := getTestClient(hsrv.Addr)
-	resp, err := client.createUser("Alice", "alice.doe@gmail.com")
+	ctx := context.Background()
+	cfg := ephemeralConfig()
+	tel, err := ports.NewTelemetry(ctx, cfg)
 	assert.NoError(t, err)
-	assert.Equal(t, resp.Data.ID, testusr.ID)
-	assert.Equal(t, resp.Data.Nickname, testusr.Nickname)
+	client := runAppServer(t, cfg, appmock, tel).api
 
-	resp, err = client.GetUserByID(12)
+	resp, err := client.CreateUser(ctx, "Alice", "alice.doe@gmail.com")
 	assert.NoError(t, err)
-	assert.Equal(t, resp.Data.ID, testusr.ID)
+	assert.Equal(t, resp.ID, testusr.ID)
+	assert.Equal(t, resp.Nickname, testusr.Nickname)
 
-	resp, err = client.DeleteUser(0)
+	resp, err = client.GetUser(ctx, 12)
 	assert.NoError(t, err)
-	assert.Equal(t, resp.Data.ID, testusr.ID)
+	assert.Equal(t, resp.ID, testusr.ID)
 
-	ad, err := client.createAd(0, "some", "some")
+	resp, err = client.DeleteUser(ctx, 0)
 	assert.NoError(t, err)
-	assert.Equal(t, ad.Data.ID, testad.ID)
-	assert.Equal(t, ad.Data.Title, testad.Title)
+	assert.Equal(t, resp.ID, testusr.ID)
 
-	ad, err = client.changeAdStatus(ad.Data.AuthorID, ad.Data.ID, true)
+	ad, err := client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: 0, Title: "some", Text: "some"})
 	assert.NoError(t, err)
-	assert.Equal(t, ad.Data.Published, true)
+	assert.Equal(t, ad.ID, testad.ID)
+	assert.Equal(t, ad.Title, testad.Title)
 
-	ad, err = client.updateAd(0, 0, "title", "text")
+	ad, err = client.ChangeAdStatus(ctx, ad.ID, ad.AuthorID, true)
 	assert.NoError(t, err)
-	assert.Equal(t, ad.Data.ID, testad.ID)
-	assert.Equal(t, ad.Data.Title, testad.Title)
+	assert.Equal(t, ad.Published, true)
 
-	ad, err = client.getAd(0)
+	ad, err = client.UpdateAd(ctx, 0, adsclient.UpdateAdRequest{UserID: 0, Title: "title", Text: "text"})
 	assert.NoError(t, err)
-	assert.Equal(t, ad.Data.ID, testad.ID)
+	assert.Equal(t, ad.ID, testad.ID)
+	assert.Equal(t, ad.Title, testad.Title)
 
-	ad, err = client.DeleteAd(0, 0)
+	ad, err = client.GetAd(ctx, 0)
 	assert.NoError(t, err)
-	assert.Equal(t, ad.Data.ID, testad.ID)
+	assert.Equal(t, ad.ID, testad.ID)
 
-	resp, err = client.updateUser(0, "Free", "Now")
+	ad, err = client.DeleteAd(ctx, 0, 0)
 	assert.NoError(t, err)
-	assert.Equal(t, resp.Data.ID, testusr.ID)
-	assert.Equal(t, resp.Data.Nickname, testusr.Nickname)
+	assert.Equal(t, ad.ID, testad.ID)
 
-	adsArr, err := client.listAds(nil)
+	resp, err = client.UpdateUser(ctx, 0, "Free", "Now")
 	assert.NoError(t, err)
-	assert.Len(t, adsArr.Data, 1)
+	assert.Equal(t, resp.ID, testusr.ID)
+	assert.Equal(t, resp.Nickname, testusr.Nickname)
 
-	adsArr, err = client.listAll()
+	adsArr, err := client.ListAds(ctx)
 	assert.NoError(t, err)
-	assert.Len(t, adsArr.Data, 1)
+	assert.Len(t, adsArr, 1)
 
-	adsArr, err = client.listAdsByAuthor(0)
+	adsArr, err = client.ListAllAds(ctx)
 	assert.NoError(t, err)
-	assert.Len(t, adsArr.Data, 1)
+	assert.Len(t, adsArr, 1)
 
-	adsArr, err = client.listAdsByTime(time.Now())
+	adsArr, err = client.ListAdsByAuthor(ctx, 0)
 	assert.NoError(t, err)
-	assert.Len(t, adsArr.Data, 1)
+	assert.Len(t, adsArr, 1)
 
-	cf()
-	<-endChan
+	adsArr, err = client.ListAdsCreatedAfter(ctx, time.Now())
+	assert.NoError(t, err)
+	assert.Len(t, adsArr, 1)
 }
@@ -5,7 +5,10 @@
 package mocks
 
 import (
+	context "context"
 	ads "homework10/internal/ads"
+	app "homework10/internal/app"
+	geo "homework10/internal/geo"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -35,83 +38,118 @@ func (m *MockAdRepository) EXPECT() *MockAdRepositoryMockRecorder {
 }
 
 // AppendAd mocks base method.
-func (m *MockAdRepository) AppendAd(arg0, arg1 string, arg2 int64) *ads.Ad {
+func (m *MockAdRepository) AppendAd(arg0 context.Context, arg1, arg2 string, arg3 int64, arg4 *ads.Location, arg5 *ads.Offer) (*ads.Ad, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "AppendAd", arg0, arg1, arg2)
+	ret := m.ctrl.Call(m, "AppendAd", arg0, arg1, arg2, arg3, arg4, arg5)
 	ret0, _ := ret[0].(*ads.Ad)
-	return ret0
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // AppendAd indicates an expected call of AppendAd.
-func (mr *MockAdRepositoryMockRecorder) AppendAd(arg0, arg1, arg2 interface{}) *gomock.Call {
+func (mr *MockAdRepositoryMockRecorder) AppendAd(arg0, arg1, arg2, arg3, arg4, arg5 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AppendAd", reflect.TypeOf((*MockAdRepository)(nil).AppendAd), arg0, arg1, arg2)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AppendAd", reflect.TypeOf((*MockAdRepository)(nil).AppendAd), arg0, arg1, arg2, arg3, arg4, arg5)
 }
 
 // ChangeAdStatus mocks base method.
-func (m *MockAdRepository) ChangeAdStatus(arg0 int64, arg1 bool) {
+func (m *MockAdRepository) ChangeAdStatus(arg0 context.Context, arg1 int64, arg2 bool) error {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "ChangeAdStatus", arg0, arg1)
+	ret := m.ctrl.Call(m, "ChangeAdStatus", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
 // ChangeAdStatus indicates an expected call of ChangeAdStatus.
-func (mr *MockAdRepositoryMockRecorder) ChangeAdStatus(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockAdRepositoryMockRecorder) ChangeAdStatus(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeAdStatus", reflect.TypeOf((*MockAdRepository)(nil).ChangeAdStatus), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeAdStatus", reflect.TypeOf((*MockAdRepository)(nil).ChangeAdStatus), arg0, arg1, arg2)
 }
 
 // DeleteAd mocks base method.
-func (m *MockAdRepository) DeleteAd(arg0 int64) (*ads.Ad, error) {
+func (m *MockAdRepository) DeleteAd(arg0 context.Context, arg1 int64) (*ads.Ad, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteAd", arg0)
+	ret := m.ctrl.Call(m, "DeleteAd", arg0, arg1)
 	ret0, _ := ret[0].(*ads.Ad)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // DeleteAd indicates an expected call of DeleteAd.
-func (mr *MockAdRepositoryMockRecorder) DeleteAd(arg0 interface{}) *gomock.Call {
+func (mr *MockAdRepositoryMockRecorder) DeleteAd(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAd", reflect.TypeOf((*MockAdRepository)(nil).DeleteAd), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAd", reflect.TypeOf((*MockAdRepository)(nil).DeleteAd), arg0, arg1)
 }
 
 // GetAdByID mocks base method.
-func (m *MockAdRepository) GetAdByID(arg0 int64) (*ads.Ad, error) {
+func (m *MockAdRepository) GetAdByID(arg0 context.Context, arg1 int64) (*ads.Ad, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAdByID", arg0)
+	ret := m.ctrl.Call(m, "GetAdByID", arg0, arg1)
 	ret0, _ := ret[0].(*ads.Ad)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetAdByID indicates an expected call of GetAdByID.
-func (mr *MockAdRepositoryMockRecorder) GetAdByID(arg0 interface{}) *gomock.Call {
+func (mr *MockAdRepositoryMockRecorder) GetAdByID(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAdByID", reflect.TypeOf((*MockAdRepository)(nil).GetAdByID), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAdByID", reflect.TypeOf((*MockAdRepository)(nil).GetAdByID), arg0, arg1)
 }
 
 // Select mocks base method.
-func (m *MockAdRepository) Select(arg0 func(ads.Ad) bool) []ads.Ad {
+func (m *MockAdRepository) Select(arg0 context.Context, arg1 func(ads.Ad) bool) ([]ads.Ad, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Select", arg0)
+	ret := m.ctrl.Call(m, "Select", arg0, arg1)
 	ret0, _ := ret[0].([]ads.Ad)
-	return ret0
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // Select indicates an expected call of Select.
-func (mr *MockAdRepositoryMockRecorder) Select(arg0 interface{}) *gomock.Call {
+func (mr *MockAdRepositoryMockRecorder) Select(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Select", reflect.TypeOf((*MockAdRepository)(nil).Select), arg0, arg1)
+}
+
+// SelectArea mocks base method.
+func (m *MockAdRepository) SelectArea(arg0 context.Context, arg1 geo.Box, arg2 func(ads.Ad) bool) ([]ads.Ad, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SelectArea", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]ads.Ad)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SelectArea indicates an expected call of SelectArea.
+func (mr *MockAdRepositoryMockRecorder) SelectArea(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectArea", reflect.TypeOf((*MockAdRepository)(nil).SelectArea), arg0, arg1, arg2)
+}
+
+// Transaction mocks base method.
+func (m *MockAdRepository) Transaction(arg0 context.Context, arg1 func(app.AdRepository) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Transaction", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Transaction indicates an expected call of Transaction.
+func (mr *MockAdRepositoryMockRecorder) Transaction(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Select", reflect.TypeOf((*MockAdRepository)(nil).Select), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Transaction", reflect.TypeOf((*MockAdRepository)(nil).Transaction), arg0, arg1)
 }
 
 // UpdateAd mocks base method.
-func (m *MockAdRepository) UpdateAd(arg0 int64, arg1, arg2 string) {
+func (m *MockAdRepository) UpdateAd(arg0 context.Context, arg1 int64, arg2, arg3 string, arg4 *ads.Location, arg5 *ads.Offer) error {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "UpdateAd", arg0, arg1, arg2)
+	ret := m.ctrl.Call(m, "UpdateAd", arg0, arg1, arg2, arg3, arg4, arg5)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
 // UpdateAd indicates an expected call of UpdateAd.
-func (mr *MockAdRepositoryMockRecorder) UpdateAd(arg0, arg1, arg2 interface{}) *gomock.Call {
+func (mr *MockAdRepositoryMockRecorder) UpdateAd(arg0, arg1, arg2, arg3, arg4, arg5 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAd", reflect.TypeOf((*MockAdRepository)(nil).UpdateAd), arg0, arg1, arg2)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAd", reflect.TypeOf((*MockAdRepository)(nil).UpdateAd), arg0, arg1, arg2, arg3, arg4, arg5)
 }
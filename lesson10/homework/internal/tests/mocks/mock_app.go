@@ -5,8 +5,13 @@
 package mocks
 
 import (
+	context "context"
 	ads "homework10/internal/ads"
+	app "homework10/internal/app"
+	engagement "homework10/internal/engagement"
+	revisions "homework10/internal/revisions"
 	users "homework10/internal/users"
+	webhooks "homework10/internal/webhooks"
 	reflect "reflect"
 	time "time"
 
@@ -36,207 +41,525 @@ func (m *MockApp) EXPECT() *MockAppMockRecorder {
 	return m.recorder
 }
 
+// AdRevisions mocks base method.
+func (m *MockApp) AdRevisions(arg0 context.Context, arg1 int64) ([]revisions.Revision, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AdRevisions", arg0, arg1)
+	ret0, _ := ret[0].([]revisions.Revision)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AdRevisions indicates an expected call of AdRevisions.
+func (mr *MockAppMockRecorder) AdRevisions(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AdRevisions", reflect.TypeOf((*MockApp)(nil).AdRevisions), arg0, arg1)
+}
+
+// AdsModified mocks base method.
+func (m *MockApp) AdsModified(arg0 context.Context) time.Time {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AdsModified", arg0)
+	ret0, _ := ret[0].(time.Time)
+	return ret0
+}
+
+// AdsModified indicates an expected call of AdsModified.
+func (mr *MockAppMockRecorder) AdsModified(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AdsModified", reflect.TypeOf((*MockApp)(nil).AdsModified), arg0)
+}
+
+// BulkAds mocks base method.
+func (m *MockApp) BulkAds(arg0 context.Context, arg1 int64, arg2 []app.BulkOp, arg3 bool) ([]app.BulkResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BulkAds", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]app.BulkResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BulkAds indicates an expected call of BulkAds.
+func (mr *MockAppMockRecorder) BulkAds(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BulkAds", reflect.TypeOf((*MockApp)(nil).BulkAds), arg0, arg1, arg2, arg3)
+}
+
 // ChangeAdStatus mocks base method.
-func (m *MockApp) ChangeAdStatus(arg0, arg1 int64, arg2 bool) (*ads.Ad, error) {
+func (m *MockApp) ChangeAdStatus(arg0 context.Context, arg1, arg2 int64, arg3 bool) (*ads.Ad, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ChangeAdStatus", arg0, arg1, arg2)
+	ret := m.ctrl.Call(m, "ChangeAdStatus", arg0, arg1, arg2, arg3)
 	ret0, _ := ret[0].(*ads.Ad)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // ChangeAdStatus indicates an expected call of ChangeAdStatus.
-func (mr *MockAppMockRecorder) ChangeAdStatus(arg0, arg1, arg2 interface{}) *gomock.Call {
+func (mr *MockAppMockRecorder) ChangeAdStatus(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeAdStatus", reflect.TypeOf((*MockApp)(nil).ChangeAdStatus), arg0, arg1, arg2)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeAdStatus", reflect.TypeOf((*MockApp)(nil).ChangeAdStatus), arg0, arg1, arg2, arg3)
 }
 
 // CreateAd mocks base method.
-func (m *MockApp) CreateAd(arg0, arg1 string, arg2 int64) (*ads.Ad, error) {
+func (m *MockApp) CreateAd(arg0 context.Context, arg1, arg2 string, arg3 int64, arg4 *ads.Location, arg5 *ads.Offer) (*ads.Ad, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateAd", arg0, arg1, arg2)
+	ret := m.ctrl.Call(m, "CreateAd", arg0, arg1, arg2, arg3, arg4, arg5)
 	ret0, _ := ret[0].(*ads.Ad)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateAd indicates an expected call of CreateAd.
-func (mr *MockAppMockRecorder) CreateAd(arg0, arg1, arg2 interface{}) *gomock.Call {
+func (mr *MockAppMockRecorder) CreateAd(arg0, arg1, arg2, arg3, arg4, arg5 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAd", reflect.TypeOf((*MockApp)(nil).CreateAd), arg0, arg1, arg2)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAd", reflect.TypeOf((*MockApp)(nil).CreateAd), arg0, arg1, arg2, arg3, arg4, arg5)
 }
 
 // CreateUser mocks base method.
-func (m *MockApp) CreateUser(arg0, arg1 string) *users.User {
+func (m *MockApp) CreateUser(arg0 context.Context, arg1, arg2 string) (*users.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateUser", arg0, arg1)
+	ret := m.ctrl.Call(m, "CreateUser", arg0, arg1, arg2)
 	ret0, _ := ret[0].(*users.User)
-	return ret0
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // CreateUser indicates an expected call of CreateUser.
-func (mr *MockAppMockRecorder) CreateUser(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockAppMockRecorder) CreateUser(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockApp)(nil).CreateUser), arg0, arg1, arg2)
+}
+
+// CreateWebhook mocks base method.
+func (m *MockApp) CreateWebhook(arg0 context.Context, arg1 int64, arg2 string, arg3 []webhooks.EventType, arg4 string) (*webhooks.Subscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateWebhook", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(*webhooks.Subscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateWebhook indicates an expected call of CreateWebhook.
+func (mr *MockAppMockRecorder) CreateWebhook(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockApp)(nil).CreateUser), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateWebhook", reflect.TypeOf((*MockApp)(nil).CreateWebhook), arg0, arg1, arg2, arg3, arg4)
 }
 
 // DeleteAd mocks base method.
-func (m *MockApp) DeleteAd(arg0, arg1 int64) (*ads.Ad, error) {
+func (m *MockApp) DeleteAd(arg0 context.Context, arg1, arg2 int64) (*ads.Ad, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteAd", arg0, arg1)
+	ret := m.ctrl.Call(m, "DeleteAd", arg0, arg1, arg2)
 	ret0, _ := ret[0].(*ads.Ad)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // DeleteAd indicates an expected call of DeleteAd.
-func (mr *MockAppMockRecorder) DeleteAd(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockAppMockRecorder) DeleteAd(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAd", reflect.TypeOf((*MockApp)(nil).DeleteAd), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAd", reflect.TypeOf((*MockApp)(nil).DeleteAd), arg0, arg1, arg2)
+}
+
+// DeleteAdAsStaff mocks base method.
+func (m *MockApp) DeleteAdAsStaff(arg0 context.Context, arg1 int64, arg2 string) (*ads.Ad, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAdAsStaff", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*ads.Ad)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteAdAsStaff indicates an expected call of DeleteAdAsStaff.
+func (mr *MockAppMockRecorder) DeleteAdAsStaff(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAdAsStaff", reflect.TypeOf((*MockApp)(nil).DeleteAdAsStaff), arg0, arg1, arg2)
 }
 
 // DeleteUser mocks base method.
-func (m *MockApp) DeleteUser(arg0 int64) (*users.User, error) {
+func (m *MockApp) DeleteUser(arg0 context.Context, arg1 int64) (*users.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteUser", arg0)
+	ret := m.ctrl.Call(m, "DeleteUser", arg0, arg1)
 	ret0, _ := ret[0].(*users.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // DeleteUser indicates an expected call of DeleteUser.
-func (mr *MockAppMockRecorder) DeleteUser(arg0 interface{}) *gomock.Call {
+func (mr *MockAppMockRecorder) DeleteUser(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUser", reflect.TypeOf((*MockApp)(nil).DeleteUser), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUser", reflect.TypeOf((*MockApp)(nil).DeleteUser), arg0, arg1)
+}
+
+// DeleteWebhook mocks base method.
+func (m *MockApp) DeleteWebhook(arg0 context.Context, arg1, arg2 int64) (*webhooks.Subscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteWebhook", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*webhooks.Subscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteWebhook indicates an expected call of DeleteWebhook.
+func (mr *MockAppMockRecorder) DeleteWebhook(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteWebhook", reflect.TypeOf((*MockApp)(nil).DeleteWebhook), arg0, arg1, arg2)
+}
+
+// FavoriteAd mocks base method.
+func (m *MockApp) FavoriteAd(arg0 context.Context, arg1, arg2 int64) (*ads.Ad, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FavoriteAd", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*ads.Ad)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FavoriteAd indicates an expected call of FavoriteAd.
+func (mr *MockAppMockRecorder) FavoriteAd(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FavoriteAd", reflect.TypeOf((*MockApp)(nil).FavoriteAd), arg0, arg1, arg2)
 }
 
 // FindByTitle mocks base method.
-func (m *MockApp) FindByTitle(arg0 string) []ads.Ad {
+func (m *MockApp) FindByTitle(arg0 context.Context, arg1 string) ([]ads.Ad, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "FindByTitle", arg0)
+	ret := m.ctrl.Call(m, "FindByTitle", arg0, arg1)
 	ret0, _ := ret[0].([]ads.Ad)
-	return ret0
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // FindByTitle indicates an expected call of FindByTitle.
-func (mr *MockAppMockRecorder) FindByTitle(arg0 interface{}) *gomock.Call {
+func (mr *MockAppMockRecorder) FindByTitle(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByTitle", reflect.TypeOf((*MockApp)(nil).FindByTitle), arg0, arg1)
+}
+
+// FindUsers mocks base method.
+func (m *MockApp) FindUsers(arg0 context.Context, arg1 string) ([]users.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindUsers", arg0, arg1)
+	ret0, _ := ret[0].([]users.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindUsers indicates an expected call of FindUsers.
+func (mr *MockAppMockRecorder) FindUsers(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByTitle", reflect.TypeOf((*MockApp)(nil).FindByTitle), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindUsers", reflect.TypeOf((*MockApp)(nil).FindUsers), arg0, arg1)
 }
 
 // GetAdByID mocks base method.
-func (m *MockApp) GetAdByID(arg0 int64) (*ads.Ad, error) {
+func (m *MockApp) GetAdByID(arg0 context.Context, arg1 int64) (*ads.Ad, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAdByID", arg0)
+	ret := m.ctrl.Call(m, "GetAdByID", arg0, arg1)
 	ret0, _ := ret[0].(*ads.Ad)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetAdByID indicates an expected call of GetAdByID.
-func (mr *MockAppMockRecorder) GetAdByID(arg0 interface{}) *gomock.Call {
+func (mr *MockAppMockRecorder) GetAdByID(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAdByID", reflect.TypeOf((*MockApp)(nil).GetAdByID), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAdByID", reflect.TypeOf((*MockApp)(nil).GetAdByID), arg0, arg1)
 }
 
 // GetUserByID mocks base method.
-func (m *MockApp) GetUserByID(arg0 int64) (*users.User, error) {
+func (m *MockApp) GetUserByID(arg0 context.Context, arg1 int64) (*users.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetUserByID", arg0)
+	ret := m.ctrl.Call(m, "GetUserByID", arg0, arg1)
 	ret0, _ := ret[0].(*users.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetUserByID indicates an expected call of GetUserByID.
-func (mr *MockAppMockRecorder) GetUserByID(arg0 interface{}) *gomock.Call {
+func (mr *MockAppMockRecorder) GetUserByID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByID", reflect.TypeOf((*MockApp)(nil).GetUserByID), arg0, arg1)
+}
+
+// ListDeliveries mocks base method.
+func (m *MockApp) ListDeliveries(arg0 context.Context, arg1, arg2 int64) ([]webhooks.Delivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDeliveries", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]webhooks.Delivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDeliveries indicates an expected call of ListDeliveries.
+func (mr *MockAppMockRecorder) ListDeliveries(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByID", reflect.TypeOf((*MockApp)(nil).GetUserByID), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDeliveries", reflect.TypeOf((*MockApp)(nil).ListDeliveries), arg0, arg1, arg2)
+}
+
+// ListMessages mocks base method.
+func (m *MockApp) ListMessages(arg0 context.Context, arg1, arg2 int64) ([]engagement.Message, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListMessages", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]engagement.Message)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListMessages indicates an expected call of ListMessages.
+func (mr *MockAppMockRecorder) ListMessages(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListMessages", reflect.TypeOf((*MockApp)(nil).ListMessages), arg0, arg1, arg2)
+}
+
+// ListUsers mocks base method.
+func (m *MockApp) ListUsers(arg0 context.Context) ([]users.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUsers", arg0)
+	ret0, _ := ret[0].([]users.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListUsers indicates an expected call of ListUsers.
+func (mr *MockAppMockRecorder) ListUsers(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUsers", reflect.TypeOf((*MockApp)(nil).ListUsers), arg0)
+}
+
+// ListWebhooks mocks base method.
+func (m *MockApp) ListWebhooks(arg0 context.Context, arg1 int64) ([]webhooks.Subscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListWebhooks", arg0, arg1)
+	ret0, _ := ret[0].([]webhooks.Subscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListWebhooks indicates an expected call of ListWebhooks.
+func (mr *MockAppMockRecorder) ListWebhooks(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListWebhooks", reflect.TypeOf((*MockApp)(nil).ListWebhooks), arg0, arg1)
+}
+
+// MostViewed mocks base method.
+func (m *MockApp) MostViewed(arg0 context.Context, arg1 int) ([]app.ViewedAd, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MostViewed", arg0, arg1)
+	ret0, _ := ret[0].([]app.ViewedAd)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MostViewed indicates an expected call of MostViewed.
+func (mr *MockAppMockRecorder) MostViewed(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MostViewed", reflect.TypeOf((*MockApp)(nil).MostViewed), arg0, arg1)
+}
+
+// ReplayDelivery mocks base method.
+func (m *MockApp) ReplayDelivery(arg0 context.Context, arg1, arg2, arg3 int64) (*webhooks.Delivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReplayDelivery", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(*webhooks.Delivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReplayDelivery indicates an expected call of ReplayDelivery.
+func (mr *MockAppMockRecorder) ReplayDelivery(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReplayDelivery", reflect.TypeOf((*MockApp)(nil).ReplayDelivery), arg0, arg1, arg2, arg3)
+}
+
+// SearchNearby mocks base method.
+func (m *MockApp) SearchNearby(arg0 context.Context, arg1 app.NearbyQuery) ([]app.NearbyAd, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchNearby", arg0, arg1)
+	ret0, _ := ret[0].([]app.NearbyAd)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchNearby indicates an expected call of SearchNearby.
+func (mr *MockAppMockRecorder) SearchNearby(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchNearby", reflect.TypeOf((*MockApp)(nil).SearchNearby), arg0, arg1)
 }
 
 // Select mocks base method.
-func (m *MockApp) Select() []ads.Ad {
+func (m *MockApp) Select(arg0 context.Context) ([]ads.Ad, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Select")
+	ret := m.ctrl.Call(m, "Select", arg0)
 	ret0, _ := ret[0].([]ads.Ad)
-	return ret0
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // Select indicates an expected call of Select.
-func (mr *MockAppMockRecorder) Select() *gomock.Call {
+func (mr *MockAppMockRecorder) Select(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Select", reflect.TypeOf((*MockApp)(nil).Select))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Select", reflect.TypeOf((*MockApp)(nil).Select), arg0)
 }
 
 // SelectAll mocks base method.
-func (m *MockApp) SelectAll() []ads.Ad {
+func (m *MockApp) SelectAll(arg0 context.Context) ([]ads.Ad, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SelectAll")
+	ret := m.ctrl.Call(m, "SelectAll", arg0)
 	ret0, _ := ret[0].([]ads.Ad)
-	return ret0
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // SelectAll indicates an expected call of SelectAll.
-func (mr *MockAppMockRecorder) SelectAll() *gomock.Call {
+func (mr *MockAppMockRecorder) SelectAll(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectAll", reflect.TypeOf((*MockApp)(nil).SelectAll))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectAll", reflect.TypeOf((*MockApp)(nil).SelectAll), arg0)
 }
 
 // SelectByAuthor mocks base method.
-func (m *MockApp) SelectByAuthor(arg0 int64) ([]ads.Ad, error) {
+func (m *MockApp) SelectByAuthor(arg0 context.Context, arg1 int64) ([]ads.Ad, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SelectByAuthor", arg0)
+	ret := m.ctrl.Call(m, "SelectByAuthor", arg0, arg1)
 	ret0, _ := ret[0].([]ads.Ad)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // SelectByAuthor indicates an expected call of SelectByAuthor.
-func (mr *MockAppMockRecorder) SelectByAuthor(arg0 interface{}) *gomock.Call {
+func (mr *MockAppMockRecorder) SelectByAuthor(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectByAuthor", reflect.TypeOf((*MockApp)(nil).SelectByAuthor), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectByAuthor", reflect.TypeOf((*MockApp)(nil).SelectByAuthor), arg0, arg1)
 }
 
 // SelectByCreation mocks base method.
-func (m *MockApp) SelectByCreation(arg0 time.Time) []ads.Ad {
+func (m *MockApp) SelectByCreation(arg0 context.Context, arg1 time.Time) ([]ads.Ad, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SelectByCreation", arg0)
+	ret := m.ctrl.Call(m, "SelectByCreation", arg0, arg1)
 	ret0, _ := ret[0].([]ads.Ad)
-	return ret0
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // SelectByCreation indicates an expected call of SelectByCreation.
-func (mr *MockAppMockRecorder) SelectByCreation(arg0 interface{}) *gomock.Call {
+func (mr *MockAppMockRecorder) SelectByCreation(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectByCreation", reflect.TypeOf((*MockApp)(nil).SelectByCreation), arg0, arg1)
+}
+
+// SellerStats mocks base method.
+func (m *MockApp) SellerStats(arg0 context.Context, arg1 int64) (*app.SellerStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SellerStats", arg0, arg1)
+	ret0, _ := ret[0].(*app.SellerStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SellerStats indicates an expected call of SellerStats.
+func (mr *MockAppMockRecorder) SellerStats(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SellerStats", reflect.TypeOf((*MockApp)(nil).SellerStats), arg0, arg1)
+}
+
+// SendMessage mocks base method.
+func (m *MockApp) SendMessage(arg0 context.Context, arg1, arg2 int64, arg3 string) (*engagement.Message, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendMessage", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(*engagement.Message)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SendMessage indicates an expected call of SendMessage.
+func (mr *MockAppMockRecorder) SendMessage(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendMessage", reflect.TypeOf((*MockApp)(nil).SendMessage), arg0, arg1, arg2, arg3)
+}
+
+// SimilarAds mocks base method.
+func (m *MockApp) SimilarAds(arg0 context.Context, arg1, arg2 int64, arg3 int) ([]app.SimilarAd, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SimilarAds", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]app.SimilarAd)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SimilarAds indicates an expected call of SimilarAds.
+func (mr *MockAppMockRecorder) SimilarAds(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SimilarAds", reflect.TypeOf((*MockApp)(nil).SimilarAds), arg0, arg1, arg2, arg3)
+}
+
+// UnfavoriteAd mocks base method.
+func (m *MockApp) UnfavoriteAd(arg0 context.Context, arg1, arg2 int64) (*ads.Ad, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnfavoriteAd", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*ads.Ad)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UnfavoriteAd indicates an expected call of UnfavoriteAd.
+func (mr *MockAppMockRecorder) UnfavoriteAd(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectByCreation", reflect.TypeOf((*MockApp)(nil).SelectByCreation), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnfavoriteAd", reflect.TypeOf((*MockApp)(nil).UnfavoriteAd), arg0, arg1, arg2)
+}
+
+// UnpublishAdAsStaff mocks base method.
+func (m *MockApp) UnpublishAdAsStaff(arg0 context.Context, arg1 int64, arg2 string) (*ads.Ad, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnpublishAdAsStaff", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*ads.Ad)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UnpublishAdAsStaff indicates an expected call of UnpublishAdAsStaff.
+func (mr *MockAppMockRecorder) UnpublishAdAsStaff(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnpublishAdAsStaff", reflect.TypeOf((*MockApp)(nil).UnpublishAdAsStaff), arg0, arg1, arg2)
 }
 
 // UpdateAd mocks base method.
-func (m *MockApp) UpdateAd(arg0, arg1 int64, arg2, arg3 string) (*ads.Ad, error) {
+func (m *MockApp) UpdateAd(arg0 context.Context, arg1, arg2 int64, arg3, arg4 string, arg5 *ads.Location, arg6 *ads.Offer) (*ads.Ad, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateAd", arg0, arg1, arg2, arg3)
+	ret := m.ctrl.Call(m, "UpdateAd", arg0, arg1, arg2, arg3, arg4, arg5, arg6)
 	ret0, _ := ret[0].(*ads.Ad)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // UpdateAd indicates an expected call of UpdateAd.
-func (mr *MockAppMockRecorder) UpdateAd(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+func (mr *MockAppMockRecorder) UpdateAd(arg0, arg1, arg2, arg3, arg4, arg5, arg6 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAd", reflect.TypeOf((*MockApp)(nil).UpdateAd), arg0, arg1, arg2, arg3)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAd", reflect.TypeOf((*MockApp)(nil).UpdateAd), arg0, arg1, arg2, arg3, arg4, arg5, arg6)
 }
 
 // UpdateUser mocks base method.
-func (m *MockApp) UpdateUser(arg0 int64, arg1, arg2 string) (*users.User, error) {
+func (m *MockApp) UpdateUser(arg0 context.Context, arg1 int64, arg2, arg3 string) (*users.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateUser", arg0, arg1, arg2)
+	ret := m.ctrl.Call(m, "UpdateUser", arg0, arg1, arg2, arg3)
 	ret0, _ := ret[0].(*users.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // UpdateUser indicates an expected call of UpdateUser.
-func (mr *MockAppMockRecorder) UpdateUser(arg0, arg1, arg2 interface{}) *gomock.Call {
+func (mr *MockAppMockRecorder) UpdateUser(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUser", reflect.TypeOf((*MockApp)(nil).UpdateUser), arg0, arg1, arg2, arg3)
+}
+
+// ValidateAd mocks base method.
+func (m *MockApp) ValidateAd(arg0 context.Context, arg1, arg2 string, arg3 int64, arg4 *ads.Location, arg5 *ads.Offer) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ValidateAd", arg0, arg1, arg2, arg3, arg4, arg5)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ValidateAd indicates an expected call of ValidateAd.
+func (mr *MockAppMockRecorder) ValidateAd(arg0, arg1, arg2, arg3, arg4, arg5 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUser", reflect.TypeOf((*MockApp)(nil).UpdateUser), arg0, arg1, arg2)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateAd", reflect.TypeOf((*MockApp)(nil).ValidateAd), arg0, arg1, arg2, arg3, arg4, arg5)
 }
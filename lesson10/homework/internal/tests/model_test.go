@@ -0,0 +1,285 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"homework10/internal/adapters/adrepo"
+	"homework10/internal/adapters/userrepo"
+	"homework10/internal/ads"
+	"homework10/internal/app"
+	"homework10/internal/cache"
+	"homework10/internal/users"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"pgregory.net/rapid"
+)
+
+// modelAd is the part of an ad the model predicts.
+type modelAd struct {
+	ID        int64
+	Title     string
+	Text      string
+	AuthorID  int64
+	Published bool
+}
+
+func toModelAd(ad ads.Ad) modelAd {
+	return modelAd{ID: ad.ID, Title: ad.Title, Text: ad.Text, AuthorID: ad.AuthorID, Published: ad.Published}
+}
+
+// appMachine runs random operations on app.App and on a model of its state,
+// a pair of maps, and checks after every operation that they agree. IDs are
+// given out from 0 in the order of creation and are never reused.
+type appMachine struct {
+	app        app.App
+	ctx        context.Context
+	users      map[int64]users.User
+	ads        map[int64]modelAd
+	nextUserID int64
+	nextAdID   int64
+}
+
+func (m *appMachine) Init(t *rapid.T) {
+	var adRepo app.AdRepository = adrepo.New()
+	var userRepo app.UserRepository = userrepo.New()
+	if rapid.Bool().Draw(t, "cached") {
+		adRepo = cache.NewAdRepository(adRepo, cache.WithSize(4))
+		userRepo = cache.NewUserRepository(userRepo, cache.WithSize(4))
+	}
+	m.app = app.NewApp(adRepo, userRepo)
+	m.ctx = context.Background()
+	m.users = map[int64]users.User{}
+	m.ads = map[int64]modelAd{}
+}
+
+// userID draws the ID of a user that exists, was deleted or was never
+// created.
+func (m *appMachine) userID(t *rapid.T, label string) int64 {
+	return rapid.Int64Range(-1, m.nextUserID).Draw(t, label)
+}
+
+func (m *appMachine) adID(t *rapid.T) int64 {
+	return rapid.Int64Range(-1, m.nextAdID).Draw(t, "ad_id")
+}
+
+// authorOf draws the author of the ad ID when it exists, or any user.
+func (m *appMachine) authorOf(t *rapid.T, ID int64) int64 {
+	if ad, ok := m.ads[ID]; ok && rapid.IntRange(0, 3).Draw(t, "other_user") > 0 {
+		return ad.AuthorID
+	}
+	return m.userID(t, "user_id")
+}
+
+// adContent draws a title and a text, mostly valid ones.
+func adContent(t *rapid.T) (string, string) {
+	chars := rapid.RuneFrom([]rune("abcxyz "))
+	title := rapid.StringOfN(chars, 0, 105, -1).Draw(t, "title")
+	text := rapid.StringOfN(chars, 0, 510, -1).Draw(t, "text")
+	return title, text
+}
+
+func validAd(title string, text string) bool {
+	return len(title) > 0 && len(title) < 100 && len(text) > 0 && len(text) < 500
+}
+
+// checkErr fails unless err is want, nil meaning success.
+func checkErr(t *rapid.T, want error, err error) {
+	if want == nil {
+		require.NoError(t, err)
+		return
+	}
+	require.ErrorIs(t, err, want)
+}
+
+func (m *appMachine) CreateUser(t *rapid.T) {
+	nickname := rapid.StringMatching(`[A-Za-z]{1,8}`).Draw(t, "nickname")
+	email := rapid.StringMatching(`([a-z]{1,5}@mail\.org)?`).Draw(t, "email")
+	usr, err := m.app.CreateUser(m.ctx, nickname, email)
+	require.NoError(t, err)
+	want := users.User{ID: m.nextUserID, Nickname: nickname, Email: email}
+	require.Equal(t, want, *usr)
+	m.users[usr.ID] = want
+	m.nextUserID++
+}
+
+func (m *appMachine) UpdateUser(t *rapid.T) {
+	ID := m.userID(t, "user_id")
+	nickname := rapid.StringMatching(`[A-Za-z]{0,8}`).Draw(t, "nickname")
+	email := rapid.StringMatching(`([a-z]{1,5}@mail\.org)?`).Draw(t, "email")
+	usr, err := m.app.UpdateUser(m.ctx, ID, nickname, email)
+	want, ok := m.users[ID]
+	if !ok {
+		checkErr(t, app.ErrNotFound, err)
+		return
+	}
+	require.NoError(t, err)
+	// empty values keep the old ones
+	if nickname != "" {
+		want.Nickname = nickname
+	}
+	if email != "" {
+		want.Email = email
+	}
+	require.Equal(t, want, *usr)
+	m.users[ID] = want
+}
+
+// DeleteUser keeps the ads of the user, they can still be published or
+// unpublished but not changed.
+func (m *appMachine) DeleteUser(t *rapid.T) {
+	ID := m.userID(t, "user_id")
+	usr, err := m.app.DeleteUser(m.ctx, ID)
+	want, ok := m.users[ID]
+	if !ok {
+		checkErr(t, app.ErrNotFound, err)
+		return
+	}
+	require.NoError(t, err)
+	require.Equal(t, want, *usr)
+	delete(m.users, ID)
+}
+
+func (m *appMachine) CreateAd(t *rapid.T) {
+	authorID := m.userID(t, "author_id")
+	title, text := adContent(t)
+	ad, err := m.app.CreateAd(m.ctx, title, text, authorID, nil, nil)
+	_, authorExists := m.users[authorID]
+	switch {
+	case !validAd(title, text):
+		checkErr(t, app.ErrBadRequest, err)
+	case !authorExists:
+		checkErr(t, app.ErrNotFound, err)
+	default:
+		require.NoError(t, err)
+		want := modelAd{ID: m.nextAdID, Title: title, Text: text, AuthorID: authorID}
+		require.Equal(t, want, toModelAd(*ad))
+		m.ads[ad.ID] = want
+		m.nextAdID++
+	}
+}
+
+func (m *appMachine) UpdateAd(t *rapid.T) {
+	ID := m.adID(t)
+	userID := m.authorOf(t, ID)
+	title, text := adContent(t)
+	ad, err := m.app.UpdateAd(m.ctx, ID, userID, title, text, nil, nil)
+	want, adExists := m.ads[ID]
+	_, userExists := m.users[userID]
+	switch {
+	case !validAd(title, text):
+		checkErr(t, app.ErrBadRequest, err)
+	case !userExists, !adExists:
+		checkErr(t, app.ErrNotFound, err)
+	case want.AuthorID != userID:
+		checkErr(t, app.ErrForbidden, err)
+	default:
+		require.NoError(t, err)
+		want.Title, want.Text = title, text
+		require.Equal(t, want, toModelAd(*ad))
+		m.ads[ID] = want
+	}
+}
+
+func (m *appMachine) ChangeAdStatus(t *rapid.T) {
+	ID := m.adID(t)
+	userID := m.authorOf(t, ID)
+	published := rapid.Bool().Draw(t, "published")
+	ad, err := m.app.ChangeAdStatus(m.ctx, ID, userID, published)
+	want, ok := m.ads[ID]
+	switch {
+	case !ok:
+		checkErr(t, app.ErrNotFound, err)
+	case want.AuthorID != userID:
+		checkErr(t, app.ErrForbidden, err)
+	default:
+		require.NoError(t, err)
+		want.Published = published
+		require.Equal(t, want, toModelAd(*ad))
+		m.ads[ID] = want
+	}
+}
+
+func (m *appMachine) DeleteAd(t *rapid.T) {
+	ID := m.adID(t)
+	userID := m.authorOf(t, ID)
+	ad, err := m.app.DeleteAd(m.ctx, ID, userID)
+	want, adExists := m.ads[ID]
+	_, userExists := m.users[userID]
+	switch {
+	case !userExists, !adExists:
+		checkErr(t, app.ErrNotFound, err)
+	case want.AuthorID != userID:
+		checkErr(t, app.ErrForbidden, err)
+	default:
+		require.NoError(t, err)
+		require.Equal(t, want, toModelAd(*ad))
+		delete(m.ads, ID)
+	}
+}
+
+// modelAds returns the ads of the model that satisfy f ordered by ID.
+func (m *appMachine) modelAds(f func(modelAd) bool) []modelAd {
+	res := []modelAd{}
+	for _, ad := range m.ads {
+		if f(ad) {
+			res = append(res, ad)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].ID < res[j].ID })
+	return res
+}
+
+func toModelAds(arr []ads.Ad) []modelAd {
+	res := []modelAd{}
+	for _, ad := range arr {
+		res = append(res, toModelAd(ad))
+	}
+	return res
+}
+
+// Check compares the whole state of the app with the model.
+func (m *appMachine) Check(t *rapid.T) {
+	// only the published ads are visible
+	arr, err := m.app.Select(m.ctx)
+	require.NoError(t, err)
+	require.Equal(t, m.modelAds(func(ad modelAd) bool { return ad.Published }), toModelAds(arr))
+
+	arr, err = m.app.SelectAll(m.ctx)
+	require.NoError(t, err)
+	require.Equal(t, m.modelAds(func(modelAd) bool { return true }), toModelAds(arr))
+
+	for ID := int64(0); ID < m.nextAdID; ID++ {
+		ad, err := m.app.GetAdByID(m.ctx, ID)
+		want, ok := m.ads[ID]
+		if !ok {
+			require.True(t, errors.Is(err, app.ErrNotFound), "deleted ad %d: %v", ID, err)
+			continue
+		}
+		require.NoError(t, err)
+		require.Equal(t, want, toModelAd(*ad))
+	}
+
+	for ID := int64(0); ID < m.nextUserID; ID++ {
+		usr, err := m.app.GetUserByID(m.ctx, ID)
+		want, ok := m.users[ID]
+		if !ok {
+			require.True(t, errors.Is(err, app.ErrNotFound), "deleted user %d: %v", ID, err)
+			_, err = m.app.SelectByAuthor(m.ctx, ID)
+			require.True(t, errors.Is(err, app.ErrNotFound), "ads of deleted user %d: %v", ID, err)
+			continue
+		}
+		require.NoError(t, err)
+		require.Equal(t, want, *usr)
+
+		// every ad of the author and only them, published or not
+		arr, err := m.app.SelectByAuthor(m.ctx, ID)
+		require.NoError(t, err)
+		require.Equal(t, m.modelAds(func(ad modelAd) bool { return ad.AuthorID == ID }), toModelAds(arr))
+	}
+}
+
+func TestAppModel(t *testing.T) {
+	rapid.Check(t, rapid.Run[*appMachine]())
+}
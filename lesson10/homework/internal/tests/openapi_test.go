@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"encoding/json"
+	"homework10/internal/adapters/adrepo"
+	"homework10/internal/adapters/userrepo"
+	"homework10/internal/app"
+	"homework10/internal/ports/httpgin"
+	"homework10/internal/ports/httpgin/docs"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type openAPISpec struct {
+	OpenAPI string                                `json:"openapi"`
+	Servers []struct{ URL string }                `json:"servers"`
+	Paths   map[string]map[string]json.RawMessage `json:"paths"`
+}
+
+var ginParam = regexp.MustCompile(`:(\w+)`)
+
+func TestOpenAPICoversAppRouter(t *testing.T) {
+	var spec openAPISpec
+	assert.NoError(t, json.Unmarshal(docs.OpenAPI, &spec))
+	assert.True(t, strings.HasPrefix(spec.OpenAPI, "3."))
+	assert.Len(t, spec.Servers, 1)
+	base := spec.Servers[0].URL
+
+	gin.SetMode(gin.ReleaseMode)
+	engine := gin.New()
+	httpgin.AppRouter(engine.Group(base), app.NewApp(adrepo.New(), userrepo.New()))
+
+	routes := engine.Routes()
+	assert.NotEmpty(t, routes)
+	for _, r := range routes {
+		path := ginParam.ReplaceAllString(strings.TrimPrefix(r.Path, base), "{$1}")
+		ops, ok := spec.Paths[path]
+		if !assert.Truef(t, ok, "route %s %s is missing from the OpenAPI spec", r.Method, r.Path) {
+			continue
+		}
+		_, ok = ops[strings.ToLower(r.Method)]
+		assert.Truef(t, ok, "route %s %s is missing from the OpenAPI spec", r.Method, r.Path)
+	}
+}
+
+func TestOpenAPIEnums(t *testing.T) {
+	var spec struct {
+		Components struct {
+			Schemas map[string]struct {
+				Enum     []string `json:"enum"`
+				VarNames []string `json:"x-enum-varnames"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	assert.NoError(t, json.Unmarshal(docs.OpenAPI, &spec))
+	for name, schema := range spec.Components.Schemas {
+		if len(schema.VarNames) > 0 {
+			assert.Lenf(t, schema.Enum, len(schema.VarNames), "schema %s lacks enum values", name)
+		}
+	}
+	assert.Contains(t, spec.Components.Schemas["app.Code"].Enum, string(app.CodeNotFound))
+}
+
+func TestOpenAPIServed(t *testing.T) {
+	srv := runServer(t, ephemeralConfig())
+
+	resp, err := http.Get(srv.baseURL + "/api/docs/openapi.json")
+	assert.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.JSONEq(t, string(docs.OpenAPI), string(body))
+
+	resp, err = http.Get(srv.baseURL + "/api/docs/")
+	assert.NoError(t, err)
+	body, err = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), "swagger-ui")
+}
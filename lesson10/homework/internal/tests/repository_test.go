@@ -0,0 +1,49 @@
+package tests
+
+import (
+	"homework10/internal/adapters/adrepo"
+	"homework10/internal/adapters/repotest"
+	"homework10/internal/adapters/userrepo"
+	"homework10/internal/app"
+	"homework10/internal/cache"
+	"homework10/internal/tracing"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestAdRepositoryConformance(t *testing.T) {
+	backends := map[string]func() app.AdRepository{
+		"memory": func() app.AdRepository { return adrepo.New() },
+		"memory with small cells": func() app.AdRepository {
+			return adrepo.New(adrepo.WithCellSize(0.1))
+		},
+		// the stack of the server: a cache over a traced repository
+		"cached": func() app.AdRepository {
+			traced := tracing.AdRepository(adrepo.New(), trace.NewNoopTracerProvider())
+			return cache.NewAdRepository(traced, cache.WithSize(16), cache.WithTTL(time.Minute))
+		},
+	}
+	for name, newRepo := range backends {
+		t.Run(name, func(t *testing.T) {
+			suite.Run(t, &repotest.AdRepositorySuite{New: newRepo})
+		})
+	}
+}
+
+func TestUserRepositoryConformance(t *testing.T) {
+	backends := map[string]func() app.UserRepository{
+		"memory": func() app.UserRepository { return userrepo.New() },
+		"cached": func() app.UserRepository {
+			traced := tracing.UserRepository(userrepo.New(), trace.NewNoopTracerProvider())
+			return cache.NewUserRepository(traced, cache.WithSize(16), cache.WithTTL(time.Minute))
+		},
+	}
+	for name, newRepo := range backends {
+		t.Run(name, func(t *testing.T) {
+			suite.Run(t, &repotest.UserRepositorySuite{New: newRepo})
+		})
+	}
+}
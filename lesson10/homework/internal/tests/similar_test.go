@@ -0,0 +1,196 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"homework10/internal/ads"
+	grpcPort "homework10/internal/ports/grpc"
+	"homework10/internal/similar"
+	"homework10/pkg/adsclient"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func TestTerms(t *testing.T) {
+	assert.Equal(t, []string{"red", "bike", "велосипед", "26"}, similar.Terms("Red bike, a велосипед 26!"))
+	assert.Empty(t, similar.Terms(" - "))
+}
+
+func TestSimilarIndex(t *testing.T) {
+	idx := similar.New()
+	put := func(id int64, author int64, title string, text string, published bool) ads.Ad {
+		ad := ads.Ad{ID: id, AuthorID: author, Title: title, Text: text, Published: published}
+		idx.Put(ad)
+		return ad
+	}
+	bike := put(1, 1, "red mountain bike", "good bike for the city", true)
+	put(2, 2, "blue mountain bike", "new", true)
+	put(3, 2, "city car", "good car", true)
+	put(4, 3, "red bike", "draft", false)
+	put(5, 3, "sofa", "soft", true)
+	assert.Equal(t, 4, idx.Len())
+
+	res := idx.Similar(bike, 10, -1)
+	require.Len(t, res, 2)
+	assert.Equal(t, int64(2), res[0].AdID)
+	assert.Equal(t, int64(3), res[1].AdID)
+	assert.Greater(t, res[0].Score, res[1].Score)
+	assert.LessOrEqual(t, res[0].Score, 1.0)
+
+	// the ads of the viewer are skipped
+	assert.Empty(t, idx.Similar(bike, 10, 2))
+	assert.Len(t, idx.Similar(bike, 1, -1), 1)
+
+	// an identical ad scores 1
+	twin := bike
+	twin.ID = 6
+	assert.InDelta(t, 1.0, idx.Similar(twin, 1, -1)[0].Score, 1e-9)
+
+	// updates are indexed incrementally
+	put(3, 2, "city bike", "red", true)
+	assert.Equal(t, int64(3), idx.Similar(bike, 10, -1)[0].AdID)
+	put(2, 2, "blue mountain bike", "new", false)
+	idx.Remove(3)
+	assert.Empty(t, idx.Similar(bike, 10, -1))
+	assert.Equal(t, 2, idx.Len())
+}
+
+func TestSimilarIndexBlendsOffers(t *testing.T) {
+	idx := similar.New()
+	put := func(id int64, title string, offer *ads.Offer) ads.Ad {
+		ad := ads.Ad{ID: id, AuthorID: id, Title: title, Text: "for sale", Published: true, Offer: offer}
+		idx.Put(ad)
+		return ad
+	}
+	bike := put(1, "mountain bike", &ads.Offer{Category: "Bikes", Price: 20000})
+	put(2, "mountain bike", &ads.Offer{Category: "bikes", Price: 40000})
+	put(3, "mountain bike", &ads.Offer{Category: "bikes", Price: 20000})
+	put(4, "mountain bike", nil)
+	put(5, "tandem", &ads.Offer{Category: "bikes", Price: 20000})
+	put(6, "sofa", &ads.Offer{Category: "furniture", Price: 20000})
+
+	res := idx.Similar(bike, 10, -1)
+	ids := make([]int64, len(res))
+	for i, m := range res {
+		ids[i] = m.AdID
+	}
+	// the same category and price first, then a higher price, then no
+	// offer; other terms of the same category are found too, the texts of
+	// all ads share "for sale"
+	assert.Equal(t, []int64{3, 2, 4, 5, 6}, ids)
+	assert.InDelta(t, 1.0, res[0].Score, 1e-9)
+	for _, m := range res {
+		assert.LessOrEqual(t, m.Score, 1.0)
+	}
+
+	// without an offer of the query only the terms count
+	noOffer := bike
+	noOffer.Offer = nil
+	assert.InDelta(t, 1.0, idx.Similar(noOffer, 1, -1)[0].Score, 1e-9)
+
+	idx.Remove(5)
+	idx.Put(ads.Ad{ID: 3, AuthorID: 3, Title: "mountain bike", Text: "for sale", Published: true})
+	res = idx.Similar(bike, 10, -1)
+	assert.Equal(t, int64(2), res[0].AdID)
+	assert.Len(t, res, 4)
+}
+
+func TestSimilarAdsHTTP(t *testing.T) {
+	ctx := context.Background()
+	_, srv := startServer(t, ephemeralConfig())
+	client := srv.api
+
+	seller, err := client.CreateUser(ctx, "Oleg", "")
+	require.NoError(t, err)
+	other, err := client.CreateUser(ctx, "Anna", "")
+	require.NoError(t, err)
+	create := func(author int64, title string, text string, publish bool) int64 {
+		ad, err := client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: author, Title: title, Text: text})
+		require.NoError(t, err)
+		if publish {
+			_, err = client.ChangeAdStatus(ctx, ad.ID, author, true)
+			require.NoError(t, err)
+		}
+		return ad.ID
+	}
+	bike := create(seller.ID, "red bike", "city bike", true)
+	ownBike := create(seller.ID, "blue bike", "mountain bike", true)
+	otherBike := create(other.ID, "green bike", "bike for kids", true)
+	create(other.ID, "bike lock", "bike", false)
+	create(other.ID, "sofa", "soft", true)
+
+	res, err := client.SimilarAds(ctx, bike, adsclient.SimilarQuery{})
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+	assert.ElementsMatch(t, []int64{ownBike, otherBike}, []int64{res[0].ID, res[1].ID})
+
+	res, err = client.SimilarAds(ctx, bike, adsclient.SimilarQuery{ViewerID: &seller.ID})
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	assert.Equal(t, otherBike, res[0].ID)
+	assert.Positive(t, res[0].Score)
+
+	// changes reach the index
+	_, err = client.UpdateAd(ctx, otherBike, adsclient.UpdateAdRequest{UserID: other.ID, Title: "garden chair", Text: "wooden"})
+	require.NoError(t, err)
+	_, err = client.DeleteAd(ctx, ownBike, seller.ID)
+	require.NoError(t, err)
+	res, err = client.SimilarAds(ctx, bike, adsclient.SimilarQuery{})
+	require.NoError(t, err)
+	assert.Empty(t, res)
+
+	_, err = client.SimilarAds(ctx, bike, adsclient.SimilarQuery{Limit: 51})
+	assert.ErrorIs(t, err, adsclient.ErrInvalidArgument)
+	_, err = client.SimilarAds(ctx, 100, adsclient.SimilarQuery{})
+	assert.ErrorIs(t, err, adsclient.ErrNotFound)
+
+	// malformed parameters are sent by hand
+	for _, q := range []string{"?limit=0", "?viewer_id=x", "?limit=x"} {
+		resp, err := http.Get(fmt.Sprintf("%s/api/v1/ads/%d/similar%s", srv.baseURL, bike, q))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, q)
+	}
+}
+
+func TestAdOffers(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	srv := runServer(t, ephemeralConfig())
+	client := srv.api
+
+	seller, err := client.CreateUser(ctx, "Oleg", "")
+	require.NoError(t, err)
+	bike, err := client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: seller.ID, Title: "red bike", Text: "city bike",
+		Offer: &adsclient.Offer{Category: "bikes", Price: 20000}})
+	require.NoError(t, err)
+	assert.Equal(t, &adsclient.Offer{Category: "bikes", Price: 20000}, bike.Offer)
+
+	// the offer is kept when omitted
+	bike, err = client.UpdateAd(ctx, bike.ID, adsclient.UpdateAdRequest{UserID: seller.ID, Title: "red bike", Text: "sold soon"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(20000), bike.Offer.Price)
+	bike, err = client.UpdateAd(ctx, bike.ID, adsclient.UpdateAdRequest{UserID: seller.ID, Title: "red bike", Text: "cheaper",
+		Offer: &adsclient.Offer{Category: "bikes", Price: 15000}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(15000), bike.Offer.Price)
+
+	for _, o := range []adsclient.Offer{{Category: "", Price: 1}, {Category: "bikes", Price: -1}} {
+		_, err = client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: seller.ID, Title: "bike", Text: "bike", Offer: &o})
+		assert.ErrorIs(t, err, adsclient.ErrInvalidArgument, o)
+	}
+
+	// gRPC carries the offer too
+	conn, err := grpc.DialContext(ctx, srv.grpcAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	res, err := grpcPort.NewAdServiceClient(conn).CreateAd(ctx, &grpcPort.CreateAdRequest{UserId: seller.ID, Title: "blue bike",
+		Text: "city bike", Offer: &grpcPort.Offer{Category: "bikes", Price: 18000}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(18000), res.GetOffer().GetPrice())
+}
@@ -2,36 +2,26 @@ package tests
 
 import (
 	"context"
-	"homework10/internal/ports"
 	grpcPort "homework10/internal/ports/grpc"
+	"homework10/pkg/adsclient"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 func TestServerUsingTable(t *testing.T) {
-	ctx, cf := context.WithCancel(context.Background())
-	endChan := make(chan int)
-	hsrv, _ := ports.CreateServer(ctx, endChan)
-	httpclient := getTestClient(hsrv.Addr)
+	ctx := context.Background()
+	srv := runServer(t, ephemeralConfig())
+	httpclient := srv.api
+	grpcclient := grpcPort.NewAdServiceClient(srv.dial(t))
 
-	conn, err := grpc.DialContext(context.Background(),
-		"localhost:50054",
-		grpc.WithTransportCredentials(insecure.NewCredentials()))
-	if err != nil {
-		panic(err)
-	}
-	grpcclient := grpcPort.NewAdServiceClient(conn)
-
-	anakin, err := httpclient.createUser("Anakin", "anakin.skywalker@mail.com")
+	anakin, err := httpclient.CreateUser(ctx, "Anakin", "anakin.skywalker@mail.com")
 	assert.NoError(t, err)
-	luke, err := httpclient.createUser("Luke", "luke.skywalker@mail.com")
+	luke, err := httpclient.CreateUser(ctx, "Luke", "luke.skywalker@mail.com")
 	assert.NoError(t, err)
-	lea, err := httpclient.createUser("Lea", "lea.skywalker@mail.com")
+	lea, err := httpclient.CreateUser(ctx, "Lea", "lea.skywalker@mail.com")
 	assert.NoError(t, err)
 
 	var ad_data = []struct {
@@ -39,38 +29,38 @@ func TestServerUsingTable(t *testing.T) {
 		title string
 		text string
 	}{
-		{anakin.Data.ID, "Letter to Luke", "I am your father!"},
-		{anakin.Data.ID, "Letter to Lea", "I find your lack of faith disturbing"},
-		{lea.Data.ID, "No", "I'd just as soon kiss a Wookiee"},
-		{luke.Data.ID, "Hello from Yoda", "Do, or do not. There is no try."},
-		{lea.Data.ID, "May the Force be with you", "Star wars day"},
-		{luke.Data.ID, "May the Force be with you", "Star wars day"},
+		{anakin.ID, "Letter to Luke", "I am your father!"},
+		{anakin.ID, "Letter to Lea", "I find your lack of faith disturbing"},
+		{lea.ID, "No", "I'd just as soon kiss a Wookiee"},
+		{luke.ID, "Hello from Yoda", "Do, or do not. There is no try."},
+		{lea.ID, "May the Force be with you", "Star wars day"},
+		{luke.ID, "May the Force be with you", "Star wars day"},
 	}
 
-	var ads []adResponse
+	var ads []*adsclient.Ad
 	for _, d := range(ad_data) {
-		ad, err := httpclient.createAd(d.id, d.title, d.text)
+		ad, err := httpclient.CreateAd(ctx, adsclient.CreateAdRequest{UserID: d.id, Title: d.title, Text: d.text})
 		assert.NoError(t, err)
-		_, err = httpclient.changeAdStatus(ad.Data.AuthorID, ad.Data.ID, true)
+		_, err = httpclient.ChangeAdStatus(ctx, ad.ID, ad.AuthorID, true)
 		assert.NoError(t, err)
 		ads = append(ads, ad)
 	}
 
-	_, err = httpclient.changeAdStatus(ads[3].Data.AuthorID, ads[3].Data.ID, false)
+	_, err = httpclient.ChangeAdStatus(ctx, ads[3].ID, ads[3].AuthorID, false)
 	assert.NoError(t, err)
-	_, err = httpclient.changeAdStatus(ads[4].Data.AuthorID, ads[4].Data.ID, false)
+	_, err = httpclient.ChangeAdStatus(ctx, ads[4].ID, ads[4].AuthorID, false)
 	assert.NoError(t, err)
 
 	t.Run("Default list using http", func(t *testing.T) {
-		ads_resp, err := httpclient.listAds(nil)
+		ads_resp, err := httpclient.ListAds(ctx)
 		assert.NoError(t, err)
-		assert.Equal(t, len(ads_resp.Data), 4)
+		assert.Equal(t, len(ads_resp), 4)
 	})
 	
 	t.Run("List all using http", func(t *testing.T) {
-		ads_resp, err := httpclient.listAll()
+		ads_resp, err := httpclient.ListAllAds(ctx)
 		assert.NoError(t, err)
-		assert.Equal(t, len(ads_resp.Data), 6)
+		assert.Equal(t, len(ads_resp), 6)
 	})
 	
 	t.Run("Default list using grpc", func(t *testing.T) {
@@ -90,17 +80,17 @@ func TestServerUsingTable(t *testing.T) {
 		author int64
 		expLen int
 	}{
-		{anakin.Data.ID, 2},
-		{lea.Data.ID, 2},
-		{luke.Data.ID, 2},
+		{anakin.ID, 2},
+		{lea.ID, 2},
+		{luke.ID, 2},
 	}
 	var byDateSearchTest = []struct {
 		creationTime time.Time
 		expLen       int
 	}{
-		{ads[0].Data.CreationTime, 5},
-		{ads[2].Data.CreationTime, 3},
-		{ads[4].Data.CreationTime, 1},
+		{ads[0].CreationTime, 5},
+		{ads[2].CreationTime, 3},
+		{ads[4].CreationTime, 1},
 	}
 	var byTitleSearchTest = []struct {
 		title  string
@@ -113,25 +103,25 @@ func TestServerUsingTable(t *testing.T) {
 
 	t.Run("Search by author using http", func(t *testing.T) {
 		for _, tt := range byAuthorSearchTest {
-			res, err := httpclient.listAdsByAuthor(tt.author)
+			res, err := httpclient.ListAdsByAuthor(ctx, tt.author)
 			assert.NoError(t, err)
-			assert.Len(t, res.Data, tt.expLen)
+			assert.Len(t, res, tt.expLen)
 		}
 	})
 	
 	t.Run("Search by date using http", func(t *testing.T) {
 		for _, tt := range byDateSearchTest {
-			res, err := httpclient.listAdsByTime(tt.creationTime)
+			res, err := httpclient.ListAdsCreatedAfter(ctx, tt.creationTime)
 			assert.NoError(t, err)
-			assert.Len(t, res.Data, tt.expLen)
+			assert.Len(t, res, tt.expLen)
 		}
 	})
 	
 	t.Run("Search by title using http", func(t *testing.T) {
 		for _, tt := range byTitleSearchTest {
-			res, err := httpclient.findAdByTitle(tt.title)
+			res, err := httpclient.FindAdsByTitle(ctx, tt.title)
 			assert.NoError(t, err)
-			assert.Len(t, res.Data, tt.expLen)
+			assert.Len(t, res, tt.expLen)
 		}
 	})
 	
@@ -158,7 +148,4 @@ func TestServerUsingTable(t *testing.T) {
 			assert.Len(t, res.List, tt.expLen)
 		}
 	})
-
-	cf()
-	<-endChan
 }
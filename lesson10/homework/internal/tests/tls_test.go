@@ -0,0 +1,297 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"homework10/internal/certs"
+	"homework10/internal/config"
+	"homework10/internal/logging"
+	"homework10/internal/ports"
+	grpcPort "homework10/internal/ports/grpc"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// testCA is a throwaway certificate authority.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newSerial(t *testing.T) *big.Int {
+	n, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 127))
+	require.NoError(t, err)
+	return n
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          newSerial(t),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns the PEM encoded certificate and key of subject, valid for
+// localhost.
+func (ca *testCA) issue(t *testing.T, subject pkix.Name, usage x509.ExtKeyUsage) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: newSerial(t),
+		Subject:      subject,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func (ca *testCA) pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	return pool
+}
+
+func (ca *testCA) clientCert(t *testing.T, subject pkix.Name) tls.Certificate {
+	certPEM, keyPEM := ca.issue(t, subject, x509.ExtKeyUsageClientAuth)
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+	return cert
+}
+
+func writeFile(t *testing.T, path string, data []byte) string {
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	return path
+}
+
+// serverFiles writes a server certificate of ca to dir.
+func serverFiles(t *testing.T, dir string, ca *testCA) config.TLSConfig {
+	certPEM, keyPEM := ca.issue(t, pkix.Name{CommonName: "localhost"}, x509.ExtKeyUsageServerAuth)
+	return config.TLSConfig{
+		CertFile: writeFile(t, filepath.Join(dir, "server.crt"), certPEM),
+		KeyFile:  writeFile(t, filepath.Join(dir, "server.key"), keyPEM),
+	}
+}
+
+// syncBuffer collects the log records of a server.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func runTLSServer(t *testing.T, cfg config.Config, logs *syncBuffer) *ports.Server {
+	tel, err := ports.NewTelemetry(context.Background(), cfg)
+	require.NoError(t, err)
+	tel.Logger = logging.New(cfg, logs)
+	hooks := ports.NewWebhooks(cfg, tel.Logger)
+	a, err := ports.NewApp(cfg, tel, hooks)
+	require.NoError(t, err)
+	srv, err := ports.NewServer(cfg, a, tel, ports.WithWebhooks(hooks))
+	require.NoError(t, err)
+	go func() { _ = srv.Run(context.Background()) }()
+	<-srv.Ready()
+	t.Cleanup(func() {
+		srv.Shutdown()
+		<-srv.Done()
+	})
+	return srv
+}
+
+func httpsGet(url string, roots *x509.CertPool) (*http.Response, error) {
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{RootCAs: roots},
+		DisableKeepAlives: true,
+	}}
+	return client.Get(url)
+}
+
+func TestHTTPSReloadsCertificate(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	first, second := newTestCA(t, "first"), newTestCA(t, "second")
+	cfg := ephemeralConfig()
+	cfg.HTTP.TLS = serverFiles(t, dir, first)
+	cfg.HTTP.TLS.ReloadInterval = 0
+	srv := runTLSServer(t, cfg, &syncBuffer{})
+	url := "https://" + srv.HTTPAddr().String() + "/healthz"
+
+	resp, err := httpsGet(url, first.pool())
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// plaintext is refused
+	resp, err = http.Get("http://" + srv.HTTPAddr().String() + "/healthz")
+	if err == nil {
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	}
+
+	// the rotated certificate is served on the next handshake
+	rotated := serverFiles(t, t.TempDir(), second)
+	certPEM, err := os.ReadFile(rotated.CertFile)
+	require.NoError(t, err)
+	keyPEM, err := os.ReadFile(rotated.KeyFile)
+	require.NoError(t, err)
+	writeFile(t, cfg.HTTP.TLS.KeyFile, keyPEM)
+	writeFile(t, cfg.HTTP.TLS.CertFile, certPEM)
+
+	resp, err = httpsGet(url, second.pool())
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	_, err = httpsGet(url, first.pool())
+	assert.Error(t, err)
+
+	// broken files keep the previous certificate
+	writeFile(t, cfg.HTTP.TLS.CertFile, []byte("broken"))
+	resp, err = httpsGet(url, second.pool())
+	require.NoError(t, err)
+	resp.Body.Close()
+}
+
+func TestGRPCMutualTLS(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	serverCA, clientCA, otherCA := newTestCA(t, "server"), newTestCA(t, "clients"), newTestCA(t, "other")
+	cfg := ephemeralConfig()
+	cfg.GRPC.TLS = serverFiles(t, dir, serverCA)
+	cfg.GRPC.TLS.ClientCAFile = writeFile(t, filepath.Join(dir, "clients.crt"), clientCA.pem)
+	cfg.GRPC.TLS.Principals = map[string]string{"CN=billing,O=ads": "billing-service"}
+	logs := &syncBuffer{}
+	srv := runTLSServer(t, cfg, logs)
+
+	check := func(t *testing.T, certs ...tls.Certificate) error {
+		creds := credentials.NewTLS(&tls.Config{RootCAs: serverCA.pool(), Certificates: certs})
+		conn, err := grpc.DialContext(context.Background(), srv.GRPCAddr().String(), grpc.WithTransportCredentials(creds))
+		require.NoError(t, err)
+		defer conn.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+		return err
+	}
+
+	t.Run("mapped subject", func(t *testing.T) {
+		require.NoError(t, check(t, clientCA.clientCert(t, pkix.Name{CommonName: "billing", Organization: []string{"ads"}})))
+	})
+	t.Run("unknown subject", func(t *testing.T) {
+		err := check(t, clientCA.clientCert(t, pkix.Name{CommonName: "search"}))
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+	t.Run("unknown subject on a stream", func(t *testing.T) {
+		creds := credentials.NewTLS(&tls.Config{
+			RootCAs:      serverCA.pool(),
+			Certificates: []tls.Certificate{clientCA.clientCert(t, pkix.Name{CommonName: "search"})},
+		})
+		conn, err := grpc.DialContext(context.Background(), srv.GRPCAddr().String(), grpc.WithTransportCredentials(creds))
+		require.NoError(t, err)
+		defer conn.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		stream, err := grpcPort.NewAdServiceClient(conn).ImportAds(ctx)
+		require.NoError(t, err)
+		_ = stream.Send(&grpcPort.ImportAdsRequest{Item: &grpcPort.ImportAdsRequest_Options{Options: &grpcPort.ImportOptions{UserId: 0}}})
+		_, err = stream.CloseAndRecv()
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+	t.Run("untrusted CA", func(t *testing.T) {
+		err := check(t, otherCA.clientCert(t, pkix.Name{CommonName: "billing", Organization: []string{"ads"}}))
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	})
+	t.Run("no certificate", func(t *testing.T) {
+		err := check(t)
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	})
+	t.Run("plaintext", func(t *testing.T) {
+		conn, err := grpc.DialContext(context.Background(), srv.GRPCAddr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+		require.NoError(t, err)
+		defer conn.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+		assert.Error(t, err)
+	})
+}
+
+func TestGRPCPrincipalIsLogged(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	serverCA, clientCA := newTestCA(t, "server"), newTestCA(t, "clients")
+	cfg := ephemeralConfig()
+	cfg.GRPC.TLS = serverFiles(t, dir, serverCA)
+	cfg.GRPC.TLS.ClientCAFile = writeFile(t, filepath.Join(dir, "clients.crt"), clientCA.pem)
+	logs := &syncBuffer{}
+	srv := runTLSServer(t, cfg, logs)
+
+	// without principals the common name is used
+	dir = t.TempDir()
+	certPEM, keyPEM := clientCA.issue(t, pkix.Name{CommonName: "billing"}, x509.ExtKeyUsageClientAuth)
+	tlsCfg, err := certs.ClientConfig(
+		writeFile(t, filepath.Join(dir, "ca.crt"), serverCA.pem),
+		writeFile(t, filepath.Join(dir, "client.crt"), certPEM),
+		writeFile(t, filepath.Join(dir, "client.key"), keyPEM),
+	)
+	require.NoError(t, err)
+	conn, err := grpc.DialContext(context.Background(), srv.GRPCAddr().String(), grpc.WithTransportCredentials(credentials.NewTLS(tlsCfg)))
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = grpcPort.NewAdServiceClient(conn).CreateUser(context.Background(), &grpcPort.CreateUserRequest{Name: "billing", Email: "billing@example.com"})
+	require.NoError(t, err)
+	assert.Contains(t, logs.String(), `"principal":"billing"`)
+}
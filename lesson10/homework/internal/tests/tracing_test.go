@@ -0,0 +1,109 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"homework10/internal/config"
+	"homework10/internal/ports"
+	grpcPort "homework10/internal/ports/grpc"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	httpTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	grpcTraceID = "0af7651916cd43dd8448eb211c80319c"
+	remoteSpan  = "00f067aa0ba902b7"
+)
+
+type spanContext struct {
+	TraceID string `json:"TraceID"`
+	SpanID  string `json:"SpanID"`
+}
+
+type exportedSpan struct {
+	Name        string      `json:"Name"`
+	SpanContext spanContext `json:"SpanContext"`
+	Parent      spanContext `json:"Parent"`
+}
+
+func readSpans(t *testing.T, path string) map[string]exportedSpan {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	spans := map[string]exportedSpan{}
+	dec := json.NewDecoder(f)
+	for {
+		var s exportedSpan
+		err := dec.Decode(&s)
+		if errors.Is(err, io.EOF) {
+			return spans
+		}
+		require.NoError(t, err)
+		spans[s.Name] = s
+	}
+}
+
+func TestTracePropagation(t *testing.T) {
+	cfg := ephemeralConfig()
+	cfg.Tracing.Exporter = config.ExporterFile
+	cfg.Tracing.File = filepath.Join(t.TempDir(), "spans.json")
+
+	ctx := context.Background()
+	tel, err := ports.NewTelemetry(ctx, cfg)
+	require.NoError(t, err)
+	a, err := ports.NewApp(cfg, tel, nil)
+	require.NoError(t, err)
+	srv := runAppServer(t, cfg, a, tel)
+
+	conn, err := grpc.DialContext(ctx, srv.grpcAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	client := grpcPort.NewAdServiceClient(conn)
+	mdCtx := metadata.AppendToOutgoingContext(ctx, "traceparent", "00-"+grpcTraceID+"-"+remoteSpan+"-01")
+	usr, err := client.CreateUser(mdCtx, &grpcPort.CreateUserRequest{Name: "Oleg"})
+	require.NoError(t, err)
+	conn.Close()
+
+	body := strings.NewReader(`{"user_id": ` + strconv.FormatInt(usr.GetId(), 10) + `, "title": "title", "text": "text"}`)
+	req, err := http.NewRequest(http.MethodPost, srv.baseURL+"/api/v1/ads", body)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("traceparent", "00-"+httpTraceID+"-"+remoteSpan+"-01")
+	resp, err := srv.client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// the spans are flushed when the telemetry shuts down with the servers
+	srv.stop()
+
+	spans := readSpans(t, cfg.Tracing.File)
+
+	rpc := spans["ad.AdService/CreateUser"]
+	assert.Equal(t, grpcTraceID, rpc.SpanContext.TraceID)
+	assert.Equal(t, remoteSpan, rpc.Parent.SpanID)
+	assert.Equal(t, rpc.SpanContext.SpanID, spans["App.CreateUser"].Parent.SpanID)
+	assert.Equal(t, spans["App.CreateUser"].SpanContext.SpanID, spans["UserRepository.AppendUser"].Parent.SpanID)
+
+	route := spans["POST /api/v1/ads"]
+	assert.Equal(t, httpTraceID, route.SpanContext.TraceID)
+	assert.Equal(t, remoteSpan, route.Parent.SpanID)
+	createAd := spans["App.CreateAd"]
+	assert.Equal(t, httpTraceID, createAd.SpanContext.TraceID)
+	assert.Equal(t, route.SpanContext.SpanID, createAd.Parent.SpanID)
+	assert.Equal(t, createAd.SpanContext.SpanID, spans["AdRepository.AppendAd"].Parent.SpanID)
+	assert.Equal(t, createAd.SpanContext.SpanID, spans["UserRepository.GetUserByID"].Parent.SpanID)
+}
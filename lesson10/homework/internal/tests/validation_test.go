@@ -2,8 +2,7 @@ package tests
 
 import (
 	"context"
-	"homework10/internal/ports"
-	"net/http"
+	"homework10/pkg/adsclient"
 	"strings"
 	"testing"
 
@@ -13,28 +12,20 @@ import (
 
 type ValidatonTestSuite struct {
 	suite.Suite
-	hsrv *http.Server
-	cf   context.CancelFunc
-	ch   chan int
-	t    *testing.T
+	srv *testServer
+	t   *testing.T
 }
 
 func (suite *ValidatonTestSuite) SetupTest() {
-	ctx, cf := context.WithCancel(context.Background())
-	suite.cf = cf
-	endChan := make(chan int)
-	suite.ch = endChan
-	suite.hsrv, _ = ports.CreateServer(ctx, endChan)
-	client := getTestClient(suite.hsrv.Addr)
-	_, err := client.createUser("Admin", "admin@powerful.com")
+	suite.srv = runServer(suite.t, ephemeralConfig())
+	_, err := suite.srv.api.CreateUser(context.Background(), "Admin", "admin@powerful.com")
 	if err != nil {
 		panic(err)
 	}
 }
 
 func (suite *ValidatonTestSuite) TearDownTest() {
-	suite.cf()
-	<-suite.ch
+	suite.srv.stop()
 }
 
 func TestValidatonTestSuite(t *testing.T) {
@@ -44,77 +35,98 @@ func TestValidatonTestSuite(t *testing.T) {
 }
 
 func (suite *ValidatonTestSuite) TestCreateAd_EmptyTitle() {
-	client := getTestClient(suite.hsrv.Addr)
+	ctx := context.Background()
+	client := suite.srv.api
 
-	_, err := client.createAd(0, "", "world")
-	assert.ErrorIs(suite.t, err, ErrBadRequest)
+	_, err := client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: 0, Title: "", Text: "world"})
+	assert.ErrorIs(suite.t, err, adsclient.ErrInvalidArgument)
 }
 
 func (suite *ValidatonTestSuite) TestCreateAd_TooLongTitle() {
-	client := getTestClient(suite.hsrv.Addr)
+	ctx := context.Background()
+	client := suite.srv.api
 
 	title := strings.Repeat("a", 101)
 
-	_, err := client.createAd(0, title, "world")
-	assert.ErrorIs(suite.t, err, ErrBadRequest)
+	_, err := client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: 0, Title: title, Text: "world"})
+	assert.ErrorIs(suite.t, err, adsclient.ErrInvalidArgument)
 }
 
 func (suite *ValidatonTestSuite) TestCreateAd_EmptyText() {
-	client := getTestClient(suite.hsrv.Addr)
+	ctx := context.Background()
+	client := suite.srv.api
 
-	_, err := client.createAd(0, "title", "")
-	assert.ErrorIs(suite.t, err, ErrBadRequest)
+	_, err := client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: 0, Title: "title", Text: ""})
+	assert.ErrorIs(suite.t, err, adsclient.ErrInvalidArgument)
 }
 
 func (suite *ValidatonTestSuite) TestCreateAd_TooLongText() {
-	client := getTestClient(suite.hsrv.Addr)
+	ctx := context.Background()
+	client := suite.srv.api
 
 	text := strings.Repeat("a", 501)
 
-	_, err := client.createAd(123, "title", text)
-	assert.ErrorIs(suite.t, err, ErrBadRequest)
+	_, err := client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: 123, Title: "title", Text: text})
+	assert.ErrorIs(suite.t, err, adsclient.ErrInvalidArgument)
 }
 
 func (suite *ValidatonTestSuite) TestUpdateAd_EmptyTitle() {
-	client := getTestClient(suite.hsrv.Addr)
+	ctx := context.Background()
+	client := suite.srv.api
 
-	resp, err := client.createAd(0, "hello", "world")
+	resp, err := client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: 0, Title: "hello", Text: "world"})
 	assert.NoError(suite.t, err)
 
-	_, err = client.updateAd(0, resp.Data.ID, "", "new_world")
-	assert.ErrorIs(suite.t, err, ErrBadRequest)
+	_, err = client.UpdateAd(ctx, resp.ID, adsclient.UpdateAdRequest{UserID: 0, Title: "", Text: "new_world"})
+	assert.ErrorIs(suite.t, err, adsclient.ErrInvalidArgument)
 }
 
 func (suite *ValidatonTestSuite) TestUpdateAd_TooLongTitle() {
-	client := getTestClient(suite.hsrv.Addr)
+	ctx := context.Background()
+	client := suite.srv.api
 
-	resp, err := client.createAd(0, "hello", "world")
+	resp, err := client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: 0, Title: "hello", Text: "world"})
 	assert.NoError(suite.t, err)
 
 	title := strings.Repeat("a", 101)
 
-	_, err = client.updateAd(0, resp.Data.ID, title, "world")
-	assert.ErrorIs(suite.t, err, ErrBadRequest)
+	_, err = client.UpdateAd(ctx, resp.ID, adsclient.UpdateAdRequest{UserID: 0, Title: title, Text: "world"})
+	assert.ErrorIs(suite.t, err, adsclient.ErrInvalidArgument)
 }
 
 func (suite *ValidatonTestSuite) TestUpdateAd_EmptyText() {
-	client := getTestClient(suite.hsrv.Addr)
+	ctx := context.Background()
+	client := suite.srv.api
 
-	resp, err := client.createAd(0, "hello", "world")
+	resp, err := client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: 0, Title: "hello", Text: "world"})
 	assert.NoError(suite.t, err)
 
-	_, err = client.updateAd(0, resp.Data.ID, "title", "")
-	assert.ErrorIs(suite.t, err, ErrBadRequest)
+	_, err = client.UpdateAd(ctx, resp.ID, adsclient.UpdateAdRequest{UserID: 0, Title: "title", Text: ""})
+	assert.ErrorIs(suite.t, err, adsclient.ErrInvalidArgument)
 }
 
 func (suite *ValidatonTestSuite) TestUpdateAd_TooLongText() {
-	client := getTestClient(suite.hsrv.Addr)
+	ctx := context.Background()
+	client := suite.srv.api
 
 	text := strings.Repeat("a", 501)
 
-	resp, err := client.createAd(0, "hello", "world")
+	resp, err := client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: 0, Title: "hello", Text: "world"})
 	assert.NoError(suite.t, err)
 
-	_, err = client.updateAd(0, resp.Data.ID, "title", text)
-	assert.ErrorIs(suite.t, err, ErrBadRequest)
+	_, err = client.UpdateAd(ctx, resp.ID, adsclient.UpdateAdRequest{UserID: 0, Title: "title", Text: text})
+	assert.ErrorIs(suite.t, err, adsclient.ErrInvalidArgument)
+}
+
+func (suite *ValidatonTestSuite) TestCreateAd_FieldViolations() {
+	ctx := context.Background()
+	client := suite.srv.api
+
+	_, err := client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: 0, Title: "", Text: strings.Repeat("a", 501)})
+	var apiErr *adsclient.Error
+	assert.ErrorAs(suite.t, err, &apiErr)
+	assert.Equal(suite.t, adsclient.CodeInvalidArgument, apiErr.Code)
+	assert.Len(suite.t, apiErr.InvalidParams, 2)
+	assert.Equal(suite.t, "title", apiErr.InvalidParams[0].Field)
+	assert.Equal(suite.t, "text", apiErr.InvalidParams[1].Field)
 }
@@ -0,0 +1,404 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"homework10/internal/ads"
+	"homework10/internal/webhooks"
+	"homework10/pkg/adsclient"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+const webhookSecret = "0123456789abcdef"
+
+// received is a request that reached the receiver, with a verified
+// signature.
+type received struct {
+	event    webhooks.Event
+	header   http.Header
+	verified bool
+}
+
+type WebhooksTestSuite struct {
+	suite.Suite
+	client   *adsclient.Client
+	baseURL  string
+	hooks    *webhooks.Dispatcher
+	stop     func()
+	receiver *httptest.Server
+	requests chan received
+	// failures is the number of the next requests answered with 500
+	failures atomic.Int64
+}
+
+func (suite *WebhooksTestSuite) SetupTest() {
+	suite.requests = make(chan received, 100)
+	suite.failures.Store(0)
+	suite.receiver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if suite.failures.Add(-1) >= 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		var e webhooks.Event
+		_ = json.Unmarshal(body, &e)
+		verified := webhooks.Verify(webhookSecret, r.Header.Get(webhooks.TimestampHeader), body, r.Header.Get(webhooks.SignatureHeader))
+		suite.requests <- received{event: e, header: r.Header, verified: verified}
+	}))
+
+	cfg := ephemeralConfig()
+	cfg.Webhooks.MaxAttempts = 3
+	cfg.Webhooks.Backoff = 10 * time.Millisecond
+	cfg.Webhooks.MaxBackoff = 20 * time.Millisecond
+	// the receiver listens on the loopback
+	cfg.Webhooks.AllowPrivateAddresses = true
+	_, hooks, srv := startServerWithWebhooks(suite.T(), cfg)
+	suite.hooks = hooks
+	suite.stop = srv.stop
+	suite.baseURL = srv.baseURL
+	suite.client = srv.api
+}
+
+func (suite *WebhooksTestSuite) TearDownTest() {
+	suite.stop()
+	suite.receiver.Close()
+}
+
+func TestWebhooksTestSuite(t *testing.T) {
+	suite.Run(t, new(WebhooksTestSuite))
+}
+
+func (suite *WebhooksTestSuite) subscribe(userID int64, events ...adsclient.EventType) *adsclient.Webhook {
+	hook, err := suite.client.CreateWebhook(context.Background(), userID, adsclient.CreateWebhookRequest{
+		URL: suite.receiver.URL + "/hook", Events: events, Secret: webhookSecret,
+	})
+	suite.Require().NoError(err)
+	return hook
+}
+
+// waitDeliveries waits until all deliveries of the webhook are finished.
+func (suite *WebhooksTestSuite) waitDeliveries(userID int64, webhookID int64, n int) []adsclient.Delivery {
+	var res []adsclient.Delivery
+	suite.Require().Eventually(func() bool {
+		var err error
+		res, err = suite.client.ListDeliveries(context.Background(), userID, webhookID)
+		suite.Require().NoError(err)
+		if len(res) != n {
+			return false
+		}
+		for _, d := range res {
+			if d.Status == adsclient.DeliveryPending {
+				return false
+			}
+		}
+		return true
+	}, 5*time.Second, 10*time.Millisecond)
+	return res
+}
+
+func (suite *WebhooksTestSuite) receive() received {
+	select {
+	case r := <-suite.requests:
+		return r
+	case <-time.After(5 * time.Second):
+		suite.FailNow("no delivery received")
+		return received{}
+	}
+}
+
+func (suite *WebhooksTestSuite) TestSignedEventsOfAdLifecycle() {
+	ctx := context.Background()
+	usr, err := suite.client.CreateUser(ctx, "Partner", "partner@mail.org")
+	suite.Require().NoError(err)
+	hook := suite.subscribe(usr.ID, adsclient.AdCreated, adsclient.AdPublished, adsclient.AdUpdated, adsclient.AdDeleted)
+	suite.Equal(webhookSecret, hook.Secret)
+
+	ad, err := suite.client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: usr.ID, Title: "bike", Text: "red"})
+	suite.Require().NoError(err)
+	_, err = suite.client.ChangeAdStatus(ctx, ad.ID, usr.ID, true)
+	suite.Require().NoError(err)
+	_, err = suite.client.UpdateAd(ctx, ad.ID, adsclient.UpdateAdRequest{UserID: usr.ID, Title: "bike", Text: "blue"})
+	suite.Require().NoError(err)
+	_, err = suite.client.DeleteAd(ctx, ad.ID, usr.ID)
+	suite.Require().NoError(err)
+
+	var types []string
+	for i := 0; i < 4; i++ {
+		r := suite.receive()
+		suite.True(r.verified, "signature of %s", r.event.Type)
+		suite.Equal(string(r.event.Type), r.header.Get(webhooks.EventHeader))
+		suite.NotEmpty(r.header.Get(webhooks.DeliveryHeader))
+		suite.Equal(ad.ID, r.event.Ad.ID)
+		suite.NotEmpty(r.event.ID)
+		types = append(types, string(r.event.Type))
+	}
+	sort.Strings(types)
+	suite.Equal([]string{"ad.created", "ad.deleted", "ad.published", "ad.updated"}, types)
+
+	for _, d := range suite.waitDeliveries(usr.ID, hook.ID, 4) {
+		suite.Equal(adsclient.DeliverySucceeded, d.Status)
+		suite.Equal(1, d.Attempts)
+		suite.Equal(http.StatusOK, d.ResponseCode)
+	}
+}
+
+func (suite *WebhooksTestSuite) TestOnlyEventsOfOwnAdsAndWantedTypes() {
+	ctx := context.Background()
+	partner, err := suite.client.CreateUser(ctx, "Partner", "")
+	suite.Require().NoError(err)
+	other, err := suite.client.CreateUser(ctx, "Other", "")
+	suite.Require().NoError(err)
+	hook := suite.subscribe(partner.ID, adsclient.AdPublished)
+
+	_, err = suite.client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: other.ID, Title: "car", Text: "black"})
+	suite.Require().NoError(err)
+	_, err = suite.client.ChangeAdStatus(ctx, 0, other.ID, true)
+	suite.Require().NoError(err)
+	ad, err := suite.client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: partner.ID, Title: "bike", Text: "red"})
+	suite.Require().NoError(err)
+	// publishing twice changes the status once
+	for i := 0; i < 2; i++ {
+		_, err = suite.client.ChangeAdStatus(ctx, ad.ID, partner.ID, true)
+		suite.Require().NoError(err)
+	}
+
+	r := suite.receive()
+	suite.Equal(webhooks.AdPublished, r.event.Type)
+	suite.Equal(ad.ID, r.event.Ad.ID)
+	suite.True(r.event.Ad.Published)
+	suite.Len(suite.waitDeliveries(partner.ID, hook.ID, 1), 1)
+	suite.Empty(suite.requests)
+}
+
+func (suite *WebhooksTestSuite) TestRetryWithBackoff() {
+	ctx := context.Background()
+	usr, err := suite.client.CreateUser(ctx, "Partner", "")
+	suite.Require().NoError(err)
+	hook := suite.subscribe(usr.ID, adsclient.AdCreated)
+	suite.failures.Store(2)
+
+	_, err = suite.client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: usr.ID, Title: "bike", Text: "red"})
+	suite.Require().NoError(err)
+
+	r := suite.receive()
+	suite.True(r.verified)
+	res := suite.waitDeliveries(usr.ID, hook.ID, 1)
+	suite.Equal(adsclient.DeliverySucceeded, res[0].Status)
+	suite.Equal(3, res[0].Attempts)
+}
+
+func (suite *WebhooksTestSuite) TestFailedDeliveryIsReplayed() {
+	ctx := context.Background()
+	usr, err := suite.client.CreateUser(ctx, "Partner", "")
+	suite.Require().NoError(err)
+	hook := suite.subscribe(usr.ID, adsclient.AdCreated)
+	suite.failures.Store(3)
+
+	_, err = suite.client.CreateAd(ctx, adsclient.CreateAdRequest{UserID: usr.ID, Title: "bike", Text: "red"})
+	suite.Require().NoError(err)
+	res := suite.waitDeliveries(usr.ID, hook.ID, 1)
+	suite.Equal(adsclient.DeliveryFailed, res[0].Status)
+	suite.Equal(3, res[0].Attempts)
+	suite.Equal(http.StatusInternalServerError, res[0].ResponseCode)
+
+	replay, err := suite.client.ReplayDelivery(ctx, usr.ID, hook.ID, res[0].ID)
+	suite.Require().NoError(err)
+	suite.Equal(res[0].ID, replay.ReplayOf)
+
+	r := suite.receive()
+	suite.True(r.verified)
+	suite.Equal(webhooks.AdCreated, r.event.Type)
+	res = suite.waitDeliveries(usr.ID, hook.ID, 2)
+	suite.Equal(adsclient.DeliverySucceeded, res[1].Status)
+	suite.Equal(1, res[1].Attempts)
+
+	_, err = suite.client.ReplayDelivery(ctx, usr.ID, hook.ID, 42)
+	suite.ErrorIs(err, adsclient.ErrNotFound)
+}
+
+func (suite *WebhooksTestSuite) TestManageWebhooks() {
+	ctx := context.Background()
+	usr, err := suite.client.CreateUser(ctx, "Partner", "")
+	suite.Require().NoError(err)
+	other, err := suite.client.CreateUser(ctx, "Other", "")
+	suite.Require().NoError(err)
+
+	created, err := suite.client.CreateWebhook(ctx, usr.ID, adsclient.CreateWebhookRequest{
+		URL: "https://partner.org/hook", Events: []adsclient.EventType{adsclient.AdCreated},
+	})
+	suite.Require().NoError(err)
+	suite.Len(created.Secret, 64, "a secret is generated")
+
+	list, err := suite.client.ListWebhooks(ctx, usr.ID)
+	suite.Require().NoError(err)
+	suite.Require().Len(list, 1)
+	suite.Equal(created.ID, list[0].ID)
+	// the secret is not even sent back
+	resp, err := http.Get(fmt.Sprintf("%s/api/v1/users/%d/webhooks", suite.baseURL, usr.ID))
+	suite.Require().NoError(err)
+	defer resp.Body.Close()
+	var raw struct {
+		Data []map[string]any `json:"data"`
+	}
+	suite.Require().NoError(json.NewDecoder(resp.Body).Decode(&raw))
+	suite.Require().Len(raw.Data, 1)
+	suite.NotContains(raw.Data[0], "secret")
+
+	_, err = suite.client.DeleteWebhook(ctx, other.ID, created.ID)
+	suite.ErrorIs(err, adsclient.ErrPermissionDenied)
+	_, err = suite.client.DeleteWebhook(ctx, usr.ID, created.ID)
+	suite.NoError(err)
+	_, err = suite.client.DeleteWebhook(ctx, usr.ID, created.ID)
+	suite.ErrorIs(err, adsclient.ErrNotFound)
+
+	_, err = suite.client.CreateWebhook(ctx, usr.ID, adsclient.CreateWebhookRequest{
+		URL: "ftp://partner.org", Events: []adsclient.EventType{"ad.sold"}, Secret: "short",
+	})
+	var apiErr *adsclient.Error
+	suite.Require().ErrorAs(err, &apiErr)
+	suite.Equal(http.StatusBadRequest, apiErr.Status)
+	var fields []string
+	for _, f := range apiErr.InvalidParams {
+		fields = append(fields, f.Field)
+	}
+	suite.Equal([]string{"url", "events", "secret"}, fields)
+
+	_, err = suite.client.ListWebhooks(ctx, 42)
+	suite.ErrorIs(err, adsclient.ErrNotFound)
+
+	// webhooks of a deleted user are deleted too
+	suite.subscribe(other.ID, adsclient.AdCreated)
+	_, err = suite.client.DeleteUser(ctx, other.ID)
+	suite.Require().NoError(err)
+	subs, err := suite.hooks.Subscriptions(context.Background(), other.ID)
+	suite.NoError(err)
+	suite.Empty(subs)
+}
+
+func TestWebhookSignature(t *testing.T) {
+	body := []byte(`{"type":"ad.created"}`)
+	sig := webhooks.Sign(webhookSecret, "1700000000", body)
+	assert.Regexp(t, "^sha256=[0-9a-f]{64}$", sig)
+	assert.True(t, webhooks.Verify(webhookSecret, "1700000000", body, sig))
+	assert.False(t, webhooks.Verify(webhookSecret, "1700000001", body, sig))
+	assert.False(t, webhooks.Verify("another secret!!", "1700000000", body, sig))
+	assert.False(t, webhooks.Verify(webhookSecret, "1700000000", []byte(`{"type":"ad.deleted"}`), sig))
+}
+
+// finished waits until the deliveries of the subscription are finished and
+// returns them.
+func finished(t *testing.T, d *webhooks.Dispatcher, subscriptionID int64) []webhooks.Delivery {
+	var res []webhooks.Delivery
+	require.Eventually(t, func() bool {
+		var err error
+		res, err = d.Deliveries(context.Background(), subscriptionID)
+		require.NoError(t, err)
+		for _, dl := range res {
+			if dl.Status == webhooks.StatusPending {
+				return false
+			}
+		}
+		return true
+	}, 5*time.Second, 10*time.Millisecond)
+	return res
+}
+
+func TestWebhookHistoryKeepsPendingDeliveries(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	ok := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer ok.Close()
+
+	d := webhooks.New(webhooks.WithHistory(3), webhooks.WithRetries(2, time.Hour, time.Hour), webhooks.WithPrivateAddresses(true))
+	defer d.Close(context.Background())
+	ctx := context.Background()
+	slow, err := d.Subscribe(ctx, webhooks.Subscription{UserID: 1, URL: failing.URL, Events: webhooks.EventTypes})
+	require.NoError(t, err)
+	fast, err := d.Subscribe(ctx, webhooks.Subscription{UserID: 2, URL: ok.URL, Events: webhooks.EventTypes})
+	require.NoError(t, err)
+
+	// the oldest delivery waits an hour for its retry
+	d.Publish(ctx, webhooks.Event{Type: webhooks.AdCreated, Ad: ads.Ad{ID: 1, AuthorID: 1}})
+	require.Eventually(t, func() bool {
+		arr, err := d.Deliveries(ctx, slow.ID)
+		require.NoError(t, err)
+		return len(arr) == 1 && arr[0].Attempts == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	for i := 0; i < 6; i++ {
+		d.Publish(ctx, webhooks.Event{Type: webhooks.AdCreated, Ad: ads.Ad{ID: int64(i + 2), AuthorID: 2}})
+		finished(t, d, fast.ID)
+	}
+	// the finished deliveries behind it are dropped
+	assert.Len(t, finished(t, d, fast.ID), 2)
+	arr, err := d.Deliveries(ctx, slow.ID)
+	require.NoError(t, err)
+	require.Len(t, arr, 1)
+	assert.Equal(t, webhooks.StatusPending, arr[0].Status)
+}
+
+func TestWebhookPrivateAddressesRefused(t *testing.T) {
+	var hits atomic.Int64
+	receiver := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		hits.Add(1)
+	}))
+	defer receiver.Close()
+
+	d := webhooks.New(webhooks.WithRetries(1, time.Millisecond, time.Millisecond))
+	defer d.Close(context.Background())
+	ctx := context.Background()
+	for _, url := range []string{
+		receiver.URL,
+		strings.Replace(receiver.URL, "127.0.0.1", "localhost", 1),
+		"http://169.254.169.254/latest/meta-data/",
+		"http://10.0.0.1/hook",
+		"http://[::1]:1/hook",
+	} {
+		s, err := d.Subscribe(ctx, webhooks.Subscription{UserID: 1, URL: url, Events: webhooks.EventTypes})
+		require.NoError(t, err)
+		d.Publish(ctx, webhooks.Event{Type: webhooks.AdCreated, Ad: ads.Ad{AuthorID: 1}})
+		arr := finished(t, d, s.ID)
+		require.Len(t, arr, 1, url)
+		assert.Equal(t, webhooks.StatusFailed, arr[0].Status, url)
+		assert.Contains(t, arr[0].LastError, "is not public", url)
+		_, err = d.Unsubscribe(ctx, s.ID)
+		require.NoError(t, err)
+	}
+	assert.Zero(t, hits.Load())
+}
+
+func TestWebhookRedirectNotFollowed(t *testing.T) {
+	var hits atomic.Int64
+	target := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		hits.Add(1)
+	}))
+	defer target.Close()
+	redirect := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusTemporaryRedirect))
+	defer redirect.Close()
+
+	d := webhooks.New(webhooks.WithRetries(1, time.Millisecond, time.Millisecond), webhooks.WithPrivateAddresses(true))
+	defer d.Close(context.Background())
+	ctx := context.Background()
+	s, err := d.Subscribe(ctx, webhooks.Subscription{UserID: 1, URL: redirect.URL, Events: webhooks.EventTypes})
+	require.NoError(t, err)
+	d.Publish(ctx, webhooks.Event{Type: webhooks.AdCreated, Ad: ads.Ad{AuthorID: 1}})
+	arr := finished(t, d, s.ID)
+	require.Len(t, arr, 1)
+	assert.Equal(t, webhooks.StatusFailed, arr[0].Status)
+	assert.Equal(t, http.StatusTemporaryRedirect, arr[0].ResponseCode)
+	assert.Zero(t, hits.Load())
+}
@@ -0,0 +1,288 @@
+package tracing
+
+import (
+	"context"
+	"homework10/internal/ads"
+	"homework10/internal/app"
+	"homework10/internal/engagement"
+	"homework10/internal/revisions"
+	"homework10/internal/users"
+	"homework10/internal/webhooks"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type tracedApp struct {
+	next   app.App
+	tracer trace.Tracer
+}
+
+// App decorates a with a span per method call.
+func App(a app.App, tp trace.TracerProvider) app.App {
+	return &tracedApp{next: a, tracer: tp.Tracer(instrumentation)}
+}
+
+// record marks the span as failed when err is not nil and returns err.
+func record(span trace.Span, err error) error {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+func adID(id int64) attribute.KeyValue      { return attribute.Int64("ad.id", id) }
+func authorID(id int64) attribute.KeyValue  { return attribute.Int64("ad.author_id", id) }
+func userID(id int64) attribute.KeyValue    { return attribute.Int64("user.id", id) }
+func webhookID(id int64) attribute.KeyValue { return attribute.Int64("webhook.id", id) }
+func staff(name string) attribute.KeyValue  { return attribute.String("staff", name) }
+
+func (t *tracedApp) CreateAd(ctx context.Context, Title string, Text string, AuthorID int64, Location *ads.Location, Offer *ads.Offer) (*ads.Ad, error) {
+	ctx, span := t.tracer.Start(ctx, "App.CreateAd", trace.WithAttributes(authorID(AuthorID)))
+	defer span.End()
+	ad, err := t.next.CreateAd(ctx, Title, Text, AuthorID, Location, Offer)
+	return ad, record(span, err)
+}
+
+func (t *tracedApp) ValidateAd(ctx context.Context, Title string, Text string, AuthorID int64, Location *ads.Location, Offer *ads.Offer) error {
+	ctx, span := t.tracer.Start(ctx, "App.ValidateAd", trace.WithAttributes(authorID(AuthorID)))
+	defer span.End()
+	return record(span, t.next.ValidateAd(ctx, Title, Text, AuthorID, Location, Offer))
+}
+
+func (t *tracedApp) ChangeAdStatus(ctx context.Context, ID int64, AuthorID int64, status bool) (*ads.Ad, error) {
+	ctx, span := t.tracer.Start(ctx, "App.ChangeAdStatus", trace.WithAttributes(adID(ID), authorID(AuthorID)))
+	defer span.End()
+	ad, err := t.next.ChangeAdStatus(ctx, ID, AuthorID, status)
+	return ad, record(span, err)
+}
+
+func (t *tracedApp) UpdateAd(ctx context.Context, ID int64, AuthorID int64, Title string, Text string, Location *ads.Location, Offer *ads.Offer) (*ads.Ad, error) {
+	ctx, span := t.tracer.Start(ctx, "App.UpdateAd", trace.WithAttributes(adID(ID), authorID(AuthorID)))
+	defer span.End()
+	ad, err := t.next.UpdateAd(ctx, ID, AuthorID, Title, Text, Location, Offer)
+	return ad, record(span, err)
+}
+
+func (t *tracedApp) GetAdByID(ctx context.Context, ID int64) (*ads.Ad, error) {
+	ctx, span := t.tracer.Start(ctx, "App.GetAdByID", trace.WithAttributes(adID(ID)))
+	defer span.End()
+	ad, err := t.next.GetAdByID(ctx, ID)
+	return ad, record(span, err)
+}
+
+func (t *tracedApp) DeleteAd(ctx context.Context, ID int64, AuthorID int64) (*ads.Ad, error) {
+	ctx, span := t.tracer.Start(ctx, "App.DeleteAd", trace.WithAttributes(adID(ID), authorID(AuthorID)))
+	defer span.End()
+	ad, err := t.next.DeleteAd(ctx, ID, AuthorID)
+	return ad, record(span, err)
+}
+
+func (t *tracedApp) BulkAds(ctx context.Context, AuthorID int64, ops []app.BulkOp, atomic bool) ([]app.BulkResult, error) {
+	ctx, span := t.tracer.Start(ctx, "App.BulkAds", trace.WithAttributes(
+		authorID(AuthorID),
+		attribute.Int("bulk.operations", len(ops)),
+		attribute.Bool("bulk.atomic", atomic),
+	))
+	defer span.End()
+	res, err := t.next.BulkAds(ctx, AuthorID, ops, atomic)
+	return res, record(span, err)
+}
+
+func (t *tracedApp) FavoriteAd(ctx context.Context, ID int64, UserID int64) (*ads.Ad, error) {
+	ctx, span := t.tracer.Start(ctx, "App.FavoriteAd", trace.WithAttributes(adID(ID), userID(UserID)))
+	defer span.End()
+	ad, err := t.next.FavoriteAd(ctx, ID, UserID)
+	return ad, record(span, err)
+}
+
+func (t *tracedApp) UnfavoriteAd(ctx context.Context, ID int64, UserID int64) (*ads.Ad, error) {
+	ctx, span := t.tracer.Start(ctx, "App.UnfavoriteAd", trace.WithAttributes(adID(ID), userID(UserID)))
+	defer span.End()
+	ad, err := t.next.UnfavoriteAd(ctx, ID, UserID)
+	return ad, record(span, err)
+}
+
+func (t *tracedApp) SendMessage(ctx context.Context, ID int64, SenderID int64, Text string) (*engagement.Message, error) {
+	ctx, span := t.tracer.Start(ctx, "App.SendMessage", trace.WithAttributes(adID(ID), userID(SenderID)))
+	defer span.End()
+	m, err := t.next.SendMessage(ctx, ID, SenderID, Text)
+	return m, record(span, err)
+}
+
+func (t *tracedApp) ListMessages(ctx context.Context, ID int64, AuthorID int64) ([]engagement.Message, error) {
+	ctx, span := t.tracer.Start(ctx, "App.ListMessages", trace.WithAttributes(adID(ID), authorID(AuthorID)))
+	defer span.End()
+	res, err := t.next.ListMessages(ctx, ID, AuthorID)
+	return res, record(span, err)
+}
+
+func (t *tracedApp) Select(ctx context.Context) ([]ads.Ad, error) {
+	ctx, span := t.tracer.Start(ctx, "App.Select")
+	defer span.End()
+	arr, err := t.next.Select(ctx)
+	return arr, record(span, err)
+}
+
+func (t *tracedApp) SelectByAuthor(ctx context.Context, AuthorID int64) ([]ads.Ad, error) {
+	ctx, span := t.tracer.Start(ctx, "App.SelectByAuthor", trace.WithAttributes(authorID(AuthorID)))
+	defer span.End()
+	arr, err := t.next.SelectByAuthor(ctx, AuthorID)
+	return arr, record(span, err)
+}
+
+func (t *tracedApp) SelectByCreation(ctx context.Context, time time.Time) ([]ads.Ad, error) {
+	ctx, span := t.tracer.Start(ctx, "App.SelectByCreation")
+	defer span.End()
+	arr, err := t.next.SelectByCreation(ctx, time)
+	return arr, record(span, err)
+}
+
+func (t *tracedApp) SelectAll(ctx context.Context) ([]ads.Ad, error) {
+	ctx, span := t.tracer.Start(ctx, "App.SelectAll")
+	defer span.End()
+	arr, err := t.next.SelectAll(ctx)
+	return arr, record(span, err)
+}
+
+func (t *tracedApp) FindByTitle(ctx context.Context, Title string) ([]ads.Ad, error) {
+	ctx, span := t.tracer.Start(ctx, "App.FindByTitle")
+	defer span.End()
+	arr, err := t.next.FindByTitle(ctx, Title)
+	return arr, record(span, err)
+}
+
+func (t *tracedApp) CreateUser(ctx context.Context, nickname string, email string) (*users.User, error) {
+	ctx, span := t.tracer.Start(ctx, "App.CreateUser")
+	defer span.End()
+	usr, err := t.next.CreateUser(ctx, nickname, email)
+	return usr, record(span, err)
+}
+
+func (t *tracedApp) UpdateUser(ctx context.Context, ID int64, nickname string, email string) (*users.User, error) {
+	ctx, span := t.tracer.Start(ctx, "App.UpdateUser", trace.WithAttributes(userID(ID)))
+	defer span.End()
+	usr, err := t.next.UpdateUser(ctx, ID, nickname, email)
+	return usr, record(span, err)
+}
+
+func (t *tracedApp) GetUserByID(ctx context.Context, ID int64) (*users.User, error) {
+	ctx, span := t.tracer.Start(ctx, "App.GetUserByID", trace.WithAttributes(userID(ID)))
+	defer span.End()
+	usr, err := t.next.GetUserByID(ctx, ID)
+	return usr, record(span, err)
+}
+
+func (t *tracedApp) ListUsers(ctx context.Context) ([]users.User, error) {
+	ctx, span := t.tracer.Start(ctx, "App.ListUsers")
+	defer span.End()
+	arr, err := t.next.ListUsers(ctx)
+	return arr, record(span, err)
+}
+
+func (t *tracedApp) DeleteUser(ctx context.Context, ID int64) (*users.User, error) {
+	ctx, span := t.tracer.Start(ctx, "App.DeleteUser", trace.WithAttributes(userID(ID)))
+	defer span.End()
+	usr, err := t.next.DeleteUser(ctx, ID)
+	return usr, record(span, err)
+}
+
+func (t *tracedApp) CreateWebhook(ctx context.Context, UserID int64, URL string, Events []webhooks.EventType, Secret string) (*webhooks.Subscription, error) {
+	ctx, span := t.tracer.Start(ctx, "App.CreateWebhook", trace.WithAttributes(userID(UserID)))
+	defer span.End()
+	s, err := t.next.CreateWebhook(ctx, UserID, URL, Events, Secret)
+	return s, record(span, err)
+}
+
+func (t *tracedApp) ListWebhooks(ctx context.Context, UserID int64) ([]webhooks.Subscription, error) {
+	ctx, span := t.tracer.Start(ctx, "App.ListWebhooks", trace.WithAttributes(userID(UserID)))
+	defer span.End()
+	subs, err := t.next.ListWebhooks(ctx, UserID)
+	return subs, record(span, err)
+}
+
+func (t *tracedApp) DeleteWebhook(ctx context.Context, ID int64, UserID int64) (*webhooks.Subscription, error) {
+	ctx, span := t.tracer.Start(ctx, "App.DeleteWebhook", trace.WithAttributes(webhookID(ID), userID(UserID)))
+	defer span.End()
+	s, err := t.next.DeleteWebhook(ctx, ID, UserID)
+	return s, record(span, err)
+}
+
+func (t *tracedApp) ListDeliveries(ctx context.Context, WebhookID int64, UserID int64) ([]webhooks.Delivery, error) {
+	ctx, span := t.tracer.Start(ctx, "App.ListDeliveries", trace.WithAttributes(webhookID(WebhookID), userID(UserID)))
+	defer span.End()
+	res, err := t.next.ListDeliveries(ctx, WebhookID, UserID)
+	return res, record(span, err)
+}
+
+func (t *tracedApp) ReplayDelivery(ctx context.Context, ID int64, WebhookID int64, UserID int64) (*webhooks.Delivery, error) {
+	ctx, span := t.tracer.Start(ctx, "App.ReplayDelivery", trace.WithAttributes(attribute.Int64("webhook.delivery_id", ID), webhookID(WebhookID), userID(UserID)))
+	defer span.End()
+	d, err := t.next.ReplayDelivery(ctx, ID, WebhookID, UserID)
+	return d, record(span, err)
+}
+
+func (t *tracedApp) SellerStats(ctx context.Context, UserID int64) (*app.SellerStats, error) {
+	ctx, span := t.tracer.Start(ctx, "App.SellerStats", trace.WithAttributes(userID(UserID)))
+	defer span.End()
+	stats, err := t.next.SellerStats(ctx, UserID)
+	return stats, record(span, err)
+}
+
+func (t *tracedApp) SimilarAds(ctx context.Context, ID int64, ViewerID int64, Limit int) ([]app.SimilarAd, error) {
+	ctx, span := t.tracer.Start(ctx, "App.SimilarAds", trace.WithAttributes(adID(ID)))
+	defer span.End()
+	res, err := t.next.SimilarAds(ctx, ID, ViewerID, Limit)
+	return res, record(span, err)
+}
+
+func (t *tracedApp) SearchNearby(ctx context.Context, q app.NearbyQuery) ([]app.NearbyAd, error) {
+	ctx, span := t.tracer.Start(ctx, "App.SearchNearby")
+	defer span.End()
+	res, err := t.next.SearchNearby(ctx, q)
+	return res, record(span, err)
+}
+
+func (t *tracedApp) FindUsers(ctx context.Context, Query string) ([]users.User, error) {
+	ctx, span := t.tracer.Start(ctx, "App.FindUsers")
+	defer span.End()
+	arr, err := t.next.FindUsers(ctx, Query)
+	return arr, record(span, err)
+}
+
+func (t *tracedApp) AdRevisions(ctx context.Context, ID int64) ([]revisions.Revision, error) {
+	ctx, span := t.tracer.Start(ctx, "App.AdRevisions", trace.WithAttributes(adID(ID)))
+	defer span.End()
+	revs, err := t.next.AdRevisions(ctx, ID)
+	return revs, record(span, err)
+}
+
+func (t *tracedApp) UnpublishAdAsStaff(ctx context.Context, ID int64, Staff string) (*ads.Ad, error) {
+	ctx, span := t.tracer.Start(ctx, "App.UnpublishAdAsStaff", trace.WithAttributes(adID(ID), staff(Staff)))
+	defer span.End()
+	ad, err := t.next.UnpublishAdAsStaff(ctx, ID, Staff)
+	return ad, record(span, err)
+}
+
+func (t *tracedApp) DeleteAdAsStaff(ctx context.Context, ID int64, Staff string) (*ads.Ad, error) {
+	ctx, span := t.tracer.Start(ctx, "App.DeleteAdAsStaff", trace.WithAttributes(adID(ID), staff(Staff)))
+	defer span.End()
+	ad, err := t.next.DeleteAdAsStaff(ctx, ID, Staff)
+	return ad, record(span, err)
+}
+
+func (t *tracedApp) AdsModified(ctx context.Context) time.Time {
+	ctx, span := t.tracer.Start(ctx, "App.AdsModified")
+	defer span.End()
+	return t.next.AdsModified(ctx)
+}
+
+func (t *tracedApp) MostViewed(ctx context.Context, Limit int) ([]app.ViewedAd, error) {
+	ctx, span := t.tracer.Start(ctx, "App.MostViewed")
+	defer span.End()
+	res, err := t.next.MostViewed(ctx, Limit)
+	return res, record(span, err)
+}
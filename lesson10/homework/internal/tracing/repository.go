@@ -0,0 +1,120 @@
+package tracing
+
+import (
+	"context"
+	"homework10/internal/ads"
+	"homework10/internal/app"
+	"homework10/internal/geo"
+	"homework10/internal/users"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+type tracedAdRepository struct {
+	next   app.AdRepository
+	tracer trace.Tracer
+}
+
+// AdRepository decorates r with a span per method call.
+func AdRepository(r app.AdRepository, tp trace.TracerProvider) app.AdRepository {
+	return &tracedAdRepository{next: r, tracer: tp.Tracer(instrumentation)}
+}
+
+func (t *tracedAdRepository) AppendAd(ctx context.Context, Title string, Text string, AuthorID int64, Location *ads.Location, Offer *ads.Offer) (*ads.Ad, error) {
+	ctx, span := t.tracer.Start(ctx, "AdRepository.AppendAd", trace.WithAttributes(authorID(AuthorID)))
+	defer span.End()
+	ad, err := t.next.AppendAd(ctx, Title, Text, AuthorID, Location, Offer)
+	return ad, record(span, err)
+}
+
+func (t *tracedAdRepository) ChangeAdStatus(ctx context.Context, ID int64, status bool) error {
+	ctx, span := t.tracer.Start(ctx, "AdRepository.ChangeAdStatus", trace.WithAttributes(adID(ID)))
+	defer span.End()
+	return record(span, t.next.ChangeAdStatus(ctx, ID, status))
+}
+
+func (t *tracedAdRepository) UpdateAd(ctx context.Context, ID int64, Text string, Title string, Location *ads.Location, Offer *ads.Offer) error {
+	ctx, span := t.tracer.Start(ctx, "AdRepository.UpdateAd", trace.WithAttributes(adID(ID)))
+	defer span.End()
+	return record(span, t.next.UpdateAd(ctx, ID, Text, Title, Location, Offer))
+}
+
+func (t *tracedAdRepository) GetAdByID(ctx context.Context, ID int64) (*ads.Ad, error) {
+	ctx, span := t.tracer.Start(ctx, "AdRepository.GetAdByID", trace.WithAttributes(adID(ID)))
+	defer span.End()
+	ad, err := t.next.GetAdByID(ctx, ID)
+	return ad, record(span, err)
+}
+
+func (t *tracedAdRepository) Select(ctx context.Context, f func(ads.Ad) bool) ([]ads.Ad, error) {
+	ctx, span := t.tracer.Start(ctx, "AdRepository.Select")
+	defer span.End()
+	arr, err := t.next.Select(ctx, f)
+	return arr, record(span, err)
+}
+
+func (t *tracedAdRepository) SelectArea(ctx context.Context, box geo.Box, f func(ads.Ad) bool) ([]ads.Ad, error) {
+	ctx, span := t.tracer.Start(ctx, "AdRepository.SelectArea")
+	defer span.End()
+	arr, err := t.next.SelectArea(ctx, box, f)
+	return arr, record(span, err)
+}
+
+func (t *tracedAdRepository) DeleteAd(ctx context.Context, ID int64) (*ads.Ad, error) {
+	ctx, span := t.tracer.Start(ctx, "AdRepository.DeleteAd", trace.WithAttributes(adID(ID)))
+	defer span.End()
+	ad, err := t.next.DeleteAd(ctx, ID)
+	return ad, record(span, err)
+}
+
+func (t *tracedAdRepository) Transaction(ctx context.Context, fn func(tx app.AdRepository) error) error {
+	ctx, span := t.tracer.Start(ctx, "AdRepository.Transaction")
+	defer span.End()
+	return record(span, t.next.Transaction(ctx, func(tx app.AdRepository) error {
+		return fn(&tracedAdRepository{next: tx, tracer: t.tracer})
+	}))
+}
+
+type tracedUserRepository struct {
+	next   app.UserRepository
+	tracer trace.Tracer
+}
+
+// UserRepository decorates r with a span per method call.
+func UserRepository(r app.UserRepository, tp trace.TracerProvider) app.UserRepository {
+	return &tracedUserRepository{next: r, tracer: tp.Tracer(instrumentation)}
+}
+
+func (t *tracedUserRepository) AppendUser(ctx context.Context, nickname string, email string) (*users.User, error) {
+	ctx, span := t.tracer.Start(ctx, "UserRepository.AppendUser")
+	defer span.End()
+	usr, err := t.next.AppendUser(ctx, nickname, email)
+	return usr, record(span, err)
+}
+
+func (t *tracedUserRepository) UpdateUser(ctx context.Context, ID int64, nickname string, email string) error {
+	ctx, span := t.tracer.Start(ctx, "UserRepository.UpdateUser", trace.WithAttributes(userID(ID)))
+	defer span.End()
+	return record(span, t.next.UpdateUser(ctx, ID, nickname, email))
+}
+
+func (t *tracedUserRepository) GetUserByID(ctx context.Context, ID int64) (*users.User, error) {
+	ctx, span := t.tracer.Start(ctx, "UserRepository.GetUserByID", trace.WithAttributes(userID(ID)))
+	defer span.End()
+	usr, err := t.next.GetUserByID(ctx, ID)
+	return usr, record(span, err)
+}
+
+func (t *tracedUserRepository) Select(ctx context.Context, f func(users.User) bool) ([]users.User, error) {
+	ctx, span := t.tracer.Start(ctx, "UserRepository.Select")
+	defer span.End()
+	arr, err := t.next.Select(ctx, f)
+	return arr, record(span, err)
+}
+
+func (t *tracedUserRepository) DeleteUser(ctx context.Context, ID int64) (*users.User, error) {
+	ctx, span := t.tracer.Start(ctx, "UserRepository.DeleteUser", trace.WithAttributes(userID(ID)))
+	defer span.End()
+	usr, err := t.next.DeleteUser(ctx, ID)
+	return usr, record(span, err)
+}
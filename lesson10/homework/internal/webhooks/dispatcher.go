@@ -0,0 +1,449 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultWorkers     = 4
+	defaultMaxAttempts = 6
+	defaultBackoff     = time.Second
+	defaultMaxBackoff  = 5 * time.Minute
+	defaultTimeout     = 10 * time.Second
+	defaultHistory     = 1000
+	queueSize          = 1024
+)
+
+// Dispatcher keeps the subscriptions and the delivery log in memory and
+// delivers the events by a pool of workers. A failed attempt is retried
+// with exponential backoff until the attempts are used up. Close must be
+// called to stop the workers.
+//
+// The URLs of the subscriptions are chosen by the users, so by default the
+// dispatcher only connects to public addresses, checked after the name is
+// resolved, and doesn't follow redirects.
+type Dispatcher struct {
+	client       *http.Client
+	logger       *slog.Logger
+	workers      int
+	maxAttempts  int
+	backoff      time.Duration
+	maxBackoff   time.Duration
+	timeout      time.Duration
+	allowPrivate bool
+	history      int
+	now          func() time.Time
+
+	mtx           sync.Mutex
+	closed        bool
+	subscriptions map[int64]*Subscription
+	nextSub       int64
+	deliveries    map[int64]*Delivery
+	order         []int64
+	nextDelivery  int64
+	retries       map[int64]*time.Timer
+
+	queue  chan int64
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+type Option func(*Dispatcher)
+
+// WithWorkers sets the number of concurrent deliveries.
+func WithWorkers(n int) Option {
+	return func(d *Dispatcher) {
+		d.workers = n
+	}
+}
+
+// WithRetries sets how many times an event is sent at most and the backoff
+// after the first failed attempt. The backoff doubles after every attempt up
+// to max.
+func WithRetries(attempts int, backoff time.Duration, max time.Duration) Option {
+	return func(d *Dispatcher) {
+		d.maxAttempts = attempts
+		d.backoff = backoff
+		d.maxBackoff = max
+	}
+}
+
+// WithTimeout bounds a single attempt.
+func WithTimeout(timeout time.Duration) Option {
+	return func(d *Dispatcher) {
+		d.timeout = timeout
+	}
+}
+
+// WithPrivateAddresses allows deliveries to loopback, private and
+// link-local addresses, e.g. for receivers in the same network.
+func WithPrivateAddresses(allowed bool) Option {
+	return func(d *Dispatcher) {
+		d.allowPrivate = allowed
+	}
+}
+
+// WithHistory sets how many deliveries are kept in the log. The oldest
+// finished ones are dropped first.
+func WithHistory(n int) Option {
+	return func(d *Dispatcher) {
+		d.history = n
+	}
+}
+
+// WithLogger sets the logger of the delivery attempts.
+func WithLogger(logger *slog.Logger) Option {
+	return func(d *Dispatcher) {
+		d.logger = logger
+	}
+}
+
+// New creates a dispatcher and starts its workers.
+func New(opts ...Option) *Dispatcher {
+	d := &Dispatcher{
+		logger:        slog.Default(),
+		workers:       defaultWorkers,
+		maxAttempts:   defaultMaxAttempts,
+		backoff:       defaultBackoff,
+		maxBackoff:    defaultMaxBackoff,
+		timeout:       defaultTimeout,
+		history:       defaultHistory,
+		now:           time.Now,
+		subscriptions: map[int64]*Subscription{},
+		deliveries:    map[int64]*Delivery{},
+		retries:       map[int64]*time.Timer{},
+		queue:         make(chan int64, queueSize),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	d.client = newClient(d.timeout, d.allowPrivate)
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+	for i := 0; i < d.workers; i++ {
+		d.wg.Add(1)
+		go d.work()
+	}
+	return d
+}
+
+// Close stops accepting events and waits for the running attempts. Retries
+// that are not due yet are dropped. When ctx is done first, the running
+// attempts are canceled.
+func (d *Dispatcher) Close(ctx context.Context) error {
+	d.mtx.Lock()
+	if d.closed {
+		d.mtx.Unlock()
+		return nil
+	}
+	d.closed = true
+	for id, t := range d.retries {
+		t.Stop()
+		delete(d.retries, id)
+	}
+	close(d.queue)
+	d.mtx.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		d.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// Publish creates a delivery of e for every subscription of the author of
+// the ad that wants its type. It does not wait for the deliveries.
+func (d *Dispatcher) Publish(ctx context.Context, e Event) {
+	if e.ID == "" {
+		e.ID = newEventID()
+	}
+	payload, err := json.Marshal(e)
+	if err != nil {
+		d.logger.ErrorContext(ctx, "webhook event not encoded", "event", e.Type, "error", err)
+		return
+	}
+
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	if d.closed {
+		return
+	}
+	for _, s := range d.subscriptions {
+		if s.UserID != e.Ad.AuthorID || !s.Wants(e.Type) {
+			continue
+		}
+		dl := d.addDelivery(Delivery{SubscriptionID: s.ID, EventID: e.ID, Event: e.Type, Payload: payload})
+		d.logger.InfoContext(ctx, "webhook delivery queued", "delivery_id", dl.ID, "webhook_id", s.ID, "event", e.Type, "ad_id", e.Ad.ID)
+	}
+}
+
+// Subscribe stores s with a new ID and CreatedAt.
+func (d *Dispatcher) Subscribe(ctx context.Context, s Subscription) (*Subscription, error) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	s.ID = d.nextSub
+	s.CreatedAt = d.now().UTC()
+	d.nextSub++
+	d.subscriptions[s.ID] = &s
+	res := s
+	return &res, nil
+}
+
+// Subscriptions returns the subscriptions of a user ordered by ID.
+func (d *Dispatcher) Subscriptions(ctx context.Context, userID int64) ([]Subscription, error) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	res := []Subscription{}
+	for id := int64(0); id < d.nextSub; id++ {
+		if s, ok := d.subscriptions[id]; ok && s.UserID == userID {
+			res = append(res, *s)
+		}
+	}
+	return res, nil
+}
+
+func (d *Dispatcher) Subscription(ctx context.Context, ID int64) (*Subscription, error) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	s, ok := d.subscriptions[ID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	res := *s
+	return &res, nil
+}
+
+// Unsubscribe deletes the subscription. Its pending deliveries fail at the
+// next attempt.
+func (d *Dispatcher) Unsubscribe(ctx context.Context, ID int64) (*Subscription, error) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	s, ok := d.subscriptions[ID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	delete(d.subscriptions, ID)
+	return s, nil
+}
+
+// Deliveries returns the logged deliveries of a subscription, the oldest
+// first.
+func (d *Dispatcher) Deliveries(ctx context.Context, subscriptionID int64) ([]Delivery, error) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	res := []Delivery{}
+	for _, id := range d.order {
+		if dl := d.deliveries[id]; dl.SubscriptionID == subscriptionID {
+			res = append(res, *dl)
+		}
+	}
+	return res, nil
+}
+
+func (d *Dispatcher) Delivery(ctx context.Context, ID int64) (*Delivery, error) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	dl, ok := d.deliveries[ID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	res := *dl
+	return &res, nil
+}
+
+// Replay sends the event of a finished delivery again as a new delivery
+// with a fresh set of attempts.
+func (d *Dispatcher) Replay(ctx context.Context, ID int64) (*Delivery, error) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	dl, ok := d.deliveries[ID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if dl.Status == StatusPending {
+		return nil, ErrPending
+	}
+	if _, ok := d.subscriptions[dl.SubscriptionID]; !ok {
+		return nil, ErrNotFound
+	}
+	res := d.addDelivery(Delivery{
+		SubscriptionID: dl.SubscriptionID,
+		EventID:        dl.EventID,
+		Event:          dl.Event,
+		ReplayOf:       dl.ID,
+		Payload:        dl.Payload,
+	})
+	d.logger.InfoContext(ctx, "webhook delivery replayed", "delivery_id", res.ID, "replay_of", ID)
+	return &res, nil
+}
+
+// addDelivery logs a pending delivery and queues it. d.mtx must be held.
+func (d *Dispatcher) addDelivery(dl Delivery) Delivery {
+	dl.ID = d.nextDelivery
+	dl.Status = StatusPending
+	dl.CreatedAt = d.now().UTC()
+	dl.UpdatedAt = dl.CreatedAt
+	d.nextDelivery++
+	d.deliveries[dl.ID] = &dl
+	d.order = append(d.order, dl.ID)
+	d.trim()
+	d.enqueue(dl.ID)
+	return dl
+}
+
+// trim drops the oldest finished deliveries above the history size. The
+// pending ones are kept, so the log may be longer while they are running.
+// d.mtx must be held.
+func (d *Dispatcher) trim() {
+	excess := len(d.order) - d.history
+	if excess <= 0 {
+		return
+	}
+	kept := d.order[:0]
+	for _, id := range d.order {
+		if excess > 0 && d.deliveries[id].Status != StatusPending {
+			delete(d.deliveries, id)
+			excess--
+			continue
+		}
+		kept = append(kept, id)
+	}
+	d.order = kept
+}
+
+// enqueue hands the delivery to the workers. When the queue is full it is
+// retried after the first backoff. d.mtx must be held.
+func (d *Dispatcher) enqueue(id int64) {
+	if d.closed {
+		return
+	}
+	select {
+	case d.queue <- id:
+	default:
+		d.schedule(id, d.backoff)
+	}
+}
+
+func (d *Dispatcher) schedule(id int64, after time.Duration) {
+	next := d.now().Add(after).UTC()
+	d.deliveries[id].NextAttemptAt = &next
+	d.retries[id] = time.AfterFunc(after, func() {
+		d.mtx.Lock()
+		defer d.mtx.Unlock()
+		delete(d.retries, id)
+		if _, ok := d.deliveries[id]; ok {
+			d.deliveries[id].NextAttemptAt = nil
+			d.enqueue(id)
+		}
+	})
+}
+
+func (d *Dispatcher) work() {
+	defer d.wg.Done()
+	for id := range d.queue {
+		d.attempt(id)
+	}
+}
+
+// attempt sends a delivery once and records the outcome.
+func (d *Dispatcher) attempt(id int64) {
+	d.mtx.Lock()
+	dl, ok := d.deliveries[id]
+	if !ok {
+		d.mtx.Unlock()
+		return
+	}
+	s, subscribed := d.subscriptions[dl.SubscriptionID]
+	var sub Subscription
+	if subscribed {
+		sub = *s
+	}
+	payload, event := dl.Payload, dl.Event
+	d.mtx.Unlock()
+
+	code, err := 0, fmt.Errorf("webhook %d was deleted", dl.SubscriptionID)
+	if subscribed {
+		code, err = d.send(sub, id, event, payload)
+	}
+
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	dl.Attempts++
+	dl.ResponseCode = code
+	dl.UpdatedAt = d.now().UTC()
+	if err == nil {
+		dl.Status = StatusSucceeded
+		dl.LastError = ""
+		d.logger.Info("webhook delivered", "delivery_id", id, "webhook_id", dl.SubscriptionID, "event", event, "attempt", dl.Attempts, "status", code)
+		return
+	}
+	dl.LastError = err.Error()
+	if !subscribed || dl.Attempts >= d.maxAttempts || d.closed {
+		dl.Status = StatusFailed
+		d.logger.Warn("webhook delivery failed", "delivery_id", id, "webhook_id", dl.SubscriptionID, "event", event, "attempt", dl.Attempts, "error", err)
+		return
+	}
+	backoff := d.backoffAfter(dl.Attempts)
+	d.schedule(id, backoff)
+	d.logger.Info("webhook attempt failed, retrying", "delivery_id", id, "webhook_id", dl.SubscriptionID, "event", event, "attempt", dl.Attempts, "retry_in", backoff, "error", err)
+}
+
+// backoffAfter is the wait after the given number of failed attempts.
+func (d *Dispatcher) backoffAfter(attempts int) time.Duration {
+	b := d.backoff
+	for i := 1; i < attempts && b < d.maxBackoff; i++ {
+		b *= 2
+	}
+	return min(b, d.maxBackoff)
+}
+
+// send POSTs the payload. Responses other than 2xx are errors.
+func (d *Dispatcher) send(s Subscription, id int64, event EventType, payload []byte) (int, error) {
+	req, err := http.NewRequestWithContext(d.ctx, http.MethodPost, s.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(d.now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "ads-webhooks")
+	req.Header.Set(EventHeader, string(event))
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(id, 10))
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, Sign(s.Secret, timestamp, payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver answered %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+func newEventID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,110 @@
+// Package webhooks notifies subscribers about changes of ads. Every event is
+// POSTed as JSON to the URL of each matching subscription, signed with the
+// subscription secret, see Sign.
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"homework10/internal/ads"
+	"slices"
+	"strings"
+	"time"
+)
+
+type EventType string
+
+const (
+	AdCreated     EventType = "ad.created"
+	AdPublished   EventType = "ad.published"
+	AdUnpublished EventType = "ad.unpublished"
+	AdUpdated     EventType = "ad.updated"
+	AdDeleted     EventType = "ad.deleted"
+)
+
+// EventTypes are all the events a subscription can receive.
+var EventTypes = []EventType{AdCreated, AdPublished, AdUnpublished, AdUpdated, AdDeleted}
+
+// Headers of a delivery. The signature covers the timestamp and the body,
+// so a captured delivery can't be sent again with another timestamp.
+const (
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+	TimestampHeader = "X-Webhook-Timestamp"
+	SignatureHeader = "X-Webhook-Signature"
+)
+
+var (
+	ErrNotFound = errors.New("webhooks: not found")
+	ErrPending  = errors.New("webhooks: delivery is not finished")
+)
+
+// Event is a change of an ad. It is the body of a delivery.
+type Event struct {
+	ID   string    `json:"id"`
+	Type EventType `json:"type" enums:"ad.created,ad.published,ad.unpublished,ad.updated,ad.deleted"`
+	Time time.Time `json:"time"`
+	Ad   ads.Ad    `json:"data"`
+}
+
+// Subscription receives the events of the given types about the ads of its
+// user.
+type Subscription struct {
+	ID        int64       `json:"id"`
+	UserID    int64       `json:"user_id"`
+	URL       string      `json:"url"`
+	Events    []EventType `json:"events" enums:"ad.created,ad.published,ad.unpublished,ad.updated,ad.deleted"`
+	Secret    string      `json:"-"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// Wants reports whether the subscription receives events of type t.
+func (s Subscription) Wants(t EventType) bool {
+	return slices.Contains(s.Events, t)
+}
+
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+)
+
+// Delivery is the log entry of sending an event to a subscription. It is
+// pending until the receiver answers 2xx or the attempts are used up.
+// ReplayOf is the ID of the delivery it was replayed from.
+type Delivery struct {
+	ID             int64      `json:"id"`
+	SubscriptionID int64      `json:"webhook_id"`
+	EventID        string     `json:"event_id"`
+	Event          EventType  `json:"event" enums:"ad.created,ad.published,ad.unpublished,ad.updated,ad.deleted"`
+	Status         Status     `json:"status" enums:"pending,succeeded,failed"`
+	Attempts       int        `json:"attempts"`
+	ResponseCode   int        `json:"response_code,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	ReplayOf       int64      `json:"replay_of,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"`
+	Payload        []byte     `json:"-"`
+}
+
+// Sign returns the value of SignatureHeader: "sha256=" followed by the hex
+// HMAC-SHA256 of timestamp, a dot and body, keyed with secret.
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks signature in constant time. Receivers should also reject
+// timestamps that are too old.
+func Verify(secret string, timestamp string, body []byte, signature string) bool {
+	want := Sign(secret, timestamp, body)
+	return strings.HasPrefix(signature, "sha256=") && hmac.Equal([]byte(want), []byte(signature))
+}
@@ -0,0 +1,340 @@
+package adsclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+type CreateAdRequest struct {
+	UserID   int64     `json:"user_id"`
+	Title    string    `json:"title"`
+	Text     string    `json:"text"`
+	Location *Location `json:"location,omitempty"`
+	Offer    *Offer    `json:"offer,omitempty"`
+}
+
+// UpdateAdRequest replaces the title and the text of an ad. Empty fields
+// keep the current values, a nil Location or Offer keeps the current one.
+type UpdateAdRequest struct {
+	UserID   int64     `json:"user_id"`
+	Title    string    `json:"title"`
+	Text     string    `json:"text"`
+	Location *Location `json:"location,omitempty"`
+	Offer    *Offer    `json:"offer,omitempty"`
+}
+
+// CreateAd creates an unpublished ad of r.UserID.
+func (c *Client) CreateAd(ctx context.Context, r CreateAdRequest) (*Ad, error) {
+	req, err := jsonRequest(http.MethodPost, "/ads", r)
+	if err != nil {
+		return nil, err
+	}
+	var ad Ad
+	if err := c.do(ctx, req, &ad); err != nil {
+		return nil, err
+	}
+	return &ad, nil
+}
+
+// GetAd returns the ad ID. The request counts as a view of the ad by the
+// address of the client.
+func (c *Client) GetAd(ctx context.Context, ID int64) (*Ad, error) {
+	return c.getAd(ctx, ID, nil)
+}
+
+func (c *Client) getAd(ctx context.Context, ID int64, query url.Values) (*Ad, error) {
+	var ad Ad
+	req := request{method: http.MethodGet, path: fmt.Sprintf("/ads/%d", ID), query: query}
+	if err := c.do(ctx, req, &ad); err != nil {
+		return nil, err
+	}
+	return &ad, nil
+}
+
+// UpdateAd changes the ad ID of the author r.UserID.
+func (c *Client) UpdateAd(ctx context.Context, ID int64, r UpdateAdRequest) (*Ad, error) {
+	req, err := jsonRequest(http.MethodPut, fmt.Sprintf("/ads/%d", ID), r)
+	if err != nil {
+		return nil, err
+	}
+	var ad Ad
+	if err := c.do(ctx, req, &ad); err != nil {
+		return nil, err
+	}
+	return &ad, nil
+}
+
+// ChangeAdStatus publishes or unpublishes the ad ID of the author userID.
+func (c *Client) ChangeAdStatus(ctx context.Context, ID int64, userID int64, published bool) (*Ad, error) {
+	req, err := jsonRequest(http.MethodPut, fmt.Sprintf("/ads/%d/status", ID), map[string]any{
+		"user_id":   userID,
+		"published": published,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var ad Ad
+	if err := c.do(ctx, req, &ad); err != nil {
+		return nil, err
+	}
+	return &ad, nil
+}
+
+// DeleteAd deletes the ad ID of the author authorID and returns it.
+func (c *Client) DeleteAd(ctx context.Context, ID int64, authorID int64) (*Ad, error) {
+	var ad Ad
+	req := request{
+		method: http.MethodDelete,
+		path:   fmt.Sprintf("/ads/%d", ID),
+		query:  url.Values{"author": {strconv.FormatInt(authorID, 10)}},
+	}
+	if err := c.do(ctx, req, &ad); err != nil {
+		return nil, err
+	}
+	return &ad, nil
+}
+
+// FavoriteAd adds the ad ID to the favorites of userID.
+func (c *Client) FavoriteAd(ctx context.Context, ID int64, userID int64) (*Ad, error) {
+	var ad Ad
+	req := request{method: http.MethodPut, path: fmt.Sprintf("/ads/%d/favorites/%d", ID, userID)}
+	if err := c.do(ctx, req, &ad); err != nil {
+		return nil, err
+	}
+	return &ad, nil
+}
+
+// UnfavoriteAd removes the ad ID from the favorites of userID.
+func (c *Client) UnfavoriteAd(ctx context.Context, ID int64, userID int64) (*Ad, error) {
+	var ad Ad
+	req := request{method: http.MethodDelete, path: fmt.Sprintf("/ads/%d/favorites/%d", ID, userID)}
+	if err := c.do(ctx, req, &ad); err != nil {
+		return nil, err
+	}
+	return &ad, nil
+}
+
+// SendMessage sends text from senderID to the author of the ad ID.
+func (c *Client) SendMessage(ctx context.Context, ID int64, senderID int64, text string) (*Message, error) {
+	req, err := jsonRequest(http.MethodPost, fmt.Sprintf("/ads/%d/messages", ID), map[string]any{
+		"user_id": senderID,
+		"text":    text,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var m Message
+	if err := c.do(ctx, req, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// AdMessages returns the messages about the ad ID to its author authorID,
+// the oldest first.
+func (c *Client) AdMessages(ctx context.Context, ID int64, authorID int64) ([]Message, error) {
+	var res []Message
+	req := request{
+		method: http.MethodGet,
+		path:   fmt.Sprintf("/ads/%d/messages", ID),
+		query:  url.Values{"author": {strconv.FormatInt(authorID, 10)}},
+	}
+	if err := c.do(ctx, req, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// selectAds is the filter of GET /ads, the first one set is applied.
+type selectAds struct {
+	ByAuthor     bool      `json:"by_author"`
+	AuthorID     int64     `json:"author_id"`
+	ByCreation   bool      `json:"by_creation"`
+	CreationTime time.Time `json:"creation_time"`
+	All          bool      `json:"all"`
+}
+
+func (c *Client) selectAds(ctx context.Context, s selectAds) ([]Ad, error) {
+	req, err := jsonRequest(http.MethodGet, "/ads", s)
+	if err != nil {
+		return nil, err
+	}
+	var arr []Ad
+	if err := c.do(ctx, req, &arr); err != nil {
+		return nil, err
+	}
+	return arr, nil
+}
+
+// ListAds returns the published ads ordered by ID.
+func (c *Client) ListAds(ctx context.Context) ([]Ad, error) {
+	return c.selectAds(ctx, selectAds{})
+}
+
+// ListAllAds returns the published and unpublished ads.
+func (c *Client) ListAllAds(ctx context.Context) ([]Ad, error) {
+	return c.selectAds(ctx, selectAds{All: true})
+}
+
+// ListAdsByAuthor returns the ads of the author, published or not.
+func (c *Client) ListAdsByAuthor(ctx context.Context, authorID int64) ([]Ad, error) {
+	return c.selectAds(ctx, selectAds{ByAuthor: true, AuthorID: authorID})
+}
+
+// ListAdsCreatedAfter returns the published ads created after t.
+func (c *Client) ListAdsCreatedAfter(ctx context.Context, t time.Time) ([]Ad, error) {
+	return c.selectAds(ctx, selectAds{ByCreation: true, CreationTime: t})
+}
+
+// FindAdsByTitle returns the published ads with title in their title.
+func (c *Client) FindAdsByTitle(ctx context.Context, title string) ([]Ad, error) {
+	var arr []Ad
+	req := request{method: http.MethodGet, path: "/ads/title", query: url.Values{"title": {title}}}
+	if err := c.do(ctx, req, &arr); err != nil {
+		return nil, err
+	}
+	return arr, nil
+}
+
+// MostViewedAds returns the published ads with the most views first. A
+// limit of 0 uses the default of the server.
+func (c *Client) MostViewedAds(ctx context.Context, limit int) ([]ViewedAd, error) {
+	var arr []ViewedAd
+	req := request{method: http.MethodGet, path: "/ads/most-viewed", query: limitQuery(url.Values{}, limit)}
+	if err := c.do(ctx, req, &arr); err != nil {
+		return nil, err
+	}
+	return arr, nil
+}
+
+// SimilarQuery are the options of SimilarAds.
+type SimilarQuery struct {
+	// ViewerID skips the ads of the viewer when set.
+	ViewerID *int64
+	// Limit of 0 uses the default of the server.
+	Limit int
+}
+
+// SimilarAds returns the published ads similar to the ad ID, the most
+// similar first.
+func (c *Client) SimilarAds(ctx context.Context, ID int64, q SimilarQuery) ([]SimilarAd, error) {
+	query := url.Values{}
+	if q.ViewerID != nil {
+		query.Set("viewer_id", strconv.FormatInt(*q.ViewerID, 10))
+	}
+	var arr []SimilarAd
+	req := request{method: http.MethodGet, path: fmt.Sprintf("/ads/%d/similar", ID), query: limitQuery(query, q.Limit)}
+	if err := c.do(ctx, req, &arr); err != nil {
+		return nil, err
+	}
+	return arr, nil
+}
+
+// Circle is the area within RadiusKm of a point.
+type Circle struct {
+	Lat      float64
+	Lon      float64
+	RadiusKm float64
+}
+
+// Box is the area between the latitudes and the longitudes. A box with
+// MinLon greater than MaxLon crosses the antimeridian.
+type Box struct {
+	MinLat float64
+	MinLon float64
+	MaxLat float64
+	MaxLon float64
+}
+
+// NearbyQuery selects the ads of SearchNearby. Circle or Box is required,
+// the other fields are optional.
+type NearbyQuery struct {
+	Circle       *Circle
+	Box          *Box
+	AuthorID     *int64
+	CreatedAfter time.Time
+	Title        string
+	// All finds unpublished ads too.
+	All bool
+	// SortByDistance orders the ads by the distance to the center of
+	// Circle instead of by ID.
+	SortByDistance bool
+	// Limit of 0 uses the default of the server.
+	Limit int
+}
+
+func (q NearbyQuery) values() url.Values {
+	v := url.Values{}
+	float := func(name string, f float64) {
+		v.Set(name, strconv.FormatFloat(f, 'f', -1, 64))
+	}
+	if q.Circle != nil {
+		float("lat", q.Circle.Lat)
+		float("lon", q.Circle.Lon)
+		float("radius_km", q.Circle.RadiusKm)
+	}
+	if q.Box != nil {
+		float("min_lat", q.Box.MinLat)
+		float("min_lon", q.Box.MinLon)
+		float("max_lat", q.Box.MaxLat)
+		float("max_lon", q.Box.MaxLon)
+	}
+	if q.AuthorID != nil {
+		v.Set("author_id", strconv.FormatInt(*q.AuthorID, 10))
+	}
+	if !q.CreatedAfter.IsZero() {
+		v.Set("created_after", q.CreatedAfter.Format(time.RFC3339))
+	}
+	if q.Title != "" {
+		v.Set("title", q.Title)
+	}
+	if q.All {
+		v.Set("all", "true")
+	}
+	if q.SortByDistance {
+		v.Set("sort", "distance")
+	}
+	return limitQuery(v, q.Limit)
+}
+
+// SearchNearby returns the ads located in the area of q.
+func (c *Client) SearchNearby(ctx context.Context, q NearbyQuery) ([]NearbyAd, error) {
+	var arr []NearbyAd
+	req := request{method: http.MethodGet, path: "/ads/nearby", query: q.values()}
+	if err := c.do(ctx, req, &arr); err != nil {
+		return nil, err
+	}
+	return arr, nil
+}
+
+// BulkRequest applies Operations to the ads of UserID. An Atomic request
+// changes nothing when an operation fails.
+type BulkRequest struct {
+	UserID     int64           `json:"user_id"`
+	Atomic     bool            `json:"atomic"`
+	Operations []BulkOperation `json:"operations"`
+}
+
+// BulkAds applies the operations of r and returns a result per operation.
+func (c *Client) BulkAds(ctx context.Context, r BulkRequest) ([]BulkResult, error) {
+	req, err := jsonRequest(http.MethodPost, "/ads/bulk", r)
+	if err != nil {
+		return nil, err
+	}
+	var res []BulkResult
+	if err := c.do(ctx, req, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func limitQuery(v url.Values, limit int) url.Values {
+	if limit != 0 {
+		v.Set("limit", strconv.Itoa(limit))
+	}
+	return v
+}
@@ -0,0 +1,116 @@
+package adsclient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Format is the file format of ExportAds and ImportAds.
+type Format string
+
+const (
+	FormatCSV    Format = "csv"
+	FormatNDJSON Format = "ndjson"
+)
+
+// ExportQuery selects the exported ads, the first filter set is applied.
+// Without filters the published ads are exported.
+type ExportQuery struct {
+	// Format is CSV when empty.
+	Format       Format
+	AuthorID     *int64
+	CreatedAfter time.Time
+	// All exports unpublished ads too.
+	All   bool
+	Title string
+}
+
+func (q ExportQuery) values() url.Values {
+	v := url.Values{}
+	if q.Format != "" {
+		v.Set("format", string(q.Format))
+	}
+	switch {
+	case q.AuthorID != nil:
+		v.Set("author_id", strconv.FormatInt(*q.AuthorID, 10))
+	case !q.CreatedAfter.IsZero():
+		v.Set("created_after", q.CreatedAfter.Format(time.RFC3339))
+	case q.All:
+		v.Set("all", "true")
+	case q.Title != "":
+		v.Set("title", q.Title)
+	}
+	return v
+}
+
+// ExportAds streams the ads selected by q in the format of q. The caller
+// must close the returned reader.
+func (c *Client) ExportAds(ctx context.Context, q ExportQuery) (io.ReadCloser, error) {
+	resp, err := c.send(ctx, request{method: http.MethodGet, path: "/ads/export", query: q.values()})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// ImportRequest is a file of ads of UserID. Format is taken from the
+// extension of FileName when empty. A DryRun import only checks the rows.
+type ImportRequest struct {
+	UserID   int64
+	DryRun   bool
+	Format   Format
+	FileName string
+	File     io.Reader
+}
+
+// ImportAds creates an ad per row of the file and reports the failed rows.
+// The file is streamed, so the request is never retried.
+func (c *Client) ImportAds(ctx context.Context, r ImportRequest) (*ImportReport, error) {
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	go func() {
+		pw.CloseWithError(writeImport(mw, r))
+	}()
+	defer pr.Close()
+	req := request{method: http.MethodPost, path: "/ads/import", stream: pr, contentType: mw.FormDataContentType()}
+	var report ImportReport
+	if err := c.do(ctx, req, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// writeImport writes the form of r, the server needs the fields before the
+// file.
+func writeImport(mw *multipart.Writer, r ImportRequest) error {
+	fields := [][2]string{
+		{"user_id", strconv.FormatInt(r.UserID, 10)},
+		{"dry_run", strconv.FormatBool(r.DryRun)},
+	}
+	if r.Format != "" {
+		fields = append(fields, [2]string{"format", string(r.Format)})
+	}
+	for _, f := range fields {
+		if err := mw.WriteField(f[0], f[1]); err != nil {
+			return err
+		}
+	}
+	name := r.FileName
+	if name == "" {
+		name = "ads"
+	}
+	part, err := mw.CreateFormFile("file", name)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, r.File); err != nil {
+		return fmt.Errorf("adsclient: read import file: %w", err)
+	}
+	return mw.Close()
+}
@@ -0,0 +1,271 @@
+// Package adsclient is a client of the v1 HTTP API of the ads service.
+//
+// Every method takes a context and returns the typed data of the response,
+// failed requests return an *Error with the problem details sent by the
+// server:
+//
+//	c, err := adsclient.New("http://localhost:18080")
+//	...
+//	ad, err := c.GetAd(ctx, 1)
+//	if errors.Is(err, adsclient.ErrNotFound) {
+//		...
+//	}
+//
+// GET, PUT and DELETE requests are retried with exponential backoff on
+// network errors and on responses the server marks as retryable. POST
+// requests are retried only with an idempotency key, see
+// WithIdempotencyKey.
+package adsclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultPath is the path of the API used when the base URL has none.
+const DefaultPath = "/api/v1"
+
+const (
+	DefaultRetries    = 3
+	DefaultMinBackoff = 100 * time.Millisecond
+	DefaultMaxBackoff = 2 * time.Second
+)
+
+// IdempotencyKeyHeader is the header that makes the server replay the first
+// response to a retried POST.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// Client calls the API. It is safe for concurrent use.
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	retries    int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+}
+
+type Option func(*Client)
+
+// WithHTTPClient sets the client that sends the requests. By default
+// http.DefaultClient is used.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithRetries sets how many times a failed idempotent request is retried,
+// 0 disables retries.
+func WithRetries(n int) Option {
+	return func(c *Client) {
+		c.retries = max(n, 0)
+	}
+}
+
+// WithBackoff sets the delay before the first retry and the limit of the
+// delay, that doubles with every retry.
+func WithBackoff(first, limit time.Duration) Option {
+	return func(c *Client) {
+		c.minBackoff = first
+		c.maxBackoff = limit
+	}
+}
+
+// New returns a client of the API at baseURL, e.g. "http://localhost:18080"
+// or "https://ads.example.com/api/v1".
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("adsclient: invalid base URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("adsclient: base URL %q must be an absolute http or https URL", baseURL)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	if u.Path == "" {
+		u.Path = DefaultPath
+	}
+	c := &Client{
+		baseURL:    u,
+		httpClient: http.DefaultClient,
+		retries:    DefaultRetries,
+		minBackoff: DefaultMinBackoff,
+		maxBackoff: DefaultMaxBackoff,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+type idempotencyKey struct{}
+
+// WithIdempotencyKey returns a context that sends key in the
+// Idempotency-Key header of POST requests. The server answers the retries
+// of such a request with the first response, so they are retried like the
+// other methods. The key must be unique per operation.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKey{}, key)
+}
+
+// request describes a call of the API. The body is kept in memory, so the
+// request can be sent again, unless it is streamed.
+type request struct {
+	method      string
+	path        string
+	query       url.Values
+	body        []byte
+	stream      io.Reader
+	contentType string
+}
+
+// jsonRequest returns a request with in encoded as the JSON body.
+func jsonRequest(method, path string, in any) (request, error) {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return request{}, fmt.Errorf("adsclient: encode request: %w", err)
+	}
+	return request{method: method, path: path, body: body, contentType: "application/json"}, nil
+}
+
+func (c *Client) url(path string, query url.Values) string {
+	u := *c.baseURL
+	u.Path += path
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+func (c *Client) newHTTPRequest(ctx context.Context, r request, key string) (*http.Request, error) {
+	body := r.stream
+	if r.body != nil {
+		body = bytes.NewReader(r.body)
+	}
+	req, err := http.NewRequestWithContext(ctx, r.method, c.url(r.path, r.query), body)
+	if err != nil {
+		return nil, fmt.Errorf("adsclient: %w", err)
+	}
+	if r.contentType != "" {
+		req.Header.Set("Content-Type", r.contentType)
+	}
+	req.Header.Set("Accept", "application/json")
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	return req, nil
+}
+
+// do sends r, retrying it when it is idempotent, and decodes the data of a
+// successful response into out.
+func (c *Client) do(ctx context.Context, r request, out any) error {
+	resp, err := c.send(ctx, r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return decode(resp, out)
+}
+
+// send sends r and returns a successful response. Failed attempts are
+// retried while the request is idempotent and the error is temporary.
+func (c *Client) send(ctx context.Context, r request) (*http.Response, error) {
+	key, _ := ctx.Value(idempotencyKey{}).(string)
+	if r.method != http.MethodPost {
+		key = ""
+	}
+	idempotent := r.stream == nil && (r.method != http.MethodPost || key != "")
+	for attempt := 0; ; attempt++ {
+		req, err := c.newHTTPRequest(ctx, r, key)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := c.httpClient.Do(req)
+		var retryAfter time.Duration
+		if err == nil {
+			if resp.StatusCode < http.StatusBadRequest {
+				return resp, nil
+			}
+			err = readError(resp)
+			retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+			resp.Body.Close()
+		} else if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if !idempotent || attempt >= c.retries || !temporary(err) {
+			return nil, err
+		}
+		t := time.NewTimer(max(c.backoff(attempt), retryAfter))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// backoff returns the delay before the retry after the attempt, a random
+// duration between the half and the whole of the doubled delay.
+func (c *Client) backoff(attempt int) time.Duration {
+	d := c.minBackoff
+	for i := 0; i < attempt && d < c.maxBackoff; i++ {
+		d *= 2
+	}
+	d = min(d, c.maxBackoff)
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// temporary reports whether a request that failed with err may succeed
+// when sent again.
+func temporary(err error) bool {
+	var e *Error
+	if !errors.As(err, &e) {
+		// the response was not received
+		return true
+	}
+	switch e.Status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return e.Retryable
+}
+
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if s, err := strconv.Atoi(v); err == nil && s > 0 {
+		return time.Duration(s) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+// decode reads the data field of a successful response into out.
+func decode(resp *http.Response, out any) error {
+	if out == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	envelope := struct {
+		Data any `json:"data"`
+	}{out}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("adsclient: decode response: %w", err)
+	}
+	return nil
+}
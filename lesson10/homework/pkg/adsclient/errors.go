@@ -0,0 +1,129 @@
+package adsclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// Code classifies an Error, it is the code of the problem details sent by
+// the server.
+type Code string
+
+const (
+	CodeInvalidArgument  Code = "invalid_argument"
+	CodeNotFound         Code = "not_found"
+	CodePermissionDenied Code = "permission_denied"
+	CodeUnavailable      Code = "unavailable"
+	CodeDeadlineExceeded Code = "deadline_exceeded"
+	CodeCanceled         Code = "canceled"
+	CodeConflict         Code = "conflict"
+	CodeUnprocessable    Code = "unprocessable"
+	CodeUnauthenticated  Code = "unauthenticated"
+	CodeUnimplemented    Code = "unimplemented"
+	CodeTooLarge         Code = "too_large"
+	CodeInternal         Code = "internal"
+)
+
+// FieldViolation describes a single invalid field of a request.
+type FieldViolation struct {
+	Field       string `json:"field"`
+	Description string `json:"description"`
+}
+
+// Error is a failed response of the API. The fields are the RFC 7807
+// problem details of the response, a response without them gets the Code
+// of its status.
+type Error struct {
+	Status        int              `json:"status"`
+	Type          string           `json:"type"`
+	Title         string           `json:"title"`
+	Detail        string           `json:"detail"`
+	Instance      string           `json:"instance"`
+	Code          Code             `json:"code"`
+	Retryable     bool             `json:"retryable"`
+	InvalidParams []FieldViolation `json:"invalid_params"`
+}
+
+func (e *Error) Error() string {
+	if e.Detail == "" {
+		return fmt.Sprintf("adsclient: %d %s", e.Status, e.Title)
+	}
+	return fmt.Sprintf("adsclient: %d %s: %s", e.Status, e.Title, e.Detail)
+}
+
+// Is reports whether target is an *Error with the same Code, so that
+// errors.Is(err, ErrNotFound) holds for every not found error.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+var (
+	ErrInvalidArgument  = &Error{Code: CodeInvalidArgument}
+	ErrNotFound         = &Error{Code: CodeNotFound}
+	ErrPermissionDenied = &Error{Code: CodePermissionDenied}
+	ErrUnavailable      = &Error{Code: CodeUnavailable}
+	ErrDeadlineExceeded = &Error{Code: CodeDeadlineExceeded}
+	ErrConflict         = &Error{Code: CodeConflict}
+	ErrUnprocessable    = &Error{Code: CodeUnprocessable}
+	ErrUnauthenticated  = &Error{Code: CodeUnauthenticated}
+	ErrUnimplemented    = &Error{Code: CodeUnimplemented}
+	ErrTooLarge         = &Error{Code: CodeTooLarge}
+	ErrInternal         = &Error{Code: CodeInternal}
+)
+
+// statusCode returns the code of a response with status that has no
+// problem details, e.g. of a proxy.
+func statusCode(status int) Code {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeInvalidArgument
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusForbidden:
+		return CodePermissionDenied
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable:
+		return CodeUnavailable
+	case http.StatusGatewayTimeout:
+		return CodeDeadlineExceeded
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusUnprocessableEntity:
+		return CodeUnprocessable
+	case http.StatusUnauthorized:
+		return CodeUnauthenticated
+	case http.StatusMethodNotAllowed:
+		return CodeUnimplemented
+	case http.StatusRequestEntityTooLarge:
+		return CodeTooLarge
+	default:
+		return CodeInternal
+	}
+}
+
+// maxErrorBody limits the body of a failed response that is read.
+const maxErrorBody = 64 << 10
+
+// readError returns the *Error of a failed response.
+func readError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	e := &Error{}
+	mt, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mt == "application/problem+json" {
+		_ = json.Unmarshal(body, e)
+	} else {
+		e.Detail = strings.TrimSpace(string(body))
+	}
+	e.Status = resp.StatusCode
+	if e.Title == "" {
+		e.Title = http.StatusText(resp.StatusCode)
+	}
+	if e.Code == "" {
+		e.Code = statusCode(resp.StatusCode)
+	}
+	return e
+}
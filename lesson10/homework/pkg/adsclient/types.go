@@ -0,0 +1,204 @@
+package adsclient
+
+import "time"
+
+// Location is where the item of an ad is.
+type Location struct {
+	Lat  float64 `json:"lat"`
+	Lon  float64 `json:"lon"`
+	City string  `json:"city,omitempty"`
+}
+
+type Ad struct {
+	ID           int64     `json:"id"`
+	Title        string    `json:"title"`
+	Text         string    `json:"text"`
+	AuthorID     int64     `json:"author_id"`
+	Published    bool      `json:"published"`
+	CreationTime time.Time `json:"creation_time"`
+	UpdateTime   time.Time `json:"update_time"`
+	// Location is nil when unknown.
+	Location *Location `json:"location,omitempty"`
+	// Offer is nil when unknown.
+	Offer *Offer `json:"offer,omitempty"`
+}
+
+// Offer is the category of the item of an ad and its price in the minor
+// units of the currency.
+type Offer struct {
+	Category string `json:"category"`
+	Price    int64  `json:"price"`
+}
+
+type User struct {
+	ID       int64  `json:"id"`
+	Nickname string `json:"nickname"`
+	Email    string `json:"email"`
+}
+
+// ViewedAd is an ad with the number of its views.
+type ViewedAd struct {
+	Ad
+	Views int64 `json:"views"`
+}
+
+// SimilarAd is an ad with its similarity to another one, from 0 to 1.
+type SimilarAd struct {
+	Ad
+	Score float64 `json:"score"`
+}
+
+// NearbyAd is an ad with its distance to the center of the search.
+type NearbyAd struct {
+	Ad
+	DistanceKm float64 `json:"distance_km"`
+}
+
+// ViewBucket is the number of views in an hour or a day.
+type ViewBucket struct {
+	Start time.Time `json:"start"`
+	Views int64     `json:"views"`
+}
+
+type AdViews struct {
+	AdID  int64 `json:"ad_id"`
+	Views int64 `json:"views"`
+}
+
+// SellerViews are the views of all ads of a seller.
+type SellerViews struct {
+	Total  int64        `json:"total"`
+	Today  int64        `json:"today"`
+	Hourly []ViewBucket `json:"hourly"`
+	Daily  []ViewBucket `json:"daily"`
+	PerAd  []AdViews    `json:"per_ad"`
+}
+
+// UserStats are the statistics of the ads of a seller.
+type UserStats struct {
+	UserID      int64       `json:"user_id"`
+	Ads         int         `json:"ads"`
+	Published   int         `json:"published"`
+	Unpublished int         `json:"unpublished"`
+	ViewedAds   int         `json:"viewed_ads"`
+	Conversion  float64     `json:"conversion"`
+	Favorites   int64       `json:"favorites"`
+	Messages    int64       `json:"messages"`
+	Views       SellerViews `json:"views"`
+}
+
+// Message is a message to the author of an ad.
+type Message struct {
+	ID       int64     `json:"id"`
+	AdID     int64     `json:"ad_id"`
+	SenderID int64     `json:"sender_id"`
+	Text     string    `json:"text"`
+	Time     time.Time `json:"time"`
+}
+
+// BulkAction is the action of a BulkOperation.
+type BulkAction string
+
+const (
+	BulkPublish   BulkAction = "publish"
+	BulkUnpublish BulkAction = "unpublish"
+	BulkUpdate    BulkAction = "update"
+	BulkDelete    BulkAction = "delete"
+)
+
+// BulkOperation is an action on an ad, Title and Text are used by updates.
+type BulkOperation struct {
+	Action BulkAction `json:"action"`
+	AdID   int64      `json:"ad_id"`
+	Title  string     `json:"title,omitempty"`
+	Text   string     `json:"text,omitempty"`
+}
+
+// OperationError is the error of a single operation of a bulk request or a
+// row of an import.
+type OperationError struct {
+	Code          Code             `json:"code"`
+	Detail        string           `json:"detail"`
+	Retryable     bool             `json:"retryable"`
+	InvalidParams []FieldViolation `json:"invalid_params,omitempty"`
+}
+
+func (e *OperationError) Error() string {
+	return e.Detail
+}
+
+// Is lets errors.Is match an OperationError with the sentinel errors by
+// Code, like an *Error.
+func (e *OperationError) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// BulkResult is the result of a BulkOperation, Ad is the changed ad or
+// Error tells why the operation failed.
+type BulkResult struct {
+	AdID  int64           `json:"ad_id"`
+	Ad    *Ad             `json:"ad,omitempty"`
+	Error *OperationError `json:"error,omitempty"`
+}
+
+// ImportError is the error of a row of an import, Line counts from 1.
+type ImportError struct {
+	Line int `json:"line"`
+	OperationError
+}
+
+// ImportReport is the result of an import.
+type ImportReport struct {
+	Created int           `json:"created"`
+	Failed  int           `json:"failed"`
+	DryRun  bool          `json:"dry_run"`
+	Errors  []ImportError `json:"errors"`
+}
+
+// EventType is an event webhooks are subscribed to.
+type EventType string
+
+const (
+	AdCreated     EventType = "ad.created"
+	AdPublished   EventType = "ad.published"
+	AdUnpublished EventType = "ad.unpublished"
+	AdUpdated     EventType = "ad.updated"
+	AdDeleted     EventType = "ad.deleted"
+)
+
+// Webhook is a subscription to events of the ads of a user. Secret is only
+// returned by CreateWebhook.
+type Webhook struct {
+	ID        int64       `json:"id"`
+	UserID    int64       `json:"user_id"`
+	URL       string      `json:"url"`
+	Events    []EventType `json:"events"`
+	Secret    string      `json:"secret,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// DeliveryStatus is the state of a Delivery.
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+// Delivery is an event sent to a webhook.
+type Delivery struct {
+	ID            int64          `json:"id"`
+	WebhookID     int64          `json:"webhook_id"`
+	EventID       string         `json:"event_id"`
+	Event         EventType      `json:"event"`
+	Status        DeliveryStatus `json:"status"`
+	Attempts      int            `json:"attempts"`
+	ResponseCode  int            `json:"response_code,omitempty"`
+	LastError     string         `json:"last_error,omitempty"`
+	ReplayOf      int64          `json:"replay_of,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	NextAttemptAt *time.Time     `json:"next_attempt_at,omitempty"`
+}
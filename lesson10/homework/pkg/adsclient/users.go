@@ -0,0 +1,55 @@
+package adsclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+type userRequest struct {
+	Nickname string `json:"nickname"`
+	Email    string `json:"email"`
+}
+
+func (c *Client) user(ctx context.Context, req request) (*User, error) {
+	var u User
+	if err := c.do(ctx, req, &u); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (c *Client) CreateUser(ctx context.Context, nickname string, email string) (*User, error) {
+	req, err := jsonRequest(http.MethodPost, "/users", userRequest{nickname, email})
+	if err != nil {
+		return nil, err
+	}
+	return c.user(ctx, req)
+}
+
+// UpdateUser changes the user ID, empty fields keep the current values.
+func (c *Client) UpdateUser(ctx context.Context, ID int64, nickname string, email string) (*User, error) {
+	req, err := jsonRequest(http.MethodPut, fmt.Sprintf("/users/%d", ID), userRequest{nickname, email})
+	if err != nil {
+		return nil, err
+	}
+	return c.user(ctx, req)
+}
+
+func (c *Client) GetUser(ctx context.Context, ID int64) (*User, error) {
+	return c.user(ctx, request{method: http.MethodGet, path: fmt.Sprintf("/users/%d", ID)})
+}
+
+// DeleteUser deletes the user ID with its webhooks and returns the user.
+func (c *Client) DeleteUser(ctx context.Context, ID int64) (*User, error) {
+	return c.user(ctx, request{method: http.MethodDelete, path: fmt.Sprintf("/users/%d", ID)})
+}
+
+// UserStats returns the statistics of the ads of the user ID.
+func (c *Client) UserStats(ctx context.Context, ID int64) (*UserStats, error) {
+	var s UserStats
+	if err := c.do(ctx, request{method: http.MethodGet, path: fmt.Sprintf("/users/%d/stats", ID)}, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
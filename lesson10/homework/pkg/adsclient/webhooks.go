@@ -0,0 +1,68 @@
+package adsclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// CreateWebhookRequest subscribes URL to Events. Without Secret the server
+// generates one.
+type CreateWebhookRequest struct {
+	URL    string      `json:"url"`
+	Events []EventType `json:"events"`
+	Secret string      `json:"secret,omitempty"`
+}
+
+// CreateWebhook subscribes to events of the ads of the user userID. The
+// returned webhook has the secret that signs the deliveries.
+func (c *Client) CreateWebhook(ctx context.Context, userID int64, r CreateWebhookRequest) (*Webhook, error) {
+	req, err := jsonRequest(http.MethodPost, fmt.Sprintf("/users/%d/webhooks", userID), r)
+	if err != nil {
+		return nil, err
+	}
+	var w Webhook
+	if err := c.do(ctx, req, &w); err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+func (c *Client) ListWebhooks(ctx context.Context, userID int64) ([]Webhook, error) {
+	var arr []Webhook
+	if err := c.do(ctx, request{method: http.MethodGet, path: fmt.Sprintf("/users/%d/webhooks", userID)}, &arr); err != nil {
+		return nil, err
+	}
+	return arr, nil
+}
+
+// DeleteWebhook deletes the webhook ID of the user userID and returns it.
+func (c *Client) DeleteWebhook(ctx context.Context, userID int64, ID int64) (*Webhook, error) {
+	var w Webhook
+	if err := c.do(ctx, request{method: http.MethodDelete, path: fmt.Sprintf("/users/%d/webhooks/%d", userID, ID)}, &w); err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+// ListDeliveries returns the latest deliveries of the webhook, the oldest
+// first.
+func (c *Client) ListDeliveries(ctx context.Context, userID int64, webhookID int64) ([]Delivery, error) {
+	var arr []Delivery
+	req := request{method: http.MethodGet, path: fmt.Sprintf("/users/%d/webhooks/%d/deliveries", userID, webhookID)}
+	if err := c.do(ctx, req, &arr); err != nil {
+		return nil, err
+	}
+	return arr, nil
+}
+
+// ReplayDelivery sends the event of a finished delivery again and returns
+// the new delivery.
+func (c *Client) ReplayDelivery(ctx context.Context, userID int64, webhookID int64, ID int64) (*Delivery, error) {
+	var d Delivery
+	req := request{method: http.MethodPost, path: fmt.Sprintf("/users/%d/webhooks/%d/deliveries/%d/replay", userID, webhookID, ID)}
+	if err := c.do(ctx, req, &d); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}